@@ -0,0 +1,387 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/auth"
+	"github.com/replicatedhq/kots/pkg/dbbackup"
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/print"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+func AdminConsoleDBCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage backups of the Admin Console database",
+		Long: `Backups of the Admin Console database are stored in the same object storage or volume as app version archives.
+Scheduled backups are enabled by setting the "db-backup-schedule" key of the kotsadm-confg config map to a cron expression,
+and the "db-backup-retention-count" key to the number of backups to keep (defaults to 7).`,
+	}
+
+	cmd.AddCommand(AdminConsoleDBBackupCmd())
+	cmd.AddCommand(AdminConsoleDBListCmd())
+	cmd.AddCommand(AdminConsoleDBRestoreCmd())
+
+	return cmd
+}
+
+func AdminConsoleDBBackupCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "backup [namespace]",
+		Short:         "Take a backup of the Admin Console database",
+		Long:          "",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			namespace, err := getNamespaceFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			b, statusCode, err := callKotsadmAPI(namespace, "POST", "/api/v1/db/backups", stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to take database backup")
+			}
+
+			response := handlers.CreateDBBackupResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if statusCode != http.StatusCreated || response.Backup == nil {
+				return errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+			}
+
+			log.ActionWithoutSpinner("Created database backup %s", response.Backup.Name)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func AdminConsoleDBListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list [namespace]",
+		Aliases:       []string{"ls"},
+		Short:         "List backups of the Admin Console database",
+		Long:          "",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			output := v.GetString("output")
+			if output != "json" && output != "" {
+				return errors.Errorf("output format %s not supported (allowed formats are: json)", output)
+			}
+
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			namespace, err := getNamespaceFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			b, statusCode, err := callKotsadmAPI(namespace, "GET", "/api/v1/db/backups", stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to list database backups")
+			}
+
+			response := handlers.ListDBBackupsResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if statusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+			}
+
+			print.DBBackups(response.Backups, output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "output format (currently supported: json)")
+
+	return cmd
+}
+
+func AdminConsoleDBRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [namespace]",
+		Short: "Restore the Admin Console database from a backup",
+		Long: `Restores the Admin Console database from a backup file or from the name of a backup in the Admin Console's storage.
+The backup is validated before anything is changed. The Admin Console is then stopped so that nothing writes to the database
+while its contents are replaced, and started again once the backup has been loaded.`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			from := v.GetString("from")
+			if from == "" {
+				return errors.New("--from is required")
+			}
+
+			timeout, err := time.ParseDuration(v.GetString("wait-duration"))
+			if err != nil {
+				return errors.Wrap(err, "failed to parse wait duration")
+			}
+
+			namespace, err := getNamespaceFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			clientset, err := k8sutil.GetClientset()
+			if err != nil {
+				return errors.Wrap(err, "failed to get clientset")
+			}
+
+			archive, err := readDBBackup(namespace, from, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to read backup")
+			}
+
+			database, metadata, err := dbbackup.ReadBackupArchive(bytes.NewReader(archive))
+			if err != nil {
+				return errors.Wrap(err, "backup is not valid")
+			}
+			log.ActionWithoutSpinner("Validated backup taken at %s by Admin Console version %s", metadata.CreatedAt.Format(time.RFC3339), metadata.KotsadmVersion)
+
+			rqliteSecret, err := clientset.CoreV1().Secrets(namespace).Get(cmd.Context(), "kotsadm-rqlite", metav1.GetOptions{})
+			if err != nil {
+				return errors.Wrap(err, "failed to get rqlite secret")
+			}
+
+			log.ActionWithSpinner("Stopping the Admin Console")
+			replicas, err := k8sutil.ScaleKotsadm(cmd.Context(), clientset, namespace, 0)
+			if err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to scale down the admin console")
+			}
+
+			// start the admin console again if the restore fails before it is started
+			stopped := true
+			defer func() {
+				if !stopped {
+					return
+				}
+				if _, err := k8sutil.ScaleKotsadm(context.Background(), clientset, namespace, replicas); err != nil {
+					log.Error(errors.Wrap(err, "failed to scale up the admin console"))
+				}
+			}()
+
+			if err := k8sutil.WaitForKotsadmStopped(cmd.Context(), clientset, namespace, timeout); err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to wait for the admin console to stop")
+			}
+			log.FinishSpinner()
+
+			restoreErr := loadDBBackup(clientset, namespace, string(rqliteSecret.Data["password"]), database, log)
+
+			log.ActionWithSpinner("Starting the Admin Console")
+			if _, err := k8sutil.ScaleKotsadm(context.Background(), clientset, namespace, replicas); err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to scale up the admin console")
+			}
+			stopped = false
+
+			if _, err := k8sutil.WaitForKotsadm(clientset, namespace, timeout); err != nil {
+				log.FinishSpinnerWithError()
+				return errors.Wrap(err, "failed to wait for the admin console to start")
+			}
+			log.FinishSpinner()
+
+			if restoreErr != nil {
+				return errors.Wrap(restoreErr, "failed to restore database")
+			}
+
+			log.ActionWithoutSpinner("The Admin Console database has been restored")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().String("from", "", "path to a backup file, or the name of a backup in the Admin Console's storage")
+	cmd.Flags().String("wait-duration", "2m", "timeout to be used while waiting for the Admin Console to stop and start. must be in Go duration format (eg: 10s, 2m)")
+
+	return cmd
+}
+
+// readDBBackup reads the backup from a local file, or downloads it from the admin console if from is the name of a backup
+func readDBBackup(namespace string, from string, log *logger.CLILogger) ([]byte, error) {
+	if _, err := os.Stat(from); err == nil {
+		return os.ReadFile(from)
+	} else if !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to stat backup file")
+	}
+
+	if !dbbackup.IsValidBackupName(from) {
+		return nil, errors.Errorf("%s is not a backup file or the name of a backup", from)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	b, statusCode, err := callKotsadmAPI(namespace, "GET", fmt.Sprintf("/api/v1/db/backups/%s/download", url.PathEscape(from)), stopCh, log)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download backup")
+	}
+
+	if statusCode == http.StatusNotFound {
+		return nil, errors.Errorf("backup %s not found", from)
+	}
+	if statusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+	}
+
+	return b, nil
+}
+
+// loadDBBackup replaces the contents of the rqlite database with the backup. the admin console must be stopped.
+func loadDBBackup(clientset *kubernetes.Clientset, namespace string, password string, database []byte, log *logger.CLILogger) error {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	rqliteURI, err := portForwardRqlite(clientset, namespace, password, stopCh, log)
+	if err != nil {
+		return err
+	}
+
+	log.ActionWithSpinner("Loading the backup into the database")
+	if err := dbbackup.Restore(rqliteURI, database); err != nil {
+		log.FinishSpinnerWithError()
+		return err
+	}
+	log.FinishSpinner()
+
+	return nil
+}
+
+// portForwardRqlite port forwards to rqlite until stopCh is closed, and returns the uri to connect to it with
+func portForwardRqlite(clientset *kubernetes.Clientset, namespace string, password string, stopCh chan struct{}, log *logger.CLILogger) (string, error) {
+	getPodName := func() (string, error) {
+		return k8sutil.FindRqlite(clientset, namespace)
+	}
+
+	localPort, errChan, err := k8sutil.PortForward(0, 4001, namespace, getPodName, false, stopCh, log)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to start port forwarding")
+	}
+
+	go func() {
+		select {
+		case err := <-errChan:
+			if err != nil {
+				log.Error(err)
+			}
+		case <-stopCh:
+		}
+	}()
+
+	return fmt.Sprintf("http://%s@localhost:%d", url.UserPassword("kotsadm", password).String(), localPort), nil
+}
+
+// callKotsadmAPI port forwards to the admin console and makes an authenticated request to the api
+func callKotsadmAPI(namespace string, method string, path string, stopCh chan struct{}, log *logger.CLILogger) ([]byte, int, error) {
+	v := viper.GetViper()
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get clientset")
+	}
+
+	getPodName := func() (string, error) {
+		return k8sutil.FindKotsadm(clientset, namespace)
+	}
+
+	localPort, errChan, err := k8sutil.PortForward(0, 3000, namespace, getPodName, false, stopCh, log)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to start port forwarding")
+	}
+
+	go func() {
+		select {
+		case err := <-errChan:
+			if err != nil {
+				log.Error(err)
+			}
+		case <-stopCh:
+		}
+	}()
+
+	authSlug, err := auth.GetOrCreateAuthSlug(clientset, namespace)
+	if err != nil {
+		log.Info("Unable to authenticate to the Admin Console running in the %s namespace. Ensure you have read access to secrets in this namespace and try again.", namespace)
+		if v.GetBool("debug") {
+			return nil, 0, errors.Wrap(err, "failed to get kotsadm auth slug")
+		}
+		os.Exit(2) // not returning error here as we don't want to show the entire stack trace to normal users
+	}
+
+	newReq, err := http.NewRequest(method, fmt.Sprintf("http://localhost:%d%s", localPort, path), nil)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to create request")
+	}
+	newReq.Header.Add("Content-Type", "application/json")
+	newReq.Header.Add("Authorization", authSlug)
+
+	resp, err := http.DefaultClient.Do(newReq)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to read")
+	}
+
+	return b, resp.StatusCode, nil
+}
@@ -106,6 +106,9 @@ func AdminConsoleCmd() *cobra.Command {
 	cmd.AddCommand(AdminPushImagesCmd())
 	cmd.AddCommand(AdminCopyPublicImagesCmd())
 	cmd.AddCommand(GarbageCollectImagesCmd())
+	cmd.AddCommand(GarbageCollectVersionsCmd())
+	cmd.AddCommand(RotateEncryptionKeyCmd())
+	cmd.AddCommand(AdminConsoleDBCmd())
 	cmd.AddCommand(AdminGenerateManifestsCmd())
 
 	return cmd
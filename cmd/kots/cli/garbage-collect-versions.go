@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/auth"
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/print"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func GarbageCollectVersionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "garbage-collect-versions [namespace]",
+		Short: "Prune old app versions",
+		Long: `Deletes app versions and their archives that are not retained by the version retention policy.
+The currently deployed version, pending versions and the rollback target are always retained.
+The policy is read from the "version-retention-count" and "version-retention-days" keys of the kotsadm-confg config map unless overridden with flags.`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			output := v.GetString("output")
+			if output != "json" && output != "" {
+				return errors.Errorf("output format %s not supported (allowed formats are: json)", output)
+			}
+
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			// use namespace-as-arg if provided, else use namespace from -n/--namespace
+			namespace, err := getNamespaceOrDefault(v.GetString("namespace"))
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			if len(args) == 1 {
+				namespace = args[0]
+			} else if len(args) > 1 {
+				fmt.Printf("more than one argument supplied: %+v\n", args)
+				os.Exit(1)
+			}
+
+			if err := validateNamespace(namespace); err != nil {
+				return errors.Wrap(err, "failed to validate namespace")
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			clientset, err := k8sutil.GetClientset()
+			if err != nil {
+				return errors.Wrap(err, "failed to get clientset")
+			}
+
+			getPodName := func() (string, error) {
+				return k8sutil.FindKotsadm(clientset, namespace)
+			}
+
+			localPort, errChan, err := k8sutil.PortForward(0, 3000, namespace, getPodName, false, stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to start port forwarding")
+			}
+
+			go func() {
+				select {
+				case err := <-errChan:
+					if err != nil {
+						log.Error(err)
+					}
+				case <-stopCh:
+				}
+			}()
+
+			url := fmt.Sprintf("http://localhost:%d/api/v1/garbage-collect-versions", localPort)
+
+			authSlug, err := auth.GetOrCreateAuthSlug(clientset, namespace)
+			if err != nil {
+				log.Info("Unable to authenticate to the Admin Console running in the %s namespace. Ensure you have read access to secrets in this namespace and try again.", namespace)
+				if v.GetBool("debug") {
+					return errors.Wrap(err, "failed to get kotsadm auth slug")
+				}
+				os.Exit(2) // not returning error here as we don't want to show the entire stack trace to normal users
+			}
+
+			requestPayload := handlers.GarbageCollectVersionsRequest{
+				DryRun: v.GetBool("dry-run"),
+			}
+			if cmd.Flags().Changed("keep-versions") {
+				keepCount := v.GetInt("keep-versions")
+				requestPayload.KeepCount = &keepCount
+			}
+			if cmd.Flags().Changed("keep-days") {
+				keepDays := v.GetInt("keep-days")
+				requestPayload.KeepDays = &keepDays
+			}
+			requestBody, err := json.Marshal(requestPayload)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal request json")
+			}
+			newReq, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+			if err != nil {
+				return errors.Wrap(err, "failed to create request")
+			}
+			newReq.Header.Add("Content-Type", "application/json")
+			newReq.Header.Add("Authorization", authSlug)
+
+			resp, err := http.DefaultClient.Do(newReq)
+			if err != nil {
+				return errors.Wrap(err, "failed to garbage collect versions")
+			}
+			defer resp.Body.Close()
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return errors.Wrap(err, "failed to read")
+			}
+
+			response := handlers.GarbageCollectVersionsResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", resp.StatusCode, b)
+			}
+
+			print.GarbageCollectVersionsReport(response.Report, output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("dry-run", false, "report the versions that would be pruned and the space that would be reclaimed without deleting anything")
+	cmd.Flags().Int("keep-versions", 0, "number of most recent versions to retain (overrides the configured retention policy)")
+	cmd.Flags().Int("keep-days", 0, "retain versions that were deployed within this number of days (overrides the configured retention policy)")
+	cmd.Flags().StringP("output", "o", "", "output format (currently supported: json)")
+
+	return cmd
+}
@@ -4,9 +4,13 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/api/handlers/types"
@@ -14,6 +18,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/crypto"
 	"github.com/replicatedhq/kots/pkg/handlers"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kotskinds/multitype"
@@ -41,6 +46,7 @@ func GetConfigCmd() *cobra.Command {
 	cmd.Flags().String("appslug", "", "app slug to retrieve config for")
 	cmd.Flags().Bool("decrypt", false, "decrypt encrypted config items")
 	cmd.Flags().Bool("current", false, "get config values for the currently deployed version of the app")
+	cmd.Flags().String("diff", "", "show the config changes between two sequences, such as 41..47. the latest version is used if the second sequence is omitted, such as 41..")
 
 	return cmd
 }
@@ -76,6 +82,11 @@ func getConfigCmd(cmd *cobra.Command, args []string) error {
 		return errors.New("cannot use --current and --sequence together")
 	}
 
+	diff := v.GetString("diff")
+	if diff != "" && (current || appSequence != -1 || decrypt) {
+		return errors.New("cannot use --diff with --current, --sequence or --decrypt")
+	}
+
 	localPort, errChan, err := k8sutil.PortForward(0, 3000, namespace, getPodName, false, stopCh, log)
 	if err != nil {
 		log.FinishSpinnerWithError()
@@ -126,6 +137,25 @@ func getConfigCmd(cmd *cobra.Command, args []string) error {
 		return errors.Errorf("app %s not found", appSlug)
 	}
 
+	if diff != "" {
+		from, to, err := parseSequenceRange(diff)
+		if err != nil {
+			return errors.Wrap(err, "failed to parse --diff")
+		}
+
+		getConfigHistoryURL := fmt.Sprintf("http://localhost:%d/api/v1/app/%s/config/history?from=%d", localPort, appSlug, from)
+		if to != nil {
+			getConfigHistoryURL = fmt.Sprintf("%s&to=%d", getConfigHistoryURL, *to)
+		}
+		history, err := getConfigHistory(getConfigHistoryURL, authSlug)
+		if err != nil {
+			return errors.Wrap(err, "failed to get config history")
+		}
+
+		printConfigHistory(cmd.OutOrStdout(), history)
+		return nil
+	}
+
 	if current {
 		if foundApp.Downstream.CurrentVersion == nil {
 			return errors.Errorf("no deployed version found for app %s", appSlug)
@@ -192,6 +222,100 @@ func getConfig(url string, authSlug string) (*handlers.CurrentAppConfigResponse,
 	return config, nil
 }
 
+func getConfigHistory(url string, authSlug string) (*handlers.GetAppConfigHistoryResponse, error) {
+	newReq, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	newReq.Header.Add("Content-Type", "application/json")
+	newReq.Header.Add("Authorization", authSlug)
+
+	resp, err := http.DefaultClient.Do(newReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read")
+	}
+
+	history := &handlers.GetAppConfigHistoryResponse{}
+	if err := json.Unmarshal(b, history); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config history")
+	}
+
+	if !history.Success {
+		return nil, fmt.Errorf("failed to get config history: %s", history.Error)
+	}
+
+	return history, nil
+}
+
+// parseSequenceRange parses a range of sequences such as 41..47. the second sequence is nil if it is omitted.
+func parseSequenceRange(sequenceRange string) (int64, *int64, error) {
+	parts := strings.SplitN(sequenceRange, "..", 2)
+	if len(parts) != 2 {
+		return 0, nil, errors.Errorf("%q is not in the format FROM..TO", sequenceRange)
+	}
+
+	from, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed to parse sequence %q", parts[0])
+	}
+
+	if parts[1] == "" {
+		return from, nil, nil
+	}
+	to, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, nil, errors.Wrapf(err, "failed to parse sequence %q", parts[1])
+	}
+	if to < from {
+		return 0, nil, errors.Errorf("sequence %d is before sequence %d", to, from)
+	}
+
+	return from, &to, nil
+}
+
+func printConfigHistory(w io.Writer, history *handlers.GetAppConfigHistoryResponse) {
+	if len(history.Versions) == 0 {
+		fmt.Fprintf(w, "No config changes between sequence %d and sequence %d\n", history.From, history.To)
+		return
+	}
+
+	for _, version := range history.Versions {
+		createdOn := ""
+		if version.CreatedOn != nil {
+			createdOn = fmt.Sprintf(", %s", version.CreatedOn.UTC().Format(time.RFC3339))
+		}
+		fmt.Fprintf(w, "Sequence %d (%s by %s%s):\n", version.Sequence, version.Source, version.ChangedBy, createdOn)
+		printConfigItemChanges(w, version.Changes)
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintf(w, "Changes from sequence %d to sequence %d:\n", history.From, history.To)
+	printConfigItemChanges(w, history.Changes)
+}
+
+func printConfigItemChanges(w io.Writer, changes []configtypes.ConfigItemChange) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "  none")
+		return
+	}
+	for _, change := range changes {
+		switch change.Change {
+		case configtypes.ConfigItemAdded:
+			fmt.Fprintf(w, "  + %s: %s\n", change.Name, change.To)
+		case configtypes.ConfigItemRemoved:
+			fmt.Fprintf(w, "  - %s: %s\n", change.Name, change.From)
+		default:
+			fmt.Fprintf(w, "  ~ %s: %s -> %s\n", change.Name, change.From, change.To)
+		}
+	}
+}
+
 func configGroupToValues(groups []v1beta1.ConfigGroup) v1beta1.ConfigValues {
 	extractedValues := v1beta1.ConfigValues{
 		TypeMeta: v1.TypeMeta{
@@ -104,3 +104,66 @@ func Test_decryptGroups(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseSequenceRange(t *testing.T) {
+	int64Ptr := func(i int64) *int64 { return &i }
+
+	tests := []struct {
+		name          string
+		sequenceRange string
+		wantFrom      int64
+		wantTo        *int64
+		wantErr       bool
+	}{
+		{
+			name:          "from and to",
+			sequenceRange: "41..47",
+			wantFrom:      41,
+			wantTo:        int64Ptr(47),
+		},
+		{
+			name:          "to omitted",
+			sequenceRange: "41..",
+			wantFrom:      41,
+			wantTo:        nil,
+		},
+		{
+			name:          "same sequence",
+			sequenceRange: "3..3",
+			wantFrom:      3,
+			wantTo:        int64Ptr(3),
+		},
+		{
+			name:          "no separator",
+			sequenceRange: "41",
+			wantErr:       true,
+		},
+		{
+			name:          "from omitted",
+			sequenceRange: "..47",
+			wantErr:       true,
+		},
+		{
+			name:          "not a number",
+			sequenceRange: "41..abc",
+			wantErr:       true,
+		},
+		{
+			name:          "to before from",
+			sequenceRange: "47..41",
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parseSequenceRange(tt.sequenceRange)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFrom, from)
+			assert.Equal(t, tt.wantTo, to)
+		})
+	}
+}
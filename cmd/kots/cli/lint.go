@@ -1,10 +1,15 @@
 package cli
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/lint"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/print"
 	"github.com/sirupsen/logrus"
@@ -45,8 +50,21 @@ This is a development/test CLI. Production usage will be via 'replicated kots li
 			offline := v.GetBool("offline")
 			verbose := v.GetBool("verbose")
 
+			if !v.GetBool("render") && (v.GetString("config-values") != "" || v.GetString("license") != "") {
+				return errors.New("--config-values and --license can only be used with --render")
+			}
+
+			fix := v.GetBool("fix")
+			dryRun := v.GetBool("dry-run")
+			if dryRun && !fix {
+				return errors.New("--dry-run can only be used with --fix")
+			}
+			if fix && isLintArchive(path) {
+				return errors.New("--fix can only be used with a directory or a file")
+			}
+
 			// Silence loggers for structured output formats to prevent pollution
-			if outputFormat == "json" || outputFormat == "yaml" {
+			if outputFormat == "json" || outputFormat == "yaml" || outputFormat == "sarif" || outputFormat == "junit" {
 				logrus.SetLevel(logrus.PanicLevel) // Silence logrus (used by lint package)
 				logger.SetSilent()                 // Silence zap logger
 				log.Silence()                      // Silence CLI logger
@@ -71,21 +89,66 @@ This is a development/test CLI. Production usage will be via 'replicated kots li
 
 			// Run linter
 			log.ActionWithSpinner("Running linter...")
-			result, err := lint.LintSpecFiles(cmd.Context(), files, lint.LintOptions{
+			lintOptions := lint.LintOptions{
 				SkipNetworkChecks: offline,
 				Verbose:           verbose,
-			})
+				PolicyDirs:        v.GetStringSlice("policy-dir"),
+				Render:            v.GetBool("render"),
+				ConfigValuesFile:  v.GetString("config-values"),
+				LicenseFile:       v.GetString("license"),
+				KubernetesVersion: v.GetString("kubernetes-version"),
+			}
+			var result *types.LintResult
+			fixedFiles := []lint.FixedFile{}
+			if fix {
+				result, fixedFiles, err = lint.LintAndFixSpecFiles(cmd.Context(), files, lintOptions)
+			} else {
+				result, err = lint.LintSpecFiles(cmd.Context(), files, lintOptions)
+			}
 			if err != nil {
 				log.FinishSpinnerWithError()
 				return errors.Wrap(err, "failed to lint files")
 			}
 			log.FinishSpinner()
 
+			if dryRun {
+				for _, fixedFile := range fixedFiles {
+					diff, err := fixedFile.Diff()
+					if err != nil {
+						return errors.Wrapf(err, "failed to diff %s", fixedFile.Path)
+					}
+					fmt.Fprint(cmd.OutOrStdout(), diff)
+				}
+				if len(fixedFiles) == 0 {
+					log.Info("No findings can be fixed automatically")
+				}
+				return nil
+			}
+
+			for _, fixedFile := range fixedFiles {
+				if err := writeLintFixedFile(path, fixedFile); err != nil {
+					return errors.Wrapf(err, "failed to write fixes to %s", fixedFile.Path)
+				}
+				log.Info("Fixed %s (%s)", fixedFile.Path, strings.Join(fixedFile.Rules, ", "))
+			}
+
 			// Format output
 			if err := print.LintResult(result, outputFormat, verbose); err != nil {
 				return errors.Wrap(err, "failed to print result")
 			}
 
+			if !fix {
+				fixable := 0
+				for _, lintExpression := range result.LintExpressions {
+					if lint.IsFixable(lintExpression.Rule) {
+						fixable++
+					}
+				}
+				if fixable > 0 {
+					log.Info("%d finding(s) can be fixed automatically with --fix", fixable)
+				}
+			}
+
 			// Exit code
 			if result.HasErrors() {
 				log.Error(errors.Errorf("linting failed with %d error(s)", result.ErrorCount()))
@@ -106,10 +169,44 @@ This is a development/test CLI. Production usage will be via 'replicated kots li
 		},
 	}
 
-	cmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml)")
+	cmd.Flags().StringP("output", "o", "table", "Output format (table, json, yaml, sarif, junit)")
 	cmd.Flags().Bool("fail-on-warn", false, "Exit with error on warnings")
 	cmd.Flags().Bool("offline", false, "Skip checks that require network (e.g., version validation)")
+	cmd.Flags().StringSlice("policy-dir", []string{}, "Directory with custom rego policies to evaluate against the spec files and the rendered manifests. Policies must be in a package under kots.custom. Can be specified multiple times")
+	cmd.Flags().Bool("render", false, "Render the release like it is deployed, including Helm charts, and validate the rendered manifests against the Kubernetes schemas. Cluster lookups in templates are made against an empty cluster")
+	cmd.Flags().String("config-values", "", "Path to a ConfigValues file with sample config values to render the release with")
+	cmd.Flags().String("license", "", "Path to a license file to render the release with")
+	cmd.Flags().String("kubernetes-version", validators.DefaultKubernetesVersion, fmt.Sprintf("Kubernetes version to render the release for and to validate the rendered manifests against (%s)", strings.Join(validators.SupportedKubernetesVersions(), ", ")))
+	cmd.Flags().Bool("fix", false, "Fix the findings that have automatic fixes in place, keeping the comments and formatting of the files")
+	cmd.Flags().Bool("dry-run", false, "With --fix, print the fixes as a unified diff instead of writing them")
 	cmd.Flags().BoolP("verbose", "v", false, "Show info-level messages (default: only show warnings and errors)")
 
 	return cmd
 }
+
+// isLintArchive returns true if the path to lint is a tar archive
+func isLintArchive(path string) bool {
+	return strings.HasSuffix(path, ".tar") || strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// writeLintFixedFile writes a fixed file back to the directory or the file that was linted
+func writeLintFixedFile(path string, fixedFile lint.FixedFile) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Wrap(err, "failed to stat path")
+	}
+
+	filePath := path
+	if info.IsDir() {
+		filePath = filepath.Join(path, filepath.FromSlash(fixedFile.Path))
+		if info, err = os.Stat(filePath); err != nil {
+			return errors.Wrap(err, "failed to stat file")
+		}
+	}
+
+	if err := os.WriteFile(filePath, []byte(fixedFile.Fixed), info.Mode().Perm()); err != nil {
+		return errors.Wrap(err, "failed to write file")
+	}
+
+	return nil
+}
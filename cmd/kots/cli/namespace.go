@@ -1,9 +1,12 @@
 package cli
 
 import (
+	"fmt"
+	"os"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/clientcmd"
@@ -48,3 +51,26 @@ func validateNamespace(namespace string) error {
 
 	return nil
 }
+
+// getNamespaceFromArgs returns the namespace given as the only argument, or else the namespace from -n/--namespace
+func getNamespaceFromArgs(args []string) (string, error) {
+	v := viper.GetViper()
+
+	// use namespace-as-arg if provided, else use namespace from -n/--namespace
+	namespace, err := getNamespaceOrDefault(v.GetString("namespace"))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get namespace")
+	}
+	if len(args) == 1 {
+		namespace = args[0]
+	} else if len(args) > 1 {
+		fmt.Printf("more than one argument supplied: %+v\n", args)
+		os.Exit(1)
+	}
+
+	if err := validateNamespace(namespace); err != nil {
+		return "", errors.Wrap(err, "failed to validate namespace")
+	}
+
+	return namespace, nil
+}
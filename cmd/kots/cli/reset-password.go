@@ -1,23 +1,30 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
 	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/mfa"
 	"github.com/replicatedhq/kots/pkg/password"
 	"github.com/replicatedhq/kots/pkg/util"
+	"github.com/rqlite/gorqlite"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 func ResetPasswordCmd() *cobra.Command {
 	cmd := &cobra.Command{
-		Use:           "reset-password [namespace]",
-		Short:         "Change the password on the admin console",
-		Long:          `Change the password on the Admin Console`,
+		Use:   "reset-password [namespace]",
+		Short: "Change the password on the admin console",
+		Long: `Change the password on the Admin Console.
+With --reset-mfa, multi-factor authentication is turned off and the Admin Console is unlocked instead, leaving the password unchanged.`,
 		SilenceUsage:  true,
 		SilenceErrors: false,
 		PreRun: func(cmd *cobra.Command, args []string) {
@@ -45,6 +52,14 @@ func ResetPasswordCmd() *cobra.Command {
 				os.Exit(1)
 			}
 
+			if v.GetBool("reset-mfa") {
+				if err := resetKotsadmMFA(namespace, log); err != nil {
+					return errors.Wrap(err, "failed to reset multi-factor authentication")
+				}
+				log.ActionWithoutSpinner("Multi-factor authentication has been turned off for the admin console in %s", namespace)
+				return nil
+			}
+
 			log.ActionWithoutSpinner("Reset the admin console password for %s", namespace)
 			newPassword, err := util.PromptForNewPassword()
 			if err != nil {
@@ -60,6 +75,8 @@ func ResetPasswordCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool("reset-mfa", false, "turn off multi-factor authentication and unlock the admin console without changing the password")
+
 	return cmd
 }
 
@@ -74,3 +91,47 @@ func setKotsadmPassword(newPassword string, namespace string) error {
 	}
 	return nil
 }
+
+func resetKotsadmMFA(namespace string, log *logger.CLILogger) error {
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return errors.Wrap(err, "failed to create k8s client")
+	}
+
+	if err := mfa.Reset(clientset, namespace); err != nil {
+		return errors.Wrap(err, "failed to reset mfa")
+	}
+
+	if err := resetKotsadmFailedLoginCount(clientset, namespace, log); err != nil {
+		return errors.Wrap(err, "failed to reset failed login count")
+	}
+	return nil
+}
+
+// resetKotsadmFailedLoginCount unlocks the admin console when the failed logins are counted in the database
+func resetKotsadmFailedLoginCount(clientset *kubernetes.Clientset, namespace string, log *logger.CLILogger) error {
+	rqliteSecret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), "kotsadm-rqlite", metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get rqlite secret")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	rqliteURI, err := portForwardRqlite(clientset, namespace, string(rqliteSecret.Data["password"]), stopCh, log)
+	if err != nil {
+		return err
+	}
+
+	// the peers that rqlite advertises are not reachable through the port forward
+	db, err := gorqlite.Open(rqliteURI + "?disableClusterDiscovery=true")
+	if err != nil {
+		return errors.Wrap(err, "failed to connect to rqlite")
+	}
+	defer db.Close()
+
+	return mfa.ResetFailedLoginCount(&db)
+}
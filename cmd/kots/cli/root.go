@@ -41,6 +41,7 @@ func RootCmd() *cobra.Command {
 	cmd.AddCommand(RemoveCmd())
 	cmd.AddCommand(AdminConsoleCmd())
 	cmd.AddCommand(ResetPasswordCmd())
+	cmd.AddCommand(SessionCmd())
 	cmd.AddCommand(ResetTLSCmd())
 	cmd.AddCommand(VersionCmd())
 	cmd.AddCommand(VeleroCmd())
@@ -52,6 +53,7 @@ func RootCmd() *cobra.Command {
 	cmd.AddCommand(AppStatusCmd())
 	cmd.AddCommand(GetCmd())
 	cmd.AddCommand(SetCmd())
+	cmd.AddCommand(TemplateCmd())
 	cmd.AddCommand(CompletionCmd())
 	cmd.AddCommand(DockerRegistryCmd())
 	cmd.AddCommand(EnableHACmd())
@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/auth"
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func RotateEncryptionKeyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rotate-encryption-key [namespace]",
+		Short: "Rotate the key used to encrypt config values and stored secrets",
+		Long: `Generates a new encryption key and stores it in the kotsadm-encryption secret alongside the current key.
+Config values in app version archives, registry passwords, GitOps credentials and encrypted archives are then re-encrypted with the new key.
+The previous key is removed from the secret once nothing is encrypted with it anymore.
+If a previous rotation did not complete, it is resumed instead of generating another key.`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			// use namespace-as-arg if provided, else use namespace from -n/--namespace
+			namespace, err := getNamespaceOrDefault(v.GetString("namespace"))
+			if err != nil {
+				return errors.Wrap(err, "failed to get namespace")
+			}
+			if len(args) == 1 {
+				namespace = args[0]
+			} else if len(args) > 1 {
+				fmt.Printf("more than one argument supplied: %+v\n", args)
+				os.Exit(1)
+			}
+
+			if err := validateNamespace(namespace); err != nil {
+				return errors.Wrap(err, "failed to validate namespace")
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			clientset, err := k8sutil.GetClientset()
+			if err != nil {
+				return errors.Wrap(err, "failed to get clientset")
+			}
+
+			getPodName := func() (string, error) {
+				return k8sutil.FindKotsadm(clientset, namespace)
+			}
+
+			localPort, errChan, err := k8sutil.PortForward(0, 3000, namespace, getPodName, false, stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to start port forwarding")
+			}
+
+			go func() {
+				select {
+				case err := <-errChan:
+					if err != nil {
+						log.Error(err)
+					}
+				case <-stopCh:
+				}
+			}()
+
+			authSlug, err := auth.GetOrCreateAuthSlug(clientset, namespace)
+			if err != nil {
+				log.Info("Unable to authenticate to the Admin Console running in the %s namespace. Ensure you have read access to secrets in this namespace and try again.", namespace)
+				if v.GetBool("debug") {
+					return errors.Wrap(err, "failed to get kotsadm auth slug")
+				}
+				os.Exit(2) // not returning error here as we don't want to show the entire stack trace to normal users
+			}
+
+			url := fmt.Sprintf("http://localhost:%d/api/v1/encryption-key/rotate", localPort)
+			newReq, err := http.NewRequest("POST", url, nil)
+			if err != nil {
+				return errors.Wrap(err, "failed to create request")
+			}
+			newReq.Header.Add("Content-Type", "application/json")
+			newReq.Header.Add("Authorization", authSlug)
+
+			resp, err := http.DefaultClient.Do(newReq)
+			if err != nil {
+				return errors.Wrap(err, "failed to rotate encryption key")
+			}
+			defer resp.Body.Close()
+
+			b, err := io.ReadAll(resp.Body)
+			if err != nil {
+				return errors.Wrap(err, "failed to read")
+			}
+
+			response := handlers.RotateEncryptionKeyResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", resp.StatusCode, b)
+			}
+
+			if response.Resumed {
+				log.ActionWithoutSpinner("Resuming incomplete encryption key rotation")
+			} else {
+				log.ActionWithoutSpinner("Generated a new encryption key")
+			}
+
+			if !v.GetBool("wait") {
+				log.ActionWithoutSpinner("Data is being re-encrypted in the background. Run this command again to check on its progress.")
+				return nil
+			}
+
+			statusURL := fmt.Sprintf("http://localhost:%d/api/v1/encryption-key/rotate/status", localPort)
+			if err := waitForEncryptionKeyRotation(statusURL, authSlug, log); err != nil {
+				return err
+			}
+
+			log.ActionWithoutSpinner("All data has been re-encrypted and the previous key has been retired")
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("wait", true, "wait for all data to be re-encrypted and report progress")
+
+	return cmd
+}
+
+func waitForEncryptionKeyRotation(url string, authSlug string, log *logger.CLILogger) error {
+	lastMessage := ""
+	for {
+		newReq, err := http.NewRequest("GET", url, nil)
+		if err != nil {
+			return errors.Wrap(err, "failed to create request")
+		}
+		newReq.Header.Add("Authorization", authSlug)
+
+		resp, err := http.DefaultClient.Do(newReq)
+		if err != nil {
+			return errors.Wrap(err, "failed to get rotation status")
+		}
+
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return errors.Wrap(err, "failed to read")
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return errors.Errorf("unexpected response from server %v: %s", resp.StatusCode, b)
+		}
+
+		status := handlers.GetRotateEncryptionKeyStatusResponse{}
+		if err := json.Unmarshal(b, &status); err != nil {
+			return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+		}
+
+		switch status.Status {
+		case "running":
+			if status.CurrentMessage != lastMessage {
+				log.ActionWithoutSpinner("%s", status.CurrentMessage)
+				lastMessage = status.CurrentMessage
+			}
+		case "failed":
+			return errors.Errorf("failed to re-encrypt data: %s. run this command again to resume the rotation", status.CurrentMessage)
+		default:
+			// the task status is cleared when the rotation completes
+			return nil
+		}
+
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/print"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func SessionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage Admin Console sessions",
+		Long: `List and revoke the sessions that are logged in to the Admin Console.
+Sessions expire 12 hours after the last time they were extended. Setting the "session-idle-timeout" key of the kotsadm-confg config map
+to a duration (eg: 30m) also expires sessions that have not been used for that long. The address of a session is where the login request came from.
+Setting the "session-trusted-proxies" key to a comma separated list of addresses or CIDRs of proxies in front of the Admin Console records
+the address from the X-Forwarded-For header of requests from those proxies instead. The Admin Console must be restarted for a change to take effect.`,
+	}
+
+	cmd.AddCommand(SessionListCmd())
+	cmd.AddCommand(SessionRevokeCmd())
+	cmd.AddCommand(SessionRevokeAllCmd())
+
+	return cmd
+}
+
+func SessionListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "list [namespace]",
+		Aliases:       []string{"ls"},
+		Short:         "List active Admin Console sessions",
+		Long:          "",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			output := v.GetString("output")
+			if output != "json" && output != "" {
+				return errors.Errorf("output format %s not supported (allowed formats are: json)", output)
+			}
+
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			namespace, err := getNamespaceFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			b, statusCode, err := callKotsadmAPI(namespace, "GET", "/api/v1/sessions", stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to list sessions")
+			}
+
+			response := handlers.ListSessionsResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if statusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+			}
+
+			print.Sessions(response.Sessions, output)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", "", "output format (currently supported: json)")
+
+	return cmd
+}
+
+func SessionRevokeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "revoke [session id]",
+		Short:         "Log out an Admin Console session",
+		Long:          "",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		Args:          cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			sessionID := args[0]
+
+			namespace, err := getNamespaceFromArgs(nil)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			b, statusCode, err := callKotsadmAPI(namespace, "DELETE", fmt.Sprintf("/api/v1/sessions/%s", url.PathEscape(sessionID)), stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to revoke session")
+			}
+
+			response := handlers.RevokeSessionResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if statusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+			}
+
+			log.ActionWithoutSpinner("Revoked session %s", sessionID)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func SessionRevokeAllCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "revoke-all [namespace]",
+		Short:         "Log out all Admin Console sessions",
+		Long:          "",
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			log := logger.NewCLILogger(cmd.OutOrStdout())
+
+			namespace, err := getNamespaceFromArgs(args)
+			if err != nil {
+				return err
+			}
+
+			stopCh := make(chan struct{})
+			defer close(stopCh)
+
+			b, statusCode, err := callKotsadmAPI(namespace, "DELETE", "/api/v1/sessions", stopCh, log)
+			if err != nil {
+				return errors.Wrap(err, "failed to revoke sessions")
+			}
+
+			response := handlers.RevokeAllSessionsResponse{}
+			if err = json.Unmarshal(b, &response); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal server response: %s", b)
+			}
+
+			if response.Error != "" {
+				return errors.New(response.Error)
+			}
+
+			if statusCode != http.StatusOK {
+				return errors.Errorf("unexpected response from server %v: %s", statusCode, b)
+			}
+
+			log.ActionWithoutSpinner("Revoked all Admin Console sessions in %s", namespace)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -61,9 +61,17 @@ func SetConfigCmd() *cobra.Command {
 				return errors.New("cannot use --current and --sequence together")
 			}
 
-			configValues, err := getConfigValuesFromArgs(v, args)
-			if err != nil {
-				return errors.Wrap(err, "failed to create config values from arguments")
+			restoreSequence := v.GetInt64("restore-sequence")
+			var configValues []byte
+			if restoreSequence != -1 {
+				if len(args) > 1 || v.GetString("key") != "" || v.GetString("config-file") != "" || v.GetBool("merge") {
+					return errors.New("--restore-sequence cannot be used with config values or --merge")
+				}
+			} else {
+				configValues, err = getConfigValuesFromArgs(v, args)
+				if err != nil {
+					return errors.Wrap(err, "failed to create config values from arguments")
+				}
 			}
 
 			clientset, err := k8sutil.GetClientset()
@@ -101,7 +109,7 @@ func SetConfigCmd() *cobra.Command {
 			}
 
 			merge := v.GetBool("merge")
-			if !merge && v.GetString("config-file") == "" {
+			if !merge && v.GetString("config-file") == "" && restoreSequence == -1 {
 				merge = true
 			}
 
@@ -113,6 +121,9 @@ func SetConfigCmd() *cobra.Command {
 				"current":        v.GetBool("current"),
 				"sequence":       v.GetInt64("sequence"),
 			}
+			if restoreSequence != -1 {
+				requestPayload["restoreSequence"] = restoreSequence
+			}
 
 			requestBody, err := json.Marshal(requestPayload)
 			if err != nil {
@@ -173,6 +184,7 @@ func SetConfigCmd() *cobra.Command {
 	cmd.Flags().Bool("skip-preflights", false, "set to true to skip preflight checks when deploying new version")
 	cmd.Flags().Bool("current", false, "set to true to use the currently deployed version of the app as the base for the new version")
 	cmd.Flags().Int64("sequence", -1, "sequence of the app version to use as the base for the new version (defaults to the latest version unless --current flag is set)")
+	cmd.Flags().Int64("restore-sequence", -1, "sequence of an app version to restore the config values of. This flag cannot be used with config values.")
 
 	return cmd
 }
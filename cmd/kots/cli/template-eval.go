@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/auth"
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TemplateEvalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval [template] --app=my-app",
+		Short: "Evaluate a template expression or file against a version of an application",
+		Long: `Evaluate a template expression or file with the same template functions and values that are used to render a version of an application.
+Password config items evaluate to a mask unless --show-passwords is set.
+Functions that read from the cluster, such as Lookup, or that persist generated values, such as GenerateOnce and SSHPrivateKey, are not available.
+
+Examples:
+kubectl kots template eval --app my-app 'repl{{ ConfigOption "hostname" }}'
+kubectl kots template eval --app my-app --sequence 4 --file manifests/deployment.yaml`,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: templateEvalCmd,
+	}
+
+	cmd.Flags().String("app", "", "slug of the app to evaluate the template for")
+	cmd.Flags().Int64("sequence", -1, "app sequence to evaluate the template against. the latest version is used if not set")
+	cmd.Flags().String("file", "", "path to a file to evaluate instead of a template expression")
+	cmd.Flags().Bool("show-passwords", false, "evaluate password config items to their values instead of a mask")
+
+	return cmd
+}
+
+func templateEvalCmd(cmd *cobra.Command, args []string) error {
+	v := viper.GetViper()
+
+	request, err := templateEvalRequestFromArgs(args, v.GetString("file"), v.GetInt64("sequence"), v.GetBool("show-passwords"))
+	if err != nil {
+		return err
+	}
+
+	log := logger.NewCLILogger(cmd.OutOrStdout())
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return errors.Wrap(err, "failed to get clientset")
+	}
+
+	namespace, err := getNamespaceOrDefault(v.GetString("namespace"))
+	if err != nil {
+		return errors.Wrap(err, "failed to get namespace")
+	}
+
+	getPodName := func() (string, error) {
+		return k8sutil.FindKotsadm(clientset, namespace)
+	}
+
+	localPort, errChan, err := k8sutil.PortForward(0, 3000, namespace, getPodName, false, stopCh, log)
+	if err != nil {
+		log.FinishSpinnerWithError()
+		return errors.Wrap(err, "failed to start port forwarding")
+	}
+
+	go func() {
+		select {
+		case err := <-errChan:
+			if err != nil {
+				log.Error(err)
+			}
+		case <-stopCh:
+		}
+	}()
+
+	authSlug, err := auth.GetOrCreateAuthSlug(clientset, namespace)
+	if err != nil {
+		log.FinishSpinnerWithError()
+		log.Info("Unable to authenticate to the Admin Console running in the %s namespace. Ensure you have read access to secrets in this namespace and try again.", namespace)
+		if v.GetBool("debug") {
+			return errors.Wrap(err, "failed to get kotsadm auth slug")
+		}
+		os.Exit(2) // not returning error here as we don't want to show the entire stack trace to normal users
+	}
+
+	appSlug := v.GetString("app")
+	if appSlug == "" {
+		getAppsURL := fmt.Sprintf("http://localhost:%d/api/v1/apps", localPort)
+		apps, err := getApps(getAppsURL, authSlug)
+		if err != nil {
+			return errors.Wrap(err, "failed to get apps")
+		}
+		if len(apps.Apps) != 1 {
+			return errors.New("app is required")
+		}
+		appSlug = apps.Apps[0].Slug
+	}
+
+	evalTemplateURL := fmt.Sprintf("http://localhost:%d/api/v1/app/%s/template/eval", localPort, appSlug)
+	response, err := evalTemplate(evalTemplateURL, authSlug, request)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(cmd.OutOrStdout(), response.Output)
+	if request.Name == "" {
+		// expressions usually do not end with a newline, files usually do
+		fmt.Fprintln(cmd.OutOrStdout())
+	}
+
+	return nil
+}
+
+func templateEvalRequestFromArgs(args []string, file string, sequence int64, showPasswords bool) (*handlers.EvalAppTemplateRequest, error) {
+	request := &handlers.EvalAppTemplateRequest{
+		ShowPasswords: showPasswords,
+	}
+	if sequence != -1 {
+		request.Sequence = &sequence
+	}
+
+	switch {
+	case file != "" && len(args) > 0:
+		return nil, errors.New("cannot use a template expression and --file together")
+	case file != "":
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read file")
+		}
+		request.Name = filepath.Base(file)
+		request.Template = string(content)
+	case len(args) == 1:
+		request.Template = args[0]
+	case len(args) > 1:
+		return nil, errors.New("only one template expression can be evaluated at a time")
+	default:
+		return nil, errors.New("a template expression or --file is required")
+	}
+
+	return request, nil
+}
+
+func evalTemplate(url string, authSlug string, request *handlers.EvalAppTemplateRequest) (*handlers.EvalAppTemplateResponse, error) {
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal request")
+	}
+
+	newReq, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create request")
+	}
+	newReq.Header.Add("Content-Type", "application/json")
+	newReq.Header.Add("Authorization", authSlug)
+
+	resp, err := http.DefaultClient.Do(newReq)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read")
+	}
+
+	response := &handlers.EvalAppTemplateResponse{}
+	if err := json.Unmarshal(b, response); err != nil {
+		return nil, errors.Wrapf(err, "failed to unmarshal response (status %d)", resp.StatusCode)
+	}
+
+	if !response.Success {
+		return nil, fmt.Errorf("failed to evaluate template: %s", response.Error)
+	}
+
+	return response, nil
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/handlers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_templateEvalRequestFromArgs(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "deployment.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("image: repl{{ LocalImageName \"nginx\" }}\n"), 0644))
+
+	sequence := int64(4)
+
+	tests := []struct {
+		name          string
+		args          []string
+		file          string
+		sequence      int64
+		showPasswords bool
+		want          *handlers.EvalAppTemplateRequest
+		wantErr       string
+	}{
+		{
+			name:     "expression",
+			args:     []string{`repl{{ ConfigOption "hostname" }}`},
+			sequence: -1,
+			want:     &handlers.EvalAppTemplateRequest{Template: `repl{{ ConfigOption "hostname" }}`},
+		},
+		{
+			name:          "file at a sequence",
+			file:          file,
+			sequence:      4,
+			showPasswords: true,
+			want: &handlers.EvalAppTemplateRequest{
+				Sequence:      &sequence,
+				Name:          "deployment.yaml",
+				Template:      "image: repl{{ LocalImageName \"nginx\" }}\n",
+				ShowPasswords: true,
+			},
+		},
+		{
+			name:     "expression and file",
+			args:     []string{`repl{{ ConfigOption "hostname" }}`},
+			file:     file,
+			sequence: -1,
+			wantErr:  "cannot use a template expression and --file together",
+		},
+		{
+			name:     "nothing to evaluate",
+			sequence: -1,
+			wantErr:  "a template expression or --file is required",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templateEvalRequestFromArgs(tt.args, tt.file, tt.sequence, tt.showPasswords)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template [command]",
+		Short: "Debug template functions against an installed application",
+		Long: `Examples:
+kubectl kots template eval --app my-app 'repl{{ ConfigOption "hostname" }}'`,
+
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+	}
+
+	cmd.AddCommand(TemplateEvalCmd())
+
+	return cmd
+}
@@ -21,6 +21,7 @@ func MigrateCmd() *cobra.Command {
 
 	cmd.AddCommand(MigrateS3ToRqliteCmd())
 	cmd.AddCommand(MigratePVCToRqliteCmd())
+	cmd.AddCommand(MigrateToContentAddressedCmd())
 
 	return cmd
 }
@@ -97,3 +98,31 @@ func MigratePVCToRqliteCmd() *cobra.Command {
 
 	return cmd
 }
+
+func MigrateToContentAddressedCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:           "to-content-addressed",
+		Short:         "Migrate object storage archives to deduplicated content addressed storage",
+		Long:          ``,
+		SilenceUsage:  true,
+		SilenceErrors: false,
+		PreRun: func(cmd *cobra.Command, args []string) {
+			viper.BindPFlags(cmd.Flags())
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Check if required env vars are set
+			if os.Getenv("RQLITE_URI") == "" {
+				return errors.New("RQLITE_URI is not set")
+			}
+
+			// Migrate to content addressed storage
+			if err := filestore.MigrateToContentAddressedStore(cmd.Context()); err != nil {
+				return err
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/blang/semver"
 	"github.com/replicatedhq/kots/pkg/cursor"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
 	"github.com/replicatedhq/kots/pkg/kotsutil"
 	kotssemver "github.com/replicatedhq/kots/pkg/semver"
 	storetypes "github.com/replicatedhq/kots/pkg/store/types"
@@ -36,7 +37,9 @@ type DownstreamVersion struct {
 	Source             string                             `json:"source"`
 	PreflightSkipped   bool                               `json:"preflightSkipped"`
 	CommitURL          string                             `json:"commitUrl,omitempty"`
+	CommitHash         string                             `json:"commitHash,omitempty"`
 	GitDeployable      bool                               `json:"gitDeployable,omitempty"`
+	PullRequest        *gitopstypes.PullRequest           `json:"pullRequest,omitempty"`
 	UpstreamReleasedAt *time.Time                         `json:"upstreamReleasedAt,omitempty"`
 
 	// The following fields are not queried by default and are only added as additional details when needed
@@ -76,6 +76,7 @@ type ResponseGitOps struct {
 	Branch      string `json:"branch"`
 	Format      string `json:"format"`
 	Action      string `json:"action"`
+	SyncStatus  bool   `json:"syncStatus"`
 	DeployKey   string `json:"deployKey"`
 	IsConnected bool   `json:"isConnected"`
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/keyrotation"
 	"github.com/replicatedhq/kots/pkg/kotsutil"
 	"github.com/replicatedhq/kots/pkg/store"
 )
@@ -53,6 +54,11 @@ func bootstrapClusterToken(autoCreateClusterToken string) error {
 }
 
 func loadEncryptionKeys() error {
+	// keys replaced by an incomplete rotation are needed to read data that has not been re-encrypted yet
+	if err := keyrotation.LoadPreviousKeys(); err != nil {
+		return errors.Wrap(err, "failed to load previous encryption keys")
+	}
+
 	apps, err := store.GetStore().ListInstalledApps()
 	if err != nil {
 		return errors.Wrap(err, "failed to list apps")
@@ -13,9 +13,12 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/replicatedhq/kots/pkg/automation"
 	"github.com/replicatedhq/kots/pkg/binaries"
+	"github.com/replicatedhq/kots/pkg/dbbackup"
+	"github.com/replicatedhq/kots/pkg/filestore"
 	"github.com/replicatedhq/kots/pkg/handlers"
 	identitymigrate "github.com/replicatedhq/kots/pkg/identity/migrate"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/keyrotation"
 	"github.com/replicatedhq/kots/pkg/operator"
 	operatorclient "github.com/replicatedhq/kots/pkg/operator/client"
 	"github.com/replicatedhq/kots/pkg/persistence"
@@ -30,6 +33,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/updatechecker"
 	"github.com/replicatedhq/kots/pkg/upgradeservice"
 	"github.com/replicatedhq/kots/pkg/util"
+	"github.com/replicatedhq/kots/pkg/versionretention"
 	"github.com/replicatedhq/kots/pkg/watchers"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -78,6 +82,9 @@ func Start(params *APIServerParams) {
 		panic(err)
 	}
 
+	filestore.StartEncryptionMigration()
+	keyrotation.ResumeIfIncomplete()
+
 	kotsStore := store.GetStore()
 
 	operatorClient := &operatorclient.Client{
@@ -132,10 +139,22 @@ func Start(params *APIServerParams) {
 		log.Println("Failed to start snapshot scheduler:", err)
 	}
 
+	if err := session.InitSettings(); err != nil {
+		log.Println("Failed to initialize session settings:", err)
+	}
+
 	if err := session.StartSessionPurgeCronJob(); err != nil {
 		log.Println("Failed to start session purge cron job:", err)
 	}
 
+	if err := versionretention.StartGarbageCollectCronJob(); err != nil {
+		log.Println("Failed to start version garbage collection cron job:", err)
+	}
+
+	if err := dbbackup.StartBackupCronJob(); err != nil {
+		log.Println("Failed to start database backup cron job:", err)
+	}
+
 	waitForAirgap, err := automation.NeedToWaitForAirgapApp()
 	if err != nil {
 		log.Println("Failed to check if airgap install is in progress:", err)
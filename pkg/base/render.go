@@ -6,6 +6,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/logger"
 	registrytypes "github.com/replicatedhq/kots/pkg/registry/types"
 	upstreamtypes "github.com/replicatedhq/kots/pkg/upstream/types"
+	"k8s.io/client-go/kubernetes"
 )
 
 type RenderOptions struct {
@@ -20,6 +21,8 @@ type RenderOptions struct {
 	IsAirgap          bool
 	UseHelmInstall    bool
 	Log               *logger.CLILogger
+	// Clientset is used for the cluster lookups of the templates. the cluster of the current kube context is used if nil
+	Clientset kubernetes.Interface
 }
 
 // RenderKotsKinds is responsible for rendering KOTS custom resources
@@ -152,7 +152,15 @@ func renderKotsKinds(u *upstreamtypes.Upstream, renderOptions *RenderOptions) (m
 	versionInfo := template.VersionInfoFromInstallationSpec(renderOptions.Sequence, renderOptions.IsAirgap, tkk.Installation.Spec)
 	appInfo := template.ApplicationInfo{Slug: renderOptions.AppSlug}
 
-	renderedConfig, err := kotsconfig.TemplateConfigObjects(tkk.Config, itemValues, tkk.License, &tkk.KotsApplication, renderOptions.RegistrySettings, &versionInfo, &appInfo, tkk.IdentityConfig, util.PodNamespace, true)
+	// the rendered config is shown to the user and saved with their values, so it must not have the referenced values
+	configItemValues := map[string]template.ItemValue{}
+	for name, itemValue := range itemValues {
+		if _, ok := tkk.ConfigValueReferences[name]; !ok {
+			configItemValues[name] = itemValue
+		}
+	}
+
+	renderedConfig, err := kotsconfig.TemplateConfigObjects(tkk.Config, configItemValues, tkk.License, &tkk.KotsApplication, renderOptions.RegistrySettings, &versionInfo, &appInfo, tkk.IdentityConfig, util.PodNamespace, true)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to template config objects")
 	}
@@ -465,6 +473,13 @@ func getTemplatingKotsKinds(u *upstreamtypes.Upstream) (*kotsutil.KotsKinds, err
 			switch gvk.String() {
 			case "kots.io/v1beta1, Kind=Config":
 				kotsKinds.Config = decoded.(*kotsv1beta1.Config)
+				configSpecExtras, err := kotsutil.ParseConfigSpecExtras(doc)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to parse config value references in %s", file.Path)
+				}
+				if len(configSpecExtras.ValueReferences) > 0 {
+					kotsKinds.ConfigValueReferences = configSpecExtras.ValueReferences
+				}
 			case "kots.io/v1beta1, Kind=ConfigValues":
 				kotsKinds.ConfigValues = decoded.(*kotsv1beta1.ConfigValues)
 			case "kots.io/v1beta1, Kind=Application":
@@ -61,6 +61,8 @@ func NewConfigContextTemplateBuilder(u *upstreamtypes.Upstream, renderOptions *R
 		IdentityConfig:  kotsKinds.IdentityConfig,
 		Namespace:       renderOptions.Namespace,
 		DecryptValues:   true,
+		ValueReferences: kotsKinds.ConfigValueReferences,
+		Clientset:       renderOptions.Clientset,
 	}
 	builder, itemValues, err := template.NewBuilder(builderOptions)
 	if err != nil {
@@ -9,8 +9,10 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -23,6 +25,12 @@ type aesCipher struct {
 
 const keyLength = 24 // 192 bit
 
+const (
+	EncryptionSecretName            = "kotsadm-encryption"
+	EncryptionKeySecretKey          = "encryptionKey"
+	PreviousEncryptionKeysSecretKey = "previousEncryptionKeys"
+)
+
 var decryptionCiphers []*aesCipher // used to decrypt data
 var encryptionCipher *aesCipher    // used to encrypt data
 
@@ -42,12 +50,12 @@ func init() {
 
 // InitFromSecret reads the encryption key from kubernetes and adds it to the list of decryptionCiphers, and sets this key to be used for encryption.
 func InitFromSecret(clientset kubernetes.Interface, namespace string) error {
-	sec, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), "kotsadm-encryption", metav1.GetOptions{})
+	sec, err := clientset.CoreV1().Secrets(namespace).Get(context.Background(), EncryptionSecretName, metav1.GetOptions{})
 	if err != nil {
 		return errors.Wrap(err, "get kotsadm-encryption secret")
 	}
 
-	secData, ok := sec.Data["encryptionKey"]
+	secData, ok := sec.Data[EncryptionKeySecretKey]
 	if !ok {
 		return fmt.Errorf("kotsadm-encryption secret in %s does not have member encryptionKey", namespace)
 	}
@@ -60,9 +68,27 @@ func InitFromSecret(clientset kubernetes.Interface, namespace string) error {
 	addCipher(secCipher)
 	encryptionCipher = secCipher
 
+	// keys that were replaced by a rotation that has not completed yet are still needed for decryption
+	for _, previousKey := range PreviousKeysFromSecret(sec) {
+		if err := InitFromString(previousKey); err != nil {
+			return errors.Wrap(err, "parse previous kotsadm-encryption key")
+		}
+	}
+
 	return nil
 }
 
+// PreviousKeysFromSecret returns the keys that were replaced by an encryption key rotation and are still referenced by encrypted data
+func PreviousKeysFromSecret(secret *corev1.Secret) []string {
+	keys := []string{}
+	for _, key := range strings.Split(string(secret.Data[PreviousEncryptionKeysSecretKey]), "\n") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
 // InitFromString parses the encryption key from the provided string and adds it to the list of decryptionCiphers
 func InitFromString(data string) error {
 	if data == "" {
@@ -97,35 +123,63 @@ func NewAESCipher() error {
 		return nil
 	}
 
+	newCipher, err := newRandomAESCipher()
+	if err != nil {
+		return err
+	}
+
+	addCipher(newCipher)
+	encryptionCipher = newCipher
+	return nil
+}
+
+// GenerateKey creates a new random encryption key and returns its string representation. The key is not registered.
+func GenerateKey() (string, error) {
+	newCipher, err := newRandomAESCipher()
+	if err != nil {
+		return "", err
+	}
+	return newCipher.String(), nil
+}
+
+// SetEncryptionKey parses the encryption key from the provided string, adds it to the list of decryptionCiphers and sets it to be used for encryption.
+// Previously registered keys are kept so that data encrypted with them can still be decrypted.
+func SetEncryptionKey(data string) error {
+	newCipher, err := aesCipherFromString(data)
+	if err != nil {
+		return err
+	}
+	addCipher(newCipher)
+	encryptionCipher = newCipher
+	return nil
+}
+
+func newRandomAESCipher() (*aesCipher, error) {
 	key := make([]byte, keyLength)
 	if _, err := rand.Read(key); err != nil {
-		return errors.Wrap(err, "failed to read key")
+		return nil, errors.Wrap(err, "failed to read key")
 	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return errors.Wrap(err, "failed to create new cipher")
+		return nil, errors.Wrap(err, "failed to create new cipher")
 	}
 
 	gcm, err := cipher.NewGCM(block)
 	if err != nil {
-		return errors.Wrap(err, "failed to wrap cipher gcm")
+		return nil, errors.Wrap(err, "failed to wrap cipher gcm")
 	}
 
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := rand.Read(nonce); err != nil {
-		return errors.Wrap(err, "failed to read nonce")
+		return nil, errors.Wrap(err, "failed to read nonce")
 	}
 
-	newCipher := &aesCipher{
+	return &aesCipher{
 		key:    key,
 		cipher: gcm,
 		nonce:  nonce,
-	}
-
-	addCipher(newCipher)
-	encryptionCipher = newCipher
-	return nil
+	}, nil
 }
 
 func aesCipherFromString(data string) (newCipher *aesCipher, initErr error) {
@@ -179,7 +233,11 @@ func ToString() string {
 	if encryptionCipher == nil {
 		return ""
 	}
-	return base64.StdEncoding.EncodeToString(append(encryptionCipher.key, encryptionCipher.nonce...))
+	return encryptionCipher.String()
+}
+
+func (c *aesCipher) String() string {
+	return base64.StdEncoding.EncodeToString(append(append([]byte{}, c.key...), c.nonce...))
 }
 
 func (c *aesCipher) decrypt(in []byte) (result []byte, err error) {
@@ -202,6 +260,42 @@ func Encrypt(in []byte) []byte {
 	return encryptionCipher.cipher.Seal(nil, encryptionCipher.nonce, in, nil)
 }
 
+// EncryptWithRandomNonce encrypts the data with the registered encryption key using a newly generated nonce,
+// which is prepended to the result. Unlike Encrypt, no key is generated if none has been registered.
+func EncryptWithRandomNonce(in []byte) ([]byte, error) {
+	if encryptionCipher == nil {
+		return nil, NoEncryptionKeyErr{}
+	}
+
+	nonce := make([]byte, encryptionCipher.cipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to read nonce")
+	}
+
+	return encryptionCipher.cipher.Seal(nonce, nonce, in, nil), nil
+}
+
+// DecryptWithRandomNonce attempts to decrypt data produced by EncryptWithRandomNonce with all registered keys
+func DecryptWithRandomNonce(in []byte) (result []byte, err error) {
+	if len(decryptionCiphers) == 0 {
+		return nil, NoDecryptionKeysErr{}
+	}
+
+	err = errors.New("ciphertext too short")
+	for _, decryptCipher := range decryptionCiphers {
+		nonceSize := decryptCipher.cipher.NonceSize()
+		if len(in) < nonceSize {
+			continue
+		}
+		result, err = decryptCipher.cipher.Open(nil, in[:nonceSize], in[nonceSize:], nil)
+		if err != nil {
+			continue
+		}
+		return result, nil
+	}
+	return nil, err
+}
+
 // Decrypt attempts to decrypt the provided data with all registered keys
 func Decrypt(in []byte) (result []byte, err error) {
 	if len(decryptionCiphers) == 0 {
@@ -219,8 +313,36 @@ func Decrypt(in []byte) (result []byte, err error) {
 	return nil, err
 }
 
+// IsEncryptedWithCurrentKey returns true if the data produced by Encrypt can be decrypted with the registered encryption key
+func IsEncryptedWithCurrentKey(in []byte) bool {
+	if encryptionCipher == nil {
+		return false
+	}
+	_, err := encryptionCipher.decrypt(in)
+	return err == nil
+}
+
+// IsEncryptedWithCurrentKeyRandomNonce returns true if the data produced by EncryptWithRandomNonce can be decrypted with the registered encryption key
+func IsEncryptedWithCurrentKeyRandomNonce(in []byte) bool {
+	if encryptionCipher == nil {
+		return false
+	}
+	nonceSize := encryptionCipher.cipher.NonceSize()
+	if len(in) < nonceSize {
+		return false
+	}
+	_, err := encryptionCipher.cipher.Open(nil, in[:nonceSize], in[nonceSize:], nil)
+	return err == nil
+}
+
 type NoDecryptionKeysErr struct{}
 
 func (e NoDecryptionKeysErr) Error() string {
 	return "no decryption ciphers loaded"
 }
+
+type NoEncryptionKeyErr struct{}
+
+func (e NoEncryptionKeyErr) Error() string {
+	return "no encryption cipher loaded"
+}
@@ -160,3 +160,96 @@ func Test_InitFromSecret(t *testing.T) {
 	req.NoError(err)
 	req.Equal(testString, string(decryptedData))
 }
+
+func Test_RandomNonce(t *testing.T) {
+	req := require.New(t)
+
+	encryptionCipher = nil
+	decryptionCiphers = nil
+
+	// a key is never generated implicitly
+	_, err := EncryptWithRandomNonce([]byte("this is a test"))
+	req.ErrorIs(err, NoEncryptionKeyErr{})
+
+	req.NoError(NewAESCipher())
+
+	// encrypting the same value twice produces different ciphertexts
+	first, err := EncryptWithRandomNonce([]byte("this is a test"))
+	req.NoError(err)
+	second, err := EncryptWithRandomNonce([]byte("this is a test"))
+	req.NoError(err)
+	req.NotEqual(first, second)
+
+	decrypted, err := DecryptWithRandomNonce(first)
+	req.NoError(err)
+	req.Equal("this is a test", string(decrypted))
+
+	decrypted, err = DecryptWithRandomNonce(second)
+	req.NoError(err)
+	req.Equal("this is a test", string(decrypted))
+
+	// tampered data fails authentication
+	first[len(first)-1] ^= 0xff
+	_, err = DecryptWithRandomNonce(first)
+	req.Error(err)
+
+	_, err = DecryptWithRandomNonce([]byte("short"))
+	req.Error(err)
+}
+
+func Test_KeyRotation(t *testing.T) {
+	req := require.New(t)
+
+	encryptionCipher = nil
+	decryptionCiphers = nil
+
+	req.NoError(NewAESCipher())
+	oldKey := ToString()
+	encryptedWithOldKey := Encrypt([]byte("this is a test"))
+	randomNonceWithOldKey, err := EncryptWithRandomNonce([]byte("this is a test"))
+	req.NoError(err)
+	req.True(IsEncryptedWithCurrentKey(encryptedWithOldKey))
+	req.True(IsEncryptedWithCurrentKeyRandomNonce(randomNonceWithOldKey))
+
+	newKey, err := GenerateKey()
+	req.NoError(err)
+	req.NotEqual(oldKey, newKey)
+	req.Equal(oldKey, ToString(), "generating a key does not register it")
+
+	req.NoError(SetEncryptionKey(newKey))
+	req.Equal(newKey, ToString())
+
+	// data encrypted with the old key can still be decrypted, but is no longer encrypted with the current key
+	req.False(IsEncryptedWithCurrentKey(encryptedWithOldKey))
+	req.False(IsEncryptedWithCurrentKeyRandomNonce(randomNonceWithOldKey))
+	decrypted, err := Decrypt(encryptedWithOldKey)
+	req.NoError(err)
+	req.Equal("this is a test", string(decrypted))
+
+	req.True(IsEncryptedWithCurrentKey(Encrypt([]byte("this is a test"))))
+
+	// previous keys are loaded from the secret alongside the current key
+	encryptionCipher = nil
+	decryptionCiphers = nil
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "kotsadm-encryption",
+				Namespace: "testns",
+			},
+			Data: map[string][]byte{
+				"encryptionKey":          []byte(newKey),
+				"previousEncryptionKeys": []byte(oldKey + "\n"),
+			},
+		})
+	req.NoError(InitFromSecret(clientset, "testns"))
+	req.Equal(newKey, ToString())
+
+	decrypted, err = Decrypt(encryptedWithOldKey)
+	req.NoError(err)
+	req.Equal("this is a test", string(decrypted))
+	decrypted, err = DecryptWithRandomNonce(randomNonceWithOldKey)
+	req.NoError(err)
+	req.Equal("this is a test", string(decrypted))
+}
@@ -0,0 +1,131 @@
+package dbbackup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/buildversion"
+	"github.com/replicatedhq/kots/pkg/dbbackup/types"
+)
+
+const (
+	backupsPrefix    = "db-backups/"
+	backupSuffix     = ".sqlite.gz"
+	backupNamePrefix = "kotsadm-"
+	backupTimeLayout = "20060102T150405Z"
+)
+
+var (
+	backupNameRegex = regexp.MustCompile(`^kotsadm-\d{8}T\d{6}Z$`)
+
+	// every sqlite database file starts with this header
+	sqliteHeader = []byte("SQLite format 3\x00")
+)
+
+func backupName(createdAt time.Time) string {
+	return backupNamePrefix + createdAt.UTC().Format(backupTimeLayout)
+}
+
+func backupPath(name string) string {
+	return backupsPrefix + name + backupSuffix
+}
+
+// IsValidBackupName returns true if the name refers to a backup created by kotsadm
+func IsValidBackupName(name string) bool {
+	return backupNameRegex.MatchString(name)
+}
+
+func backupFromPath(path string) (*types.Backup, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(path, backupsPrefix), backupSuffix)
+	if !IsValidBackupName(name) {
+		return nil, false
+	}
+
+	createdAt, err := time.Parse(backupTimeLayout, strings.TrimPrefix(name, backupNamePrefix))
+	if err != nil {
+		return nil, false
+	}
+
+	return &types.Backup{
+		Name:      name,
+		CreatedAt: createdAt,
+	}, true
+}
+
+// WriteBackupArchive compresses the database and writes it to w. the metadata used to validate the backup
+// is stored in the gzip header so that the archive remains a plain gzipped sqlite database.
+func WriteBackupArchive(w io.Writer, database []byte, createdAt time.Time) error {
+	checksum := sha256.Sum256(database)
+	metadata := types.Metadata{
+		KotsadmVersion: buildversion.Version(),
+		CreatedAt:      createdAt.UTC(),
+		Size:           int64(len(database)),
+		SHA256:         hex.EncodeToString(checksum[:]),
+	}
+
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal metadata")
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	gzipWriter.Name = "kotsadm.sqlite"
+	gzipWriter.ModTime = createdAt
+	gzipWriter.Comment = string(b)
+
+	if _, err := gzipWriter.Write(database); err != nil {
+		return errors.Wrap(err, "failed to write database")
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return errors.Wrap(err, "failed to close gzip writer")
+	}
+
+	return nil
+}
+
+// ReadBackupArchive reads a backup archive written by WriteBackupArchive and validates that it contains an intact kotsadm database
+func ReadBackupArchive(r io.Reader) ([]byte, *types.Metadata, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "backup is not a gzip archive")
+	}
+	defer gzipReader.Close()
+
+	metadata := types.Metadata{}
+	if err := json.Unmarshal([]byte(gzipReader.Comment), &metadata); err != nil || metadata.SHA256 == "" {
+		return nil, nil, errors.New("backup does not contain kotsadm database backup metadata")
+	}
+
+	database, err := io.ReadAll(gzipReader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to decompress backup")
+	}
+
+	if int64(len(database)) != metadata.Size {
+		return nil, nil, errors.Errorf("backup size %d does not match expected size %d", len(database), metadata.Size)
+	}
+
+	checksum := sha256.Sum256(database)
+	if hex.EncodeToString(checksum[:]) != metadata.SHA256 {
+		return nil, nil, errors.New("backup checksum does not match")
+	}
+
+	if !bytes.HasPrefix(database, sqliteHeader) {
+		return nil, nil, errors.New("backup is not a sqlite database")
+	}
+
+	// the schema is stored in plain text in the sqlite_master table
+	if !bytes.Contains(database, []byte("kotsadm_params")) {
+		return nil, nil, errors.New("backup is not a kotsadm database")
+	}
+
+	return database, &metadata, nil
+}
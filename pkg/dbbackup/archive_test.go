@@ -0,0 +1,116 @@
+package dbbackup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDatabase() []byte {
+	return append([]byte("SQLite format 3\x00"), []byte("...CREATE TABLE kotsadm_params (key TEXT PRIMARY KEY, value TEXT)...")...)
+}
+
+func TestBackupArchive(t *testing.T) {
+	req := require.New(t)
+
+	createdAt := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	database := testDatabase()
+
+	archive := bytes.NewBuffer(nil)
+	req.NoError(WriteBackupArchive(archive, database, createdAt))
+
+	// the archive is a plain gzipped database
+	gzipReader, err := gzip.NewReader(bytes.NewReader(archive.Bytes()))
+	req.NoError(err)
+	assert.Equal(t, "kotsadm.sqlite", gzipReader.Name)
+
+	restored, metadata, err := ReadBackupArchive(bytes.NewReader(archive.Bytes()))
+	req.NoError(err)
+	assert.Equal(t, database, restored)
+	assert.Equal(t, createdAt, metadata.CreatedAt)
+	assert.Equal(t, int64(len(database)), metadata.Size)
+}
+
+func TestReadBackupArchive_Invalid(t *testing.T) {
+	gzipped := func(comment string, data []byte) []byte {
+		b := bytes.NewBuffer(nil)
+		w := gzip.NewWriter(b)
+		w.Comment = comment
+		w.Write(data)
+		w.Close()
+		return b.Bytes()
+	}
+
+	valid := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBackupArchive(valid, testDatabase(), time.Now()))
+	truncated := valid.Bytes()[:valid.Len()-10]
+
+	notSqlite := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBackupArchive(notSqlite, []byte("not a database"), time.Now()))
+
+	notKotsadm := bytes.NewBuffer(nil)
+	require.NoError(t, WriteBackupArchive(notKotsadm, []byte("SQLite format 3\x00other"), time.Now()))
+
+	tests := []struct {
+		name    string
+		archive []byte
+		wantErr string
+	}{
+		{
+			name:    "not gzip",
+			archive: []byte("SQLite format 3\x00"),
+			wantErr: "backup is not a gzip archive",
+		},
+		{
+			name:    "no metadata",
+			archive: gzipped("", testDatabase()),
+			wantErr: "backup does not contain kotsadm database backup metadata",
+		},
+		{
+			name:    "checksum mismatch",
+			archive: gzipped(fmt.Sprintf(`{"size":%d,"sha256":"0000"}`, len(testDatabase())), testDatabase()),
+			wantErr: "backup checksum does not match",
+		},
+		{
+			name:    "truncated",
+			archive: truncated,
+			wantErr: "failed to decompress backup",
+		},
+		{
+			name:    "not sqlite",
+			archive: notSqlite.Bytes(),
+			wantErr: "backup is not a sqlite database",
+		},
+		{
+			name:    "not kotsadm",
+			archive: notKotsadm.Bytes(),
+			wantErr: "backup is not a kotsadm database",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ReadBackupArchive(bytes.NewReader(tt.archive))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestBackupFromPath(t *testing.T) {
+	createdAt := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+
+	backup, ok := backupFromPath(backupPath(backupName(createdAt)))
+	require.True(t, ok)
+	assert.Equal(t, "kotsadm-20230405T060708Z", backup.Name)
+	assert.Equal(t, createdAt, backup.CreatedAt)
+
+	for _, path := range []string{"db-backups/other.sqlite.gz", "db-backups/kotsadm-20230405T060708Z.tar.gz", "db-backups/../kotsadm-20230405T060708Z.sqlite.gz"} {
+		_, ok := backupFromPath(path)
+		assert.False(t, ok, path)
+	}
+}
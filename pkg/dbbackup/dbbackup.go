@@ -0,0 +1,272 @@
+package dbbackup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/dbbackup/types"
+	"github.com/replicatedhq/kots/pkg/filestore"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	// DefaultRetentionCount - the number of backups to keep when no retention count is configured
+	DefaultRetentionCount = 7
+)
+
+var (
+	// ErrDatabaseBackedStore is returned when archives are stored in rqlite, in which case a backup of
+	// the database can't be stored outside of the database it's backing up
+	ErrDatabaseBackedStore = errors.New("database backups require object storage or a persistent volume for archives")
+
+	// ErrBackupNotFound is returned when a backup with the requested name does not exist
+	ErrBackupNotFound = errors.New("backup not found")
+)
+
+// StartBackupCronJob - start the cron job which backs up the database according to the schedule
+// configured in the kotsadm config map. the job is not started if no schedule is configured.
+func StartBackupCronJob() error {
+	installParams, err := kotsutil.GetInstallationParams(kotsadmtypes.KotsadmConfigMap)
+	if err != nil {
+		return errors.Wrap(err, "failed to get installation params")
+	}
+
+	if installParams.DBBackupSchedule == "" {
+		return nil
+	}
+
+	if filestore.IsDatabaseBacked() {
+		logger.Infof("not scheduling database backups: %v", ErrDatabaseBackedStore)
+		return nil
+	}
+
+	logger.Debugf("starting database backup cron job with schedule %q", installParams.DBBackupSchedule)
+
+	cronJob := cron.New(cron.WithChain(
+		cron.Recover(cron.DefaultLogger),
+		cron.SkipIfStillRunning(cron.DefaultLogger),
+	))
+
+	_, err = cronJob.AddFunc(installParams.DBBackupSchedule, func() {
+		backup, err := TakeBackup()
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to take database backup"))
+			return
+		}
+		logger.Infof("created database backup %s (%d bytes)", backup.Name, backup.Size)
+
+		pruned, err := PruneBackups(installParams.DBBackupRetentionCount)
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to prune database backups"))
+			return
+		}
+		for _, name := range pruned {
+			logger.Infof("pruned database backup %s", name)
+		}
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to add cron job with schedule %q", installParams.DBBackupSchedule)
+	}
+	cronJob.Start()
+	return nil
+}
+
+// TakeBackup takes a consistent snapshot of the rqlite database and uploads it to the file store
+func TakeBackup() (*types.Backup, error) {
+	if filestore.IsDatabaseBacked() {
+		return nil, ErrDatabaseBackedStore
+	}
+
+	database, err := downloadDatabase(os.Getenv("RQLITE_URI"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to download database")
+	}
+
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	archive := bytes.NewBuffer(nil)
+	if err := WriteBackupArchive(archive, database, createdAt); err != nil {
+		return nil, errors.Wrap(err, "failed to write backup archive")
+	}
+
+	backup := &types.Backup{
+		Name:      backupName(createdAt),
+		Size:      int64(archive.Len()),
+		CreatedAt: createdAt,
+	}
+
+	if err := filestore.GetStore().WriteArchive(backupPath(backup.Name), bytes.NewReader(archive.Bytes())); err != nil {
+		return nil, errors.Wrap(err, "failed to write backup to file store")
+	}
+
+	return backup, nil
+}
+
+// ListBackups returns the database backups in the file store, newest first
+func ListBackups() ([]types.Backup, error) {
+	if filestore.IsDatabaseBacked() {
+		return []types.Backup{}, nil
+	}
+
+	paths, err := filestore.ListArchives(backupsPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list archives")
+	}
+
+	backups := []types.Backup{}
+	for _, path := range paths {
+		backup, ok := backupFromPath(path)
+		if !ok {
+			continue
+		}
+
+		size, err := filestore.GetStore().GetArchiveSize(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get size of backup %s", backup.Name)
+		}
+		backup.Size = size
+
+		backups = append(backups, *backup)
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// PruneBackups deletes all but the newest retentionCount backups and returns the names of the deleted backups.
+// if retentionCount is not positive, DefaultRetentionCount is used.
+func PruneBackups(retentionCount int) ([]string, error) {
+	if retentionCount <= 0 {
+		retentionCount = DefaultRetentionCount
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list backups")
+	}
+
+	pruned := []string{}
+	for _, backup := range backupsToPrune(backups, retentionCount) {
+		if err := filestore.GetStore().DeleteArchive(backupPath(backup.Name)); err != nil {
+			return pruned, errors.Wrapf(err, "failed to delete backup %s", backup.Name)
+		}
+		pruned = append(pruned, backup.Name)
+	}
+
+	return pruned, nil
+}
+
+// backupsToPrune returns the backups that are not retained, given a list of backups sorted newest first
+func backupsToPrune(backups []types.Backup, retentionCount int) []types.Backup {
+	if len(backups) <= retentionCount {
+		return []types.Backup{}
+	}
+	return backups[retentionCount:]
+}
+
+// GetBackup downloads the backup with the given name from the file store and returns the path to the archive.
+// the caller is responsible for removing the file.
+func GetBackup(name string) (string, error) {
+	if !IsValidBackupName(name) {
+		return "", ErrBackupNotFound
+	}
+
+	backups, err := ListBackups()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list backups")
+	}
+
+	for _, backup := range backups {
+		if backup.Name != name {
+			continue
+		}
+		path, err := filestore.GetStore().ReadArchive(backupPath(name))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to read backup from file store")
+		}
+		return path, nil
+	}
+
+	return "", ErrBackupNotFound
+}
+
+// downloadDatabase uses the rqlite backup endpoint to get a consistent copy of the sqlite database
+func downloadDatabase(rqliteURI string) ([]byte, error) {
+	endpoint, err := rqliteEndpoint(rqliteURI, "/db/backup")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get backup endpoint")
+	}
+
+	resp, err := http.Get(endpoint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, errors.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	database, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read response body")
+	}
+
+	return database, nil
+}
+
+// Restore loads the database into rqlite, replacing its contents. nothing else should be writing to
+// rqlite while the database is loaded, so kotsadm must be stopped first.
+func Restore(rqliteURI string, database []byte) error {
+	endpoint, err := rqliteEndpoint(rqliteURI, "/db/load")
+	if err != nil {
+		return errors.Wrap(err, "failed to get load endpoint")
+	}
+
+	resp, err := http.Post(endpoint, "application/octet-stream", bytes.NewReader(database))
+	if err != nil {
+		return errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return errors.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// rqliteEndpoint returns the url of an rqlite http endpoint, keeping the credentials from the connection uri
+func rqliteEndpoint(rqliteURI string, path string) (string, error) {
+	if rqliteURI == "" {
+		return "", errors.New("rqlite uri is not set")
+	}
+
+	u, err := url.Parse(rqliteURI)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse rqlite uri")
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid rqlite uri")
+	}
+
+	u.Path = path
+	u.RawQuery = ""
+
+	return u.String(), nil
+}
@@ -0,0 +1,18 @@
+package types
+
+import "time"
+
+// Backup is a database backup stored in the file store
+type Backup struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Metadata is stored alongside the database in a backup archive and used to validate it before it's restored
+type Metadata struct {
+	KotsadmVersion string    `json:"kotsadmVersion"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Size           int64     `json:"size"`
+	SHA256         string    `json:"sha256"`
+}
@@ -105,3 +105,14 @@ func (s *BlobStore) deleteFile(path string) error {
 	}
 	return nil
 }
+
+func (s *BlobStore) GetArchiveSize(path string) (int64, error) {
+	fileInfo, err := os.Stat(filepath.Join(ArchivesDir, path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, ErrNotFound
+		}
+		return 0, errors.Wrapf(err, "failed to stat file %q", path)
+	}
+	return fileInfo.Size(), nil
+}
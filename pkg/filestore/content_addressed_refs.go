@@ -0,0 +1,134 @@
+package filestore
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/persistence"
+	"github.com/rqlite/gorqlite"
+)
+
+// rqliteBlobRefCounter stores blob reference counts in the object_store_blob table
+type rqliteBlobRefCounter struct {
+}
+
+func (c *rqliteBlobRefCounter) GetRefs(hashes []string) (map[string]int64, error) {
+	db := persistence.MustGetDBSession()
+
+	refs := map[string]int64{}
+	for _, hash := range hashes {
+		rows, err := db.QueryOneParameterized(gorqlite.ParameterizedStatement{
+			Query:     `SELECT ref_count FROM object_store_blob WHERE hash = ?`,
+			Arguments: []interface{}{hash},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+		}
+		if !rows.Next() {
+			continue
+		}
+
+		var refCount int64
+		if err := rows.Scan(&refCount); err != nil {
+			return nil, errors.Wrap(err, "failed to scan")
+		}
+		if refCount > 0 {
+			refs[hash] = refCount
+		}
+	}
+
+	return refs, nil
+}
+
+func (c *rqliteBlobRefCounter) IncrementRefs(hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	db := persistence.MustGetDBSession()
+
+	statements := []gorqlite.ParameterizedStatement{}
+	for _, hash := range hashes {
+		statements = append(statements, gorqlite.ParameterizedStatement{
+			Query: `
+INSERT INTO object_store_blob (hash, ref_count)
+VALUES (?, 1)
+ON CONFLICT (hash) DO UPDATE SET
+	ref_count = ref_count + 1
+`,
+			Arguments: []interface{}{hash},
+		})
+	}
+
+	if wrs, err := db.WriteParameterized(statements); err != nil {
+		wrErrs := []error{}
+		for _, wr := range wrs {
+			wrErrs = append(wrErrs, wr.Err)
+		}
+		return fmt.Errorf("failed to write: %v: %v", err, wrErrs)
+	}
+
+	return nil
+}
+
+func (c *rqliteBlobRefCounter) DecrementRefs(hashes []string) ([]string, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	db := persistence.MustGetDBSession()
+
+	statements := []gorqlite.ParameterizedStatement{}
+	for _, hash := range hashes {
+		statements = append(statements, gorqlite.ParameterizedStatement{
+			Query:     `UPDATE object_store_blob SET ref_count = ref_count - 1 WHERE hash = ?`,
+			Arguments: []interface{}{hash},
+		})
+	}
+
+	if wrs, err := db.WriteParameterized(statements); err != nil {
+		wrErrs := []error{}
+		for _, wr := range wrs {
+			wrErrs = append(wrErrs, wr.Err)
+		}
+		return nil, fmt.Errorf("failed to write: %v: %v", err, wrErrs)
+	}
+
+	unreferenced := []string{}
+	for _, hash := range hashes {
+		rows, err := db.QueryOneParameterized(gorqlite.ParameterizedStatement{
+			Query:     `SELECT ref_count FROM object_store_blob WHERE hash = ?`,
+			Arguments: []interface{}{hash},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+		}
+		if !rows.Next() {
+			continue
+		}
+
+		var refCount int64
+		if err := rows.Scan(&refCount); err != nil {
+			return nil, errors.Wrap(err, "failed to scan")
+		}
+		if refCount > 0 {
+			continue
+		}
+
+		// only delete the row if no write referenced the blob again since it was decremented
+		wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+			Query:     `DELETE FROM object_store_blob WHERE hash = ? AND ref_count <= 0`,
+			Arguments: []interface{}{hash},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete: %v: %v", err, wr.Err)
+		}
+		if wr.RowsAffected == 0 {
+			continue
+		}
+
+		unreferenced = append(unreferenced, hash)
+	}
+
+	return unreferenced, nil
+}
@@ -0,0 +1,517 @@
+package filestore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	contentAddressedPrefix = "cas"
+	manifestVersion        = 1
+)
+
+var (
+	// writes and deletes are serialized across all content addressed stores in the process, such as the one used by
+	// the migration, so that a blob isn't deleted between the time a write references it and checks that it exists
+	contentAddressedMtx sync.Mutex
+
+	// paths under these prefixes are stored as is.
+	// support bundles are unique per collection and are deleted by prefix, which a manifest per archive can't support.
+	contentAddressedSkipPrefixes = []string{
+		"supportbundles/",
+		"db-backups/",
+	}
+)
+
+// ContentAddressedStore deduplicates tar.gz archives by storing each file in them as a blob keyed by its sha256 hash,
+// plus a manifest per archive that's used to reassemble it on read. blobs are reference counted by manifest and
+// are deleted once no manifest references them anymore.
+// archives that were written before the store was enabled are read from the backend as is.
+type ContentAddressedStore struct {
+	backend FileStore
+	refs    blobRefCounter
+}
+
+type archiveManifest struct {
+	Version int             `json:"version"`
+	Size    int64           `json:"size"`
+	Entries []manifestEntry `json:"entries"`
+}
+
+type manifestEntry struct {
+	Name     string    `json:"name"`
+	Typeflag byte      `json:"typeflag"`
+	Mode     int64     `json:"mode"`
+	ModTime  time.Time `json:"modTime"`
+	Linkname string    `json:"linkname,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	Hash     string    `json:"hash,omitempty"`
+}
+
+// blobRefCounter keeps track of the number of manifests referencing a blob
+type blobRefCounter interface {
+	// GetRefs returns the current number of references per hash. hashes that are not referenced are not included.
+	GetRefs(hashes []string) (map[string]int64, error)
+	IncrementRefs(hashes []string) error
+	// DecrementRefs returns the hashes that are no longer referenced
+	DecrementRefs(hashes []string) ([]string, error)
+}
+
+func NewContentAddressedStore(backend FileStore) *ContentAddressedStore {
+	return &ContentAddressedStore{
+		backend: backend,
+		refs:    &rqliteBlobRefCounter{},
+	}
+}
+
+func (s *ContentAddressedStore) Init() error {
+	return s.backend.Init()
+}
+
+func (s *ContentAddressedStore) WaitForReady(ctx context.Context) error {
+	return s.backend.WaitForReady(ctx)
+}
+
+func (s *ContentAddressedStore) WriteArchive(outputPath string, body io.ReadSeeker) error {
+	if !shouldStoreContentAddressed(outputPath) {
+		return s.backend.WriteArchive(outputPath, body)
+	}
+
+	contentAddressedMtx.Lock()
+	defer contentAddressedMtx.Unlock()
+
+	previousManifest, err := s.readManifest(outputPath)
+	if err != nil && errors.Cause(err) != ErrNotFound {
+		return errors.Wrap(err, "failed to read previous manifest")
+	}
+
+	manifest, blobs, err := splitArchive(body)
+	if err != nil {
+		// not a tar.gz archive, nothing to deduplicate
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return errors.Wrap(err, "failed to seek body")
+		}
+		if err := s.backend.WriteArchive(outputPath, body); err != nil {
+			return err
+		}
+		if previousManifest != nil {
+			if err := s.deleteManifest(outputPath, previousManifest); err != nil {
+				return errors.Wrap(err, "failed to delete previous manifest")
+			}
+		}
+		return nil
+	}
+
+	// the refs are incremented before checking which blobs exist, so that the blobs can't be released in the meantime
+	if err := s.refs.IncrementRefs(manifest.hashes()); err != nil {
+		return errors.Wrap(err, "failed to increment blob refs")
+	}
+	if err := s.writeManifest(outputPath, manifest, blobs); err != nil {
+		// roll back the refs, which also deletes the blobs that were written for this manifest only
+		if releaseErr := s.releaseBlobs(manifest); releaseErr != nil {
+			return errors.Wrapf(err, "failed to release blobs: %v", releaseErr)
+		}
+		return err
+	}
+
+	if previousManifest != nil {
+		if err := s.releaseBlobs(previousManifest); err != nil {
+			return errors.Wrap(err, "failed to release previous blobs")
+		}
+	} else if err := s.backend.DeleteArchive(outputPath); err != nil {
+		// remove the legacy archive, if any, so that it doesn't shadow a later delete
+		return errors.Wrap(err, "failed to delete legacy archive")
+	}
+
+	return nil
+}
+
+// writeManifest writes the blobs that don't exist yet, and then the manifest that references them
+func (s *ContentAddressedStore) writeManifest(outputPath string, manifest *archiveManifest, blobs map[string][]byte) error {
+	for hash, contents := range blobs {
+		if _, err := s.backend.GetArchiveSize(blobPath(hash)); err == nil {
+			continue
+		} else if errors.Cause(err) != ErrNotFound {
+			return errors.Wrapf(err, "failed to check blob %s", hash)
+		}
+		if err := s.backend.WriteArchive(blobPath(hash), bytes.NewReader(contents)); err != nil {
+			return errors.Wrapf(err, "failed to write blob %s", hash)
+		}
+	}
+
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal manifest")
+	}
+	if err := s.backend.WriteArchive(manifestPath(outputPath), bytes.NewReader(manifestBytes)); err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+
+	return nil
+}
+
+func (s *ContentAddressedStore) ReadArchive(archivePath string) (string, error) {
+	if !shouldStoreContentAddressed(archivePath) {
+		return s.backend.ReadArchive(archivePath)
+	}
+
+	manifest, err := s.readManifest(archivePath)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return s.backend.ReadArchive(archivePath)
+		}
+		return "", errors.Wrap(err, "failed to read manifest")
+	}
+
+	tmpFile, err := os.CreateTemp("", "kotsadm")
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create temp file")
+	}
+	defer tmpFile.Close()
+
+	if err := s.joinArchive(manifest, tmpFile); err != nil {
+		os.RemoveAll(tmpFile.Name())
+		return "", errors.Wrap(err, "failed to reassemble archive")
+	}
+
+	return tmpFile.Name(), nil
+}
+
+func (s *ContentAddressedStore) DeleteArchive(archivePath string) error {
+	if !shouldStoreContentAddressed(archivePath) {
+		return s.backend.DeleteArchive(archivePath)
+	}
+
+	contentAddressedMtx.Lock()
+	defer contentAddressedMtx.Unlock()
+
+	manifest, err := s.readManifest(archivePath)
+	if err != nil && errors.Cause(err) != ErrNotFound {
+		return errors.Wrap(err, "failed to read manifest")
+	}
+
+	if manifest != nil {
+		if err := s.deleteManifest(archivePath, manifest); err != nil {
+			return errors.Wrap(err, "failed to delete manifest")
+		}
+	}
+
+	return s.backend.DeleteArchive(archivePath)
+}
+
+func (s *ContentAddressedStore) GetArchiveSize(archivePath string) (int64, error) {
+	if !shouldStoreContentAddressed(archivePath) {
+		return s.backend.GetArchiveSize(archivePath)
+	}
+
+	manifest, err := s.readManifest(archivePath)
+	if err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return s.backend.GetArchiveSize(archivePath)
+		}
+		return 0, errors.Wrap(err, "failed to read manifest")
+	}
+
+	return manifest.Size, nil
+}
+
+// GetReclaimableSize returns the stored size of the manifests and legacy archives of the paths, plus the blobs that
+// only the paths reference. blobs that other archives share are not freed when the paths are deleted.
+func (s *ContentAddressedStore) GetReclaimableSize(paths []string) (int64, error) {
+	total := int64(0)
+	addSize := func(p string) error {
+		size, err := s.backend.GetArchiveSize(p)
+		if err != nil && errors.Cause(err) != ErrNotFound {
+			return errors.Wrapf(err, "failed to get size of %s", p)
+		}
+		total += size
+		return nil
+	}
+
+	// key is the blob hash, value is the number of the paths referencing it
+	pathRefs := map[string]int64{}
+	for _, p := range paths {
+		if !shouldStoreContentAddressed(p) {
+			if err := addSize(p); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		manifest, err := s.readManifest(p)
+		if errors.Cause(err) == ErrNotFound {
+			if err := addSize(p); err != nil {
+				return 0, err
+			}
+			continue
+		} else if err != nil {
+			return 0, errors.Wrapf(err, "failed to read manifest for %s", p)
+		}
+
+		if err := addSize(manifestPath(p)); err != nil {
+			return 0, err
+		}
+		for _, hash := range manifest.hashes() {
+			pathRefs[hash]++
+		}
+	}
+
+	hashes := []string{}
+	for hash := range pathRefs {
+		hashes = append(hashes, hash)
+	}
+	refs, err := s.refs.GetRefs(hashes)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get blob refs")
+	}
+
+	for _, hash := range hashes {
+		if refs[hash] > pathRefs[hash] {
+			continue
+		}
+		if err := addSize(blobPath(hash)); err != nil {
+			return 0, err
+		}
+	}
+
+	return total, nil
+}
+
+func (s *ContentAddressedStore) readManifest(archivePath string) (*archiveManifest, error) {
+	if _, err := s.backend.GetArchiveSize(manifestPath(archivePath)); err != nil {
+		return nil, err
+	}
+
+	localPath, err := s.backend.ReadArchive(manifestPath(archivePath))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest")
+	}
+	defer os.RemoveAll(localPath)
+
+	b, err := os.ReadFile(localPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest file")
+	}
+
+	manifest := archiveManifest{}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal manifest")
+	}
+	if manifest.Version != manifestVersion {
+		return nil, errors.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+func (s *ContentAddressedStore) deleteManifest(archivePath string, manifest *archiveManifest) error {
+	if err := s.backend.DeleteArchive(manifestPath(archivePath)); err != nil {
+		return errors.Wrap(err, "failed to delete manifest")
+	}
+	if err := s.releaseBlobs(manifest); err != nil {
+		return errors.Wrap(err, "failed to release blobs")
+	}
+	return nil
+}
+
+func (s *ContentAddressedStore) releaseBlobs(manifest *archiveManifest) error {
+	unreferenced, err := s.refs.DecrementRefs(manifest.hashes())
+	if err != nil {
+		return errors.Wrap(err, "failed to decrement blob refs")
+	}
+
+	for _, hash := range unreferenced {
+		if err := s.backend.DeleteArchive(blobPath(hash)); err != nil {
+			return errors.Wrapf(err, "failed to delete blob %s", hash)
+		}
+	}
+
+	return nil
+}
+
+func (s *ContentAddressedStore) joinArchive(manifest *archiveManifest, w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, entry := range manifest.Entries {
+		header := &tar.Header{
+			Name:     entry.Name,
+			Typeflag: entry.Typeflag,
+			Mode:     entry.Mode,
+			ModTime:  entry.ModTime,
+			Linkname: entry.Linkname,
+			Size:     entry.Size,
+		}
+		if err := tarWriter.WriteHeader(header); err != nil {
+			return errors.Wrapf(err, "failed to write header for %s", entry.Name)
+		}
+
+		if entry.Hash == "" {
+			continue
+		}
+
+		if err := s.copyBlob(entry.Hash, tarWriter); err != nil {
+			return errors.Wrapf(err, "failed to copy blob for %s", entry.Name)
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return errors.Wrap(err, "failed to close tar writer")
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return errors.Wrap(err, "failed to close gzip writer")
+	}
+
+	return nil
+}
+
+func (s *ContentAddressedStore) copyBlob(hash string, w io.Writer) error {
+	localPath, err := s.backend.ReadArchive(blobPath(hash))
+	if err != nil {
+		return errors.Wrap(err, "failed to read blob")
+	}
+	defer os.RemoveAll(localPath)
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to open blob")
+	}
+	defer f.Close()
+
+	gzipReader, err := gzip.NewReader(f)
+	if err != nil {
+		return errors.Wrap(err, "failed to create gzip reader")
+	}
+	defer gzipReader.Close()
+
+	if _, err := io.Copy(w, gzipReader); err != nil {
+		return errors.Wrap(err, "failed to copy blob")
+	}
+
+	return nil
+}
+
+// splitArchive reads a tar.gz archive and returns its manifest along with the gzipped contents of its files keyed by hash
+func splitArchive(body io.Reader) (*archiveManifest, map[string][]byte, error) {
+	counter := &countingReader{r: body}
+
+	gzipReader, err := gzip.NewReader(counter)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create gzip reader")
+	}
+	defer gzipReader.Close()
+
+	manifest := &archiveManifest{
+		Version: manifestVersion,
+		Entries: []manifestEntry{},
+	}
+	blobs := map[string][]byte{}
+
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to read tar header")
+		}
+
+		entry := manifestEntry{
+			Name:     header.Name,
+			Typeflag: header.Typeflag,
+			Mode:     header.Mode,
+			ModTime:  header.ModTime,
+			Linkname: header.Linkname,
+		}
+
+		if header.Typeflag == tar.TypeReg {
+			contents, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "failed to read %s", header.Name)
+			}
+
+			sum := sha256.Sum256(contents)
+			entry.Hash = hex.EncodeToString(sum[:])
+			entry.Size = int64(len(contents))
+
+			if _, ok := blobs[entry.Hash]; !ok {
+				var gzipped bytes.Buffer
+				gzipWriter := gzip.NewWriter(&gzipped)
+				if _, err := gzipWriter.Write(contents); err != nil {
+					return nil, nil, errors.Wrapf(err, "failed to compress %s", header.Name)
+				}
+				if err := gzipWriter.Close(); err != nil {
+					return nil, nil, errors.Wrapf(err, "failed to compress %s", header.Name)
+				}
+				blobs[entry.Hash] = gzipped.Bytes()
+			}
+		}
+
+		manifest.Entries = append(manifest.Entries, entry)
+	}
+
+	// drain the rest of the stream so that the recorded size matches the original archive
+	if _, err := io.Copy(io.Discard, counter); err != nil {
+		return nil, nil, errors.Wrap(err, "failed to read archive")
+	}
+	manifest.Size = counter.n
+
+	return manifest, blobs, nil
+}
+
+// hashes returns the unique blob hashes referenced by the manifest
+func (m *archiveManifest) hashes() []string {
+	seen := map[string]bool{}
+	hashes := []string{}
+	for _, entry := range m.Entries {
+		if entry.Hash == "" || seen[entry.Hash] {
+			continue
+		}
+		seen[entry.Hash] = true
+		hashes = append(hashes, entry.Hash)
+	}
+	return hashes
+}
+
+func shouldStoreContentAddressed(archivePath string) bool {
+	for _, prefix := range contentAddressedSkipPrefixes {
+		if strings.HasPrefix(archivePath, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func blobPath(hash string) string {
+	return path.Join(contentAddressedPrefix, "blobs", hash[:2], hash)
+}
+
+func manifestPath(archivePath string) string {
+	return path.Join(contentAddressedPrefix, "manifests", fmt.Sprintf("%s.json", archivePath))
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
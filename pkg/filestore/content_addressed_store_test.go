@@ -0,0 +1,324 @@
+package filestore
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memoryBlobRefCounter struct {
+	refs map[string]int
+}
+
+func (c *memoryBlobRefCounter) GetRefs(hashes []string) (map[string]int64, error) {
+	refs := map[string]int64{}
+	for _, hash := range hashes {
+		if c.refs[hash] > 0 {
+			refs[hash] = int64(c.refs[hash])
+		}
+	}
+	return refs, nil
+}
+
+func (c *memoryBlobRefCounter) IncrementRefs(hashes []string) error {
+	for _, hash := range hashes {
+		c.refs[hash]++
+	}
+	return nil
+}
+
+func (c *memoryBlobRefCounter) DecrementRefs(hashes []string) ([]string, error) {
+	unreferenced := []string{}
+	for _, hash := range hashes {
+		c.refs[hash]--
+		if c.refs[hash] <= 0 {
+			delete(c.refs, hash)
+			unreferenced = append(unreferenced, hash)
+		}
+	}
+	return unreferenced, nil
+}
+
+func newTestContentAddressedStore(t *testing.T) (*ContentAddressedStore, *memoryBlobRefCounter) {
+	archivesDir := ArchivesDir
+	ArchivesDir = t.TempDir()
+	t.Cleanup(func() {
+		ArchivesDir = archivesDir
+	})
+
+	refs := &memoryBlobRefCounter{refs: map[string]int{}}
+	return &ContentAddressedStore{
+		backend: &BlobStore{},
+		refs:    refs,
+	}, refs
+}
+
+func createTestArchive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	modTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: "upstream/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: modTime}))
+	for _, name := range []string{"upstream/a.yaml", "upstream/b.yaml", "upstream/c.yaml"} {
+		contents, ok := files[name]
+		if !ok {
+			continue
+		}
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, ModTime: modTime, Size: int64(len(contents))}))
+		_, err := tarWriter.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	require.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+func readTestArchive(t *testing.T, archivePath string) map[string]string {
+	f, err := os.Open(archivePath)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gzipReader, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tarReader := tar.NewReader(gzipReader)
+
+	files := map[string]string{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		contents, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+		files[header.Name] = string(contents)
+	}
+	return files
+}
+
+func countBlobs(t *testing.T) int {
+	count := 0
+	err := filepath.Walk(filepath.Join(ArchivesDir, contentAddressedPrefix, "blobs"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return 0
+	}
+	require.NoError(t, err)
+	return count
+}
+
+func TestContentAddressedStore_Deduplication(t *testing.T) {
+	req := require.New(t)
+	store, refs := newTestContentAddressedStore(t)
+
+	v1 := map[string]string{"upstream/a.yaml": "a: 1", "upstream/b.yaml": "b: 1"}
+	v2 := map[string]string{"upstream/a.yaml": "a: 1", "upstream/b.yaml": "b: 2", "upstream/c.yaml": "a: 1"}
+
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(createTestArchive(t, v1))))
+	req.NoError(store.WriteArchive("app/1.tar.gz", bytes.NewReader(createTestArchive(t, v2))))
+
+	// "a: 1" is shared between both versions and files, so there are only three unique blobs
+	assert.Equal(t, 3, countBlobs(t))
+	assert.Len(t, refs.refs, 3)
+
+	for archivePath, want := range map[string]map[string]string{"app/0.tar.gz": v1, "app/1.tar.gz": v2} {
+		localPath, err := store.ReadArchive(archivePath)
+		req.NoError(err)
+		assert.Equal(t, want, readTestArchive(t, localPath))
+		os.RemoveAll(localPath)
+	}
+
+	size, err := store.GetArchiveSize("app/1.tar.gz")
+	req.NoError(err)
+	assert.Equal(t, int64(len(createTestArchive(t, v2))), size)
+
+	// deleting the first version only removes the blob that's not used by the second one
+	req.NoError(store.DeleteArchive("app/0.tar.gz"))
+	assert.Equal(t, 2, countBlobs(t))
+
+	_, err = store.GetArchiveSize("app/0.tar.gz")
+	assert.Equal(t, ErrNotFound, err)
+
+	req.NoError(store.DeleteArchive("app/1.tar.gz"))
+	assert.Equal(t, 0, countBlobs(t))
+	assert.Empty(t, refs.refs)
+}
+
+func TestContentAddressedStore_Overwrite(t *testing.T) {
+	req := require.New(t)
+	store, refs := newTestContentAddressedStore(t)
+
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{"upstream/a.yaml": "a: 1"}))))
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{"upstream/a.yaml": "a: 2"}))))
+
+	assert.Equal(t, 1, countBlobs(t))
+	assert.Len(t, refs.refs, 1)
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+	assert.Equal(t, map[string]string{"upstream/a.yaml": "a: 2"}, readTestArchive(t, localPath))
+}
+
+// manifestFailingStore fails to write manifests, like a backend that becomes unavailable in the middle of a write
+type manifestFailingStore struct {
+	BlobStore
+}
+
+func (s *manifestFailingStore) WriteArchive(outputPath string, body io.ReadSeeker) error {
+	if strings.HasPrefix(outputPath, path.Join(contentAddressedPrefix, "manifests")+"/") {
+		return errors.New("manifest write failed")
+	}
+	return s.BlobStore.WriteArchive(outputPath, body)
+}
+
+func TestContentAddressedStore_ManifestWriteFails(t *testing.T) {
+	store, refs := newTestContentAddressedStore(t)
+
+	require.NoError(t, store.WriteArchive("a.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{
+		"upstream/a.yaml": "shared",
+	}))))
+	require.Equal(t, 1, countBlobs(t))
+
+	store.backend = &manifestFailingStore{}
+	err := store.WriteArchive("b.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{
+		"upstream/a.yaml": "shared",
+		"upstream/b.yaml": "only in b",
+	})))
+	require.Error(t, err)
+
+	// the refs are rolled back, and only the blob that is still referenced is kept
+	assert.Len(t, refs.refs, 1)
+	for _, count := range refs.refs {
+		assert.Equal(t, 1, count)
+	}
+	assert.Equal(t, 1, countBlobs(t))
+
+	store.backend = &BlobStore{}
+	_, err = store.ReadArchive("b.tar.gz")
+	assert.Error(t, err)
+	archivePath, err := store.ReadArchive("a.tar.gz")
+	require.NoError(t, err)
+	defer os.RemoveAll(archivePath)
+	assert.Equal(t, map[string]string{"upstream/a.yaml": "shared"}, readTestArchive(t, archivePath))
+}
+
+func TestContentAddressedStore_GetReclaimableSize(t *testing.T) {
+	store, _ := newTestContentAddressedStore(t)
+
+	blobSize := func(contents string) int64 {
+		sum := sha256.Sum256([]byte(contents))
+		size, err := store.backend.GetArchiveSize(blobPath(hex.EncodeToString(sum[:])))
+		require.NoError(t, err)
+		return size
+	}
+	manifestSize := func(archivePath string) int64 {
+		size, err := store.backend.GetArchiveSize(manifestPath(archivePath))
+		require.NoError(t, err)
+		return size
+	}
+
+	require.NoError(t, store.WriteArchive("app/1.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{
+		"upstream/a.yaml": "shared",
+		"upstream/b.yaml": "in 1 and 2",
+	}))))
+	require.NoError(t, store.WriteArchive("app/2.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{
+		"upstream/a.yaml": "shared",
+		"upstream/b.yaml": "in 1 and 2",
+		"upstream/c.yaml": "only in 2",
+	}))))
+	require.NoError(t, store.WriteArchive("app/3.tar.gz", bytes.NewReader(createTestArchive(t, map[string]string{
+		"upstream/a.yaml": "shared",
+	}))))
+
+	// only the blob that no other version references is freed
+	size, err := store.GetReclaimableSize([]string{"app/2.tar.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, manifestSize("app/2.tar.gz")+blobSize("only in 2"), size)
+
+	// blobs shared only between the deleted versions are freed once
+	size, err = store.GetReclaimableSize([]string{"app/1.tar.gz", "app/2.tar.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, manifestSize("app/1.tar.gz")+manifestSize("app/2.tar.gz")+blobSize("in 1 and 2")+blobSize("only in 2"), size)
+
+	size, err = store.GetReclaimableSize([]string{"app/4.tar.gz"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), size)
+}
+
+func TestContentAddressedStore_Legacy(t *testing.T) {
+	req := require.New(t)
+	store, _ := newTestContentAddressedStore(t)
+
+	// archives written before the store was enabled are read as is
+	legacy := createTestArchive(t, map[string]string{"upstream/a.yaml": "a: 1"})
+	req.NoError(store.backend.WriteArchive("app/0.tar.gz", bytes.NewReader(legacy)))
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	assert.Equal(t, map[string]string{"upstream/a.yaml": "a: 1"}, readTestArchive(t, localPath))
+	os.RemoveAll(localPath)
+
+	// migrating the archive replaces the legacy copy
+	keys, err := listLegacyArchiveKeys(store.backend)
+	req.NoError(err)
+	assert.Equal(t, []string{"app/0.tar.gz"}, keys)
+
+	localPath, err = store.backend.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+	req.NoError(migrateArchiveToContentAddressedStore(store, "app/0.tar.gz", localPath))
+
+	_, err = os.Stat(filepath.Join(ArchivesDir, "app/0.tar.gz"))
+	assert.True(t, os.IsNotExist(err))
+
+	keys, err = listLegacyArchiveKeys(store.backend)
+	req.NoError(err)
+	assert.Empty(t, keys)
+
+	migratedPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(migratedPath)
+	assert.Equal(t, map[string]string{"upstream/a.yaml": "a: 1"}, readTestArchive(t, migratedPath))
+}
+
+func TestContentAddressedStore_NonArchive(t *testing.T) {
+	req := require.New(t)
+	store, refs := newTestContentAddressedStore(t)
+
+	req.NoError(store.WriteArchive("app/metadata.json", bytes.NewReader([]byte(`{"a":1}`))))
+	assert.Empty(t, refs.refs)
+
+	localPath, err := store.ReadArchive("app/metadata.json")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+
+	b, err := os.ReadFile(localPath)
+	req.NoError(err)
+	assert.Equal(t, `{"a":1}`, string(b))
+}
@@ -0,0 +1,211 @@
+package filestore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/crypto"
+)
+
+var (
+	// encryptedArchiveHeader prefixes encrypted archives so that they can be told apart from
+	// archives that were written in plaintext before encryption was enabled
+	encryptedArchiveHeader = []byte("KOTSADM-ENCRYPTED-V1\n")
+
+	// archiveLocks serializes writes, deletes and re-encryption of the same archive across all encrypted stores,
+	// so that re-encrypting an archive in the background can't overwrite a newer write with the older contents
+	archiveLocks = newPathLocker()
+)
+
+// pathLocker holds a mutex per path, which is removed once no one is holding or waiting for it
+type pathLocker struct {
+	mtx   sync.Mutex
+	locks map[string]*pathLock
+}
+
+type pathLock struct {
+	sync.Mutex
+	waiters int
+}
+
+func newPathLocker() *pathLocker {
+	return &pathLocker{
+		locks: map[string]*pathLock{},
+	}
+}
+
+// lock locks the path and returns the function that unlocks it
+func (l *pathLocker) lock(path string) func() {
+	l.mtx.Lock()
+	pl, ok := l.locks[path]
+	if !ok {
+		pl = &pathLock{}
+		l.locks[path] = pl
+	}
+	pl.waiters++
+	l.mtx.Unlock()
+
+	pl.Lock()
+
+	return func() {
+		pl.Unlock()
+
+		l.mtx.Lock()
+		pl.waiters--
+		if pl.waiters == 0 {
+			delete(l.locks, path)
+		}
+		l.mtx.Unlock()
+	}
+}
+
+// EncryptedStore encrypts archives with the kotsadm encryption key before writing them to the backend.
+// archives that were written in plaintext are read as is until they are re-encrypted.
+type EncryptedStore struct {
+	backend FileStore
+}
+
+func NewEncryptedStore(backend FileStore) *EncryptedStore {
+	return &EncryptedStore{
+		backend: backend,
+	}
+}
+
+func (s *EncryptedStore) Init() error {
+	return s.backend.Init()
+}
+
+func (s *EncryptedStore) WaitForReady(ctx context.Context) error {
+	return s.backend.WaitForReady(ctx)
+}
+
+func (s *EncryptedStore) WriteArchive(outputPath string, body io.ReadSeeker) error {
+	unlock := archiveLocks.lock(outputPath)
+	defer unlock()
+
+	return s.writeArchive(outputPath, body)
+}
+
+func (s *EncryptedStore) writeArchive(outputPath string, body io.ReadSeeker) error {
+	plaintext, err := io.ReadAll(body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read body")
+	}
+
+	encrypted, err := encryptArchive(plaintext)
+	if err != nil {
+		return errors.Wrap(err, "failed to encrypt archive")
+	}
+
+	return s.backend.WriteArchive(outputPath, bytes.NewReader(encrypted))
+}
+
+func (s *EncryptedStore) ReadArchive(path string) (string, error) {
+	localPath, err := s.backend.ReadArchive(path)
+	if err != nil {
+		return "", err
+	}
+
+	contents, err := os.ReadFile(localPath)
+	if err != nil {
+		os.RemoveAll(localPath)
+		return "", errors.Wrap(err, "failed to read archive")
+	}
+
+	if !isEncryptedArchive(contents) {
+		return localPath, nil
+	}
+
+	plaintext, err := decryptArchive(contents)
+	if err != nil {
+		os.RemoveAll(localPath)
+		return "", errors.Wrapf(err, "failed to decrypt archive %q", path)
+	}
+
+	if err := os.WriteFile(localPath, plaintext, 0644); err != nil {
+		os.RemoveAll(localPath)
+		return "", errors.Wrap(err, "failed to write decrypted archive")
+	}
+
+	return localPath, nil
+}
+
+func (s *EncryptedStore) DeleteArchive(path string) error {
+	unlock := archiveLocks.lock(path)
+	defer unlock()
+
+	return s.backend.DeleteArchive(path)
+}
+
+// GetArchiveSize returns the stored size of the archive, which includes the encryption overhead
+func (s *EncryptedStore) GetArchiveSize(path string) (int64, error) {
+	return s.backend.GetArchiveSize(path)
+}
+
+// reencryptArchive encrypts the archive in place if it's stored in plaintext or was encrypted with a key other than the current encryption key.
+// returns true if the archive needed to be re-encrypted. when dryRun is true, the archive is only checked.
+func (s *EncryptedStore) reencryptArchive(path string, dryRun bool) (bool, error) {
+	// the archive is locked from the read to the write so that a concurrent write isn't overwritten with the older contents
+	unlock := archiveLocks.lock(path)
+	defer unlock()
+
+	// the archive may have been deleted since the archives were listed
+	if _, err := s.backend.GetArchiveSize(path); err != nil {
+		if errors.Cause(err) == ErrNotFound {
+			return false, nil
+		}
+		return false, errors.Wrap(err, "failed to check archive")
+	}
+
+	localPath, err := s.backend.ReadArchive(path)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read archive")
+	}
+	defer os.RemoveAll(localPath)
+
+	contents, err := os.ReadFile(localPath)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to read archive file")
+	}
+
+	if isEncryptedArchive(contents) && crypto.IsEncryptedWithCurrentKeyRandomNonce(contents[len(encryptedArchiveHeader):]) {
+		return false, nil
+	}
+	if dryRun {
+		return true, nil
+	}
+
+	plaintext := contents
+	if isEncryptedArchive(contents) {
+		plaintext, err = decryptArchive(contents)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to decrypt archive")
+		}
+	}
+
+	if err := s.writeArchive(path, bytes.NewReader(plaintext)); err != nil {
+		return false, errors.Wrap(err, "failed to write encrypted archive")
+	}
+
+	return true, nil
+}
+
+func encryptArchive(plaintext []byte) ([]byte, error) {
+	ciphertext, err := crypto.EncryptWithRandomNonce(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, encryptedArchiveHeader...), ciphertext...), nil
+}
+
+func decryptArchive(contents []byte) ([]byte, error) {
+	return crypto.DecryptWithRandomNonce(contents[len(encryptedArchiveHeader):])
+}
+
+func isEncryptedArchive(contents []byte) bool {
+	return bytes.HasPrefix(contents, encryptedArchiveHeader)
+}
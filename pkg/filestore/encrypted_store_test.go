@@ -0,0 +1,197 @@
+package filestore
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestEncryptedStore(t *testing.T) *EncryptedStore {
+	archivesDir := ArchivesDir
+	ArchivesDir = t.TempDir()
+	t.Cleanup(func() {
+		ArchivesDir = archivesDir
+	})
+
+	require.NoError(t, crypto.NewAESCipher())
+
+	return NewEncryptedStore(&BlobStore{})
+}
+
+func TestEncryptedStore(t *testing.T) {
+	req := require.New(t)
+	store := newTestEncryptedStore(t)
+
+	plaintext := []byte("apiVersion: kots.io/v1beta1\nkind: ConfigValues\n")
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(plaintext)))
+
+	// the archive is not stored in plaintext
+	stored, err := os.ReadFile(filepath.Join(ArchivesDir, "app/0.tar.gz"))
+	req.NoError(err)
+	assert.True(t, isEncryptedArchive(stored))
+	assert.NotContains(t, string(stored), "ConfigValues")
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+
+	contents, err := os.ReadFile(localPath)
+	req.NoError(err)
+	assert.Equal(t, plaintext, contents)
+}
+
+func TestEncryptedStore_Tampered(t *testing.T) {
+	req := require.New(t)
+	store := newTestEncryptedStore(t)
+
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader([]byte("secret"))))
+
+	archivePath := filepath.Join(ArchivesDir, "app/0.tar.gz")
+	stored, err := os.ReadFile(archivePath)
+	req.NoError(err)
+	stored[len(stored)-1] ^= 0xff
+	req.NoError(os.WriteFile(archivePath, stored, 0644))
+
+	_, err = store.ReadArchive("app/0.tar.gz")
+	assert.Error(t, err)
+}
+
+func TestEncryptedStore_Legacy(t *testing.T) {
+	req := require.New(t)
+	store := newTestEncryptedStore(t)
+
+	// archives written before encryption was enabled are read as is
+	plaintext := []byte("legacy archive")
+	req.NoError(store.backend.WriteArchive("app/0.tar.gz", bytes.NewReader(plaintext)))
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	contents, err := os.ReadFile(localPath)
+	req.NoError(err)
+	os.RemoveAll(localPath)
+	assert.Equal(t, plaintext, contents)
+
+	// and are encrypted in place by the migration
+	reencrypted, err := store.reencryptArchive("app/0.tar.gz", false)
+	req.NoError(err)
+	assert.True(t, reencrypted)
+
+	stored, err := os.ReadFile(filepath.Join(ArchivesDir, "app/0.tar.gz"))
+	req.NoError(err)
+	assert.True(t, isEncryptedArchive(stored))
+
+	reencrypted, err = store.reencryptArchive("app/0.tar.gz", false)
+	req.NoError(err)
+	assert.False(t, reencrypted)
+
+	localPath, err = store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+	contents, err = os.ReadFile(localPath)
+	req.NoError(err)
+	assert.Equal(t, plaintext, contents)
+}
+
+func TestEncryptedStore_KeyRotation(t *testing.T) {
+	req := require.New(t)
+	store := newTestEncryptedStore(t)
+
+	plaintext := []byte("encrypted with the old key")
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(plaintext)))
+
+	newKey, err := crypto.GenerateKey()
+	req.NoError(err)
+	req.NoError(crypto.SetEncryptionKey(newKey))
+
+	// archives encrypted with the previous key are re-encrypted with the new one
+	needsReencryption, err := store.reencryptArchive("app/0.tar.gz", true)
+	req.NoError(err)
+	assert.True(t, needsReencryption)
+
+	reencrypted, err := store.reencryptArchive("app/0.tar.gz", false)
+	req.NoError(err)
+	assert.True(t, reencrypted)
+
+	stored, err := os.ReadFile(filepath.Join(ArchivesDir, "app/0.tar.gz"))
+	req.NoError(err)
+	assert.True(t, crypto.IsEncryptedWithCurrentKeyRandomNonce(stored[len(encryptedArchiveHeader):]))
+
+	reencrypted, err = store.reencryptArchive("app/0.tar.gz", false)
+	req.NoError(err)
+	assert.False(t, reencrypted)
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+	contents, err := os.ReadFile(localPath)
+	req.NoError(err)
+	assert.Equal(t, plaintext, contents)
+}
+
+func TestEncryptedStore_ContentAddressed(t *testing.T) {
+	req := require.New(t)
+	encryptedStore := newTestEncryptedStore(t)
+	store := &ContentAddressedStore{
+		backend: encryptedStore,
+		refs:    &memoryBlobRefCounter{refs: map[string]int{}},
+	}
+
+	files := map[string]string{"upstream/a.yaml": "password: hunter2"}
+	req.NoError(store.WriteArchive("app/0.tar.gz", bytes.NewReader(createTestArchive(t, files))))
+
+	keys, err := listArchiveKeys(&BlobStore{})
+	req.NoError(err)
+	for _, key := range keys {
+		stored, err := os.ReadFile(filepath.Join(ArchivesDir, key))
+		req.NoError(err)
+		assert.True(t, isEncryptedArchive(stored), key)
+	}
+
+	localPath, err := store.ReadArchive("app/0.tar.gz")
+	req.NoError(err)
+	defer os.RemoveAll(localPath)
+	assert.Equal(t, files, readTestArchive(t, localPath))
+}
+
+func TestEncryptedStore_ReencryptDeleted(t *testing.T) {
+	store := newTestEncryptedStore(t)
+
+	reencrypted, err := store.reencryptArchive("app/0.tar.gz", false)
+	require.NoError(t, err)
+	assert.False(t, reencrypted)
+
+	_, err = os.Stat(filepath.Join(ArchivesDir, "app/0.tar.gz"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPathLocker(t *testing.T) {
+	locker := newPathLocker()
+
+	unlock := locker.lock("app/0.tar.gz")
+
+	// other paths are not blocked
+	locker.lock("app/1.tar.gz")()
+
+	locked := make(chan struct{})
+	go func() {
+		defer close(locked)
+		locker.lock("app/0.tar.gz")()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("path was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	<-locked
+
+	assert.Empty(t, locker.locks)
+}
@@ -7,6 +7,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/session"
@@ -23,6 +24,9 @@ const (
 	RQLITE_S3_MIGRATION_SUCCESS_KEY   = "rqlite.s3.migration.success"
 	RQLITE_BLOB_MIGRATION_SUCCESS_KEY = "rqlite.blob.migration.success"
 	RQLITE_MIGRATION_SUCCESS_VALUE    = "true"
+
+	CONTENT_ADDRESSED_MIGRATION_SUCCESS_KEY = "content.addressed.migration.success"
+	ENCRYPTION_MIGRATION_SUCCESS_KEY        = "encryption.migration.success"
 )
 
 func MigrateFromS3ToRqlite(ctx context.Context) error {
@@ -199,3 +203,273 @@ func isAlreadyMigrated(rqliteDB *gorqlite.Connection, migrationKey string) (bool
 
 	return value == RQLITE_MIGRATION_SUCCESS_VALUE, nil
 }
+
+// MigrateToContentAddressedStore rewrites the archives stored in the legacy layout of the configured store
+// as deduplicated blobs and manifests. archives that haven't been migrated yet remain readable in the meantime.
+func MigrateToContentAddressedStore(ctx context.Context) error {
+	// Check if already migrated
+	rqliteDB := persistence.MustGetDBSession()
+	alreadyMigrated, err := isAlreadyMigrated(rqliteDB, CONTENT_ADDRESSED_MIGRATION_SUCCESS_KEY)
+	if err != nil {
+		return errors.Wrap(err, "failed to check if already migrated")
+	}
+	if alreadyMigrated {
+		log.Println("Already migrated to content addressed storage. Skipping migration...")
+		return nil
+	}
+
+	log.Println("Migrating to content addressed storage...")
+
+	backend := backendFromEnv()
+	if err := backend.Init(); err != nil {
+		return errors.Wrap(err, "failed to init store")
+	}
+	if err := backend.WaitForReady(ctx); err != nil {
+		return errors.Wrap(err, "failed to wait for store to become ready")
+	}
+	store := withEncryptionFromEnv(backend)
+	casStore := NewContentAddressedStore(store)
+
+	keys, err := listLegacyArchiveKeys(backend)
+	if err != nil {
+		return errors.Wrap(err, "failed to list archives")
+	}
+
+	for _, key := range keys {
+		log.Printf("Processing key: %s\n", key)
+
+		localPath, err := store.ReadArchive(key)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read archive %s", key)
+		}
+
+		if err := migrateArchiveToContentAddressedStore(casStore, key, localPath); err != nil {
+			os.RemoveAll(localPath)
+			return errors.Wrapf(err, "failed to migrate archive %s", key)
+		}
+		os.RemoveAll(localPath)
+	}
+
+	// Record the migration success
+	query := `REPLACE INTO kotsadm_params (key, value) VALUES (?, ?)`
+	wr, err := rqliteDB.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{CONTENT_ADDRESSED_MIGRATION_SUCCESS_KEY, RQLITE_MIGRATION_SUCCESS_VALUE},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark migration as successful: %v: %v", err, wr.Err)
+	}
+
+	log.Println("Migrated to content addressed storage successfully!")
+
+	return nil
+}
+
+func migrateArchiveToContentAddressedStore(casStore *ContentAddressedStore, key string, localPath string) error {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to open file")
+	}
+	defer file.Close()
+
+	// this also removes the archive from the legacy layout
+	if err := casStore.WriteArchive(key, file); err != nil {
+		return errors.Wrap(err, "failed to write archive to content addressed store")
+	}
+
+	return nil
+}
+
+// listLegacyArchiveKeys returns the keys of the tar.gz archives that are not yet stored as blobs and manifests
+func listLegacyArchiveKeys(backend FileStore) ([]string, error) {
+	allKeys, err := listArchiveKeys(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, key := range allKeys {
+		if strings.HasPrefix(key, contentAddressedPrefix+"/") {
+			continue
+		}
+		if !strings.HasSuffix(key, ".tar.gz") || !shouldStoreContentAddressed(key) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// listArchiveKeys returns the keys of all objects in the backend
+func listArchiveKeys(backend FileStore) ([]string, error) {
+	allKeys := []string{}
+
+	switch backend.(type) {
+	case *RqliteStore:
+		rows, err := persistence.MustGetDBSession().QueryOne(`SELECT filepath FROM object_store`)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+		}
+		for rows.Next() {
+			var key string
+			if err := rows.Scan(&key); err != nil {
+				return nil, errors.Wrap(err, "failed to scan")
+			}
+			allKeys = append(allKeys, key)
+		}
+
+	case *S3Store:
+		sess, err := session.NewSession(kotss3.GetConfig())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create new s3 session")
+		}
+		err = s3.New(sess).ListObjectsV2Pages(&s3.ListObjectsV2Input{
+			Bucket: aws.String(os.Getenv("S3_BUCKET_NAME")),
+		}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+			for _, item := range page.Contents {
+				if item != nil && item.Key != nil {
+					allKeys = append(allKeys, *item.Key)
+				}
+			}
+			return true
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list objects in bucket")
+		}
+
+	case *BlobStore:
+		err := filepath.Walk(ArchivesDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return errors.Wrap(err, "failed to walk path")
+			}
+			if info.IsDir() {
+				return nil
+			}
+			key, err := filepath.Rel(ArchivesDir, path)
+			if err != nil {
+				return errors.Wrap(err, "failed to get relative path")
+			}
+			allKeys = append(allKeys, filepath.ToSlash(key))
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to walk archives dir")
+		}
+
+	default:
+		return nil, errors.Errorf("unsupported store type %T", backend)
+	}
+
+	return allKeys, nil
+}
+
+// MigrateToEncryptedStore encrypts all archives that are still stored in plaintext.
+// plaintext archives remain readable while the migration is in progress, and each archive is locked while it's
+// re-encrypted so that concurrent writes aren't lost, so it's safe to run in the background.
+func MigrateToEncryptedStore(ctx context.Context) error {
+	// Check if already migrated
+	rqliteDB := persistence.MustGetDBSession()
+	alreadyMigrated, err := isAlreadyMigrated(rqliteDB, ENCRYPTION_MIGRATION_SUCCESS_KEY)
+	if err != nil {
+		return errors.Wrap(err, "failed to check if already migrated")
+	}
+	if alreadyMigrated {
+		log.Println("Already migrated to encrypted storage. Skipping migration...")
+		return nil
+	}
+
+	log.Println("Migrating to encrypted storage...")
+
+	backend := backendFromEnv()
+	if err := backend.WaitForReady(ctx); err != nil {
+		return errors.Wrap(err, "failed to wait for store to become ready")
+	}
+	encryptedStore := NewEncryptedStore(backend)
+
+	keys, err := listArchiveKeys(backend)
+	if err != nil {
+		return errors.Wrap(err, "failed to list archives")
+	}
+
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		reencrypted, err := encryptedStore.reencryptArchive(key, false)
+		if err != nil {
+			return errors.Wrapf(err, "failed to encrypt archive %s", key)
+		}
+		if reencrypted {
+			log.Printf("Encrypted key: %s\n", key)
+		}
+	}
+
+	// Record the migration success
+	query := `REPLACE INTO kotsadm_params (key, value) VALUES (?, ?)`
+	wr, err := rqliteDB.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{ENCRYPTION_MIGRATION_SUCCESS_KEY, RQLITE_MIGRATION_SUCCESS_VALUE},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark migration as successful: %v: %v", err, wr.Err)
+	}
+
+	log.Println("Migrated to encrypted storage successfully!")
+
+	return nil
+}
+
+// ReencryptArchives re-encrypts all archives that are not encrypted with the current encryption key, which is needed after the key is rotated.
+// when dryRun is true, the archives are only checked. returns the number of archives that were not encrypted with the current key.
+// progressFn, if set, is called after each archive is processed.
+func ReencryptArchives(ctx context.Context, dryRun bool, progressFn func(done int, total int)) (int, error) {
+	if !isEncryptionEnabled() {
+		return 0, nil
+	}
+
+	backend := backendFromEnv()
+	if err := backend.WaitForReady(ctx); err != nil {
+		return 0, errors.Wrap(err, "failed to wait for store to become ready")
+	}
+	encryptedStore := NewEncryptedStore(backend)
+
+	keys, err := listArchiveKeys(backend)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list archives")
+	}
+
+	count := 0
+	for i, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+
+		reencrypted, err := encryptedStore.reencryptArchive(key, dryRun)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to re-encrypt archive %s", key)
+		}
+		if reencrypted {
+			count++
+		}
+		if progressFn != nil {
+			progressFn(i+1, len(keys))
+		}
+	}
+
+	return count, nil
+}
+
+// StartEncryptionMigration runs the re-encryption migration in the background if archive encryption is enabled
+func StartEncryptionMigration() {
+	if !isEncryptionEnabled() {
+		return
+	}
+
+	go func() {
+		if err := MigrateToEncryptedStore(context.Background()); err != nil {
+			log.Println("Failed to migrate to encrypted storage:", err)
+		}
+	}()
+}
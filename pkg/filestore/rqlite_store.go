@@ -112,3 +112,27 @@ func (s *RqliteStore) DeleteArchive(path string) error {
 
 	return nil
 }
+
+func (s *RqliteStore) GetArchiveSize(path string) (int64, error) {
+	db := persistence.MustGetDBSession()
+
+	query := `SELECT length(encoded_block) FROM object_store WHERE filepath = ?`
+	rows, err := db.QueryOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{path},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+	}
+	if !rows.Next() {
+		return 0, ErrNotFound
+	}
+
+	var encodedLength int64
+	if err := rows.Scan(&encodedLength); err != nil {
+		return 0, errors.Wrap(err, "failed to scan")
+	}
+
+	// blocks are stored base64 encoded, report the size of the decoded archive
+	return int64(base64.StdEncoding.DecodedLen(int(encodedLength))), nil
+}
@@ -160,3 +160,21 @@ func (s *S3Store) DeleteArchive(path string) error {
 
 	return nil
 }
+
+func (s *S3Store) GetArchiveSize(path string) (int64, error) {
+	newSession := awssession.New(kotss3.GetConfig())
+	s3Client := s3.New(newSession)
+
+	output, err := s3Client.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(os.Getenv("S3_BUCKET_NAME")),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		if awsErr, ok := err.(awserr.Error); ok && awsErr.Code() == "NotFound" {
+			return 0, ErrNotFound
+		}
+		return 0, errors.Wrapf(err, "failed to head key %q in bucket %q", path, os.Getenv("S3_BUCKET_NAME"))
+	}
+
+	return aws.Int64Value(output.ContentLength), nil
+}
@@ -2,6 +2,10 @@ package filestore
 
 import (
 	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 )
 
 var (
@@ -18,7 +22,43 @@ func GetStore() FileStore {
 	return globalStore
 }
 
+// GetReclaimableSize returns the number of bytes that deleting all of the archives would free in the store
+func GetReclaimableSize(store FileStore, paths []string) (int64, error) {
+	if sizer, ok := store.(ReclaimableSizer); ok {
+		return sizer.GetReclaimableSize(paths)
+	}
+
+	total := int64(0)
+	for _, p := range paths {
+		size, err := store.GetArchiveSize(p)
+		if err != nil && errors.Cause(err) != ErrNotFound {
+			return 0, errors.Wrapf(err, "failed to get size of %s", p)
+		}
+		total += size
+	}
+	return total, nil
+}
+
 func storeFromEnv() FileStore {
+	store := withEncryptionFromEnv(backendFromEnv())
+	if os.Getenv("ENABLE_CONTENT_ADDRESSED_STORAGE") == "1" {
+		return NewContentAddressedStore(store)
+	}
+	return store
+}
+
+func isEncryptionEnabled() bool {
+	return os.Getenv("ENABLE_ARCHIVE_ENCRYPTION") == "1"
+}
+
+func withEncryptionFromEnv(backend FileStore) FileStore {
+	if isEncryptionEnabled() {
+		return NewEncryptedStore(backend)
+	}
+	return backend
+}
+
+func backendFromEnv() FileStore {
 	if os.Getenv("S3_ENDPOINT") != "" {
 		return &S3Store{}
 	}
@@ -27,3 +67,27 @@ func storeFromEnv() FileStore {
 	}
 	return &RqliteStore{}
 }
+
+// IsDatabaseBacked returns true if archives are stored in rqlite rather than in object storage or on a volume
+func IsDatabaseBacked() bool {
+	_, ok := backendFromEnv().(*RqliteStore)
+	return ok
+}
+
+// ListArchives returns the sorted paths of all archives in the store that start with the given prefix
+func ListArchives(prefix string) ([]string, error) {
+	keys, err := listArchiveKeys(backendFromEnv())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list archives")
+	}
+
+	archives := []string{}
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			archives = append(archives, key)
+		}
+	}
+	sort.Strings(archives)
+
+	return archives, nil
+}
@@ -11,4 +11,11 @@ type FileStore interface {
 	WriteArchive(outputPath string, body io.ReadSeeker) error
 	ReadArchive(path string) (string, error)
 	DeleteArchive(path string) error
+	GetArchiveSize(path string) (int64, error)
+}
+
+// ReclaimableSizer is implemented by stores that share storage between archives,
+// where deleting an archive only frees the storage that no other archive uses
+type ReclaimableSizer interface {
+	GetReclaimableSize(paths []string) (int64, error)
 }
@@ -12,11 +12,12 @@ import (
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/transport"
+	go_git_http "github.com/go-git/go-git/v5/plumbing/transport/http"
 	go_git_ssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/mikesmitty/edkey"
 	"github.com/pkg/errors"
@@ -24,8 +25,10 @@ import (
 	"github.com/replicatedhq/kots/pkg/apparchive"
 	"github.com/replicatedhq/kots/pkg/binaries"
 	"github.com/replicatedhq/kots/pkg/crypto"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
 	"github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/util"
 	"golang.org/x/crypto/ssh"
 	v1 "k8s.io/api/core/v1"
@@ -34,28 +37,123 @@ import (
 	"k8s.io/client-go/kubernetes"
 )
 
+const (
+	// ProviderGeneric is any git server. the repo uri is used as the clone url as is.
+	ProviderGeneric = "generic"
+	// ProviderGitea is a gitea or forgejo server
+	ProviderGitea = "gitea"
+
+	// AuthTypeSSH authenticates with the generated deploy key. this is the default.
+	AuthTypeSSH = "ssh"
+	// AuthTypeToken authenticates over https with a personal access token
+	AuthTypeToken = "token"
+	// AuthTypeBasic authenticates over https with a username and password
+	AuthTypeBasic = "basic"
+
+	// commitHashPlaceholder is replaced with the commit hash in commit url templates
+	commitHashPlaceholder = "{hash}"
+
+	// defaultTokenUsername is sent with personal access tokens when no username is configured.
+	// most git servers ignore the username when the password is a token.
+	defaultTokenUsername = "git"
+)
+
+// allowLocalRepos lets file:// repos be used without auth. it's only set by tests, the api never accepts file:// repos.
+var allowLocalRepos = false
+
+func isLocalRepo(repoURI string) bool {
+	return strings.HasPrefix(repoURI, "file://")
+}
+
 type GitOpsConfig struct {
-	Provider    string `json:"provider"`
-	RepoURI     string `json:"repoUri"`
-	Hostname    string `json:"hostname"`
-	HTTPPort    string `json:"httpPort"`
-	SSHPort     string `json:"sshPort"`
-	Path        string `json:"path"`
-	Branch      string `json:"branch"`
-	Format      string `json:"format"`
-	Action      string `json:"action"`
-	PublicKey   string `json:"publicKey"`
-	PrivateKey  string `json:"-"`
-	IsConnected bool   `json:"isConnected"`
+	Provider          string `json:"provider"`
+	RepoURI           string `json:"repoUri"`
+	Hostname          string `json:"hostname"`
+	HTTPPort          string `json:"httpPort"`
+	SSHPort           string `json:"sshPort"`
+	Path              string `json:"path"`
+	Branch            string `json:"branch"`
+	Format            string `json:"format"`
+	Action            string `json:"action"`
+	HelmRepositoryURL string `json:"helmRepositoryUrl,omitempty"`
+	ArgoCDNamespace   string `json:"argoCDNamespace,omitempty"`
+	SyncStatus        bool   `json:"syncStatus"`
+	PublicKey         string `json:"publicKey"`
+	PrivateKey        string `json:"-"`
+	AuthType          string `json:"authType"`
+	Username          string `json:"username,omitempty"`
+	Password          string `json:"-"`
+	CommitURLTemplate string `json:"commitUrlTemplate,omitempty"`
+	APIURL            string `json:"apiUrl,omitempty"`
+	AuthorName        string `json:"authorName,omitempty"`
+	AuthorEmail       string `json:"authorEmail,omitempty"`
+	SigningKeyType    string `json:"signingKeyType,omitempty"`
+	SigningPublicKey  string `json:"signingPublicKey,omitempty"`
+	SigningPrivateKey string `json:"-"`
+	IsConnected       bool   `json:"isConnected"`
 }
 
 type GlobalGitOpsConfig struct {
-	Enabled  bool   `json:"enabled"`
-	Hostname string `json:"hostname"`
-	HTTPPort string `json:"httpPort"`
-	SSHPort  string `json:"sshPort"`
-	Provider string `json:"provider"`
-	URI      string `json:"uri"`
+	Enabled           bool   `json:"enabled"`
+	Hostname          string `json:"hostname"`
+	HTTPPort          string `json:"httpPort"`
+	SSHPort           string `json:"sshPort"`
+	Provider          string `json:"provider"`
+	URI               string `json:"uri"`
+	AuthType          string `json:"authType"`
+	Username          string `json:"username,omitempty"`
+	CommitURLTemplate string `json:"commitUrlTemplate,omitempty"`
+	APIURL            string `json:"apiUrl,omitempty"`
+	AuthorName        string `json:"authorName,omitempty"`
+	AuthorEmail       string `json:"authorEmail,omitempty"`
+	SigningKeyType    string `json:"signingKeyType,omitempty"`
+	SigningPublicKey  string `json:"signingPublicKey,omitempty"`
+}
+
+type CreateGitOpsOptions struct {
+	Provider string
+	RepoURI  string
+	Hostname string
+	HTTPPort string
+	SSHPort  string
+	// AuthType is one of AuthTypeSSH, AuthTypeToken or AuthTypeBasic. empty means AuthTypeSSH.
+	AuthType string
+	Username string
+	// Password is the personal access token when AuthType is AuthTypeToken. it is stored encrypted.
+	// an empty password keeps the stored password so that other settings can be changed without re-entering it.
+	Password string
+	// CommitURLTemplate is used to link to commits, with {hash} replaced by the commit hash.
+	// when empty, the url is derived from the repo uri.
+	CommitURLTemplate string
+	// APIURL is the base url of the provider's rest api, used to open pull requests.
+	// when empty, the url is derived from the repo uri.
+	APIURL string
+	// AuthorName and AuthorEmail are the commit author. when empty, DefaultAuthorName and DefaultAuthorEmail are used.
+	AuthorName  string
+	AuthorEmail string
+	// SigningKeyType is SigningKeyTypeSSH or SigningKeyTypeOpenPGP to sign commits, or empty to not sign them.
+	// a key is generated when there's no key of this type yet, or when RegenerateSigningKey is set.
+	SigningKeyType       string
+	RegenerateSigningKey bool
+}
+
+// CommitOptions describe the version being committed. they are used in pull request titles and descriptions.
+type CommitOptions struct {
+	VersionLabel string
+	ReleaseNotes string
+	DiffSummary  *apparchive.Diff
+	// SupersededPullRequests are closed after the pull request for this version is opened
+	SupersededPullRequests []gitopstypes.PullRequest
+}
+
+type CommitResult struct {
+	CommitURL string
+	// CommitHash is the commit that was pushed, which is how the version is found from the revision that flux or argo cd deploys
+	CommitHash string
+	// PullRequest is set when the gitops action is ActionPullRequest and a pull request was opened
+	PullRequest *gitopstypes.PullRequest
+	// ClosedPullRequests are the superseded pull requests that were closed on the provider
+	ClosedPullRequests []gitopstypes.PullRequest
 }
 
 type KeyPair struct {
@@ -64,6 +162,10 @@ type KeyPair struct {
 }
 
 func (g *GitOpsConfig) CommitURL(hash string) string {
+	if g.CommitURLTemplate != "" {
+		return strings.ReplaceAll(g.CommitURLTemplate, commitHashPlaceholder, hash)
+	}
+
 	switch g.Provider {
 	case "github", "github_enterprise":
 		return fmt.Sprintf("%s/commit/%s", g.RepoURI, hash)
@@ -74,12 +176,27 @@ func (g *GitOpsConfig) CommitURL(hash string) string {
 	case "bitbucket", "bitbucket_server":
 		return fmt.Sprintf("%s/commits/%s", g.RepoURI, hash)
 
+	case ProviderGeneric:
+		// there's no way to know what the web ui of an arbitrary git server looks like,
+		// but most of them serve the repo at its https clone url
+		if !strings.HasPrefix(g.RepoURI, "https://") && !strings.HasPrefix(g.RepoURI, "http://") {
+			return ""
+		}
+		return fmt.Sprintf("%s/commit/%s", strings.TrimSuffix(g.RepoURI, ".git"), hash)
+
 	default:
 		return fmt.Sprintf("%s/commit/%s", g.RepoURI, hash)
 	}
 }
 
 func (g *GitOpsConfig) CloneURL() (string, error) {
+	if g.Provider == ProviderGeneric || (allowLocalRepos && isLocalRepo(g.RepoURI)) {
+		if g.RepoURI == "" {
+			return "", errors.New("repo uri is required")
+		}
+		return g.RepoURI, nil
+	}
+
 	// copied this logic from node js api
 	uriParts := strings.Split(g.RepoURI, "/")
 
@@ -98,6 +215,17 @@ func (g *GitOpsConfig) CloneURL() (string, error) {
 		repo = uriParts[6]
 	}
 
+	if g.usesHTTPAuth() {
+		scheme, host := uriParts[0], uriParts[2]
+		switch g.Provider {
+		case "github", "gitlab", "bitbucket", "github_enterprise", "gitlab_enterprise", ProviderGitea:
+			return fmt.Sprintf("%s//%s/%s/%s.git", scheme, host, owner, repo), nil
+		case "bitbucket_server":
+			return fmt.Sprintf("%s//%s/scm/%s/%s.git", scheme, host, strings.ToLower(owner), repo), nil
+		}
+		return "", errors.Errorf("unsupported provider type: %s", g.Provider)
+	}
+
 	switch g.Provider {
 	case "github":
 		return fmt.Sprintf("git@github.com:%s/%s.git", owner, repo), nil
@@ -107,13 +235,17 @@ func (g *GitOpsConfig) CloneURL() (string, error) {
 		return fmt.Sprintf("git@bitbucket.org:%s/%s.git", owner, repo), nil
 	case "bitbucket_server":
 		return fmt.Sprintf("git@%s:%s/%s/%s.git", g.Hostname, g.SSHPort, owner, repo), nil
-	case "github_enterprise", "gitlab_enterprise":
+	case "github_enterprise", "gitlab_enterprise", ProviderGitea:
 		return fmt.Sprintf("git@%s:%s/%s.git", g.Hostname, owner, repo), nil
 	}
 
 	return "", errors.Errorf("unsupported provider type: %s", g.Provider)
 }
 
+func (g *GitOpsConfig) usesHTTPAuth() bool {
+	return g.AuthType == AuthTypeToken || g.AuthType == AuthTypeBasic
+}
+
 // GetDownstreamGitOps will return the gitops config for a downstream,
 // This implementation copies how it works in typescript.
 func GetDownstreamGitOps(appID string, clusterID string) (*GitOpsConfig, error) {
@@ -180,17 +312,52 @@ func GetDownstreamGitOpsConfig(clientset kubernetes.Interface, appID string, clu
 				}
 
 				gitOpsConfig := GitOpsConfig{
-					Provider:   provider,
-					PublicKey:  publicKey,
-					PrivateKey: string(decryptedPrivateKey),
-					RepoURI:    repoURI,
-					Hostname:   hostname,
-					HTTPPort:   httpPort,
-					SSHPort:    sshPort,
-					Branch:     configMapData["branch"],
-					Path:       configMapData["path"],
-					Format:     configMapData["format"],
-					Action:     configMapData["action"],
+					Provider:          provider,
+					PublicKey:         publicKey,
+					PrivateKey:        string(decryptedPrivateKey),
+					RepoURI:           repoURI,
+					Hostname:          hostname,
+					HTTPPort:          httpPort,
+					SSHPort:           sshPort,
+					AuthType:          providerSecretValue(secret.Data, idx, "authType"),
+					Username:          providerSecretValue(secret.Data, idx, "username"),
+					CommitURLTemplate: providerSecretValue(secret.Data, idx, "commitUrlTemplate"),
+					APIURL:            providerSecretValue(secret.Data, idx, "apiUrl"),
+					AuthorName:        providerSecretValue(secret.Data, idx, "authorName"),
+					AuthorEmail:       providerSecretValue(secret.Data, idx, "authorEmail"),
+					SigningKeyType:    providerSecretValue(secret.Data, idx, "signingKeyType"),
+					SigningPublicKey:  providerSecretValue(secret.Data, idx, "signingPublicKey"),
+					Branch:            configMapData["branch"],
+					Path:              configMapData["path"],
+					Format:            configMapData["format"],
+					Action:            configMapData["action"],
+					HelmRepositoryURL: configMapData["helmRepositoryUrl"],
+					ArgoCDNamespace:   configMapData["argoCDNamespace"],
+					SyncStatus:        configMapData["syncStatus"] == "true",
+				}
+
+				if password := providerSecretValue(secret.Data, idx, "password"); password != "" {
+					decodedPassword, err := base64.StdEncoding.DecodeString(password)
+					if err != nil {
+						return nil, errors.Wrap(err, "failed to decode password")
+					}
+					decryptedPassword, err := crypto.Decrypt(decodedPassword)
+					if err != nil {
+						return nil, errors.Wrap(err, "failed to decrypt password")
+					}
+					gitOpsConfig.Password = string(decryptedPassword)
+				}
+
+				if signingPrivateKey := providerSecretValue(secret.Data, idx, "signingPrivateKey"); signingPrivateKey != "" {
+					decodedSigningKey, err := base64.StdEncoding.DecodeString(signingPrivateKey)
+					if err != nil {
+						return nil, errors.Wrap(err, "failed to decode signing key")
+					}
+					decryptedSigningKey, err := crypto.Decrypt(decodedSigningKey)
+					if err != nil {
+						return nil, errors.Wrap(err, "failed to decrypt signing key")
+					}
+					gitOpsConfig.SigningPrivateKey = string(decryptedSigningKey)
 				}
 
 				if lastError, ok := configMapData["lastError"]; ok && lastError == "" {
@@ -316,17 +483,17 @@ func deleteKeysFromGitOpsSecret(clientset kubernetes.Interface, repoURL string)
 	return nil
 }
 
-func UpdateDownstreamGitOps(appID, clusterID, uri, branch, path, format, action string) error {
+func UpdateDownstreamGitOps(appID, clusterID, uri, branch, path, format, action, helmRepositoryURL, argoCDNamespace string, syncStatus bool) error {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
 		return errors.Wrap(err, "failed to get k8s client set")
 	}
 
-	err = updateDownstreamGitOps(clientset, appID, clusterID, uri, branch, path, format, action)
+	err = updateDownstreamGitOps(clientset, appID, clusterID, uri, branch, path, format, action, helmRepositoryURL, argoCDNamespace, syncStatus)
 	return errors.Wrap(err, "failed to update downstream gitops config")
 }
 
-func updateDownstreamGitOps(clientset kubernetes.Interface, appID, clusterID, uri, branch, path, format, action string) error {
+func updateDownstreamGitOps(clientset kubernetes.Interface, appID, clusterID, uri, branch, path, format, action, helmRepositoryURL, argoCDNamespace string, syncStatus bool) error {
 	configMap, err := clientset.CoreV1().ConfigMaps(util.PodNamespace).Get(context.TODO(), "kotsadm-gitops", metav1.GetOptions{})
 	if err != nil && !kuberneteserrors.IsNotFound(err) {
 		return errors.Wrap(err, "failed to get configmap")
@@ -350,6 +517,15 @@ func updateDownstreamGitOps(clientset kubernetes.Interface, appID, clusterID, ur
 		"format":  format,
 		"action":  action,
 	}
+	if helmRepositoryURL != "" {
+		newAppData["helmRepositoryUrl"] = helmRepositoryURL
+	}
+	if argoCDNamespace != "" {
+		newAppData["argoCDNamespace"] = argoCDNamespace
+	}
+	if syncStatus {
+		newAppData["syncStatus"] = "true"
+	}
 
 	// check if to reset or keep last error
 	appDataEncoded, ok := configMapData[appKey]
@@ -452,7 +628,7 @@ func SetGitOpsError(appID string, clusterID string, errMsg string) error {
 // TestGitOpsConnection will attempt a clone of the target gitops repo.
 // It returns the default branch name from the clone.
 func TestGitOpsConnection(gitOpsConfig *GitOpsConfig) (string, error) {
-	auth, err := getAuth(gitOpsConfig.PrivateKey)
+	auth, err := getAuth(gitOpsConfig)
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get auth")
 	}
@@ -485,17 +661,63 @@ func TestGitOpsConnection(gitOpsConfig *GitOpsConfig) (string, error) {
 	return ref.Name().Short(), nil
 }
 
-func CreateGitOps(provider string, repoURI string, hostname string, httpPort string, sshPort string) error {
+func CreateGitOps(opts CreateGitOpsOptions) error {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
 		return errors.Wrap(err, "failed to get k8s client set")
 	}
 
-	err = createGitOps(clientset, provider, repoURI, hostname, httpPort, sshPort)
+	err = createGitOps(clientset, opts)
 	return errors.Wrap(err, "failed to create gitops")
 }
 
-func createGitOps(clientset kubernetes.Interface, provider string, repoURI string, hostname string, httpPort string, sshPort string) error {
+// ValidateCreateGitOpsOptions returns an error that can be shown to the user if the options are not valid
+func ValidateCreateGitOpsOptions(opts CreateGitOpsOptions) error {
+	if opts.RepoURI == "" {
+		return errors.New("repo uri is required")
+	}
+
+	switch opts.AuthType {
+	case "", AuthTypeSSH, AuthTypeToken:
+	case AuthTypeBasic:
+		if opts.Username == "" {
+			return errors.New("username is required for basic auth")
+		}
+	default:
+		return errors.Errorf("unsupported auth type %q", opts.AuthType)
+	}
+
+	if opts.Provider == ProviderGeneric {
+		// the uri is parsed the way go-git parses it when cloning. plain paths are cloned with the file transport,
+		// which ignores auth, so only ssh, scp style and http(s) uris are accepted.
+		endpoint, err := transport.NewEndpoint(opts.RepoURI)
+		if err != nil {
+			return errors.Errorf("invalid repo uri %q", opts.RepoURI)
+		}
+		switch endpoint.Protocol {
+		case "ssh", "https", "http":
+		default:
+			return errors.Errorf("unsupported repo uri %q, use an ssh://, https:// or user@host:path uri", opts.RepoURI)
+		}
+		if (opts.AuthType == AuthTypeToken || opts.AuthType == AuthTypeBasic) && endpoint.Protocol != "https" && endpoint.Protocol != "http" {
+			return errors.New("token and basic auth require an https repo uri")
+		}
+	}
+
+	if !IsSupportedSigningKeyType(opts.SigningKeyType) {
+		return errors.Errorf("unsupported signing key type %q", opts.SigningKeyType)
+	}
+
+	if opts.CommitURLTemplate != "" && !strings.Contains(opts.CommitURLTemplate, commitHashPlaceholder) {
+		return errors.Errorf("commit url template must contain %s", commitHashPlaceholder)
+	}
+
+	return nil
+}
+
+func createGitOps(clientset kubernetes.Interface, opts CreateGitOpsOptions) error {
+	provider, repoURI, hostname, httpPort, sshPort := opts.Provider, opts.RepoURI, opts.Hostname, opts.HTTPPort, opts.SSHPort
+
 	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), "kotsadm-gitops", metav1.GetOptions{})
 	if err != nil && !kuberneteserrors.IsNotFound(err) {
 		return errors.Wrap(err, "failed to get secret")
@@ -587,6 +809,52 @@ func createGitOps(clientset kubernetes.Interface, provider string, repoURI strin
 		secretData[sshPortKey] = []byte(sshPort)
 	}
 
+	authType := opts.AuthType
+	if authType == "" {
+		authType = AuthTypeSSH
+	}
+	secretData[fmt.Sprintf("provider.%d.authType", repoIdx)] = []byte(authType)
+
+	usernameKey := fmt.Sprintf("provider.%d.username", repoIdx)
+	delete(secretData, usernameKey)
+	if opts.Username != "" {
+		secretData[usernameKey] = []byte(opts.Username)
+	}
+
+	passwordKey := fmt.Sprintf("provider.%d.password", repoIdx)
+	if authType == AuthTypeSSH {
+		delete(secretData, passwordKey)
+	} else if opts.Password != "" {
+		encryptedPassword := crypto.Encrypt([]byte(opts.Password))
+		secretData[passwordKey] = []byte(base64.StdEncoding.EncodeToString(encryptedPassword))
+	} else if _, ok := secretData[passwordKey]; !ok {
+		return errors.Errorf("a password or token is required for %s auth", authType)
+	}
+
+	commitURLTemplateKey := fmt.Sprintf("provider.%d.commitUrlTemplate", repoIdx)
+	delete(secretData, commitURLTemplateKey)
+	if opts.CommitURLTemplate != "" {
+		secretData[commitURLTemplateKey] = []byte(opts.CommitURLTemplate)
+	}
+
+	apiURLKey := fmt.Sprintf("provider.%d.apiUrl", repoIdx)
+	delete(secretData, apiURLKey)
+	if opts.APIURL != "" {
+		secretData[apiURLKey] = []byte(opts.APIURL)
+	}
+
+	for key, value := range map[string]string{"authorName": opts.AuthorName, "authorEmail": opts.AuthorEmail} {
+		secretKey := fmt.Sprintf("provider.%d.%s", repoIdx, key)
+		delete(secretData, secretKey)
+		if value != "" {
+			secretData[secretKey] = []byte(value)
+		}
+	}
+
+	if err := setSigningKey(secretData, repoIdx, opts); err != nil {
+		return errors.Wrap(err, "failed to set signing key")
+	}
+
 	if secretExists {
 		secret.Data = secretData
 		_, err = clientset.CoreV1().Secrets(util.PodNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{})
@@ -630,12 +898,73 @@ func ResetGitOps() error {
 	return nil
 }
 
+// ReencryptCredentials re-encrypts the gitops deploy keys, https passwords and commit signing keys that are not encrypted with the current encryption key.
+// when dryRun is true, the credentials are only checked. returns the number of credentials that were not encrypted with the current key.
+func ReencryptCredentials(dryRun bool) (int, error) {
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get k8s client set")
+	}
+
+	return reencryptCredentials(clientset, dryRun)
+}
+
+func reencryptCredentials(clientset kubernetes.Interface, dryRun bool) (int, error) {
+	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), "kotsadm-gitops", metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return 0, nil
+	} else if err != nil {
+		return 0, errors.Wrap(err, "get kotsadm-gitops secret")
+	}
+
+	count := 0
+	for key, val := range secret.Data {
+		splitKey := strings.Split(key, ".")
+		if len(splitKey) != 3 || (splitKey[2] != "privateKey" && splitKey[2] != "password" && splitKey[2] != "signingPrivateKey") {
+			continue
+		}
+
+		decodedPrivateKey, err := base64.StdEncoding.DecodeString(string(val))
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to decode %s", key)
+		}
+		if crypto.IsEncryptedWithCurrentKey(decodedPrivateKey) {
+			continue
+		}
+		count++
+
+		if dryRun {
+			continue
+		}
+
+		decryptedPrivateKey, err := crypto.Decrypt(decodedPrivateKey)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to decrypt %s", key)
+		}
+		secret.Data[key] = []byte(base64.StdEncoding.EncodeToString(crypto.Encrypt(decryptedPrivateKey)))
+	}
+
+	if dryRun || count == 0 {
+		return count, nil
+	}
+
+	if _, err := clientset.CoreV1().Secrets(util.PodNamespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return 0, errors.Wrap(err, "failed to update secret")
+	}
+
+	return count, nil
+}
+
 func GetGitOps() (GlobalGitOpsConfig, error) {
 	clientset, err := k8sutil.GetClientset()
 	if err != nil {
 		return GlobalGitOpsConfig{}, errors.Wrap(err, "failed to get k8s client set")
 	}
 
+	return getGitOps(clientset)
+}
+
+func getGitOps(clientset kubernetes.Interface) (GlobalGitOpsConfig, error) {
 	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), "kotsadm-gitops", metav1.GetOptions{})
 	if kuberneteserrors.IsNotFound(err) {
 		return GlobalGitOpsConfig{}, nil
@@ -644,12 +973,23 @@ func GetGitOps() (GlobalGitOpsConfig, error) {
 	}
 
 	parsedConfig := GlobalGitOpsConfig{
-		Enabled:  true,
-		Provider: string(secret.Data["provider.0.type"]),
-		URI:      string(secret.Data["provider.0.repoUri"]),
-		Hostname: string(secret.Data["provider.0.hostname"]),
-		HTTPPort: string(secret.Data["provider.0.httpPort"]),
-		SSHPort:  string(secret.Data["provider.0.sshPort"]),
+		Enabled:           true,
+		Provider:          string(secret.Data["provider.0.type"]),
+		URI:               string(secret.Data["provider.0.repoUri"]),
+		Hostname:          string(secret.Data["provider.0.hostname"]),
+		HTTPPort:          string(secret.Data["provider.0.httpPort"]),
+		SSHPort:           string(secret.Data["provider.0.sshPort"]),
+		AuthType:          string(secret.Data["provider.0.authType"]),
+		Username:          string(secret.Data["provider.0.username"]),
+		CommitURLTemplate: string(secret.Data["provider.0.commitUrlTemplate"]),
+		APIURL:            string(secret.Data["provider.0.apiUrl"]),
+		AuthorName:        string(secret.Data["provider.0.authorName"]),
+		AuthorEmail:       string(secret.Data["provider.0.authorEmail"]),
+		SigningKeyType:    string(secret.Data["provider.0.signingKeyType"]),
+		SigningPublicKey:  string(secret.Data["provider.0.signingPublicKey"]),
+	}
+	if parsedConfig.AuthType == "" {
+		parsedConfig.AuthType = AuthTypeSSH
 	}
 
 	return parsedConfig, nil
@@ -702,9 +1042,62 @@ func gitOpsConfigFromSecretData(idx int64, secretData map[string][]byte) (string
 	return provider, publicKey, privateKey, repoURI, hostname, httpPort, sshPort
 }
 
-func getAuth(privateKey string) (transport.AuthMethod, error) {
+// setSigningKey stores the commit signing key in the gitops secret data, generating one if needed
+func setSigningKey(secretData map[string][]byte, repoIdx int64, opts CreateGitOpsOptions) error {
+	typeKey := fmt.Sprintf("provider.%d.signingKeyType", repoIdx)
+	publicKeyKey := fmt.Sprintf("provider.%d.signingPublicKey", repoIdx)
+	privateKeyKey := fmt.Sprintf("provider.%d.signingPrivateKey", repoIdx)
+
+	if opts.SigningKeyType == "" {
+		delete(secretData, typeKey)
+		delete(secretData, publicKeyKey)
+		delete(secretData, privateKeyKey)
+		return nil
+	}
+
+	_, hasKey := secretData[privateKeyKey]
+	if hasKey && string(secretData[typeKey]) == opts.SigningKeyType && !opts.RegenerateSigningKey {
+		return nil
+	}
+
+	authorName, authorEmail := commitAuthor(&GitOpsConfig{AuthorName: opts.AuthorName, AuthorEmail: opts.AuthorEmail})
+	keyPair, err := GenerateSigningKey(opts.SigningKeyType, authorName, authorEmail)
+	if err != nil {
+		return errors.Wrap(err, "failed to generate signing key")
+	}
+
+	encryptedPrivateKey := crypto.Encrypt([]byte(keyPair.PrivateKey))
+	secretData[typeKey] = []byte(opts.SigningKeyType)
+	secretData[publicKeyKey] = []byte(keyPair.PublicKey)
+	secretData[privateKeyKey] = []byte(base64.StdEncoding.EncodeToString(encryptedPrivateKey))
+
+	return nil
+}
+
+// providerSecretValue returns the value of a "provider.<idx>.<key>" key in the gitops secret, or an empty string
+func providerSecretValue(secretData map[string][]byte, idx int64, key string) string {
+	return string(secretData[fmt.Sprintf("provider.%d.%s", idx, key)])
+}
+
+func getAuth(gitOpsConfig *GitOpsConfig) (transport.AuthMethod, error) {
+	// local repos don't need auth, these are only used for testing
+	if allowLocalRepos && isLocalRepo(gitOpsConfig.RepoURI) {
+		return nil, nil
+	}
+
+	switch gitOpsConfig.AuthType {
+	case AuthTypeToken:
+		username := gitOpsConfig.Username
+		if username == "" {
+			username = defaultTokenUsername
+		}
+		return &go_git_http.BasicAuth{Username: username, Password: gitOpsConfig.Password}, nil
+	case AuthTypeBasic:
+		return &go_git_http.BasicAuth{Username: gitOpsConfig.Username, Password: gitOpsConfig.Password}, nil
+	}
+
 	var auth transport.AuthMethod
-	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	signer, err := ssh.ParsePrivateKey([]byte(gitOpsConfig.PrivateKey))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to parse deploy key")
 	}
@@ -713,42 +1106,79 @@ func getAuth(privateKey string) (transport.AuthMethod, error) {
 	return auth, nil
 }
 
-func CreateGitOpsDownstreamCommit(a *apptypes.App, clusterID string, newSequence int, filesInDir string, downstreamName string) (string, error) {
+func CreateGitOpsDownstreamCommit(a *apptypes.App, clusterID string, newSequence int, filesInDir string, downstreamName string, opts CommitOptions) (*CommitResult, error) {
 	downstreamGitOps, err := GetDownstreamGitOps(a.ID, clusterID)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get downstream gitops")
+		return nil, errors.Wrap(err, "failed to get downstream gitops")
 	}
 	if downstreamGitOps == nil || !downstreamGitOps.IsConnected {
-		return "", nil
+		return &CommitResult{}, nil
 	}
-	createdCommitURL, err := CreateGitOpsCommit(downstreamGitOps, a.Slug, a.Name, int(newSequence), filesInDir, downstreamName)
+	result, err := CreateGitOpsCommit(downstreamGitOps, a.Slug, a.Name, int(newSequence), filesInDir, downstreamName, opts)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to create gitops commit")
+		return nil, errors.Wrap(err, "failed to create gitops commit")
 	}
-	return createdCommitURL, nil
+	return result, nil
 }
 
-func CreateGitOpsCommit(gitOpsConfig *GitOpsConfig, appSlug string, appName string, newSequence int, archiveDir string, downstreamName string) (string, error) {
+func CreateGitOpsCommit(gitOpsConfig *GitOpsConfig, appSlug string, appName string, newSequence int, archiveDir string, downstreamName string, opts CommitOptions) (*CommitResult, error) {
 	out, _, err := apparchive.GetRenderedApp(archiveDir, downstreamName, binaries.GetKustomizeBinPath())
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get rendered app")
+		return nil, errors.Wrap(err, "failed to get rendered app")
+	}
+	app := &renderedApp{
+		manifests: out,
 	}
 
-	// using the deploy key, create the commit in a new branch
-	auth, err := getAuth(gitOpsConfig.PrivateKey)
+	if isHelmReleaseFormat(gitOpsConfig.Format) {
+		helmCharts, err := getHelmChartReleases(archiveDir)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get helm charts")
+		}
+		app.helmCharts = helmCharts
+		app.argoCDNamespace = gitOpsConfig.ArgoCDNamespace
+
+		app.helmRepositoryURL = gitOpsConfig.HelmRepositoryURL
+		if app.helmRepositoryURL == "" && len(helmCharts) > 0 {
+			helmRepositoryURL, err := defaultHelmRepositoryURL(archiveDir, appSlug)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get helm repository url")
+			}
+			app.helmRepositoryURL = helmRepositoryURL
+			app.helmRepositorySecretName = helmRepositorySecretName(appSlug)
+		}
+	}
+
+	return commitRenderedApp(gitOpsConfig, appSlug, appName, newSequence, app, opts)
+}
+
+func commitRenderedApp(gitOpsConfig *GitOpsConfig, appSlug string, appName string, newSequence int, app *renderedApp, opts CommitOptions) (*CommitResult, error) {
+	isPullRequest := gitOpsConfig.Action == ActionPullRequest
+
+	var prClient pullRequestClient
+	if isPullRequest {
+		c, err := getPullRequestClient(gitOpsConfig)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get pull request client")
+		}
+		prClient = c
+	}
+
+	// using the deploy key or https credentials, create the commit in a new branch
+	auth, err := getAuth(gitOpsConfig)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get auth")
+		return nil, errors.Wrap(err, "failed to get auth")
 	}
 
 	workDir, err := ioutil.TempDir("", "kotsadm")
 	if err != nil {
-		return "", errors.Wrap(err, "failed to create temp dir")
+		return nil, errors.Wrap(err, "failed to create temp dir")
 	}
 	defer os.RemoveAll(workDir)
 
 	cloneURL, err := gitOpsConfig.CloneURL()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to get clone url")
+		return nil, errors.Wrap(err, "failed to get clone url")
 	}
 
 	cloneOptions := &git.CloneOptions{
@@ -759,64 +1189,104 @@ func CreateGitOpsCommit(gitOpsConfig *GitOpsConfig, appSlug string, appName stri
 	}
 	cloned, workTree, err := CloneAndCheckout(workDir, cloneOptions, gitOpsConfig.Branch)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	dirPath := filepath.Join(workDir, gitOpsConfig.Path)
-	_, err = os.Stat(dirPath)
-	if os.IsNotExist(err) {
-		// create subdirectory if not exist
-		err := os.MkdirAll(dirPath, 0755)
-		if err != nil {
-			return "", errors.Wrap(err, "failed to mkdir")
-		}
-	} // ignore error here and let the stat of the file below handle any errors
+	layout, err := layoutApp(gitOpsConfig.Format, appSlug, app)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lay out app")
+	}
 
-	filePath := filepath.Join(dirPath, fmt.Sprintf("%s.yaml", appSlug))
-	_, err = os.Stat(filePath)
-	if err == nil { // if the file has not changed, end now
-		currentRevision, err := os.ReadFile(filePath)
+	// in pull request mode, the commit goes to a branch per version that is based on the configured branch
+	pushBranch := gitOpsConfig.Branch
+	if isPullRequest {
+		pushBranch = pullRequestBranchName(appSlug, newSequence)
+		err := workTree.Checkout(&git.CheckoutOptions{
+			Branch: plumbing.NewBranchReferenceName(pushBranch),
+			Create: true,
+		})
 		if err != nil {
-			return "", errors.Wrap(err, "failed to read current app yaml")
+			return nil, errors.Wrapf(err, "failed to create branch %s", pushBranch)
 		}
-		if string(currentRevision) == string(out) {
-			return "", nil
-		}
-	} else if !os.IsNotExist(err) {
-		return "", errors.Wrap(err, "failed to stat current app yaml")
 	}
 
-	err = ioutil.WriteFile(filePath, out, 0644)
+	if err := writeAppLayout(filepath.Join(workDir, gitOpsConfig.Path), appSlug, layout); err != nil {
+		return nil, errors.Wrap(err, "failed to write app")
+	}
+
+	status, err := workTree.Status()
 	if err != nil {
-		return "", errors.Wrap(err, "failed to write updated app yaml")
+		return nil, errors.Wrap(err, "failed to get worktree status")
+	}
+	if status.IsClean() { // if the app has not changed, end now
+		return &CommitResult{}, nil
 	}
 
-	_, err = workTree.Add(strings.TrimPrefix(filepath.Join(gitOpsConfig.Path, fmt.Sprintf("%s.yaml", appSlug)), "/"))
+	// stages added, updated and deleted files
+	err = workTree.AddWithOptions(&git.AddOptions{All: true})
 	if err != nil {
-		return "", errors.Wrap(err, "failed to add to worktree")
+		return nil, errors.Wrap(err, "failed to add to worktree")
 	}
 
 	// commit it
-	updatedHash, err := workTree.Commit(fmt.Sprintf("Updating %s to version %d", appName, newSequence), &git.CommitOptions{
-		Author: &object.Signature{
-			Name:  "KOTS Admin Console",
-			Email: "help@replicated.com",
-			When:  time.Now(),
-		},
-	})
+	commitOptions, err := newCommitOptions(gitOpsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get commit options")
+	}
+	updatedHash, err := workTree.Commit(fmt.Sprintf("Updating %s to version %d", appName, newSequence), commitOptions)
 	if err != nil {
-		return "", errors.Wrap(err, "failed to commit")
+		return nil, errors.Wrap(err, "failed to commit")
 	}
 
-	err = cloned.Push(&git.PushOptions{
+	pushOptions := &git.PushOptions{
 		RemoteName: cloneOptions.RemoteName,
 		Auth:       auth,
+	}
+	if isPullRequest {
+		// force push in case the branch was left behind by a previous attempt
+		pushOptions.RefSpecs = []config.RefSpec{
+			config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/heads/%s", pushBranch, pushBranch)),
+		}
+	}
+	err = cloned.Push(pushOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to push")
+	}
+
+	result := &CommitResult{
+		CommitURL:  gitOpsConfig.CommitURL(updatedHash.String()),
+		CommitHash: updatedHash.String(),
+	}
+	if !isPullRequest {
+		return result, nil
+	}
+
+	pr, err := prClient.createPullRequest(context.TODO(), createPullRequestOptions{
+		Title:      pullRequestTitle(appName, opts.VersionLabel, newSequence),
+		Body:       pullRequestBody(opts),
+		HeadBranch: pushBranch,
+		BaseBranch: gitOpsConfig.Branch,
 	})
 	if err != nil {
-		return "", errors.Wrap(err, "failed to push")
+		return nil, errors.Wrap(err, "failed to create pull request")
+	}
+	result.PullRequest = pr
+
+	// a pull request that is superseded by a newer version would deploy an outdated version if merged
+	for _, superseded := range opts.SupersededPullRequests {
+		if superseded.Number == pr.Number || superseded.State != gitopstypes.PullRequestStateOpen {
+			continue
+		}
+		if err := prClient.closePullRequest(context.TODO(), superseded); err != nil {
+			// the pull request stays open until its state is refreshed or a newer version closes it
+			logger.Error(errors.Wrapf(err, "failed to close superseded pull request %s", superseded.URL))
+			continue
+		}
+		superseded.State = gitopstypes.PullRequestStateClosed
+		result.ClosedPullRequests = append(result.ClosedPullRequests, superseded)
 	}
 
-	return gitOpsConfig.CommitURL(updatedHash.String()), nil
+	return result, nil
 }
 
 func generatePrivateKey_ed25519() (*KeyPair, error) {
@@ -1,19 +1,35 @@
 package gitops
 
 import (
+	"context"
 	"encoding/base64"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/util"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/ssh"
 	corev1 "k8s.io/api/core/v1"
 	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
 	core "k8s.io/client-go/testing"
 )
 
+func TestMain(m *testing.M) {
+	// the tests push to local repos
+	allowLocalRepos = true
+	os.Exit(m.Run())
+}
+
 func Test_createGitOps(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -78,10 +94,16 @@ func Test_createGitOps(t *testing.T) {
 	clientset := fake.NewSimpleClientset()
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := createGitOps(clientset, test.provider, test.repoURI, test.hostname, test.httpPort, test.sshPort)
+			err := createGitOps(clientset, CreateGitOpsOptions{
+				Provider: test.provider,
+				RepoURI:  test.repoURI,
+				Hostname: test.hostname,
+				HTTPPort: test.httpPort,
+				SSHPort:  test.sshPort,
+			})
 			assert.NoError(t, err)
 
-			err = updateDownstreamGitOps(clientset, test.appID, test.clusterID, test.repoURI, test.branch, test.path, test.format, test.action)
+			err = updateDownstreamGitOps(clientset, test.appID, test.clusterID, test.repoURI, test.branch, test.path, test.format, test.action, "", "", false)
 			assert.NoError(t, err)
 
 			config, err := GetDownstreamGitOpsConfig(clientset, test.appID, test.clusterID)
@@ -96,6 +118,7 @@ func Test_createGitOps(t *testing.T) {
 			assert.Equal(t, test.branch, config.Branch)
 			assert.Equal(t, test.format, config.Format)
 			assert.Equal(t, test.path, config.Path)
+			assert.Equal(t, AuthTypeSSH, config.AuthType)
 
 			publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.PublicKey))
 			assert.NoError(t, err)
@@ -335,3 +358,269 @@ func Test_isGitOpsRepoConfiguredForMultipleApps(t *testing.T) {
 		})
 	}
 }
+
+func Test_createGitOps_generic(t *testing.T) {
+	req := require.New(t)
+
+	clientset := fake.NewSimpleClientset()
+	opts := CreateGitOpsOptions{
+		Provider:          ProviderGeneric,
+		RepoURI:           "https://git.example.com/team/test_repo.git",
+		AuthType:          AuthTypeToken,
+		Password:          "my-token",
+		CommitURLTemplate: "https://git.example.com/team/test_repo/-/commit/{hash}",
+	}
+	req.NoError(createGitOps(clientset, opts))
+	req.NoError(updateDownstreamGitOps(clientset, "app", "cluster", opts.RepoURI, "main", "", "single", "commit", "", "", false))
+
+	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), "kotsadm-gitops", metav1.GetOptions{})
+	req.NoError(err)
+	assert.NotContains(t, string(secret.Data["provider.0.password"]), "my-token")
+
+	config, err := GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, ProviderGeneric, config.Provider)
+	assert.Equal(t, AuthTypeToken, config.AuthType)
+	assert.Equal(t, "my-token", config.Password)
+	assert.Equal(t, opts.CommitURLTemplate, config.CommitURLTemplate)
+	assert.NotEmpty(t, config.PrivateKey)
+
+	// an empty password keeps the stored one
+	opts.Password = ""
+	req.NoError(createGitOps(clientset, opts))
+	config, err = GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, "my-token", config.Password)
+
+	// switching back to ssh removes the password
+	opts.AuthType = AuthTypeSSH
+	req.NoError(createGitOps(clientset, opts))
+	config, err = GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, AuthTypeSSH, config.AuthType)
+	assert.Empty(t, config.Password)
+
+	// token auth without a stored password fails
+	err = createGitOps(fake.NewSimpleClientset(), CreateGitOpsOptions{
+		Provider: ProviderGeneric,
+		RepoURI:  "https://git.example.com/team/other_repo.git",
+		AuthType: AuthTypeToken,
+	})
+	assert.Error(t, err)
+}
+
+func TestValidateCreateGitOpsOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    CreateGitOpsOptions
+		wantErr bool
+	}{
+		{
+			name: "github ssh",
+			opts: CreateGitOpsOptions{Provider: "github", RepoURI: "https://github.com/org/repo"},
+		},
+		{
+			name: "generic ssh",
+			opts: CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "ssh://git@git.example.com:2222/org/repo.git"},
+		},
+		{
+			name: "generic scp style",
+			opts: CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "git@git.example.com:org/repo.git"},
+		},
+		{
+			name: "generic token",
+			opts: CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "https://git.example.com/org/repo.git", AuthType: AuthTypeToken, Password: "token"},
+		},
+		{
+			name:    "generic token over ssh",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "ssh://git@git.example.com/org/repo.git", AuthType: AuthTypeToken, Password: "token"},
+			wantErr: true,
+		},
+		{
+			name:    "basic without username",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "https://git.example.com/org/repo.git", AuthType: AuthTypeBasic, Password: "password"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown auth type",
+			opts:    CreateGitOpsOptions{Provider: "github", RepoURI: "https://github.com/org/repo", AuthType: "oauth"},
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "ftp://git.example.com/org/repo.git"},
+			wantErr: true,
+		},
+		{
+			name:    "local repo",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "file:///var/lib/repo.git"},
+			wantErr: true,
+		},
+		{
+			name:    "local path",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "/var/lib/repo.git"},
+			wantErr: true,
+		},
+		{
+			name:    "relative local path",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "repo.git"},
+			wantErr: true,
+		},
+		{
+			name:    "generic token with scp style",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "git@git.example.com:org/repo.git", AuthType: AuthTypeToken, Password: "token"},
+			wantErr: true,
+		},
+		{
+			name:    "missing uri",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric},
+			wantErr: true,
+		},
+		{
+			name:    "commit url template without hash",
+			opts:    CreateGitOpsOptions{Provider: ProviderGeneric, RepoURI: "https://git.example.com/org/repo.git", CommitURLTemplate: "https://git.example.com/commit"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCreateGitOpsOptions(tt.opts)
+			assert.Equal(t, tt.wantErr, err != nil, "error = %v", err)
+		})
+	}
+}
+
+func TestGitOpsConfig_CloneURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		config GitOpsConfig
+		want   string
+	}{
+		{
+			name:   "github ssh",
+			config: GitOpsConfig{Provider: "github", RepoURI: "https://github.com/org/repo"},
+			want:   "git@github.com:org/repo.git",
+		},
+		{
+			name:   "github token",
+			config: GitOpsConfig{Provider: "github", RepoURI: "https://github.com/org/repo", AuthType: AuthTypeToken},
+			want:   "https://github.com/org/repo.git",
+		},
+		{
+			name:   "bitbucket server basic",
+			config: GitOpsConfig{Provider: "bitbucket_server", RepoURI: "https://bitbucket.example.com/projects/ORG/repos/repo", AuthType: AuthTypeBasic},
+			want:   "https://bitbucket.example.com/scm/org/repo.git",
+		},
+		{
+			name:   "generic",
+			config: GitOpsConfig{Provider: ProviderGeneric, RepoURI: "ssh://git@git.example.com:2222/org/repo.git"},
+			want:   "ssh://git@git.example.com:2222/org/repo.git",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.config.CloneURL()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestGitOpsConfig_CommitURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		config GitOpsConfig
+		want   string
+	}{
+		{
+			name:   "github",
+			config: GitOpsConfig{Provider: "github", RepoURI: "https://github.com/org/repo"},
+			want:   "https://github.com/org/repo/commit/abc123",
+		},
+		{
+			name:   "template",
+			config: GitOpsConfig{Provider: "github", RepoURI: "https://github.com/org/repo", CommitURLTemplate: "https://review.example.com/c/{hash}"},
+			want:   "https://review.example.com/c/abc123",
+		},
+		{
+			name:   "generic https",
+			config: GitOpsConfig{Provider: ProviderGeneric, RepoURI: "https://git.example.com/org/repo.git"},
+			want:   "https://git.example.com/org/repo/commit/abc123",
+		},
+		{
+			name:   "generic ssh",
+			config: GitOpsConfig{Provider: ProviderGeneric, RepoURI: "ssh://git@git.example.com/org/repo.git"},
+			want:   "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.config.CommitURL("abc123"))
+		})
+	}
+}
+
+func TestTestGitOpsConnection_local(t *testing.T) {
+	req := require.New(t)
+
+	repoDir := t.TempDir()
+	repo, err := git.PlainInit(repoDir, false)
+	req.NoError(err)
+	req.NoError(os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("test"), 0644))
+	worktree, err := repo.Worktree()
+	req.NoError(err)
+	_, err = worktree.Add("README.md")
+	req.NoError(err)
+	_, err = worktree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	req.NoError(err)
+
+	branch, err := TestGitOpsConnection(&GitOpsConfig{
+		Provider: ProviderGeneric,
+		RepoURI:  "file://" + repoDir,
+	})
+	req.NoError(err)
+	assert.Equal(t, "master", branch)
+}
+
+func Test_reencryptCredentials(t *testing.T) {
+	req := require.New(t)
+
+	clientset := fake.NewSimpleClientset()
+	req.NoError(createGitOps(clientset, CreateGitOpsOptions{
+		Provider: "github",
+		RepoURI:  "https://github.com/test_org/test_repo",
+		AuthType: AuthTypeToken,
+		Password: "my-token",
+	}))
+	req.NoError(updateDownstreamGitOps(clientset, "app", "cluster", "https://github.com/test_org/test_repo", "main", "", "single", "commit", "", "", false))
+
+	before, err := GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+
+	count, err := reencryptCredentials(clientset, true)
+	req.NoError(err)
+	assert.Equal(t, 0, count)
+
+	newKey, err := crypto.GenerateKey()
+	req.NoError(err)
+	req.NoError(crypto.SetEncryptionKey(newKey))
+
+	count, err = reencryptCredentials(clientset, true)
+	req.NoError(err)
+	assert.Equal(t, 2, count)
+
+	count, err = reencryptCredentials(clientset, false)
+	req.NoError(err)
+	assert.Equal(t, 2, count)
+
+	count, err = reencryptCredentials(clientset, true)
+	req.NoError(err)
+	assert.Equal(t, 0, count)
+
+	after, err := GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, before.PrivateKey, after.PrivateKey)
+	assert.Equal(t, "my-token", after.Password)
+}
@@ -0,0 +1,271 @@
+package gitops
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/util"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// defaultArgoCDNamespace is the namespace that argo cd watches for applications by default
+	defaultArgoCDNamespace = "argocd"
+	// inClusterServer is the argo cd destination for the cluster that argo cd runs in
+	inClusterServer = "https://kubernetes.default.svc"
+)
+
+// renderedApp is the content of a version that is committed to the repo
+type renderedApp struct {
+	// manifests are the rendered kustomize output, which excludes v1beta2 helm charts
+	manifests []byte
+	// helmCharts are the v1beta2 helm charts in the version. they are only committed
+	// in the flux and argocd formats, which deploy them from helmRepositoryURL.
+	helmCharts        []helmChartRelease
+	helmRepositoryURL string
+	// helmRepositorySecretName is the pull secret that flux authenticates to the helm repository with.
+	// it's only set for the replicated registry, which requires the license to pull charts.
+	helmRepositorySecretName string
+	// argoCDNamespace is the namespace that the argocd format creates applications in
+	argoCDNamespace string
+}
+
+// helmChartRelease is a v1beta2 helm chart with the values that kots renders for it
+type helmChartRelease struct {
+	ReleaseName  string
+	Namespace    string
+	ChartName    string
+	ChartVersion string
+	Values       map[string]interface{}
+}
+
+func isHelmReleaseFormat(format string) bool {
+	return format == FormatFlux || format == FormatArgoCD
+}
+
+// getHelmChartReleases reads the v1beta2 helm charts from the version archive. the values are the ones
+// written to the helm dir by the v1beta2 pipeline, so they match what kots installs with helm.
+func getHelmChartReleases(archiveDir string) ([]helmChartRelease, error) {
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kots kinds")
+	}
+	if kotsKinds.V1Beta2HelmCharts == nil {
+		return nil, nil
+	}
+
+	releases := []helmChartRelease{}
+	for _, helmChart := range kotsKinds.V1Beta2HelmCharts.Items {
+		// excluded charts are not written to the helm dir
+		valuesPath := filepath.Join(archiveDir, "helm", helmChart.GetDirName(), "values.yaml")
+		content, err := os.ReadFile(valuesPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, errors.Wrapf(err, "failed to read values for chart %s", helmChart.GetDirName())
+		}
+
+		values := map[string]interface{}{}
+		if err := yaml.Unmarshal(content, &values); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse values for chart %s", helmChart.GetDirName())
+		}
+		if values == nil {
+			values = map[string]interface{}{}
+		}
+
+		releases = append(releases, helmChartRelease{
+			ReleaseName:  helmChart.GetReleaseName(),
+			Namespace:    helmChart.GetNamespace(),
+			ChartName:    helmChart.GetChartName(),
+			ChartVersion: helmChart.GetChartVersion(),
+			Values:       values,
+		})
+	}
+
+	sort.Slice(releases, func(i, j int) bool {
+		return releases[i].ReleaseName < releases[j].ReleaseName
+	})
+
+	return releases, nil
+}
+
+// defaultHelmRepositoryURL returns the replicated registry repository that the license's channel charts are pushed to
+func defaultHelmRepositoryURL(archiveDir string, appSlug string) (string, error) {
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load kots kinds")
+	}
+	if !kotsKinds.HasLicense() {
+		return "", errors.New("no license found")
+	}
+
+	channelSlug := ""
+	for _, channel := range kotsKinds.License.GetChannels() {
+		if channel.ChannelID == kotsKinds.Installation.Spec.ChannelID {
+			channelSlug = channel.ChannelSlug
+			break
+		}
+	}
+	if channelSlug == "" {
+		return "", errors.Errorf("channel %s not found in license", kotsKinds.Installation.Spec.ChannelID)
+	}
+
+	registryDomain := kotsKinds.Installation.Spec.ReplicatedRegistryDomain
+	if registryDomain == "" {
+		registryDomain = util.DefaultReplicatedRegistryDomain()
+	}
+
+	return fmt.Sprintf("oci://%s/%s/%s", registryDomain, appSlug, channelSlug), nil
+}
+
+// helmRepositorySecretName is the name of the pull secret that the flux HelmRepository for the replicated registry references
+func helmRepositorySecretName(appSlug string) string {
+	return fmt.Sprintf("%s-registry", appSlug)
+}
+
+// fluxHelmFiles returns a flux HelmRepository for the chart registry and a HelmRelease per chart
+func fluxHelmFiles(appSlug string, app *renderedApp) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	if len(app.helmCharts) == 0 {
+		return files, nil
+	}
+
+	repositorySpec := map[string]interface{}{
+		"type":     "oci",
+		"url":      app.helmRepositoryURL,
+		"interval": "10m",
+	}
+	if app.helmRepositorySecretName != "" {
+		repositorySpec["secretRef"] = map[string]interface{}{
+			"name": app.helmRepositorySecretName,
+		}
+	}
+	repository, err := yaml.Marshal(map[string]interface{}{
+		"apiVersion": "source.toolkit.fluxcd.io/v1",
+		"kind":       "HelmRepository",
+		"metadata": map[string]interface{}{
+			"name": appSlug,
+		},
+		"spec": repositorySpec,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal helm repository")
+	}
+	if app.helmRepositorySecretName != "" {
+		// the secret holds the license, so it's never committed to the repo
+		registryHost := strings.SplitN(strings.TrimPrefix(app.helmRepositoryURL, "oci://"), "/", 2)[0]
+		repository = append([]byte(fmt.Sprintf(`# the replicated registry requires the license to pull charts. create the pull secret in the namespace of this HelmRepository with:
+# kubectl create secret docker-registry %s --docker-server=%s --docker-username=<license id> --docker-password=<license id>
+`, app.helmRepositorySecretName, registryHost)), repository...)
+	}
+	files[helmFileName("HelmRepository", appSlug)] = repository
+
+	for _, chart := range app.helmCharts {
+		spec := map[string]interface{}{
+			"releaseName": chart.ReleaseName,
+			"interval":    "10m",
+			"chart": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"chart":   chart.ChartName,
+					"version": chart.ChartVersion,
+					"sourceRef": map[string]interface{}{
+						"kind": "HelmRepository",
+						"name": appSlug,
+					},
+				},
+			},
+			"values": chart.Values,
+		}
+		if chart.Namespace != "" {
+			spec["targetNamespace"] = chart.Namespace
+			spec["install"] = map[string]interface{}{
+				"createNamespace": true,
+			}
+		}
+
+		release, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "helm.toolkit.fluxcd.io/v2",
+			"kind":       "HelmRelease",
+			"metadata": map[string]interface{}{
+				"name": chart.ReleaseName,
+			},
+			"spec": spec,
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal helm release %s", chart.ReleaseName)
+		}
+		files[helmFileName("HelmRelease", chart.ReleaseName)] = release
+	}
+
+	return files, nil
+}
+
+// argoCDHelmFiles returns an argo cd Application per chart that installs it from the chart registry
+func argoCDHelmFiles(appSlug string, app *renderedApp) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	// argo cd expects oci helm repositories without the scheme
+	repoURL := strings.TrimPrefix(app.helmRepositoryURL, "oci://")
+
+	namespace := app.argoCDNamespace
+	if namespace == "" {
+		namespace = defaultArgoCDNamespace
+	}
+
+	for _, chart := range app.helmCharts {
+		name := fmt.Sprintf("%s-%s", appSlug, chart.ReleaseName)
+		destination := map[string]interface{}{
+			"server": inClusterServer,
+		}
+		if chart.Namespace != "" {
+			destination["namespace"] = chart.Namespace
+		}
+
+		application, err := yaml.Marshal(map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Application",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"project": "default",
+				"source": map[string]interface{}{
+					"repoURL":        repoURL,
+					"chart":          chart.ChartName,
+					"targetRevision": chart.ChartVersion,
+					"helm": map[string]interface{}{
+						"releaseName":  chart.ReleaseName,
+						"valuesObject": chart.Values,
+					},
+				},
+				"destination": destination,
+				"syncPolicy": map[string]interface{}{
+					"syncOptions": []string{"CreateNamespace=true"},
+				},
+			},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal application %s", chart.ReleaseName)
+		}
+		files[helmFileName("Application", name)] = application
+	}
+
+	return files, nil
+}
+
+// helmFileName names the helm chart resources like resourceFiles does, so that the HelmRepository can't collide with a HelmRelease
+func helmFileName(kind string, name string) string {
+	return fmt.Sprintf("%s.yaml", safeFileName(fmt.Sprintf("%s-%s", kind, name)))
+}
+
+// helmFilesDir is where the flux and argocd formats write the helm chart resources, next to the namespace dirs
+func helmFilesDir(appSlug string) string {
+	return path.Join(appSlug, "_helm")
+}
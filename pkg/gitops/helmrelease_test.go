@@ -0,0 +1,222 @@
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestArchive writes kots kinds and the helm dir of a version archive with a chart that is
+// installed and a chart that is excluded, so it does not have a values file
+func writeTestArchive(t *testing.T) string {
+	archiveDir := t.TempDir()
+
+	files := map[string]string{
+		"kotsKinds/web.yaml": `apiVersion: kots.io/v1beta2
+kind: HelmChart
+metadata:
+  name: web
+spec:
+  chart:
+    name: web
+    chartVersion: 1.2.3
+  namespace: web-ns
+`,
+		"kotsKinds/redis.yaml": `apiVersion: kots.io/v1beta2
+kind: HelmChart
+metadata:
+  name: redis
+spec:
+  chart:
+    name: redis
+    chartVersion: 17.0.0
+  releaseName: cache
+`,
+		"kotsKinds/excluded.yaml": `apiVersion: kots.io/v1beta2
+kind: HelmChart
+metadata:
+  name: excluded
+spec:
+  chart:
+    name: excluded
+    chartVersion: 0.1.0
+  exclude: true
+`,
+		"kotsKinds/installation.yaml": `apiVersion: kots.io/v1beta1
+kind: Installation
+metadata:
+  name: my-app
+spec:
+  channelID: channel-2
+  replicatedRegistryDomain: registry.example.com
+`,
+		"kotsKinds/license.yaml": `apiVersion: kots.io/v1beta1
+kind: License
+metadata:
+  name: customer
+spec:
+  appSlug: my-app
+  channelID: channel-1
+  channels:
+  - channelID: channel-1
+    channelSlug: stable
+  - channelID: channel-2
+    channelSlug: beta
+`,
+		"helm/web/values.yaml":   "image:\n  repository: web\nreplicas: 2\n",
+		"helm/cache/values.yaml": "",
+	}
+	for name, content := range files {
+		filePath := filepath.Join(archiveDir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+	}
+
+	return archiveDir
+}
+
+func Test_getHelmChartReleases(t *testing.T) {
+	archiveDir := writeTestArchive(t)
+
+	releases, err := getHelmChartReleases(archiveDir)
+	require.NoError(t, err)
+	assert.Equal(t, []helmChartRelease{
+		{
+			ReleaseName:  "cache",
+			ChartName:    "redis",
+			ChartVersion: "17.0.0",
+			Values:       map[string]interface{}{},
+		},
+		{
+			ReleaseName:  "web",
+			Namespace:    "web-ns",
+			ChartName:    "web",
+			ChartVersion: "1.2.3",
+			Values: map[string]interface{}{
+				"image":    map[string]interface{}{"repository": "web"},
+				"replicas": float64(2),
+			},
+		},
+	}, releases)
+
+	helmRepositoryURL, err := defaultHelmRepositoryURL(archiveDir, "my-app")
+	require.NoError(t, err)
+	assert.Equal(t, "oci://registry.example.com/my-app/beta", helmRepositoryURL)
+
+	releases, err = getHelmChartReleases(t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, releases)
+}
+
+func Test_layoutApp_helmReleases(t *testing.T) {
+	app := &renderedApp{
+		manifests: []byte(testRenderedApp),
+		helmCharts: []helmChartRelease{
+			{
+				ReleaseName:  "web",
+				Namespace:    "web-ns",
+				ChartName:    "web",
+				ChartVersion: "1.2.3",
+				Values:       map[string]interface{}{"replicas": 2},
+			},
+		},
+		helmRepositoryURL:        "oci://registry.example.com/my-app/stable",
+		helmRepositorySecretName: "my-app-registry",
+	}
+
+	layout, err := layoutApp(FormatFlux, "my-app", app)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"my-app/_default/clusterrole-system-web.yaml",
+		"my-app/_default/deployment-web.yaml",
+		"my-app/_helm/helmrelease-web.yaml",
+		"my-app/_helm/helmrepository-my-app.yaml",
+		"my-app/app/configmap-config.yaml",
+	}, layoutFileNames(layout.files))
+	assert.Equal(t, `# the replicated registry requires the license to pull charts. create the pull secret in the namespace of this HelmRepository with:
+# kubectl create secret docker-registry my-app-registry --docker-server=registry.example.com --docker-username=<license id> --docker-password=<license id>
+apiVersion: source.toolkit.fluxcd.io/v1
+kind: HelmRepository
+metadata:
+  name: my-app
+spec:
+  interval: 10m
+  secretRef:
+    name: my-app-registry
+  type: oci
+  url: oci://registry.example.com/my-app/stable
+`, string(layout.files["my-app/_helm/helmrepository-my-app.yaml"]))
+	assert.Equal(t, `apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: web
+spec:
+  chart:
+    spec:
+      chart: web
+      sourceRef:
+        kind: HelmRepository
+        name: my-app
+      version: 1.2.3
+  install:
+    createNamespace: true
+  interval: 10m
+  releaseName: web
+  targetNamespace: web-ns
+  values:
+    replicas: 2
+`, string(layout.files["my-app/_helm/helmrelease-web.yaml"]))
+
+	// a custom helm repository without a pull secret, and a release that has the same name as the repository's file
+	app.helmCharts[0].ReleaseName = "helmrepository-my-app"
+	app.helmRepositorySecretName = ""
+	layout, err = layoutApp(FormatFlux, "my-app", app)
+	require.NoError(t, err)
+	assert.Contains(t, layout.files, "my-app/_helm/helmrelease-helmrepository-my-app.yaml")
+	assert.NotContains(t, string(layout.files["my-app/_helm/helmrepository-my-app.yaml"]), "secretRef")
+	app.helmCharts[0].ReleaseName = "web"
+
+	layout, err = layoutApp(FormatArgoCD, "my-app", app)
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"my-app/_default/clusterrole-system-web.yaml",
+		"my-app/_default/deployment-web.yaml",
+		"my-app/_helm/application-my-app-web.yaml",
+		"my-app/app/configmap-config.yaml",
+	}, layoutFileNames(layout.files))
+	assert.Equal(t, `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: my-app-web
+  namespace: argocd
+spec:
+  destination:
+    namespace: web-ns
+    server: https://kubernetes.default.svc
+  project: default
+  source:
+    chart: web
+    helm:
+      releaseName: web
+      valuesObject:
+        replicas: 2
+    repoURL: registry.example.com/my-app/stable
+    targetRevision: 1.2.3
+  syncPolicy:
+    syncOptions:
+    - CreateNamespace=true
+`, string(layout.files["my-app/_helm/application-my-app-web.yaml"]))
+
+	app.argoCDNamespace = "gitops"
+	layout, err = layoutApp(FormatArgoCD, "my-app", app)
+	require.NoError(t, err)
+	assert.Contains(t, string(layout.files["my-app/_helm/application-my-app-web.yaml"]), "\n  namespace: gitops\n")
+
+	// without helm charts, the formats are the same as the directory format
+	layout, err = layoutApp(FormatFlux, "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+	require.NoError(t, err)
+	assert.Len(t, layout.files, 3)
+}
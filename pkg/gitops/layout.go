@@ -0,0 +1,292 @@
+package gitops
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/util"
+	kustomizetypes "sigs.k8s.io/kustomize/api/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FormatSingle writes the rendered app to a single {slug}.yaml file. this is the default.
+	FormatSingle = "single"
+	// FormatDirectory writes one file per resource to {slug}/{namespace}/{kind}-{name}.yaml
+	FormatDirectory = "directory"
+	// FormatKustomize writes one file per resource to a kustomize base in {slug}/base,
+	// and an overlay in {slug}/overlays/default that customers can add their own patches to
+	FormatKustomize = "kustomize"
+	// FormatFlux writes the directory layout, plus a flux HelmRepository and a HelmRelease per v1beta2 helm chart in {slug}/_helm
+	FormatFlux = "flux"
+	// FormatArgoCD writes the directory layout, plus an argo cd Application per v1beta2 helm chart in {slug}/_helm
+	FormatArgoCD = "argocd"
+
+	// defaultNamespaceDir holds resources without a namespace, which are deployed to the app's namespace
+	defaultNamespaceDir = "_default"
+	// overlaysDir is owned by the customer. files under it are never updated or deleted once created.
+	overlaysDir = "overlays"
+	// layoutManifestDir holds a list per app of the files kots wrote, so that only those are deleted on the next commit
+	layoutManifestDir = ".kots"
+)
+
+var unsafeFileNameChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+func IsSupportedFormat(format string) bool {
+	switch format {
+	case "", FormatSingle, FormatDirectory, FormatKustomize, FormatFlux, FormatArgoCD:
+		return true
+	}
+	return false
+}
+
+// appLayout is the set of files that a version of an app is committed as. paths are relative to the gitops path.
+type appLayout struct {
+	// files are owned by kots and are replaced on every commit
+	files map[string][]byte
+	// seedFiles are only written if they don't exist yet
+	seedFiles map[string][]byte
+}
+
+func layoutApp(format string, appSlug string, app *renderedApp) (*appLayout, error) {
+	layout := &appLayout{
+		files:     map[string][]byte{},
+		seedFiles: map[string][]byte{},
+	}
+
+	switch format {
+	case "", FormatSingle:
+		layout.files[fmt.Sprintf("%s.yaml", appSlug)] = app.manifests
+
+	case FormatDirectory, FormatFlux, FormatArgoCD:
+		files, err := resourceFiles(app.manifests)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to split resources")
+		}
+		for filePath, content := range files {
+			layout.files[path.Join(appSlug, filePath)] = content
+		}
+		if !isHelmReleaseFormat(format) {
+			break
+		}
+
+		var helmFiles map[string][]byte
+		if format == FormatFlux {
+			helmFiles, err = fluxHelmFiles(appSlug, app)
+		} else {
+			helmFiles, err = argoCDHelmFiles(appSlug, app)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create helm chart resources")
+		}
+		for fileName, content := range helmFiles {
+			layout.files[path.Join(helmFilesDir(appSlug), fileName)] = content
+		}
+
+	case FormatKustomize:
+		files, err := resourceFiles(app.manifests)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to split resources")
+		}
+		resources := []string{}
+		for filePath, content := range files {
+			layout.files[path.Join(appSlug, "base", filePath)] = content
+			resources = append(resources, filePath)
+		}
+		sort.Strings(resources)
+
+		base, err := marshalKustomization(resources)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal base kustomization")
+		}
+		layout.files[path.Join(appSlug, "base", "kustomization.yaml")] = base
+
+		overlay, err := marshalKustomization([]string{"../../base"})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to marshal overlay kustomization")
+		}
+		layout.seedFiles[path.Join(appSlug, overlaysDir, "default", "kustomization.yaml")] = overlay
+
+	default:
+		return nil, errors.Errorf("unsupported gitops format %q", format)
+	}
+
+	return layout, nil
+}
+
+type resourceMetadata struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+}
+
+// resourceFiles splits the rendered app into one file per resource, grouped by namespace
+func resourceFiles(rendered []byte) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	for _, doc := range util.ConvertToSingleDocs(rendered) {
+		r := resourceMetadata{}
+		if err := yaml.Unmarshal(doc, &r); err != nil {
+			return nil, errors.Wrap(err, "failed to parse resource")
+		}
+		if r.Kind == "" {
+			// empty or comment only documents
+			continue
+		}
+
+		namespace := safeFileName(r.Metadata.Namespace)
+		if namespace == "" {
+			namespace = defaultNamespaceDir
+		}
+		baseName := safeFileName(fmt.Sprintf("%s-%s", r.Kind, r.Metadata.Name))
+
+		// the same kind and name can exist in different api groups
+		filePath := path.Join(namespace, fmt.Sprintf("%s.yaml", baseName))
+		for i := 2; files[filePath] != nil; i++ {
+			filePath = path.Join(namespace, fmt.Sprintf("%s-%d.yaml", baseName, i))
+		}
+
+		files[filePath] = append(bytes.TrimSpace(doc), '\n')
+	}
+	return files, nil
+}
+
+// safeFileName makes a resource's name or namespace usable as a single path element
+func safeFileName(name string) string {
+	name = unsafeFileNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	// leading dots would hide the file, or refer to the current or parent dir
+	return strings.TrimLeft(name, ".")
+}
+
+func marshalKustomization(resources []string) ([]byte, error) {
+	k := kustomizetypes.Kustomization{
+		TypeMeta: kustomizetypes.TypeMeta{
+			APIVersion: kustomizetypes.KustomizationVersion,
+			Kind:       kustomizetypes.KustomizationKind,
+		},
+		Resources: resources,
+	}
+	return yaml.Marshal(k)
+}
+
+func layoutManifestPath(appSlug string) string {
+	return path.Join(layoutManifestDir, fmt.Sprintf("%s-files.txt", appSlug))
+}
+
+// managedFiles returns the files in the app's part of the repo that were written by kots on the previous commit.
+// files that customers added, and files in the overlays directory, are not included.
+func managedFiles(rootDir string, appSlug string) ([]string, error) {
+	b, err := os.ReadFile(filepath.Join(rootDir, filepath.FromSlash(layoutManifestPath(appSlug))))
+	if os.IsNotExist(err) {
+		// apps committed before the manifest existed were always written to a single file
+		return []string{fmt.Sprintf("%s.yaml", appSlug)}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to read layout manifest")
+	}
+
+	files := []string{}
+	for _, relPath := range strings.Split(string(b), "\n") {
+		relPath = strings.TrimSpace(relPath)
+		if relPath == "" || strings.HasPrefix(relPath, "#") {
+			continue
+		}
+		// the manifest is in the customer's repo, so never trust it to point outside of the app's files
+		if !isAppFile(appSlug, relPath) {
+			continue
+		}
+		files = append(files, relPath)
+	}
+
+	return files, nil
+}
+
+// isAppFile returns true if relPath is the app's single file, or a file in the app's dir outside of the overlays
+func isAppFile(appSlug string, relPath string) bool {
+	if path.Clean(relPath) != relPath || path.IsAbs(relPath) {
+		return false
+	}
+	if relPath == fmt.Sprintf("%s.yaml", appSlug) {
+		return true
+	}
+	if !strings.HasPrefix(relPath, appSlug+"/") || strings.HasPrefix(relPath, path.Join(appSlug, overlaysDir)+"/") {
+		return false
+	}
+	for _, element := range strings.Split(relPath, "/") {
+		if element == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+func marshalLayoutManifest(files map[string][]byte) []byte {
+	relPaths := []string{}
+	for relPath := range files {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+
+	var b bytes.Buffer
+	b.WriteString("# files written by kots. files not listed here are never updated or deleted by kots.\n")
+	for _, relPath := range relPaths {
+		b.WriteString(relPath)
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// writeAppLayout updates the files in rootDir to match the layout, deleting files that kots wrote for resources that were removed
+func writeAppLayout(rootDir string, appSlug string, layout *appLayout) error {
+	existing, err := managedFiles(rootDir, appSlug)
+	if err != nil {
+		return errors.Wrap(err, "failed to list existing files")
+	}
+	for _, relPath := range existing {
+		if _, ok := layout.files[relPath]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(rootDir, filepath.FromSlash(relPath))); err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to remove %s", relPath)
+		}
+	}
+
+	for relPath, content := range layout.files {
+		if err := writeLayoutFile(filepath.Join(rootDir, filepath.FromSlash(relPath)), content); err != nil {
+			return errors.Wrapf(err, "failed to write %s", relPath)
+		}
+	}
+
+	for relPath, content := range layout.seedFiles {
+		filePath := filepath.Join(rootDir, filepath.FromSlash(relPath))
+		if _, err := os.Stat(filePath); err == nil {
+			continue
+		} else if !os.IsNotExist(err) {
+			return errors.Wrapf(err, "failed to stat %s", relPath)
+		}
+		if err := writeLayoutFile(filePath, content); err != nil {
+			return errors.Wrapf(err, "failed to write %s", relPath)
+		}
+	}
+
+	manifestPath := layoutManifestPath(appSlug)
+	if err := writeLayoutFile(filepath.Join(rootDir, filepath.FromSlash(manifestPath)), marshalLayoutManifest(layout.files)); err != nil {
+		return errors.Wrapf(err, "failed to write %s", manifestPath)
+	}
+
+	return nil
+}
+
+func writeLayoutFile(filePath string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return errors.Wrap(err, "failed to mkdir")
+	}
+	return os.WriteFile(filePath, content, 0644)
+}
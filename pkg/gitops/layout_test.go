@@ -0,0 +1,272 @@
+package gitops
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRenderedApp = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: app
+data:
+  key: value
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+---
+# a comment only document
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: system:web
+`
+
+func layoutFileNames(layout map[string][]byte) []string {
+	names := []string{}
+	for name := range layout {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func Test_layoutApp(t *testing.T) {
+	tests := []struct {
+		name          string
+		format        string
+		wantFiles     []string
+		wantSeedFiles []string
+	}{
+		{
+			name:          "default",
+			format:        "",
+			wantFiles:     []string{"my-app.yaml"},
+			wantSeedFiles: []string{},
+		},
+		{
+			name:   "directory",
+			format: FormatDirectory,
+			wantFiles: []string{
+				"my-app/_default/clusterrole-system-web.yaml",
+				"my-app/_default/deployment-web.yaml",
+				"my-app/app/configmap-config.yaml",
+			},
+			wantSeedFiles: []string{},
+		},
+		{
+			name:   "kustomize",
+			format: FormatKustomize,
+			wantFiles: []string{
+				"my-app/base/_default/clusterrole-system-web.yaml",
+				"my-app/base/_default/deployment-web.yaml",
+				"my-app/base/app/configmap-config.yaml",
+				"my-app/base/kustomization.yaml",
+			},
+			wantSeedFiles: []string{"my-app/overlays/default/kustomization.yaml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout, err := layoutApp(tt.format, "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFiles, layoutFileNames(layout.files))
+			assert.Equal(t, tt.wantSeedFiles, layoutFileNames(layout.seedFiles))
+		})
+	}
+
+	layout, err := layoutApp(FormatKustomize, "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- _default/clusterrole-system-web.yaml
+- _default/deployment-web.yaml
+- app/configmap-config.yaml
+`, string(layout.files["my-app/base/kustomization.yaml"]))
+	assert.Equal(t, "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: app\ndata:\n  key: value\n", string(layout.files["my-app/base/app/configmap-config.yaml"]))
+
+	_, err = layoutApp("helm", "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+	assert.Error(t, err)
+}
+
+func Test_resourceFiles_duplicateNames(t *testing.T) {
+	files, err := resourceFiles([]byte(`apiVersion: v1
+kind: Service
+metadata:
+  name: web
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: web
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"_default/service-web-2.yaml", "_default/service-web.yaml"}, layoutFileNames(files))
+}
+
+func Test_resourceFiles_unsafeNamespace(t *testing.T) {
+	files, err := resourceFiles([]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: ../../web
+  namespace: ../../x
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: web
+  namespace: ..
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-..-x/configmap-..-..-web.yaml", "_default/secret-web.yaml"}, layoutFileNames(files))
+}
+
+func Test_commitRenderedApp_layouts(t *testing.T) {
+	req := require.New(t)
+
+	remoteDir := newTestRemoteRepo(t, t.TempDir())
+	gitOpsConfig := &GitOpsConfig{
+		Provider: ProviderGeneric,
+		RepoURI:  "file://" + remoteDir,
+		Branch:   "main",
+		Path:     "apps",
+		Format:   FormatKustomize,
+	}
+
+	result, err := commitRenderedApp(gitOpsConfig, "my-app", "My App", 1, &renderedApp{manifests: []byte(testRenderedApp)}, CommitOptions{})
+	req.NoError(err)
+	assert.Equal(t, "", result.CommitURL) // generic file:// repos don't have commit urls
+	assert.ElementsMatch(t, []string{
+		"README.md",
+		"apps/.kots/my-app-files.txt",
+		"apps/my-app/base/_default/clusterrole-system-web.yaml",
+		"apps/my-app/base/_default/deployment-web.yaml",
+		"apps/my-app/base/app/configmap-config.yaml",
+		"apps/my-app/base/kustomization.yaml",
+		"apps/my-app/overlays/default/kustomization.yaml",
+	}, remoteFiles(t, remoteDir))
+
+	// the same app again does not create a commit
+	before := remoteHead(t, remoteDir)
+	_, err = commitRenderedApp(gitOpsConfig, "my-app", "My App", 2, &renderedApp{manifests: []byte(testRenderedApp)}, CommitOptions{})
+	req.NoError(err)
+	assert.Equal(t, before, remoteHead(t, remoteDir))
+
+	// removed resources are deleted, and switching formats removes the old layout except for the overlays
+	gitOpsConfig.Format = FormatDirectory
+	_, err = commitRenderedApp(gitOpsConfig, "my-app", "My App", 3, &renderedApp{manifests: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")}, CommitOptions{})
+	req.NoError(err)
+	assert.ElementsMatch(t, []string{
+		"README.md",
+		"apps/.kots/my-app-files.txt",
+		"apps/my-app/_default/deployment-web.yaml",
+		"apps/my-app/overlays/default/kustomization.yaml",
+	}, remoteFiles(t, remoteDir))
+
+	gitOpsConfig.Format = FormatSingle
+	_, err = commitRenderedApp(gitOpsConfig, "my-app", "My App", 4, &renderedApp{manifests: []byte(testRenderedApp)}, CommitOptions{})
+	req.NoError(err)
+	assert.ElementsMatch(t, []string{
+		"README.md",
+		"apps/.kots/my-app-files.txt",
+		"apps/my-app.yaml",
+		"apps/my-app/overlays/default/kustomization.yaml",
+	}, remoteFiles(t, remoteDir))
+}
+
+func Test_writeAppLayout_keepsOverlays(t *testing.T) {
+	req := require.New(t)
+
+	rootDir := t.TempDir()
+	patch := filepath.Join(rootDir, "my-app", "overlays", "default", "patch.yaml")
+	req.NoError(os.MkdirAll(filepath.Dir(patch), 0755))
+	req.NoError(os.WriteFile(patch, []byte("custom"), 0644))
+	kustomization := filepath.Join(rootDir, "my-app", "overlays", "default", "kustomization.yaml")
+	req.NoError(os.WriteFile(kustomization, []byte("custom"), 0644))
+
+	layout, err := layoutApp(FormatKustomize, "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+	req.NoError(err)
+	req.NoError(writeAppLayout(rootDir, "my-app", layout))
+
+	b, err := os.ReadFile(kustomization)
+	req.NoError(err)
+	assert.Equal(t, "custom", string(b))
+	_, err = os.Stat(patch)
+	assert.NoError(t, err)
+}
+
+func Test_writeAppLayout_keepsCustomerFiles(t *testing.T) {
+	req := require.New(t)
+
+	rootDir := t.TempDir()
+	custom := filepath.Join(rootDir, "my-app", "_default", "custom.yaml")
+	req.NoError(os.MkdirAll(filepath.Dir(custom), 0755))
+	req.NoError(os.WriteFile(custom, []byte("custom"), 0644))
+	outside := filepath.Join(rootDir, "other.yaml")
+	req.NoError(os.WriteFile(outside, []byte("custom"), 0644))
+
+	layout, err := layoutApp(FormatDirectory, "my-app", &renderedApp{manifests: []byte(testRenderedApp)})
+	req.NoError(err)
+	req.NoError(writeAppLayout(rootDir, "my-app", layout))
+
+	// a tampered manifest can't delete files outside of the app's files
+	manifest := filepath.Join(rootDir, ".kots", "my-app-files.txt")
+	b, err := os.ReadFile(manifest)
+	req.NoError(err)
+	req.NoError(os.WriteFile(manifest, append(b, []byte("other.yaml\nmy-app/../other.yaml\n")...), 0644))
+
+	layout, err = layoutApp(FormatDirectory, "my-app", &renderedApp{manifests: []byte("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n")})
+	req.NoError(err)
+	req.NoError(writeAppLayout(rootDir, "my-app", layout))
+
+	_, err = os.Stat(filepath.Join(rootDir, "my-app", "app", "configmap-config.yaml"))
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(custom)
+	assert.NoError(t, err)
+	_, err = os.Stat(outside)
+	assert.NoError(t, err)
+
+	b, err = os.ReadFile(manifest)
+	req.NoError(err)
+	assert.Contains(t, string(b), "\nmy-app/_default/deployment-web.yaml\n")
+	assert.NotContains(t, string(b), "other.yaml")
+}
+
+func remoteHead(t *testing.T, remoteDir string) plumbing.Hash {
+	repo, err := git.PlainOpen(remoteDir)
+	require.NoError(t, err)
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	require.NoError(t, err)
+	return ref.Hash()
+}
+
+func remoteFiles(t *testing.T, remoteDir string) []string {
+	repo, err := git.PlainOpen(remoteDir)
+	require.NoError(t, err)
+	commit, err := repo.CommitObject(remoteHead(t, remoteDir))
+	require.NoError(t, err)
+	iter, err := commit.Files()
+	require.NoError(t, err)
+
+	files := []string{}
+	for {
+		f, err := iter.Next()
+		if err != nil {
+			break
+		}
+		files = append(files, f.Name)
+	}
+	return files
+}
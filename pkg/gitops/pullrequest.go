@@ -0,0 +1,454 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/apparchive"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
+)
+
+const (
+	// ActionCommit pushes commits directly to the configured branch. this is the default.
+	ActionCommit = "commit"
+	// ActionPullRequest pushes commits to a branch per version and opens a pull request against the configured branch
+	ActionPullRequest = "pull_request"
+)
+
+type pullRequestClient interface {
+	createPullRequest(ctx context.Context, opts createPullRequestOptions) (*gitopstypes.PullRequest, error)
+	closePullRequest(ctx context.Context, pr gitopstypes.PullRequest) error
+	// getPullRequestState returns the current state of the pull request on the provider,
+	// and the commit that it was merged as if the provider reports one
+	getPullRequestState(ctx context.Context, number int64) (gitopstypes.PullRequestState, string, error)
+}
+
+type createPullRequestOptions struct {
+	Title      string
+	Body       string
+	HeadBranch string
+	BaseBranch string
+}
+
+// pullRequestBranchName is the branch that the commit for a version is pushed to in pull request mode
+func pullRequestBranchName(appSlug string, sequence int) string {
+	return fmt.Sprintf("kots/%s/version-%d", appSlug, sequence)
+}
+
+func pullRequestTitle(appName string, versionLabel string, sequence int) string {
+	if versionLabel == "" {
+		return fmt.Sprintf("Update %s to sequence %d", appName, sequence)
+	}
+	return fmt.Sprintf("Update %s to %s (sequence %d)", appName, versionLabel, sequence)
+}
+
+func pullRequestBody(opts CommitOptions) string {
+	var b strings.Builder
+
+	b.WriteString("This pull request was opened by the KOTS Admin Console.\n\n")
+
+	b.WriteString("## Release notes\n\n")
+	if strings.TrimSpace(opts.ReleaseNotes) == "" {
+		b.WriteString("_No release notes were provided for this version._\n\n")
+	} else {
+		b.WriteString(strings.TrimSpace(opts.ReleaseNotes))
+		b.WriteString("\n\n")
+	}
+
+	b.WriteString("## Changes\n\n")
+	if opts.DiffSummary == nil {
+		b.WriteString("_No diff is available for this version._\n")
+	} else {
+		b.WriteString(formatDiffSummary(opts.DiffSummary))
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+func formatDiffSummary(diff *apparchive.Diff) string {
+	files := "files"
+	if diff.FilesChanged == 1 {
+		files = "file"
+	}
+	return fmt.Sprintf("%d %s changed, %d lines added, %d lines removed", diff.FilesChanged, files, diff.LinesAdded, diff.LinesRemoved)
+}
+
+// RefreshPullRequests reads the state of the pull requests from the provider and returns the ones whose
+// state changed, such as pull requests that were merged or closed outside of kots
+func RefreshPullRequests(gitOpsConfig *GitOpsConfig, prs []gitopstypes.PullRequest) ([]gitopstypes.PullRequest, error) {
+	if len(prs) == 0 {
+		return nil, nil
+	}
+
+	prClient, err := getPullRequestClient(gitOpsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pull request client")
+	}
+
+	changed := []gitopstypes.PullRequest{}
+	for _, pr := range prs {
+		state, mergeCommitHash, err := prClient.getPullRequestState(context.TODO(), pr.Number)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get state of pull request %s", pr.URL)
+		}
+		if state != pr.State || mergeCommitHash != pr.MergeCommitHash {
+			pr.State = state
+			pr.MergeCommitHash = mergeCommitHash
+			changed = append(changed, pr)
+		}
+	}
+
+	return changed, nil
+}
+
+// getPullRequestClient returns the client for the provider's pull request api.
+// pull requests are opened with the same https credentials used to push.
+func getPullRequestClient(gitOpsConfig *GitOpsConfig) (pullRequestClient, error) {
+	if !gitOpsConfig.usesHTTPAuth() {
+		return nil, errors.New("the pull request action requires token or basic auth")
+	}
+
+	apiURL, err := gitOpsConfig.pullRequestAPIURL()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get api url")
+	}
+
+	repoPath, err := gitOpsConfig.repoPath()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get repo path")
+	}
+
+	api := &providerAPI{
+		baseURL:    strings.TrimSuffix(apiURL, "/"),
+		authType:   gitOpsConfig.AuthType,
+		username:   gitOpsConfig.Username,
+		password:   gitOpsConfig.Password,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+
+	switch gitOpsConfig.Provider {
+	case "github", "github_enterprise", ProviderGitea:
+		parts := strings.Split(repoPath, "/")
+		owner, repo := parts[len(parts)-2], parts[len(parts)-1]
+		return &githubClient{api: api, owner: owner, repo: repo}, nil
+	case "gitlab", "gitlab_enterprise":
+		return &gitlabClient{api: api, project: repoPath}, nil
+	case "bitbucket_server":
+		parts := strings.Split(repoPath, "/")
+		if len(parts) < 4 || parts[0] != "projects" || parts[2] != "repos" {
+			return nil, errors.Errorf("unexpected bitbucket server repo path: %s", repoPath)
+		}
+		return &bitbucketServerClient{api: api, projectKey: parts[1], repoSlug: parts[3]}, nil
+	}
+
+	return nil, errors.Errorf("pull requests are not supported for provider %q", gitOpsConfig.Provider)
+}
+
+// pullRequestAPIURL returns the base url of the provider's rest api
+func (g *GitOpsConfig) pullRequestAPIURL() (string, error) {
+	if g.APIURL != "" {
+		return g.APIURL, nil
+	}
+
+	u, err := url.Parse(g.RepoURI)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse repo uri")
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		return "", errors.Errorf("api url cannot be derived from repo uri %s", g.RepoURI)
+	}
+	base := fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+
+	switch g.Provider {
+	case "github":
+		return "https://api.github.com", nil
+	case "github_enterprise":
+		return base + "/api/v3", nil
+	case "gitlab", "gitlab_enterprise":
+		return base + "/api/v4", nil
+	case "bitbucket_server":
+		return base + "/rest/api/1.0", nil
+	case ProviderGitea:
+		return base + "/api/v1", nil
+	}
+
+	return "", errors.Errorf("pull requests are not supported for provider %q", g.Provider)
+}
+
+// repoPath returns the path of the repo without a leading slash or .git suffix, e.g. "owner/repo"
+func (g *GitOpsConfig) repoPath() (string, error) {
+	u, err := url.Parse(g.RepoURI)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse repo uri")
+	}
+
+	repoPath := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	if strings.Count(repoPath, "/") < 1 {
+		return "", errors.Errorf("unexpected repo uri format: %s", g.RepoURI)
+	}
+
+	return repoPath, nil
+}
+
+type providerAPI struct {
+	baseURL    string
+	authType   string
+	username   string
+	password   string
+	httpClient *http.Client
+}
+
+func (a *providerAPI) do(ctx context.Context, method string, path string, body interface{}, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal request")
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reqBody)
+	if err != nil {
+		return errors.Wrap(err, "failed to create request")
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if a.authType == AuthTypeBasic {
+		req.SetBasicAuth(a.username, a.password)
+	} else {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", a.password))
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to execute request")
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "failed to read response body")
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("unexpected status code %d from %s %s: %s", resp.StatusCode, method, path, string(respBody))
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return errors.Wrap(err, "failed to unmarshal response")
+		}
+	}
+
+	return nil
+}
+
+// githubClient also works for gitea, which implements the same pull request api
+type githubClient struct {
+	api   *providerAPI
+	owner string
+	repo  string
+}
+
+type githubPullRequest struct {
+	Number         int64  `json:"number"`
+	HTMLURL        string `json:"html_url"`
+	State          string `json:"state"`
+	Merged         bool   `json:"merged"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+}
+
+func (c *githubClient) pullsPath() string {
+	return fmt.Sprintf("/repos/%s/%s/pulls", url.PathEscape(c.owner), url.PathEscape(c.repo))
+}
+
+func (c *githubClient) createPullRequest(ctx context.Context, opts createPullRequestOptions) (*gitopstypes.PullRequest, error) {
+	body := map[string]string{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.HeadBranch,
+		"base":  opts.BaseBranch,
+	}
+	created := githubPullRequest{}
+	if err := c.api.do(ctx, http.MethodPost, c.pullsPath(), body, &created); err != nil {
+		return nil, err
+	}
+	return &gitopstypes.PullRequest{
+		Number: created.Number,
+		URL:    created.HTMLURL,
+		State:  gitopstypes.PullRequestStateOpen,
+		Branch: opts.HeadBranch,
+	}, nil
+}
+
+func (c *githubClient) closePullRequest(ctx context.Context, pr gitopstypes.PullRequest) error {
+	body := map[string]string{"state": "closed"}
+	return c.api.do(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", c.pullsPath(), pr.Number), body, nil)
+}
+
+func (c *githubClient) getPullRequestState(ctx context.Context, number int64) (gitopstypes.PullRequestState, string, error) {
+	current := githubPullRequest{}
+	if err := c.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.pullsPath(), number), nil, &current); err != nil {
+		return "", "", err
+	}
+	switch {
+	case current.Merged:
+		// this is the merge, squash or last rebased commit, depending on how the pull request was merged
+		return gitopstypes.PullRequestStateMerged, current.MergeCommitSHA, nil
+	case current.State == "closed":
+		return gitopstypes.PullRequestStateClosed, "", nil
+	}
+	return gitopstypes.PullRequestStateOpen, "", nil
+}
+
+type gitlabClient struct {
+	api *providerAPI
+	// project is the full path of the project, including any subgroups
+	project string
+}
+
+type gitlabMergeRequest struct {
+	IID             int64  `json:"iid"`
+	WebURL          string `json:"web_url"`
+	State           string `json:"state"`
+	MergeCommitSHA  string `json:"merge_commit_sha"`
+	SquashCommitSHA string `json:"squash_commit_sha"`
+}
+
+func (c *gitlabClient) mergeRequestsPath() string {
+	return fmt.Sprintf("/projects/%s/merge_requests", url.PathEscape(c.project))
+}
+
+func (c *gitlabClient) createPullRequest(ctx context.Context, opts createPullRequestOptions) (*gitopstypes.PullRequest, error) {
+	body := map[string]string{
+		"title":         opts.Title,
+		"description":   opts.Body,
+		"source_branch": opts.HeadBranch,
+		"target_branch": opts.BaseBranch,
+	}
+	created := gitlabMergeRequest{}
+	if err := c.api.do(ctx, http.MethodPost, c.mergeRequestsPath(), body, &created); err != nil {
+		return nil, err
+	}
+	return &gitopstypes.PullRequest{
+		Number: created.IID,
+		URL:    created.WebURL,
+		State:  gitopstypes.PullRequestStateOpen,
+		Branch: opts.HeadBranch,
+	}, nil
+}
+
+func (c *gitlabClient) closePullRequest(ctx context.Context, pr gitopstypes.PullRequest) error {
+	body := map[string]string{"state_event": "close"}
+	return c.api.do(ctx, http.MethodPut, fmt.Sprintf("%s/%d", c.mergeRequestsPath(), pr.Number), body, nil)
+}
+
+func (c *gitlabClient) getPullRequestState(ctx context.Context, number int64) (gitopstypes.PullRequestState, string, error) {
+	current := gitlabMergeRequest{}
+	if err := c.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.mergeRequestsPath(), number), nil, &current); err != nil {
+		return "", "", err
+	}
+	switch current.State {
+	case "merged":
+		// fast-forward merges have neither, and deploy the commit that kots pushed
+		if current.MergeCommitSHA != "" {
+			return gitopstypes.PullRequestStateMerged, current.MergeCommitSHA, nil
+		}
+		return gitopstypes.PullRequestStateMerged, current.SquashCommitSHA, nil
+	case "closed":
+		return gitopstypes.PullRequestStateClosed, "", nil
+	}
+	// "opened" or "locked", which is a merge request that is being merged
+	return gitopstypes.PullRequestStateOpen, "", nil
+}
+
+type bitbucketServerClient struct {
+	api        *providerAPI
+	projectKey string
+	repoSlug   string
+}
+
+type bitbucketServerPullRequest struct {
+	ID         int64  `json:"id"`
+	Version    int64  `json:"version"`
+	State      string `json:"state"`
+	Properties struct {
+		MergeCommit struct {
+			ID string `json:"id"`
+		} `json:"mergeCommit"`
+	} `json:"properties"`
+	Links struct {
+		Self []struct {
+			Href string `json:"href"`
+		} `json:"self"`
+	} `json:"links"`
+}
+
+type bitbucketServerRef struct {
+	ID string `json:"id"`
+}
+
+func (c *bitbucketServerClient) pullRequestsPath() string {
+	return fmt.Sprintf("/projects/%s/repos/%s/pull-requests", url.PathEscape(c.projectKey), url.PathEscape(c.repoSlug))
+}
+
+func (c *bitbucketServerClient) createPullRequest(ctx context.Context, opts createPullRequestOptions) (*gitopstypes.PullRequest, error) {
+	body := map[string]interface{}{
+		"title":       opts.Title,
+		"description": opts.Body,
+		"fromRef":     bitbucketServerRef{ID: fmt.Sprintf("refs/heads/%s", opts.HeadBranch)},
+		"toRef":       bitbucketServerRef{ID: fmt.Sprintf("refs/heads/%s", opts.BaseBranch)},
+	}
+	created := bitbucketServerPullRequest{}
+	if err := c.api.do(ctx, http.MethodPost, c.pullRequestsPath(), body, &created); err != nil {
+		return nil, err
+	}
+
+	pr := &gitopstypes.PullRequest{
+		Number: created.ID,
+		State:  gitopstypes.PullRequestStateOpen,
+		Branch: opts.HeadBranch,
+	}
+	if len(created.Links.Self) > 0 {
+		pr.URL = created.Links.Self[0].Href
+	}
+	return pr, nil
+}
+
+func (c *bitbucketServerClient) closePullRequest(ctx context.Context, pr gitopstypes.PullRequest) error {
+	// declining requires the current version of the pull request
+	current := bitbucketServerPullRequest{}
+	if err := c.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.pullRequestsPath(), pr.Number), nil, &current); err != nil {
+		return errors.Wrap(err, "failed to get pull request")
+	}
+	if current.State != "OPEN" {
+		return nil
+	}
+	return c.api.do(ctx, http.MethodPost, fmt.Sprintf("%s/%d/decline?version=%d", c.pullRequestsPath(), pr.Number, current.Version), nil, nil)
+}
+
+func (c *bitbucketServerClient) getPullRequestState(ctx context.Context, number int64) (gitopstypes.PullRequestState, string, error) {
+	current := bitbucketServerPullRequest{}
+	if err := c.api.do(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.pullRequestsPath(), number), nil, &current); err != nil {
+		return "", "", err
+	}
+	switch current.State {
+	case "MERGED":
+		return gitopstypes.PullRequestStateMerged, current.Properties.MergeCommit.ID, nil
+	case "DECLINED":
+		return gitopstypes.PullRequestStateClosed, "", nil
+	}
+	return gitopstypes.PullRequestStateOpen, "", nil
+}
@@ -0,0 +1,379 @@
+package gitops
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/replicatedhq/kots/pkg/apparchive"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+// stubProviderAPI records requests and replies with the response registered for "METHOD path"
+type stubProviderAPI struct {
+	mu        sync.Mutex
+	requests  []stubRequest
+	responses map[string]string
+}
+
+func newStubProviderAPI(t *testing.T, responses map[string]string) (*stubProviderAPI, *httptest.Server) {
+	stub := &stubProviderAPI{responses: responses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		stub.mu.Lock()
+		stub.requests = append(stub.requests, stubRequest{
+			Method: r.Method,
+			Path:   r.URL.EscapedPath(),
+			Query:  r.URL.RawQuery,
+			Auth:   r.Header.Get("Authorization"),
+			Body:   body,
+		})
+		stub.mu.Unlock()
+
+		response, ok := responses[fmt.Sprintf("%s %s", r.Method, r.URL.EscapedPath())]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return stub, server
+}
+
+func TestPullRequestClients(t *testing.T) {
+	tests := []struct {
+		name           string
+		provider       string
+		repoURI        string
+		authType       string
+		responses      map[string]string
+		wantPR         gitopstypes.PullRequest
+		wantCreate     string
+		wantClose      []string
+		wantBodyKey    string
+		wantAuthPrefix string
+	}{
+		{
+			name:     "github",
+			provider: "github",
+			repoURI:  "https://github.com/org/repo",
+			authType: AuthTypeToken,
+			responses: map[string]string{
+				"POST /repos/org/repo/pulls":    `{"number": 12, "html_url": "https://github.com/org/repo/pull/12", "state": "open"}`,
+				"PATCH /repos/org/repo/pulls/3": `{"number": 3, "state": "closed"}`,
+			},
+			wantPR:         gitopstypes.PullRequest{Number: 12, URL: "https://github.com/org/repo/pull/12", State: gitopstypes.PullRequestStateOpen, Branch: "kots/app/version-2"},
+			wantCreate:     "POST /repos/org/repo/pulls",
+			wantClose:      []string{"PATCH /repos/org/repo/pulls/3"},
+			wantBodyKey:    "body",
+			wantAuthPrefix: "Bearer my-token",
+		},
+		{
+			name:     "gitea",
+			provider: ProviderGitea,
+			repoURI:  "https://gitea.example.com/org/repo.git",
+			authType: AuthTypeBasic,
+			responses: map[string]string{
+				"POST /repos/org/repo/pulls":    `{"number": 12, "html_url": "https://gitea.example.com/org/repo/pulls/12", "state": "open"}`,
+				"PATCH /repos/org/repo/pulls/3": `{"number": 3, "state": "closed"}`,
+			},
+			wantPR:         gitopstypes.PullRequest{Number: 12, URL: "https://gitea.example.com/org/repo/pulls/12", State: gitopstypes.PullRequestStateOpen, Branch: "kots/app/version-2"},
+			wantCreate:     "POST /repos/org/repo/pulls",
+			wantClose:      []string{"PATCH /repos/org/repo/pulls/3"},
+			wantBodyKey:    "body",
+			wantAuthPrefix: "Basic ",
+		},
+		{
+			name:     "gitlab with subgroup",
+			provider: "gitlab_enterprise",
+			repoURI:  "https://gitlab.example.com/group/subgroup/repo",
+			authType: AuthTypeToken,
+			responses: map[string]string{
+				"POST /projects/group%2Fsubgroup%2Frepo/merge_requests":  `{"iid": 12, "web_url": "https://gitlab.example.com/group/subgroup/repo/-/merge_requests/12", "state": "opened"}`,
+				"PUT /projects/group%2Fsubgroup%2Frepo/merge_requests/3": `{"iid": 3, "state": "closed"}`,
+			},
+			wantPR:         gitopstypes.PullRequest{Number: 12, URL: "https://gitlab.example.com/group/subgroup/repo/-/merge_requests/12", State: gitopstypes.PullRequestStateOpen, Branch: "kots/app/version-2"},
+			wantCreate:     "POST /projects/group%2Fsubgroup%2Frepo/merge_requests",
+			wantClose:      []string{"PUT /projects/group%2Fsubgroup%2Frepo/merge_requests/3"},
+			wantBodyKey:    "description",
+			wantAuthPrefix: "Bearer my-token",
+		},
+		{
+			name:     "bitbucket server",
+			provider: "bitbucket_server",
+			repoURI:  "https://bitbucket.example.com/projects/ORG/repos/repo",
+			authType: AuthTypeToken,
+			responses: map[string]string{
+				"POST /projects/ORG/repos/repo/pull-requests":           `{"id": 12, "version": 0, "state": "OPEN", "links": {"self": [{"href": "https://bitbucket.example.com/projects/ORG/repos/repo/pull-requests/12"}]}}`,
+				"GET /projects/ORG/repos/repo/pull-requests/3":          `{"id": 3, "version": 4, "state": "OPEN"}`,
+				"POST /projects/ORG/repos/repo/pull-requests/3/decline": `{"id": 3, "version": 5, "state": "DECLINED"}`,
+			},
+			wantPR:         gitopstypes.PullRequest{Number: 12, URL: "https://bitbucket.example.com/projects/ORG/repos/repo/pull-requests/12", State: gitopstypes.PullRequestStateOpen, Branch: "kots/app/version-2"},
+			wantCreate:     "POST /projects/ORG/repos/repo/pull-requests",
+			wantClose:      []string{"GET /projects/ORG/repos/repo/pull-requests/3", "POST /projects/ORG/repos/repo/pull-requests/3/decline"},
+			wantBodyKey:    "description",
+			wantAuthPrefix: "Bearer my-token",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			stub, server := newStubProviderAPI(t, tt.responses)
+
+			client, err := getPullRequestClient(&GitOpsConfig{
+				Provider: tt.provider,
+				RepoURI:  tt.repoURI,
+				AuthType: tt.authType,
+				Username: "user",
+				Password: "my-token",
+				APIURL:   server.URL,
+			})
+			req.NoError(err)
+
+			pr, err := client.createPullRequest(context.Background(), createPullRequestOptions{
+				Title:      "title",
+				Body:       "release notes",
+				HeadBranch: "kots/app/version-2",
+				BaseBranch: "main",
+			})
+			req.NoError(err)
+			assert.Equal(t, tt.wantPR, *pr)
+
+			err = client.closePullRequest(context.Background(), gitopstypes.PullRequest{Number: 3, State: gitopstypes.PullRequestStateOpen})
+			req.NoError(err)
+
+			req.Len(stub.requests, 1+len(tt.wantClose))
+			assert.Equal(t, tt.wantCreate, fmt.Sprintf("%s %s", stub.requests[0].Method, stub.requests[0].Path))
+			assert.Equal(t, "release notes", stub.requests[0].Body[tt.wantBodyKey])
+			assert.True(t, strings.HasPrefix(stub.requests[0].Auth, tt.wantAuthPrefix), "auth header %q", stub.requests[0].Auth)
+			for i, want := range tt.wantClose {
+				r := stub.requests[i+1]
+				assert.Equal(t, want, fmt.Sprintf("%s %s", r.Method, r.Path))
+			}
+		})
+	}
+}
+
+func TestRefreshPullRequests(t *testing.T) {
+	tests := []struct {
+		name      string
+		provider  string
+		repoURI   string
+		responses map[string]string
+	}{
+		{
+			name:     "github",
+			provider: "github",
+			repoURI:  "https://github.com/org/repo",
+			responses: map[string]string{
+				"GET /repos/org/repo/pulls/1": `{"number": 1, "state": "open"}`,
+				"GET /repos/org/repo/pulls/2": `{"number": 2, "state": "closed", "merged": true, "merge_commit_sha": "abc"}`,
+				"GET /repos/org/repo/pulls/3": `{"number": 3, "state": "closed", "merged": false}`,
+			},
+		},
+		{
+			name:     "gitlab",
+			provider: "gitlab",
+			repoURI:  "https://gitlab.com/org/repo",
+			responses: map[string]string{
+				"GET /projects/org%2Frepo/merge_requests/1": `{"iid": 1, "state": "opened"}`,
+				"GET /projects/org%2Frepo/merge_requests/2": `{"iid": 2, "state": "merged", "merge_commit_sha": "abc"}`,
+				"GET /projects/org%2Frepo/merge_requests/3": `{"iid": 3, "state": "closed"}`,
+			},
+		},
+		{
+			name:     "bitbucket server",
+			provider: "bitbucket_server",
+			repoURI:  "https://bitbucket.example.com/projects/ORG/repos/repo",
+			responses: map[string]string{
+				"GET /projects/ORG/repos/repo/pull-requests/1": `{"id": 1, "state": "OPEN"}`,
+				"GET /projects/ORG/repos/repo/pull-requests/2": `{"id": 2, "state": "MERGED", "properties": {"mergeCommit": {"id": "abc"}}}`,
+				"GET /projects/ORG/repos/repo/pull-requests/3": `{"id": 3, "state": "DECLINED"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, server := newStubProviderAPI(t, tt.responses)
+
+			changed, err := RefreshPullRequests(&GitOpsConfig{
+				Provider: tt.provider,
+				RepoURI:  tt.repoURI,
+				AuthType: AuthTypeToken,
+				Password: "my-token",
+				APIURL:   server.URL,
+			}, []gitopstypes.PullRequest{
+				{Number: 1, State: gitopstypes.PullRequestStateOpen},
+				{Number: 2, State: gitopstypes.PullRequestStateOpen},
+				{Number: 3, State: gitopstypes.PullRequestStateOpen},
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []gitopstypes.PullRequest{
+				{Number: 2, State: gitopstypes.PullRequestStateMerged, MergeCommitHash: "abc"},
+				{Number: 3, State: gitopstypes.PullRequestStateClosed},
+			}, changed)
+		})
+	}
+}
+
+func TestGetPullRequestClient_errors(t *testing.T) {
+	_, err := getPullRequestClient(&GitOpsConfig{Provider: "github", RepoURI: "https://github.com/org/repo", AuthType: AuthTypeSSH})
+	assert.Error(t, err)
+
+	_, err = getPullRequestClient(&GitOpsConfig{Provider: ProviderGeneric, RepoURI: "https://git.example.com/org/repo.git", AuthType: AuthTypeToken})
+	assert.Error(t, err)
+}
+
+func TestGitOpsConfig_pullRequestAPIURL(t *testing.T) {
+	tests := []struct {
+		provider string
+		repoURI  string
+		want     string
+	}{
+		{provider: "github", repoURI: "https://github.com/org/repo", want: "https://api.github.com"},
+		{provider: "github_enterprise", repoURI: "https://github.example.com/org/repo", want: "https://github.example.com/api/v3"},
+		{provider: "gitlab", repoURI: "https://gitlab.com/org/repo", want: "https://gitlab.com/api/v4"},
+		{provider: "bitbucket_server", repoURI: "https://bitbucket.example.com/projects/ORG/repos/repo", want: "https://bitbucket.example.com/rest/api/1.0"},
+		{provider: ProviderGitea, repoURI: "http://gitea.example.com:3000/org/repo", want: "http://gitea.example.com:3000/api/v1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			g := &GitOpsConfig{Provider: tt.provider, RepoURI: tt.repoURI}
+			got, err := g.pullRequestAPIURL()
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPullRequestBody(t *testing.T) {
+	body := pullRequestBody(CommitOptions{
+		ReleaseNotes: "Fixes a bug",
+		DiffSummary:  &apparchive.Diff{FilesChanged: 2, LinesAdded: 10, LinesRemoved: 3},
+	})
+	assert.Contains(t, body, "Fixes a bug")
+	assert.Contains(t, body, "2 files changed, 10 lines added, 3 lines removed")
+
+	body = pullRequestBody(CommitOptions{})
+	assert.Contains(t, body, "No release notes")
+	assert.Contains(t, body, "No diff")
+}
+
+func Test_commitRenderedApp_pullRequest(t *testing.T) {
+	req := require.New(t)
+
+	// the remote is under an owner/repo path so that the api path can be derived
+	remoteDir := newTestRemoteRepo(t, filepath.Join(t.TempDir(), "org", "repo"))
+
+	stub, server := newStubProviderAPI(t, map[string]string{
+		"POST /repos/org/repo/pulls":    `{"number": 2, "html_url": "https://github.example.com/org/repo/pull/2", "state": "open"}`,
+		"PATCH /repos/org/repo/pulls/1": `{"number": 1, "state": "closed"}`,
+	})
+
+	gitOpsConfig := &GitOpsConfig{
+		Provider: "github_enterprise",
+		RepoURI:  "file://" + remoteDir,
+		Branch:   "main",
+		Path:     "apps",
+		Action:   ActionPullRequest,
+		AuthType: AuthTypeToken,
+		Password: "my-token",
+		APIURL:   server.URL,
+	}
+
+	result, err := commitRenderedApp(gitOpsConfig, "my-app", "My App", 5, &renderedApp{manifests: []byte("kind: ConfigMap\n")}, CommitOptions{
+		VersionLabel: "1.2.0",
+		ReleaseNotes: "New things",
+		SupersededPullRequests: []gitopstypes.PullRequest{
+			{Number: 1, URL: "https://github.example.com/org/repo/pull/1", State: gitopstypes.PullRequestStateOpen},
+			// closing this one fails, so it's not reported as closed
+			{Number: 4, URL: "https://github.example.com/org/repo/pull/4", State: gitopstypes.PullRequestStateOpen},
+		},
+	})
+	req.NoError(err)
+	req.NotNil(result.PullRequest)
+	assert.Equal(t, int64(2), result.PullRequest.Number)
+	assert.Equal(t, "kots/my-app/version-5", result.PullRequest.Branch)
+	assert.Equal(t, []gitopstypes.PullRequest{
+		{Number: 1, URL: "https://github.example.com/org/repo/pull/1", State: gitopstypes.PullRequestStateClosed},
+	}, result.ClosedPullRequests)
+
+	req.Len(stub.requests, 3)
+	assert.Equal(t, "POST", stub.requests[0].Method)
+	assert.Equal(t, "kots/my-app/version-5", stub.requests[0].Body["head"])
+	assert.Equal(t, "main", stub.requests[0].Body["base"])
+	assert.Equal(t, "Update My App to 1.2.0 (sequence 5)", stub.requests[0].Body["title"])
+	assert.Contains(t, stub.requests[0].Body["body"], "New things")
+	assert.Equal(t, "PATCH", stub.requests[1].Method)
+	assert.Equal(t, "closed", stub.requests[1].Body["state"])
+
+	// the version branch has the commit, the base branch is untouched
+	remote, err := git.PlainOpen(remoteDir)
+	req.NoError(err)
+	versionRef, err := remote.Reference(plumbing.NewBranchReferenceName("kots/my-app/version-5"), true)
+	req.NoError(err)
+	versionCommit, err := remote.CommitObject(versionRef.Hash())
+	req.NoError(err)
+	file, err := versionCommit.File("apps/my-app.yaml")
+	req.NoError(err)
+	contents, err := file.Contents()
+	req.NoError(err)
+	assert.Equal(t, "kind: ConfigMap\n", contents)
+
+	mainRef, err := remote.Reference(plumbing.NewBranchReferenceName("main"), true)
+	req.NoError(err)
+	mainCommit, err := remote.CommitObject(mainRef.Hash())
+	req.NoError(err)
+	_, err = mainCommit.File("apps/my-app.yaml")
+	assert.Equal(t, object.ErrFileNotFound, err)
+}
+
+// newTestRemoteRepo creates a bare repo in dir with a single commit on main
+func newTestRemoteRepo(t *testing.T, dir string) string {
+	req := require.New(t)
+
+	seedDir := t.TempDir()
+	seed, err := git.PlainInit(seedDir, false)
+	req.NoError(err)
+	req.NoError(seed.Storer.SetReference(plumbing.NewSymbolicReference(plumbing.HEAD, plumbing.NewBranchReferenceName("main"))))
+	req.NoError(os.WriteFile(filepath.Join(seedDir, "README.md"), []byte("test"), 0644))
+	seedTree, err := seed.Worktree()
+	req.NoError(err)
+	_, err = seedTree.Add("README.md")
+	req.NoError(err)
+	_, err = seedTree.Commit("initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+	})
+	req.NoError(err)
+
+	_, err = git.PlainClone(dir, true, &git.CloneOptions{URL: seedDir})
+	req.NoError(err)
+
+	return dir
+}
@@ -0,0 +1,229 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// SigningKeyTypeSSH signs commits with an ed25519 ssh key
+	SigningKeyTypeSSH = "ssh"
+	// SigningKeyTypeOpenPGP signs commits with an ed25519 openpgp key
+	SigningKeyTypeOpenPGP = "openpgp"
+
+	DefaultAuthorName  = "KOTS Admin Console"
+	DefaultAuthorEmail = "help@replicated.com"
+
+	// sshSignatureNamespace is the namespace git uses when signing and verifying commits with ssh keys
+	sshSignatureNamespace = "git"
+)
+
+type SigningKeyPair struct {
+	// PublicKey is registered with the git provider so that it can verify the signatures.
+	// it is an authorized key for ssh keys and an armored public key for openpgp keys.
+	PublicKey  string
+	PrivateKey string
+}
+
+func IsSupportedSigningKeyType(keyType string) bool {
+	switch keyType {
+	case "", SigningKeyTypeSSH, SigningKeyTypeOpenPGP:
+		return true
+	}
+	return false
+}
+
+// GenerateSigningKey generates a commit signing key. the author is only used for the openpgp key's identity,
+// which some providers require to match the commit author's email.
+func GenerateSigningKey(keyType string, authorName string, authorEmail string) (*SigningKeyPair, error) {
+	switch keyType {
+	case SigningKeyTypeSSH:
+		keyPair, err := generatePrivateKey_ed25519()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to generate ssh key")
+		}
+		return &SigningKeyPair{
+			PublicKey:  keyPair.PublicKeySSH,
+			PrivateKey: keyPair.PrivateKeyPEM,
+		}, nil
+
+	case SigningKeyTypeOpenPGP:
+		return generateOpenPGPKey(authorName, authorEmail)
+	}
+
+	return nil, errors.Errorf("unsupported signing key type %q", keyType)
+}
+
+func generateOpenPGPKey(name string, email string) (*SigningKeyPair, error) {
+	entity, err := openpgp.NewEntity(name, "KOTS GitOps signing key", email, &packet.Config{
+		Algorithm: packet.PubKeyAlgoEdDSA,
+		Curve:     packet.Curve25519,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create entity")
+	}
+
+	privateKey := bytes.Buffer{}
+	w, err := armor.Encode(&privateKey, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create private key armor")
+	}
+	if err := entity.SerializePrivate(w, nil); err != nil {
+		return nil, errors.Wrap(err, "failed to serialize private key")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close private key armor")
+	}
+
+	publicKey := bytes.Buffer{}
+	w, err = armor.Encode(&publicKey, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create public key armor")
+	}
+	if err := entity.Serialize(w); err != nil {
+		return nil, errors.Wrap(err, "failed to serialize public key")
+	}
+	if err := w.Close(); err != nil {
+		return nil, errors.Wrap(err, "failed to close public key armor")
+	}
+
+	return &SigningKeyPair{
+		PublicKey:  publicKey.String() + "\n",
+		PrivateKey: privateKey.String() + "\n",
+	}, nil
+}
+
+// commitAuthor returns the configured commit author, falling back to the admin console
+func commitAuthor(gitOpsConfig *GitOpsConfig) (string, string) {
+	name, email := gitOpsConfig.AuthorName, gitOpsConfig.AuthorEmail
+	if name == "" {
+		name = DefaultAuthorName
+	}
+	if email == "" {
+		email = DefaultAuthorEmail
+	}
+	return name, email
+}
+
+// setCommitSigner configures the commit options to sign with the configured signing key, if any
+func setCommitSigner(gitOpsConfig *GitOpsConfig, commitOptions *git.CommitOptions) error {
+	switch gitOpsConfig.SigningKeyType {
+	case "":
+		return nil
+
+	case SigningKeyTypeSSH:
+		signer, err := ssh.ParsePrivateKey([]byte(gitOpsConfig.SigningPrivateKey))
+		if err != nil {
+			return errors.Wrap(err, "failed to parse ssh signing key")
+		}
+		commitOptions.Signer = &sshCommitSigner{signer: signer}
+		return nil
+
+	case SigningKeyTypeOpenPGP:
+		entities, err := openpgp.ReadArmoredKeyRing(strings.NewReader(gitOpsConfig.SigningPrivateKey))
+		if err != nil {
+			return errors.Wrap(err, "failed to read openpgp signing key")
+		}
+		if len(entities) == 0 {
+			return errors.New("no openpgp signing key found")
+		}
+		commitOptions.SignKey = entities[0]
+		return nil
+	}
+
+	return errors.Errorf("unsupported signing key type %q", gitOpsConfig.SigningKeyType)
+}
+
+// newCommitOptions returns the options for commits authored by the configured identity and signed with the configured key
+func newCommitOptions(gitOpsConfig *GitOpsConfig) (*git.CommitOptions, error) {
+	name, email := commitAuthor(gitOpsConfig)
+	commitOptions := &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  name,
+			Email: email,
+			When:  time.Now(),
+		},
+	}
+	if err := setCommitSigner(gitOpsConfig, commitOptions); err != nil {
+		return nil, errors.Wrap(err, "failed to set commit signer")
+	}
+	return commitOptions, nil
+}
+
+// sshCommitSigner creates the armored SSHSIG signatures that git uses for commits signed with ssh keys.
+// see https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig
+type sshCommitSigner struct {
+	signer ssh.Signer
+}
+
+func (s *sshCommitSigner) Sign(message io.Reader) ([]byte, error) {
+	h := sha512.New()
+	if _, err := io.Copy(h, message); err != nil {
+		return nil, errors.Wrap(err, "failed to hash message")
+	}
+
+	signedData := sshSignedData(sshSignatureNamespace, h.Sum(nil))
+	signature, err := s.signer.Sign(rand.Reader, signedData)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to sign")
+	}
+
+	blob := append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature string
+	}{
+		Version:   1,
+		PublicKey: string(s.signer.PublicKey().Marshal()),
+		Namespace: sshSignatureNamespace,
+		HashAlg:   "sha512",
+		Signature: string(ssh.Marshal(signature)),
+	})...)
+
+	return armorSSHSignature(blob), nil
+}
+
+// sshSignedData is the data that is signed for an SSHSIG signature
+func sshSignedData(namespace string, hash []byte) []byte {
+	return append([]byte("SSHSIG"), ssh.Marshal(struct {
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Hash      string
+	}{
+		Namespace: namespace,
+		HashAlg:   "sha512",
+		Hash:      string(hash),
+	})...)
+}
+
+func armorSSHSignature(blob []byte) []byte {
+	encoded := base64.StdEncoding.EncodeToString(blob)
+
+	var b strings.Builder
+	b.WriteString("-----BEGIN SSH SIGNATURE-----\n")
+	for len(encoded) > 70 {
+		b.WriteString(encoded[:70])
+		b.WriteString("\n")
+		encoded = encoded[70:]
+	}
+	b.WriteString(encoded)
+	b.WriteString("\n-----END SSH SIGNATURE-----\n")
+	return []byte(b.String())
+}
@@ -0,0 +1,165 @@
+package gitops
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func Test_createGitOps_signingKey(t *testing.T) {
+	req := require.New(t)
+
+	clientset := fake.NewSimpleClientset()
+	opts := CreateGitOpsOptions{
+		Provider:       "github",
+		RepoURI:        "https://github.com/org/repo",
+		AuthorName:     "Release Bot",
+		AuthorEmail:    "release-bot@example.com",
+		SigningKeyType: SigningKeyTypeSSH,
+	}
+	req.NoError(createGitOps(clientset, opts))
+	req.NoError(updateDownstreamGitOps(clientset, "app", "cluster", opts.RepoURI, "main", "", FormatSingle, ActionCommit, "", "", false))
+
+	config, err := GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, "Release Bot", config.AuthorName)
+	assert.Equal(t, "release-bot@example.com", config.AuthorEmail)
+	assert.Equal(t, SigningKeyTypeSSH, config.SigningKeyType)
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(config.SigningPublicKey))
+	req.NoError(err)
+	signer, err := ssh.ParsePrivateKey([]byte(config.SigningPrivateKey))
+	req.NoError(err)
+	assert.Equal(t, publicKey.Marshal(), signer.PublicKey().Marshal())
+
+	// the key is kept when the config is saved again
+	req.NoError(createGitOps(clientset, opts))
+	updated, err := GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, config.SigningPublicKey, updated.SigningPublicKey)
+
+	// and replaced when asked to
+	opts.RegenerateSigningKey = true
+	req.NoError(createGitOps(clientset, opts))
+	updated, err = GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.NotEqual(t, config.SigningPublicKey, updated.SigningPublicKey)
+
+	// changing the type generates a new key
+	opts.RegenerateSigningKey = false
+	opts.SigningKeyType = SigningKeyTypeOpenPGP
+	req.NoError(createGitOps(clientset, opts))
+	updated, err = GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Equal(t, SigningKeyTypeOpenPGP, updated.SigningKeyType)
+	assert.True(t, strings.HasPrefix(updated.SigningPublicKey, "-----BEGIN PGP PUBLIC KEY BLOCK-----"))
+
+	global, err := getGitOps(clientset)
+	req.NoError(err)
+	assert.Equal(t, updated.SigningPublicKey, global.SigningPublicKey)
+
+	// disabling signing removes the key
+	opts.SigningKeyType = ""
+	req.NoError(createGitOps(clientset, opts))
+	updated, err = GetDownstreamGitOpsConfig(clientset, "app", "cluster")
+	req.NoError(err)
+	assert.Empty(t, updated.SigningPublicKey)
+	assert.Empty(t, updated.SigningPrivateKey)
+}
+
+func Test_commitRenderedApp_signed(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyType string
+	}{
+		{name: "ssh", keyType: SigningKeyTypeSSH},
+		{name: "openpgp", keyType: SigningKeyTypeOpenPGP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+
+			keyPair, err := GenerateSigningKey(tt.keyType, "Release Bot", "release-bot@example.com")
+			req.NoError(err)
+
+			remoteDir := newTestRemoteRepo(t, t.TempDir())
+			gitOpsConfig := &GitOpsConfig{
+				Provider:          ProviderGeneric,
+				RepoURI:           "file://" + remoteDir,
+				Branch:            "main",
+				AuthorName:        "Release Bot",
+				AuthorEmail:       "release-bot@example.com",
+				SigningKeyType:    tt.keyType,
+				SigningPublicKey:  keyPair.PublicKey,
+				SigningPrivateKey: keyPair.PrivateKey,
+			}
+			_, err = commitRenderedApp(gitOpsConfig, "my-app", "My App", 1, &renderedApp{manifests: []byte("kind: ConfigMap\n")}, CommitOptions{})
+			req.NoError(err)
+
+			repo, err := git.PlainOpen(remoteDir)
+			req.NoError(err)
+			commit, err := repo.CommitObject(remoteHead(t, remoteDir))
+			req.NoError(err)
+			assert.Equal(t, "Release Bot", commit.Author.Name)
+			assert.Equal(t, "release-bot@example.com", commit.Author.Email)
+			req.NotEmpty(commit.PGPSignature)
+
+			if tt.keyType == SigningKeyTypeOpenPGP {
+				_, err := commit.Verify(keyPair.PublicKey)
+				req.NoError(err)
+				return
+			}
+
+			// verify the ssh signature the same way git does, over the commit without its signature
+			obj := repo.Storer.NewEncodedObject()
+			req.NoError(commit.EncodeWithoutSignature(obj))
+			reader, err := obj.Reader()
+			req.NoError(err)
+			message := &bytes.Buffer{}
+			_, err = message.ReadFrom(reader)
+			req.NoError(err)
+
+			verifySSHSignature(t, keyPair.PublicKey, message.Bytes(), commit.PGPSignature)
+		})
+	}
+}
+
+func verifySSHSignature(t *testing.T, authorizedKey string, message []byte, armored string) {
+	req := require.New(t)
+
+	req.True(strings.HasPrefix(armored, "-----BEGIN SSH SIGNATURE-----\n"))
+	body := strings.TrimSuffix(strings.TrimPrefix(armored, "-----BEGIN SSH SIGNATURE-----\n"), "-----END SSH SIGNATURE-----\n")
+	blob, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\n", ""))
+	req.NoError(err)
+	req.Equal("SSHSIG", string(blob[:6]))
+
+	parsed := struct {
+		Version   uint32
+		PublicKey string
+		Namespace string
+		Reserved  string
+		HashAlg   string
+		Signature string
+	}{}
+	req.NoError(ssh.Unmarshal(blob[6:], &parsed))
+	assert.Equal(t, uint32(1), parsed.Version)
+	assert.Equal(t, "git", parsed.Namespace)
+	assert.Equal(t, "sha512", parsed.HashAlg)
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	req.NoError(err)
+	assert.Equal(t, string(publicKey.Marshal()), parsed.PublicKey)
+
+	signature := &ssh.Signature{}
+	req.NoError(ssh.Unmarshal([]byte(parsed.Signature), signature))
+
+	hash := sha512.Sum512(message)
+	req.NoError(publicKey.Verify(sshSignedData("git", hash[:]), signature))
+}
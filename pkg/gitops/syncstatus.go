@@ -0,0 +1,426 @@
+package gitops
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	appstatetypes "github.com/replicatedhq/kots/pkg/appstate/types"
+	"github.com/replicatedhq/kots/pkg/logger"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+type SyncState string
+
+const (
+	// SyncStatePending means the latest revision of the repo has not been applied yet
+	SyncStatePending SyncState = "pending"
+	// SyncStateDeployed means the revision was applied and everything is ready
+	SyncStateDeployed SyncState = "deployed"
+	// SyncStateFailed means the revision failed to apply or is not healthy
+	SyncStateFailed SyncState = "failed"
+
+	// fluxKustomizationNameLabel and fluxKustomizationNamespaceLabel are set by flux on the objects that a Kustomization applies
+	fluxKustomizationNameLabel      = "kustomize.toolkit.fluxcd.io/name"
+	fluxKustomizationNamespaceLabel = "kustomize.toolkit.fluxcd.io/namespace"
+	// argoCDInstanceLabel is set by argo cd on the objects that an Application applies, including child Applications
+	argoCDInstanceLabel = "app.kubernetes.io/instance"
+)
+
+var (
+	fluxGitRepositoryGVR = schema.GroupVersionResource{Group: "source.toolkit.fluxcd.io", Version: "v1", Resource: "gitrepositories"}
+	fluxKustomizationGVR = schema.GroupVersionResource{Group: "kustomize.toolkit.fluxcd.io", Version: "v1", Resource: "kustomizations"}
+	fluxHelmReleaseGVR   = schema.GroupVersionResource{Group: "helm.toolkit.fluxcd.io", Version: "v2", Resource: "helmreleases"}
+	argoCDApplicationGVR = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "applications"}
+
+	commitHashRegexp = regexp.MustCompile(`[0-9a-f]{40}$`)
+
+	// forbiddenResources are the resources that kotsadm is not allowed to list. they are only logged the first time,
+	// since the sync status is read in a loop.
+	forbiddenResources sync.Map
+)
+
+// SyncStatus is the status of the flux or argo cd objects that deploy the gitops repo
+type SyncStatus struct {
+	// Revision is the commit that was applied. it is empty when the objects have applied different commits.
+	Revision       string
+	State          SyncState
+	Message        string
+	ResourceStates appstatetypes.ResourceStates
+}
+
+// objectStatus is the status of a single flux or argo cd object
+type objectStatus struct {
+	resourceState appstatetypes.ResourceState
+	// revision is only set for objects that deploy the repo directly
+	revision string
+	state    SyncState
+	message  string
+}
+
+// GetSyncStatus reads the status of the flux Kustomizations and HelmReleases or argo cd Applications that deploy
+// the repo and path. it returns nil if no object deploys them, including when flux and argo cd are not installed.
+func GetSyncStatus(ctx context.Context, dynamicClient dynamic.Interface, gitOpsConfig *GitOpsConfig) (*SyncStatus, error) {
+	statuses, err := getFluxStatuses(ctx, dynamicClient, gitOpsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get flux status")
+	}
+
+	argoCDStatuses, err := getArgoCDStatuses(ctx, dynamicClient, gitOpsConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get argo cd status")
+	}
+	statuses = append(statuses, argoCDStatuses...)
+
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	return aggregateSyncStatus(statuses), nil
+}
+
+func aggregateSyncStatus(statuses []objectStatus) *SyncStatus {
+	syncStatus := &SyncStatus{
+		State:          SyncStateDeployed,
+		ResourceStates: appstatetypes.ResourceStates{},
+	}
+
+	revisions := map[string]bool{}
+	messages := []string{}
+	for _, s := range statuses {
+		syncStatus.ResourceStates = append(syncStatus.ResourceStates, s.resourceState)
+		if s.revision != "" {
+			revisions[s.revision] = true
+		}
+
+		switch s.state {
+		case SyncStateFailed:
+			syncStatus.State = SyncStateFailed
+			messages = append(messages, fmt.Sprintf("%s %s/%s: %s", s.resourceState.Kind, s.resourceState.Namespace, s.resourceState.Name, s.message))
+		case SyncStatePending:
+			if syncStatus.State != SyncStateFailed {
+				syncStatus.State = SyncStatePending
+			}
+		}
+	}
+
+	if len(revisions) == 1 {
+		for revision := range revisions {
+			syncStatus.Revision = revision
+		}
+	} else if syncStatus.State == SyncStateDeployed {
+		// objects that are ready at different commits are still rolling out
+		syncStatus.State = SyncStatePending
+	}
+	syncStatus.Message = strings.Join(messages, "\n")
+
+	return syncStatus
+}
+
+func getFluxStatuses(ctx context.Context, dynamicClient dynamic.Interface, gitOpsConfig *GitOpsConfig) ([]objectStatus, error) {
+	gitRepositories, err := listObjects(ctx, dynamicClient, fluxGitRepositoryGVR, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list git repositories")
+	}
+
+	// key is namespace/name
+	sources := map[string]bool{}
+	for _, gitRepository := range gitRepositories {
+		url, _, _ := unstructured.NestedString(gitRepository.Object, "spec", "url")
+		branch, _, _ := unstructured.NestedString(gitRepository.Object, "spec", "ref", "branch")
+		if !isSameRepo(url, gitOpsConfig.RepoURI) || (branch != "" && branch != gitOpsConfig.Branch) {
+			continue
+		}
+		sources[fmt.Sprintf("%s/%s", gitRepository.GetNamespace(), gitRepository.GetName())] = true
+	}
+	if len(sources) == 0 {
+		return nil, nil
+	}
+
+	kustomizations, err := listObjects(ctx, dynamicClient, fluxKustomizationGVR, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list kustomizations")
+	}
+
+	statuses := []objectStatus{}
+	for _, kustomization := range kustomizations {
+		sourceKind, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "kind")
+		sourceName, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "name")
+		sourceNamespace, _, _ := unstructured.NestedString(kustomization.Object, "spec", "sourceRef", "namespace")
+		if sourceNamespace == "" {
+			sourceNamespace = kustomization.GetNamespace()
+		}
+		if sourceKind != "GitRepository" || !sources[fmt.Sprintf("%s/%s", sourceNamespace, sourceName)] {
+			continue
+		}
+		kustomizationPath, _, _ := unstructured.NestedString(kustomization.Object, "spec", "path")
+		if !isOverlappingPath(kustomizationPath, gitOpsConfig.Path) {
+			continue
+		}
+
+		status := fluxObjectStatus(kustomization)
+		status.revision = fluxRevisionCommit(kustomization, "lastAppliedRevision")
+		if status.state == SyncStateFailed {
+			// the failure is for the last attempted revision, which can be newer than the one that was applied
+			if attempted := fluxRevisionCommit(kustomization, "lastAttemptedRevision"); attempted != "" {
+				status.revision = attempted
+			}
+		}
+		statuses = append(statuses, status)
+
+		helmReleases, err := listObjects(ctx, dynamicClient, fluxHelmReleaseGVR, labels.SelectorFromSet(labels.Set{
+			fluxKustomizationNameLabel:      kustomization.GetName(),
+			fluxKustomizationNamespaceLabel: kustomization.GetNamespace(),
+		}).String())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list helm releases")
+		}
+		for _, helmRelease := range helmReleases {
+			statuses = append(statuses, fluxObjectStatus(helmRelease))
+		}
+	}
+
+	return statuses, nil
+}
+
+// fluxObjectStatus maps the conditions of a flux object to a status
+func fluxObjectStatus(obj unstructured.Unstructured) objectStatus {
+	status := objectStatus{
+		resourceState: appstatetypes.ResourceState{
+			Kind:      obj.GetKind(),
+			Name:      obj.GetName(),
+			Namespace: obj.GetNamespace(),
+			State:     appstatetypes.StateUpdating,
+		},
+		state: SyncStatePending,
+	}
+
+	if suspended, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend"); suspended {
+		status.resourceState.State = appstatetypes.StateUnavailable
+		return status
+	}
+
+	ready := findCondition(obj, "Ready")
+	if ready == nil {
+		return status
+	}
+	readyStatus, message := ready["status"], ready["message"]
+	if stalled := findCondition(obj, "Stalled"); stalled != nil && stalled["status"] == "True" {
+		// stalled objects are not retried until they change
+		readyStatus, message = "False", stalled["message"]
+	} else if reconciling := findCondition(obj, "Reconciling"); reconciling != nil && reconciling["status"] == "True" {
+		return status
+	}
+
+	switch readyStatus {
+	case "True":
+		if obj.GetGeneration() == observedGeneration(obj) {
+			status.resourceState.State = appstatetypes.StateReady
+			status.state = SyncStateDeployed
+		}
+	case "False":
+		status.resourceState.State = appstatetypes.StateDegraded
+		status.state = SyncStateFailed
+		status.message, _ = message.(string)
+	}
+
+	return status
+}
+
+// fluxRevisionCommit returns the commit of a flux revision, which is either main@sha1:<hash> or main/<hash> in older versions
+func fluxRevisionCommit(obj unstructured.Unstructured, field string) string {
+	revision, _, _ := unstructured.NestedString(obj.Object, "status", field)
+	return commitHashRegexp.FindString(revision)
+}
+
+func getArgoCDStatuses(ctx context.Context, dynamicClient dynamic.Interface, gitOpsConfig *GitOpsConfig) ([]objectStatus, error) {
+	applications, err := listObjects(ctx, dynamicClient, argoCDApplicationGVR, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list applications")
+	}
+
+	// key is the application name, which is the value of the instance label on the objects it applies
+	parents := map[string]bool{}
+	statuses := []objectStatus{}
+	for _, application := range applications {
+		if !argoCDApplicationDeploysRepo(application, gitOpsConfig) {
+			continue
+		}
+		parents[application.GetName()] = true
+
+		status := argoCDObjectStatus(application)
+		status.revision, _, _ = unstructured.NestedString(application.Object, "status", "sync", "revision")
+		if phase, _, _ := unstructured.NestedString(application.Object, "status", "operationState", "phase"); phase == "Failed" || phase == "Error" {
+			// the failure is for the operation's revision, which can be newer than the one that is synced
+			if revision, _, _ := unstructured.NestedString(application.Object, "status", "operationState", "syncResult", "revision"); revision != "" {
+				status.revision = revision
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	// applications created by the argocd format for helm charts are applied by the application that deploys the repo
+	for _, application := range applications {
+		if parents[application.GetName()] || !parents[application.GetLabels()[argoCDInstanceLabel]] {
+			continue
+		}
+		statuses = append(statuses, argoCDObjectStatus(application))
+	}
+
+	return statuses, nil
+}
+
+func argoCDApplicationDeploysRepo(application unstructured.Unstructured, gitOpsConfig *GitOpsConfig) bool {
+	sources := []interface{}{}
+	if source, ok, _ := unstructured.NestedMap(application.Object, "spec", "source"); ok {
+		sources = append(sources, source)
+	}
+	if multipleSources, ok, _ := unstructured.NestedSlice(application.Object, "spec", "sources"); ok {
+		sources = append(sources, multipleSources...)
+	}
+
+	for _, s := range sources {
+		source, ok := s.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		repoURL, _ := source["repoURL"].(string)
+		sourcePath, _ := source["path"].(string)
+		targetRevision, _ := source["targetRevision"].(string)
+		if !isSameRepo(repoURL, gitOpsConfig.RepoURI) || !isOverlappingPath(sourcePath, gitOpsConfig.Path) {
+			continue
+		}
+		if targetRevision != "" && targetRevision != "HEAD" && targetRevision != gitOpsConfig.Branch {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// argoCDObjectStatus maps the sync and health status of an argo cd application to a status
+func argoCDObjectStatus(application unstructured.Unstructured) objectStatus {
+	status := objectStatus{
+		resourceState: appstatetypes.ResourceState{
+			Kind:      application.GetKind(),
+			Name:      application.GetName(),
+			Namespace: application.GetNamespace(),
+			State:     appstatetypes.StateUpdating,
+		},
+		state: SyncStatePending,
+	}
+
+	syncStatus, _, _ := unstructured.NestedString(application.Object, "status", "sync", "status")
+	health, _, _ := unstructured.NestedString(application.Object, "status", "health", "status")
+	phase, _, _ := unstructured.NestedString(application.Object, "status", "operationState", "phase")
+
+	switch {
+	case phase == "Failed" || phase == "Error":
+		status.resourceState.State = appstatetypes.StateDegraded
+		status.state = SyncStateFailed
+		status.message, _, _ = unstructured.NestedString(application.Object, "status", "operationState", "message")
+	case syncStatus != "Synced":
+	case health == "Healthy":
+		status.resourceState.State = appstatetypes.StateReady
+		status.state = SyncStateDeployed
+	case health == "Degraded":
+		status.resourceState.State = appstatetypes.StateDegraded
+		status.state = SyncStateFailed
+		status.message, _, _ = unstructured.NestedString(application.Object, "status", "health", "message")
+	case health == "Missing":
+		status.resourceState.State = appstatetypes.StateMissing
+	case health == "Suspended":
+		status.resourceState.State = appstatetypes.StateUnavailable
+	}
+
+	return status
+}
+
+// listObjects lists the objects in all namespaces. no objects are returned if the resource is not installed.
+func listObjects(ctx context.Context, dynamicClient dynamic.Interface, gvr schema.GroupVersionResource, labelSelector string) ([]unstructured.Unstructured, error) {
+	list, err := dynamicClient.Resource(gvr).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: labelSelector,
+	})
+	if kuberneteserrors.IsNotFound(err) {
+		return nil, nil
+	} else if kuberneteserrors.IsForbidden(err) {
+		// without rbac to read the objects, their status is unknown
+		if _, logged := forbiddenResources.LoadOrStore(gvr.String(), true); !logged {
+			logger.Infof("Not allowed to list %s, their sync status will not be reported: %v", gvr.GroupResource(), err)
+		}
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func findCondition(obj unstructured.Unstructured, conditionType string) map[string]interface{} {
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == conditionType {
+			return condition
+		}
+	}
+	return nil
+}
+
+func observedGeneration(obj unstructured.Unstructured) int64 {
+	generation, ok, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	if !ok {
+		// not all versions report the observed generation
+		return obj.GetGeneration()
+	}
+	return generation
+}
+
+// isSameRepo compares repo urls regardless of the protocol, credentials, port and .git suffix,
+// so that the ssh url used by flux matches the https url of the gitops config
+func isSameRepo(a string, b string) bool {
+	return a != "" && normalizeRepoURL(a) == normalizeRepoURL(b)
+}
+
+func normalizeRepoURL(repoURL string) string {
+	u := strings.ToLower(strings.TrimSpace(repoURL))
+	if i := strings.Index(u, "://"); i != -1 {
+		u = u[i+len("://"):]
+	} else {
+		// scp like syntax: git@github.com:org/repo
+		u = strings.Replace(u, ":", "/", 1)
+	}
+	if i := strings.Index(u, "@"); i != -1 && i < strings.Index(u+"/", "/") {
+		u = u[i+1:]
+	}
+
+	host, repoPath, _ := strings.Cut(u, "/")
+	if h, port, ok := strings.Cut(host, ":"); ok && port != "" {
+		host = h
+	}
+
+	repoPath = strings.TrimSuffix(strings.Trim(repoPath, "/"), ".git")
+	return host + "/" + repoPath
+}
+
+// isOverlappingPath is true when one of the paths contains the other, so that objects that deploy the whole repo
+// or only the app's part of the gitops path are both found
+func isOverlappingPath(a string, b string) bool {
+	a, b = cleanRepoPath(a), cleanRepoPath(b)
+	if a == "" || b == "" || a == b {
+		return true
+	}
+	return strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+func cleanRepoPath(p string) string {
+	p = path.Clean("/" + p)
+	return strings.TrimPrefix(p, "/")
+}
@@ -0,0 +1,19 @@
+package types
+
+type PullRequestState string
+
+const (
+	PullRequestStateOpen   PullRequestState = "open"
+	PullRequestStateClosed PullRequestState = "closed"
+	PullRequestStateMerged PullRequestState = "merged"
+)
+
+// PullRequest is a pull request (or merge request) opened for an app version when the gitops action is "pull_request"
+type PullRequest struct {
+	Number int64            `json:"number"`
+	URL    string           `json:"url"`
+	State  PullRequestState `json:"state"`
+	Branch string           `json:"branch"`
+	// MergeCommitHash is the commit that the pull request was merged as, which is what gets deployed
+	MergeCommitHash string `json:"mergeCommitHash,omitempty"`
+}
@@ -25,6 +25,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/store"
 	storetypes "github.com/replicatedhq/kots/pkg/store/types"
 	"github.com/replicatedhq/kots/pkg/tasks"
+	"github.com/replicatedhq/kots/pkg/template"
 	"github.com/replicatedhq/kots/pkg/update"
 	"github.com/replicatedhq/kots/pkg/util"
 	"github.com/replicatedhq/kots/pkg/version"
@@ -269,6 +270,7 @@ func responseAppFromApp(ctx context.Context, a *apptypes.App, kcb kubeclient.Kub
 			Branch:      downstreamGitOps.Branch,
 			Format:      downstreamGitOps.Format,
 			Action:      downstreamGitOps.Action,
+			SyncStatus:  downstreamGitOps.SyncStatus,
 			DeployKey:   downstreamGitOps.PublicKey,
 			IsConnected: downstreamGitOps.IsConnected,
 		}
@@ -491,6 +493,14 @@ func (h *Handler) RemoveApp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the app is removed already, so the generated values that are left behind are only logged
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get clientset to delete generated values"))
+	} else if err := template.DeleteGeneratedValues(clientset, app.Slug); err != nil {
+		logger.Error(errors.Wrapf(err, "failed to delete generated values of app %s", app.Slug))
+	}
+
 	JSON(w, http.StatusOK, response)
 }
 
@@ -56,6 +56,7 @@ type UpdateAppConfigResponse struct {
 	Error            string                                   `json:"error,omitempty"`
 	RequiredItems    []string                                 `json:"requiredItems,omitempty"`
 	ValidationErrors []configtypes.ConfigGroupValidationError `json:"validationErrors,omitempty"`
+	TemplateError    *TemplateErrorDetails                    `json:"templateError,omitempty"`
 }
 
 type LiveAppConfigResponse struct {
@@ -63,6 +64,7 @@ type LiveAppConfigResponse struct {
 	Error            string                                   `json:"error,omitempty"`
 	ConfigGroups     []kotsv1beta1.ConfigGroup                `json:"configGroups"`
 	ValidationErrors []configtypes.ConfigGroupValidationError `json:"validationErrors,omitempty"`
+	TemplateError    *TemplateErrorDetails                    `json:"templateError,omitempty"`
 }
 
 type CurrentAppConfigResponse struct {
@@ -71,6 +73,7 @@ type CurrentAppConfigResponse struct {
 	DownstreamVersion *downstreamtypes.DownstreamVersion       `json:"downstreamVersion"`
 	ConfigGroups      []kotsv1beta1.ConfigGroup                `json:"configGroups"`
 	ValidationErrors  []configtypes.ConfigGroupValidationError `json:"validationErrors,omitempty"`
+	TemplateError     *TemplateErrorDetails                    `json:"templateError,omitempty"`
 }
 
 type DownloadFileFromConfigResponse struct {
@@ -172,39 +175,47 @@ func (h *Handler) UpdateAppConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validationErrors, err := configvalidation.ValidateConfigSpec(kotsv1beta1.ConfigSpec{Groups: updateAppConfigRequest.ConfigGroups})
+	archiveDir, err := ioutil.TempDir("", "kotsadm")
 	if err != nil {
-		updateAppConfigResponse.Error = "failed to validate config spec."
+		updateAppConfigResponse.Error = "failed to create temp dir"
 		logger.Error(errors.Wrap(err, updateAppConfigResponse.Error))
 		JSON(w, http.StatusInternalServerError, updateAppConfigResponse)
 		return
 	}
+	defer os.RemoveAll(archiveDir)
 
-	if len(validationErrors) > 0 {
-		updateAppConfigResponse.Error = "invalid config values"
-		updateAppConfigResponse.ValidationErrors = validationErrors
-		logger.Errorf("%v, validation errors: %+v", updateAppConfigResponse.Error, validationErrors)
-		JSON(w, http.StatusBadRequest, updateAppConfigResponse)
+	err = store.GetStore().GetAppVersionArchive(foundApp.ID, updateAppConfigRequest.Sequence, archiveDir)
+	if err != nil {
+		updateAppConfigResponse.Error = "failed to get app version archive"
+		logger.Error(errors.Wrap(err, updateAppConfigResponse.Error))
+		JSON(w, http.StatusInternalServerError, updateAppConfigResponse)
 		return
 	}
 
-	archiveDir, err := ioutil.TempDir("", "kotsadm")
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(archiveDir, "upstream"))
 	if err != nil {
-		updateAppConfigResponse.Error = "failed to create temp dir"
+		updateAppConfigResponse.Error = "failed to find config validations"
 		logger.Error(errors.Wrap(err, updateAppConfigResponse.Error))
 		JSON(w, http.StatusInternalServerError, updateAppConfigResponse)
 		return
 	}
-	defer os.RemoveAll(archiveDir)
 
-	err = store.GetStore().GetAppVersionArchive(foundApp.ID, updateAppConfigRequest.Sequence, archiveDir)
+	validationErrors, err := configvalidation.ValidateConfigSpec(kotsv1beta1.ConfigSpec{Groups: updateAppConfigRequest.ConfigGroups}, configSpecExtras.Validations)
 	if err != nil {
-		updateAppConfigResponse.Error = "failed to get app version archive"
+		updateAppConfigResponse.Error = "failed to validate config spec."
 		logger.Error(errors.Wrap(err, updateAppConfigResponse.Error))
 		JSON(w, http.StatusInternalServerError, updateAppConfigResponse)
 		return
 	}
 
+	if len(validationErrors) > 0 {
+		updateAppConfigResponse.Error = "invalid config values"
+		updateAppConfigResponse.ValidationErrors = validationErrors
+		logger.Errorf("%v, validation errors: %+v", updateAppConfigResponse.Error, validationErrors)
+		JSON(w, http.StatusBadRequest, updateAppConfigResponse)
+		return
+	}
+
 	createNewVersion, err := shouldCreateNewAppVersion(archiveDir, foundApp.ID, updateAppConfigRequest.Sequence)
 	if err != nil {
 		updateAppConfigResponse.Error = "failed to check if version should be created"
@@ -216,7 +227,7 @@ func (h *Handler) UpdateAppConfig(w http.ResponseWriter, r *http.Request) {
 	isPrimaryVersion := true
 	skipPrefligths := false
 	deploy := false
-	resp, err := updateAppConfig(foundApp, updateAppConfigRequest.Sequence, updateAppConfigRequest.ConfigGroups, createNewVersion, isPrimaryVersion, skipPrefligths, deploy)
+	resp, err := updateAppConfig(foundApp, updateAppConfigRequest.Sequence, updateAppConfigRequest.ConfigGroups, createNewVersion, isPrimaryVersion, skipPrefligths, deploy, kotsadmconfig.ConfigChangeSource, "")
 	if err != nil {
 		logger.Error(err)
 		JSON(w, http.StatusInternalServerError, resp)
@@ -303,6 +314,14 @@ func (h *Handler) LiveAppConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(archiveDir, "upstream"))
+	if err != nil {
+		liveAppConfigResponse.Error = "failed to find config validations"
+		logger.Error(errors.Wrap(err, liveAppConfigResponse.Error))
+		JSON(w, http.StatusInternalServerError, liveAppConfigResponse)
+		return
+	}
+
 	registryInfo, err := store.GetStore().GetRegistryDetailsForApp(foundApp.ID)
 	if err != nil {
 		liveAppConfigResponse.Error = "failed to get app registry info"
@@ -331,6 +350,7 @@ func (h *Handler) LiveAppConfig(w http.ResponseWriter, r *http.Request) {
 	renderedConfig, err := kotsconfig.TemplateConfigObjects(nonRenderedConfig, configValues, appLicense, &kotsKinds.KotsApplication, localRegistry, &versionInfo, &appInfo, kotsKinds.IdentityConfig, foundApp.GetNamespace(), false)
 	if err != nil {
 		liveAppConfigResponse.Error = "failed to render templates"
+		liveAppConfigResponse.TemplateError = getTemplateErrorDetails(err)
 		logger.Error(errors.Wrap(err, liveAppConfigResponse.Error))
 		JSON(w, http.StatusInternalServerError, liveAppConfigResponse)
 		return
@@ -338,7 +358,7 @@ func (h *Handler) LiveAppConfig(w http.ResponseWriter, r *http.Request) {
 
 	liveAppConfigResponse.ConfigGroups = []kotsv1beta1.ConfigGroup{}
 	if renderedConfig != nil {
-		validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec)
+		validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec, configSpecExtras.Validations)
 		if err != nil {
 			liveAppConfigResponse.Error = "failed to validate config spec"
 			logger.Error(errors.Wrap(err, liveAppConfigResponse.Error))
@@ -534,6 +554,7 @@ func (h *Handler) CurrentAppConfig(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		logger.Error(err)
 		currentAppConfigResponse.Error = "failed to render templates"
+		currentAppConfigResponse.TemplateError = getTemplateErrorDetails(err)
 		JSON(w, http.StatusInternalServerError, currentAppConfigResponse)
 		return
 	}
@@ -626,8 +647,9 @@ func getAppConfigValueForFile(downloadApp *apptypes.App, sequence int64, filenam
 }
 
 // if isPrimaryVersion is false, missing a required config field will not cause a failure, and instead will create
-// the app version with status needs_config
-func updateAppConfig(updateApp *apptypes.App, sequence int64, configGroups []kotsv1beta1.ConfigGroup, createNewVersion bool, isPrimaryVersion bool, skipPreflights bool, deploy bool) (UpdateAppConfigResponse, error) {
+// the app version with status needs_config. source is the source of the new version when createNewVersion is true,
+// and changedBy is recorded for it if set.
+func updateAppConfig(updateApp *apptypes.App, sequence int64, configGroups []kotsv1beta1.ConfigGroup, createNewVersion bool, isPrimaryVersion bool, skipPreflights bool, deploy bool, source string, changedBy string) (UpdateAppConfigResponse, error) {
 	updateAppConfigResponse := UpdateAppConfigResponse{
 		Success: false,
 	}
@@ -665,6 +687,7 @@ func updateAppConfig(updateApp *apptypes.App, sequence int64, configGroups []kot
 	if kotsKinds.ConfigValues != nil {
 		values := kotsKinds.ConfigValues.Spec.Values
 		kotsKinds.ConfigValues.Spec.Values = kotsadmconfig.UpdateAppConfigValues(values, configGroups)
+		kotsKinds.ConfigValues.Spec.Values = kotsadmconfig.RemoveReferencedConfigValues(kotsKinds.ConfigValues.Spec.Values, kotsKinds.ConfigValueReferences)
 
 		configValuesSpec, err := kotsKinds.Marshal("kots.io", "v1beta1", "ConfigValues")
 		if err != nil {
@@ -746,23 +769,31 @@ func updateAppConfig(updateApp *apptypes.App, sequence int64, configGroups []kot
 		} else {
 			updateAppConfigResponse.Error = "failed to render archive directory"
 		}
+		updateAppConfigResponse.TemplateError = getTemplateErrorDetails(err)
 		return updateAppConfigResponse, err
 	}
 
 	if createNewVersion {
-		newSequence, err := store.GetStore().CreateAppVersion(updateApp.ID, &sequence, archiveDir, "Config Change", false, false, skipPreflights)
+		newSequence, err := store.GetStore().CreateAppVersion(updateApp.ID, &sequence, archiveDir, source, false, false, skipPreflights)
 		if err != nil {
 			updateAppConfigResponse.Error = "failed to create an app version"
 			return updateAppConfigResponse, err
 		}
 		sequence = newSequence
+
+		if changedBy != "" {
+			if err := store.GetStore().SetDownstreamVersionConfigChangedBy(updateApp.ID, sequence, changedBy); err != nil {
+				updateAppConfigResponse.Error = "failed to set config changed by"
+				return updateAppConfigResponse, err
+			}
+		}
 	} else {
-		source, err := store.GetStore().GetDownstreamVersionSource(updateApp.ID, sequence)
+		existingSource, err := store.GetStore().GetDownstreamVersionSource(updateApp.ID, sequence)
 		if err != nil {
 			updateAppConfigResponse.Error = "failed to get existing downstream version source"
 			return updateAppConfigResponse, err
 		}
-		if err := store.GetStore().UpdateAppVersion(updateApp.ID, sequence, nil, archiveDir, source, skipPreflights); err != nil {
+		if err := store.GetStore().UpdateAppVersion(updateApp.ID, sequence, nil, archiveDir, existingSource, skipPreflights); err != nil {
 			updateAppConfigResponse.Error = "failed to update app version"
 			return updateAppConfigResponse, err
 		}
@@ -809,12 +840,15 @@ type SetAppConfigValuesRequest struct {
 	SkipPreflights bool   `json:"skipPreflights"`
 	Current        bool   `json:"current"`
 	Sequence       int64  `json:"sequence"`
+	// RestoreSequence is the sequence of a version to restore the config values of, instead of the config values in the request
+	RestoreSequence *int64 `json:"restoreSequence,omitempty"`
 }
 
 type SetAppConfigValuesResponse struct {
 	Success          bool                                     `json:"success"`
 	Error            string                                   `json:"error,omitempty"`
 	ValidationErrors []configtypes.ConfigGroupValidationError `json:"validationErrors,omitempty"`
+	TemplateError    *TemplateErrorDetails                    `json:"templateError,omitempty"`
 }
 
 func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
@@ -830,22 +864,33 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	decode := scheme.Codecs.UniversalDeserializer().Decode
-	decoded, gvk, err := decode(setAppConfigValuesRequest.ConfigValues, nil, nil)
-	if err != nil {
-		setAppConfigValuesResponse.Error = "failed to decode config values"
-		logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
+	isRestore := setAppConfigValuesRequest.RestoreSequence != nil
+	if isRestore && setAppConfigValuesRequest.Merge {
+		setAppConfigValuesResponse.Error = "cannot merge config values when restoring config values"
+		logger.Error(errors.New(setAppConfigValuesResponse.Error))
 		JSON(w, http.StatusBadRequest, setAppConfigValuesResponse)
 		return
 	}
 
-	if gvk.String() != "kots.io/v1beta1, Kind=ConfigValues" {
-		setAppConfigValuesResponse.Error = fmt.Sprintf("%q is not a valid ConfigValues GVK", gvk.String())
-		logger.Errorf(setAppConfigValuesResponse.Error)
-		JSON(w, http.StatusInternalServerError, setAppConfigValuesResponse)
-		return
+	var newConfigValues *kotsv1beta1.ConfigValues
+	if !isRestore {
+		decode := scheme.Codecs.UniversalDeserializer().Decode
+		decoded, gvk, err := decode(setAppConfigValuesRequest.ConfigValues, nil, nil)
+		if err != nil {
+			setAppConfigValuesResponse.Error = "failed to decode config values"
+			logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
+			JSON(w, http.StatusBadRequest, setAppConfigValuesResponse)
+			return
+		}
+
+		if gvk.String() != "kots.io/v1beta1, Kind=ConfigValues" {
+			setAppConfigValuesResponse.Error = fmt.Sprintf("%q is not a valid ConfigValues GVK", gvk.String())
+			logger.Errorf(setAppConfigValuesResponse.Error)
+			JSON(w, http.StatusInternalServerError, setAppConfigValuesResponse)
+			return
+		}
+		newConfigValues = decoded.(*kotsv1beta1.ConfigValues)
 	}
-	newConfigValues := decoded.(*kotsv1beta1.ConfigValues)
 
 	foundApp, err := store.GetStore().GetAppFromSlug(mux.Vars(r)["appSlug"])
 	if err != nil {
@@ -855,6 +900,23 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isRestore {
+		restoreValues, err := getVersionConfigValues(foundApp.ID, *setAppConfigValuesRequest.RestoreSequence)
+		if err != nil {
+			setAppConfigValuesResponse.Error = "failed to get config values to restore"
+			logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
+			JSON(w, http.StatusInternalServerError, setAppConfigValuesResponse)
+			return
+		}
+		if restoreValues.configValues == nil {
+			setAppConfigValuesResponse.Error = fmt.Sprintf("version %d does not have config values", *setAppConfigValuesRequest.RestoreSequence)
+			logger.Error(errors.New(setAppConfigValuesResponse.Error))
+			JSON(w, http.StatusBadRequest, setAppConfigValuesResponse)
+			return
+		}
+		newConfigValues = restoreValues.configValues
+	}
+
 	baseSequence := setAppConfigValuesRequest.Sequence
 
 	if setAppConfigValuesRequest.Current {
@@ -938,6 +1000,14 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(archiveDir, "upstream"))
+	if err != nil {
+		setAppConfigValuesResponse.Error = "failed to find config validations"
+		logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
+		JSON(w, http.StatusInternalServerError, setAppConfigValuesResponse)
+		return
+	}
+
 	if nonRenderedConfig == nil {
 		setAppConfigValuesResponse.Error = fmt.Sprintf("app %s does not have a config", foundApp.Slug)
 		logger.Errorf(setAppConfigValuesResponse.Error)
@@ -1005,6 +1075,7 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 	renderedConfig, err := kotsconfig.TemplateConfigObjects(newConfig, configValueMap, kotsKinds.License, &kotsKinds.KotsApplication, registryInfo, &versionInfo, &appInfo, kotsKinds.IdentityConfig, util.PodNamespace, true)
 	if err != nil {
 		setAppConfigValuesResponse.Error = "failed to render templates"
+		setAppConfigValuesResponse.TemplateError = getTemplateErrorDetails(err)
 		logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
 		JSON(w, http.StatusInternalServerError, setAppConfigValuesResponse)
 		return
@@ -1017,7 +1088,7 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec)
+	validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec, configSpecExtras.Validations)
 	if err != nil {
 		setAppConfigValuesResponse.Error = "failed to validate config spec"
 		logger.Error(errors.Wrap(err, setAppConfigValuesResponse.Error))
@@ -1033,9 +1104,15 @@ func (h *Handler) SetAppConfigValues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// the source stays the same as for config changes in the admin console, what made the change is recorded separately
+	changedBy := kotsadmconfig.ChangedByCLI
+	if isRestore {
+		changedBy = kotsadmconfig.ChangedByRestore
+	}
+
 	createNewVersion := true
 	isPrimaryVersion := true // see comment in updateAppConfig
-	resp, err := updateAppConfig(foundApp, baseSequence, renderedConfig.Spec.Groups, createNewVersion, isPrimaryVersion, setAppConfigValuesRequest.SkipPreflights, setAppConfigValuesRequest.Deploy)
+	resp, err := updateAppConfig(foundApp, baseSequence, renderedConfig.Spec.Groups, createNewVersion, isPrimaryVersion, setAppConfigValuesRequest.SkipPreflights, setAppConfigValuesRequest.Deploy, kotsadmconfig.ConfigChangeSource, changedBy)
 	if err != nil {
 		logger.Error(errors.Wrap(err, "failed to create new version"))
 		JSON(w, http.StatusInternalServerError, resp)
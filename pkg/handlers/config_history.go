@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/kotsadmconfig"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/store"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+)
+
+type GetAppConfigHistoryResponse struct {
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+	// Changes are the config changes from the from sequence to the to sequence
+	Changes []configtypes.ConfigItemChange `json:"changes"`
+	// Versions are the versions after the from sequence that changed the config, in order
+	Versions []configtypes.ConfigVersionChanges `json:"versions"`
+}
+
+// versionConfigValues are the decrypted config values of a version and the names of its password items
+type versionConfigValues struct {
+	configValues  *kotsv1beta1.ConfigValues
+	passwordItems map[string]bool
+}
+
+// GetAppConfigHistory compares the config values of the versions between the from and to sequences. the
+// to sequence defaults to the latest version. versions in between that were garbage collected are skipped.
+func (h *Handler) GetAppConfigHistory(w http.ResponseWriter, r *http.Request) {
+	response := GetAppConfigHistoryResponse{
+		Success: false,
+	}
+
+	foundApp, err := store.GetStore().GetAppFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		response.Error = "failed to get app from app slug"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	from, err := strconv.ParseInt(r.URL.Query().Get("from"), 10, 64)
+	if err != nil {
+		response.Error = "failed to parse from sequence"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	to, err := store.GetStore().GetLatestAppSequence(foundApp.ID, true)
+	if err != nil {
+		response.Error = "failed to get latest app sequence"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	if toParam := r.URL.Query().Get("to"); toParam != "" {
+		to, err = strconv.ParseInt(toParam, 10, 64)
+		if err != nil {
+			response.Error = "failed to parse to sequence"
+			logger.Error(errors.Wrap(err, response.Error))
+			JSON(w, http.StatusBadRequest, response)
+			return
+		}
+	}
+
+	if from < 0 || from > to {
+		response.Error = "from sequence must not be after to sequence"
+		logger.Error(errors.New(response.Error))
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	if _, err := store.GetStore().GetAppVersion(foundApp.ID, from); err != nil {
+		if store.GetStore().IsNotFound(err) {
+			response.Error = fmt.Sprintf("version %d does not exist", from)
+			logger.Error(errors.New(response.Error))
+			JSON(w, http.StatusNotFound, response)
+			return
+		}
+		response.Error = "failed to get app version"
+		logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, from))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	fromValues, err := getVersionConfigValues(foundApp.ID, from)
+	if err != nil {
+		response.Error = "failed to get config values"
+		logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, from))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	versions := []configtypes.ConfigVersionChanges{}
+	previousValues := fromValues
+	passwordItems := fromValues.passwordItems
+	for sequence := from + 1; sequence <= to; sequence++ {
+		appVersion, err := store.GetStore().GetAppVersion(foundApp.ID, sequence)
+		if err != nil {
+			if store.GetStore().IsNotFound(err) {
+				// the version was garbage collected, its changes are part of the next version that still exists
+				continue
+			}
+			response.Error = "failed to get app version"
+			logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, sequence))
+			JSON(w, http.StatusInternalServerError, response)
+			return
+		}
+
+		values, err := getVersionConfigValues(foundApp.ID, sequence)
+		if err != nil {
+			response.Error = "failed to get config values"
+			logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, sequence))
+			JSON(w, http.StatusInternalServerError, response)
+			return
+		}
+		for name := range values.passwordItems {
+			passwordItems[name] = true
+		}
+
+		changes := kotsadmconfig.DiffConfigValues(previousValues.configValues, values.configValues, passwordItems)
+		previousValues = values
+		if len(changes) == 0 {
+			continue
+		}
+
+		source, err := store.GetStore().GetDownstreamVersionSource(foundApp.ID, sequence)
+		if err != nil {
+			response.Error = "failed to get version source"
+			logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, sequence))
+			JSON(w, http.StatusInternalServerError, response)
+			return
+		}
+
+		changedBy, err := store.GetStore().GetDownstreamVersionConfigChangedBy(foundApp.ID, sequence)
+		if err != nil {
+			response.Error = "failed to get config changed by"
+			logger.Error(errors.Wrapf(err, "%s for sequence %d", response.Error, sequence))
+			JSON(w, http.StatusInternalServerError, response)
+			return
+		}
+
+		versions = append(versions, configtypes.ConfigVersionChanges{
+			Sequence:  sequence,
+			Source:    source,
+			ChangedBy: kotsadmconfig.GetConfigChangedBy(source, changedBy),
+			CreatedOn: &appVersion.CreatedOn,
+			Changes:   changes,
+		})
+	}
+
+	response.Success = true
+	response.From = from
+	response.To = to
+	response.Changes = kotsadmconfig.DiffConfigValues(fromValues.configValues, previousValues.configValues, passwordItems)
+	response.Versions = versions
+
+	JSON(w, http.StatusOK, response)
+}
+
+func getVersionConfigValues(appID string, sequence int64) (*versionConfigValues, error) {
+	archiveDir, err := os.MkdirTemp("", "kotsadm")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(archiveDir)
+
+	if err := store.GetStore().GetAppVersionArchive(appID, sequence, archiveDir); err != nil {
+		return nil, errors.Wrap(err, "failed to get app version archive")
+	}
+
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load kots kinds from path")
+	}
+
+	// encrypting the same value twice has different results, so the values are compared decrypted
+	if err := kotsKinds.DecryptConfigValues(); err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt config values")
+	}
+
+	passwordItems := map[string]bool{}
+	if kotsKinds.Config != nil {
+		for _, group := range kotsKinds.Config.Spec.Groups {
+			for _, item := range group.Items {
+				if item.Type == configtypes.PasswordItemType {
+					passwordItems[item.Name] = true
+				}
+			}
+		}
+	}
+
+	return &versionConfigValues{
+		configValues:  kotsKinds.ConfigValues,
+		passwordItems: passwordItems,
+	}, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gorilla/mux"
+	versiontypes "github.com/replicatedhq/kots/pkg/api/version/types"
+	apptypes "github.com/replicatedhq/kots/pkg/app/types"
+	"github.com/replicatedhq/kots/pkg/kotsadmconfig"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/store"
+	"github.com/replicatedhq/kots/pkg/store/kotsstore"
+	mock_store "github.com/replicatedhq/kots/pkg/store/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_GetAppConfigHistory_garbageCollected(t *testing.T) {
+	req := require.New(t)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStore := mock_store.NewMockStore(ctrl)
+
+	store.SetStore(mockStore)
+	defer store.SetStore(nil)
+
+	hostnames := map[int64]string{
+		0: "a.example.com",
+		2: "c.example.com",
+	}
+
+	mockStore.EXPECT().GetAppFromSlug("my-app").Return(&apptypes.App{ID: "app-id", Slug: "my-app"}, nil)
+	mockStore.EXPECT().GetLatestAppSequence("app-id", true).Return(int64(2), nil)
+	mockStore.EXPECT().GetAppVersion("app-id", int64(0)).Return(&versiontypes.AppVersion{}, nil)
+	// sequence 1 was garbage collected
+	mockStore.EXPECT().GetAppVersion("app-id", int64(1)).Return(nil, kotsstore.ErrNotFound)
+	mockStore.EXPECT().IsNotFound(kotsstore.ErrNotFound).Return(true)
+	mockStore.EXPECT().GetAppVersion("app-id", int64(2)).Return(&versiontypes.AppVersion{}, nil)
+	mockStore.EXPECT().GetAppVersionArchive("app-id", gomock.Any(), gomock.Any()).DoAndReturn(func(appID string, sequence int64, dstPath string) error {
+		return writeTestConfigValues(dstPath, hostnames[sequence])
+	}).Times(2)
+	mockStore.EXPECT().GetDownstreamVersionSource("app-id", int64(2)).Return(kotsadmconfig.ConfigChangeSource, nil)
+	mockStore.EXPECT().GetDownstreamVersionConfigChangedBy("app-id", int64(2)).Return(kotsadmconfig.ChangedByCLI, nil)
+
+	r := httptest.NewRequest("GET", "/api/v1/app/my-app/config/history?from=0&to=2", nil)
+	r = mux.SetURLVars(r, map[string]string{"appSlug": "my-app"})
+	w := httptest.NewRecorder()
+
+	(&Handler{}).GetAppConfigHistory(w, r)
+	req.Equal(http.StatusOK, w.Code, w.Body.String())
+
+	response := GetAppConfigHistoryResponse{}
+	req.NoError(json.Unmarshal(w.Body.Bytes(), &response))
+
+	wantChanges := []configtypes.ConfigItemChange{
+		{Name: "hostname", Change: configtypes.ConfigItemChanged, From: "a.example.com", To: "c.example.com"},
+	}
+	assert.Equal(t, wantChanges, response.Changes)
+	req.Len(response.Versions, 1)
+	assert.Equal(t, int64(2), response.Versions[0].Sequence)
+	assert.Equal(t, kotsadmconfig.ConfigChangeSource, response.Versions[0].Source)
+	assert.Equal(t, kotsadmconfig.ChangedByCLI, response.Versions[0].ChangedBy)
+	assert.Equal(t, wantChanges, response.Versions[0].Changes)
+}
+
+func writeTestConfigValues(dir string, hostname string) error {
+	configValues := fmt.Sprintf(`apiVersion: kots.io/v1beta1
+kind: ConfigValues
+spec:
+  values:
+    hostname:
+      value: %s
+`, hostname)
+
+	userdataDir := filepath.Join(dir, "upstream", "userdata")
+	if err := os.MkdirAll(userdataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(userdataDir, "config.yaml"), []byte(configValues), 0644)
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/dbbackup"
+	dbbackuptypes "github.com/replicatedhq/kots/pkg/dbbackup/types"
+	"github.com/replicatedhq/kots/pkg/logger"
+)
+
+type CreateDBBackupResponse struct {
+	Error  string                `json:"error,omitempty"`
+	Backup *dbbackuptypes.Backup `json:"backup,omitempty"`
+}
+
+type ListDBBackupsResponse struct {
+	Error   string                 `json:"error,omitempty"`
+	Backups []dbbackuptypes.Backup `json:"backups"`
+}
+
+func (h *Handler) CreateDBBackup(w http.ResponseWriter, r *http.Request) {
+	response := CreateDBBackupResponse{}
+
+	backup, err := dbbackup.TakeBackup()
+	if err != nil {
+		response.Error = "failed to take database backup"
+		status := http.StatusInternalServerError
+		if errors.Is(err, dbbackup.ErrDatabaseBackedStore) {
+			response.Error = err.Error()
+			status = http.StatusBadRequest
+		}
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, status, response)
+		return
+	}
+	response.Backup = backup
+
+	JSON(w, http.StatusCreated, response)
+}
+
+func (h *Handler) ListDBBackups(w http.ResponseWriter, r *http.Request) {
+	response := ListDBBackupsResponse{}
+
+	backups, err := dbbackup.ListBackups()
+	if err != nil {
+		response.Error = "failed to list database backups"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	response.Backups = backups
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) DownloadDBBackup(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	archivePath, err := dbbackup.GetBackup(name)
+	if err != nil {
+		if errors.Is(err, dbbackup.ErrBackupNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		logger.Error(errors.Wrapf(err, "failed to get database backup %s", name))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(archivePath)
+
+	fi, err := os.Stat(archivePath)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to stat backup file"))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to open backup file"))
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.sqlite.gz", name))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Length", strconv.FormatInt(fi.Size(), 10))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, f)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to send backup file"))
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/keyrotation"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/tasks"
+)
+
+type RotateEncryptionKeyResponse struct {
+	Error string `json:"error,omitempty"`
+	// Resumed is true if an incomplete rotation was resumed instead of generating a new key
+	Resumed bool `json:"resumed"`
+}
+
+type GetRotateEncryptionKeyStatusResponse struct {
+	Status         string `json:"status"`
+	CurrentMessage string `json:"currentMessage"`
+}
+
+func (h *Handler) RotateEncryptionKey(w http.ResponseWriter, r *http.Request) {
+	response := RotateEncryptionKeyResponse{}
+
+	resumed, err := keyrotation.Rotate()
+	if err != nil {
+		response.Error = "failed to rotate encryption key"
+		status := http.StatusInternalServerError
+		if errors.Is(err, keyrotation.ErrRotationInProgress) {
+			response.Error = err.Error()
+			status = http.StatusConflict
+		}
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, status, response)
+		return
+	}
+	response.Resumed = resumed
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) GetRotateEncryptionKeyStatus(w http.ResponseWriter, r *http.Request) {
+	status, message, err := tasks.GetTaskStatus(keyrotation.TaskID)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	JSON(w, http.StatusOK, GetRotateEncryptionKeyStatusResponse{
+		Status:         status,
+		CurrentMessage: message,
+	})
+}
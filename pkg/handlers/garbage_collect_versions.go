@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/versionretention"
+	versionretentiontypes "github.com/replicatedhq/kots/pkg/versionretention/types"
+)
+
+type GarbageCollectVersionsRequest struct {
+	DryRun bool `json:"dryRun,omitempty"`
+	// KeepCount and KeepDays override the retention policy from the kotsadm config map when set
+	KeepCount *int `json:"keepCount,omitempty"`
+	KeepDays  *int `json:"keepDays,omitempty"`
+}
+
+type GarbageCollectVersionsResponse struct {
+	Error  string                                      `json:"error,omitempty"`
+	Report *versionretentiontypes.GarbageCollectReport `json:"report,omitempty"`
+}
+
+func (h *Handler) GarbageCollectVersions(w http.ResponseWriter, r *http.Request) {
+	response := GarbageCollectVersionsResponse{}
+
+	garbageCollectVersionsRequest := GarbageCollectVersionsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&garbageCollectVersionsRequest); err != nil {
+		response.Error = "failed to decode request"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	installParams, err := kotsutil.GetInstallationParams(kotsadmtypes.KotsadmConfigMap)
+	if err != nil {
+		response.Error = "failed to get installation params"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	policy := versionretention.PolicyFromInstallationParams(installParams)
+	if garbageCollectVersionsRequest.KeepCount != nil {
+		policy.KeepCount = *garbageCollectVersionsRequest.KeepCount
+	}
+	if garbageCollectVersionsRequest.KeepDays != nil {
+		policy.KeepDays = *garbageCollectVersionsRequest.KeepDays
+	}
+
+	if !policy.IsEnabled() {
+		response.Error = "version retention policy is not configured"
+		logger.Error(errors.New(response.Error))
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	report, err := versionretention.GarbageCollect(policy, garbageCollectVersionsRequest.DryRun)
+	if err != nil {
+		response.Error = "failed to garbage collect versions"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	response.Report = report
+
+	JSON(w, http.StatusOK, response)
+}
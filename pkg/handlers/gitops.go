@@ -5,37 +5,54 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	apptypes "github.com/replicatedhq/kots/pkg/app/types"
+	"github.com/replicatedhq/kots/pkg/apparchive"
 	"github.com/replicatedhq/kots/pkg/gitops"
 	"github.com/replicatedhq/kots/pkg/handlers/types"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/reporting"
 	"github.com/replicatedhq/kots/pkg/store"
 	"github.com/replicatedhq/kots/pkg/tasks"
+	"k8s.io/apimachinery/pkg/util/validation"
 )
 
 type UpdateAppGitOpsRequest struct {
 	GitOpsInput UpdateAppGitOpsInput `json:"gitOpsInput"`
 }
 type UpdateAppGitOpsInput struct {
-	URI    string `json:"uri"`
-	Branch string `json:"branch"`
-	Path   string `json:"path"`
-	Format string `json:"format"`
-	Action string `json:"action"`
+	URI               string `json:"uri"`
+	Branch            string `json:"branch"`
+	Path              string `json:"path"`
+	Format            string `json:"format"`
+	Action            string `json:"action"`
+	HelmRepositoryURL string `json:"helmRepositoryUrl"`
+	ArgoCDNamespace   string `json:"argoCDNamespace"`
+	SyncStatus        bool   `json:"syncStatus"`
 }
 
 type CreateGitOpsRequest struct {
 	GitOpsInput CreateGitOpsInput `json:"gitOpsInput"`
 }
 type CreateGitOpsInput struct {
-	Provider string `json:"provider"`
-	URI      string `json:"uri"`
-	Hostname string `json:"hostname"`
-	HTTPPort string `json:"httpPort"`
-	SSHPort  string `json:"sshPort"`
+	Provider             string `json:"provider"`
+	URI                  string `json:"uri"`
+	Hostname             string `json:"hostname"`
+	HTTPPort             string `json:"httpPort"`
+	SSHPort              string `json:"sshPort"`
+	AuthType             string `json:"authType"`
+	Username             string `json:"username"`
+	Password             string `json:"password"`
+	CommitURLTemplate    string `json:"commitUrlTemplate"`
+	APIURL               string `json:"apiUrl"`
+	AuthorName           string `json:"authorName"`
+	AuthorEmail          string `json:"authorEmail"`
+	SigningKeyType       string `json:"signingKeyType"`
+	RegenerateSigningKey bool   `json:"regenerateSigningKey"`
 }
 
 func (h *Handler) UpdateAppGitOps(w http.ResponseWriter, r *http.Request) {
@@ -57,7 +74,34 @@ func (h *Handler) UpdateAppGitOps(w http.ResponseWriter, r *http.Request) {
 	}
 
 	gitOpsInput := updateAppGitOpsRequest.GitOpsInput
-	if err := gitops.UpdateDownstreamGitOps(a.ID, clusterID, gitOpsInput.URI, gitOpsInput.Branch, gitOpsInput.Path, gitOpsInput.Format, gitOpsInput.Action); err != nil {
+	if gitOpsInput.Action != "" && gitOpsInput.Action != gitops.ActionCommit && gitOpsInput.Action != gitops.ActionPullRequest {
+		err := errors.Errorf("unsupported gitops action %q", gitOpsInput.Action)
+		logger.Error(err)
+		JSON(w, http.StatusBadRequest, types.NewErrorResponse(err))
+		return
+	}
+	if !gitops.IsSupportedFormat(gitOpsInput.Format) {
+		err := errors.Errorf("unsupported gitops format %q", gitOpsInput.Format)
+		logger.Error(err)
+		JSON(w, http.StatusBadRequest, types.NewErrorResponse(err))
+		return
+	}
+	if gitOpsInput.HelmRepositoryURL != "" && !strings.HasPrefix(gitOpsInput.HelmRepositoryURL, "oci://") {
+		err := errors.Errorf("helm repository url %q must be an oci:// url", gitOpsInput.HelmRepositoryURL)
+		logger.Error(err)
+		JSON(w, http.StatusBadRequest, types.NewErrorResponse(err))
+		return
+	}
+	if gitOpsInput.ArgoCDNamespace != "" {
+		if errs := validation.IsDNS1123Label(gitOpsInput.ArgoCDNamespace); len(errs) > 0 {
+			err := errors.Errorf("invalid argo cd namespace %q: %s", gitOpsInput.ArgoCDNamespace, strings.Join(errs, ", "))
+			logger.Error(err)
+			JSON(w, http.StatusBadRequest, types.NewErrorResponse(err))
+			return
+		}
+	}
+
+	if err := gitops.UpdateDownstreamGitOps(a.ID, clusterID, gitOpsInput.URI, gitOpsInput.Branch, gitOpsInput.Path, gitOpsInput.Format, gitOpsInput.Action, gitOpsInput.HelmRepositoryURL, gitOpsInput.ArgoCDNamespace, gitOpsInput.SyncStatus); err != nil {
 		logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -147,7 +191,7 @@ func (h *Handler) InitGitOpsConnection(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if downstreamGitOps.Format != "single" {
+	if !gitops.IsSupportedFormat(downstreamGitOps.Format) {
 		logger.Error(errors.New("unsupported gitops format"))
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -168,7 +212,7 @@ func (h *Handler) InitGitOpsConnection(w http.ResponseWriter, r *http.Request) {
 	// If a branch is not provided, use the default branch
 	if downstreamGitOps.Branch == "" {
 		err := gitops.UpdateDownstreamGitOps(a.ID, d.ClusterID, downstreamGitOps.RepoURI, defaultBranchName,
-			downstreamGitOps.Path, downstreamGitOps.Format, downstreamGitOps.Action)
+			downstreamGitOps.Path, downstreamGitOps.Format, downstreamGitOps.Action, downstreamGitOps.HelmRepositoryURL, downstreamGitOps.ArgoCDNamespace, downstreamGitOps.SyncStatus)
 		if err != nil {
 			logger.Infof("Failed to update the gitops configmap with the default branch: %v", err)
 
@@ -233,7 +277,7 @@ func (h *Handler) InitGitOpsConnection(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			_, err = gitops.CreateGitOpsCommit(downstreamGitOps, a.Slug, a.Name, int(appVersions.CurrentVersion.ParentSequence), currentVersionArchive, d.Name)
+			err = createInitialGitOpsCommit(downstreamGitOps, a, d, appVersions.CurrentVersion, currentVersionArchive)
 			if err != nil {
 				err = errors.Wrapf(err, "failed to create gitops commit for current version %d", appVersions.CurrentVersion.ParentSequence)
 				logger.Error(err)
@@ -261,7 +305,7 @@ func (h *Handler) InitGitOpsConnection(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			_, err = gitops.CreateGitOpsCommit(downstreamGitOps, a.Slug, a.Name, int(pendingVersion.ParentSequence), pendingVersionArchive, d.Name)
+			err = createInitialGitOpsCommit(downstreamGitOps, a, d, pendingVersion, pendingVersionArchive)
 			if err != nil {
 				err = errors.Wrapf(err, "failed to create gitops commit for pending version %d", pendingVersion.ParentSequence)
 				logger.Error(err)
@@ -281,6 +325,54 @@ func (h *Handler) InitGitOpsConnection(w http.ResponseWriter, r *http.Request) {
 	JSON(w, http.StatusNoContent, "")
 }
 
+// createInitialGitOpsCommit commits a version that was created before gitops was enabled,
+// and records the commit and the pull request if one was opened
+func createInitialGitOpsCommit(gitOpsConfig *gitops.GitOpsConfig, a *apptypes.App, d *downstreamtypes.Downstream, version *downstreamtypes.DownstreamVersion, archiveDir string) error {
+	if err := store.GetStore().AddDownstreamVersionDetails(a.ID, d.ClusterID, version, false); err != nil {
+		return errors.Wrap(err, "failed to get version details")
+	}
+
+	var diff *apparchive.Diff
+	if version.DiffSummary != "" {
+		diff = &apparchive.Diff{}
+		if err := json.Unmarshal([]byte(version.DiffSummary), diff); err != nil {
+			logger.Error(errors.Wrap(err, "failed to unmarshal diff summary"))
+			diff = nil
+		}
+	}
+
+	openPullRequests, err := store.GetStore().ListOpenGitOpsPullRequests(a.ID, d.ClusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list open pull requests")
+	}
+
+	result, err := gitops.CreateGitOpsCommit(gitOpsConfig, a.Slug, a.Name, int(version.ParentSequence), archiveDir, d.Name, gitops.CommitOptions{
+		VersionLabel:           version.VersionLabel,
+		ReleaseNotes:           version.ReleaseNotes,
+		DiffSummary:            diff,
+		SupersededPullRequests: openPullRequests,
+	})
+	if err != nil {
+		return err
+	}
+
+	if result.CommitHash != "" {
+		if err := store.GetStore().SetDownstreamVersionGitOpsCommitHash(a.ID, d.ClusterID, version.Sequence, result.CommitHash); err != nil {
+			return errors.Wrap(err, "failed to save commit hash")
+		}
+	}
+	if result.PullRequest != nil {
+		if err := store.GetStore().SetDownstreamVersionGitOpsPullRequest(a.ID, d.ClusterID, version.Sequence, *result.PullRequest); err != nil {
+			return errors.Wrap(err, "failed to save pull request")
+		}
+	}
+	if err := store.GetStore().SetGitOpsPullRequestStates(a.ID, d.ClusterID, result.ClosedPullRequests); err != nil {
+		return errors.Wrap(err, "failed to save closed pull requests")
+	}
+
+	return nil
+}
+
 func (h *Handler) ResetGitOps(w http.ResponseWriter, r *http.Request) {
 	if err := gitops.ResetGitOps(); err != nil {
 		logger.Error(err)
@@ -311,7 +403,29 @@ func (h *Handler) CreateGitOps(w http.ResponseWriter, r *http.Request) {
 	}
 
 	gitOpsInput := createGitOpsRequest.GitOpsInput
-	if err := gitops.CreateGitOps(gitOpsInput.Provider, gitOpsInput.URI, gitOpsInput.Hostname, gitOpsInput.HTTPPort, gitOpsInput.SSHPort); err != nil {
+	opts := gitops.CreateGitOpsOptions{
+		Provider:             gitOpsInput.Provider,
+		RepoURI:              gitOpsInput.URI,
+		Hostname:             gitOpsInput.Hostname,
+		HTTPPort:             gitOpsInput.HTTPPort,
+		SSHPort:              gitOpsInput.SSHPort,
+		AuthType:             gitOpsInput.AuthType,
+		Username:             gitOpsInput.Username,
+		Password:             gitOpsInput.Password,
+		CommitURLTemplate:    gitOpsInput.CommitURLTemplate,
+		APIURL:               gitOpsInput.APIURL,
+		AuthorName:           gitOpsInput.AuthorName,
+		AuthorEmail:          gitOpsInput.AuthorEmail,
+		SigningKeyType:       gitOpsInput.SigningKeyType,
+		RegenerateSigningKey: gitOpsInput.RegenerateSigningKey,
+	}
+	if err := gitops.ValidateCreateGitOpsOptions(opts); err != nil {
+		logger.Error(err)
+		JSON(w, http.StatusBadRequest, types.NewErrorResponse(err))
+		return
+	}
+
+	if err := gitops.CreateGitOps(opts); err != nil {
 		logger.Error(err)
 		w.WriteHeader(http.StatusInternalServerError)
 		return
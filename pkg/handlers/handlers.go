@@ -187,6 +187,8 @@ func RegisterSessionAuthRoutes(r *mux.Router, kotsStore store.Store, handler KOT
 		HandlerFunc(middleware.EnforceAccess(policy.RegistryRead, handler.GetImageRewriteStatus))
 	r.Name("GarbageCollectImages").Path("/api/v1/garbage-collect-images").Methods("POST").
 		HandlerFunc(middleware.EnforceAccess(policy.AppCreate, handler.GarbageCollectImages))
+	r.Name("GarbageCollectVersions").Path("/api/v1/garbage-collect-versions").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.AppCreate, handler.GarbageCollectVersions))
 	r.Name("DockerHubSecretUpdated").Path("/api/v1/docker/secret-updated").Methods("POST").
 		HandlerFunc(middleware.EnforceAccess(policy.AppCreate, handler.DockerHubSecretUpdated))
 
@@ -201,12 +203,17 @@ func RegisterSessionAuthRoutes(r *mux.Router, kotsStore store.Store, handler KOT
 
 	r.Name("UpdateAppConfig").Path("/api/v1/app/{appSlug}/config").Methods("PUT").
 		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigWrite, handler.UpdateAppConfig))
+	r.Name("GetAppConfigHistory").Path("/api/v1/app/{appSlug}/config/history").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigRead, handler.GetAppConfigHistory))
 	r.Name("CurrentAppConfig").Path("/api/v1/app/{appSlug}/config/{sequence}").Methods("GET").
 		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigRead, handler.CurrentAppConfig))
 	r.Name("LiveAppConfig").Path("/api/v1/app/{appSlug}/liveconfig").Methods("POST").
 		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigWrite, handler.LiveAppConfig))
 	r.Name("SetAppConfigValues").Path("/api/v1/app/{appSlug}/config/values").Methods("POST").
 		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigWrite, handler.SetAppConfigValues))
+	// evaluating a template can look up cluster resources and reveal passwords, so it requires write access
+	r.Name("EvalAppTemplate").Path("/api/v1/app/{appSlug}/template/eval").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigWrite, handler.EvalAppTemplate))
 	r.Name("DownloadFileFromConfig").Path("/api/v1/app/{appSlug}/config/{sequence}/{filename}/download").Methods("GET").
 		HandlerFunc(middleware.EnforceAccess(policy.AppDownstreamConfigRead, handler.DownloadFileFromConfig))
 
@@ -334,6 +341,40 @@ func RegisterSessionAuthRoutes(r *mux.Router, kotsStore store.Store, handler KOT
 	r.Name("ChangePassword").Path("/api/v1/password/change").Methods("PUT").
 		HandlerFunc(middleware.EnforceAccess(policy.PasswordChange, handler.ChangePassword))
 
+	// Multi-factor authentication
+	r.Name("GetMFAStatus").Path("/api/v1/mfa").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.PasswordChange, handler.GetMFAStatus))
+	r.Name("BeginMFAEnrollment").Path("/api/v1/mfa/enroll").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.PasswordChange, handler.BeginMFAEnrollment))
+	r.Name("ConfirmMFAEnrollment").Path("/api/v1/mfa/enroll/confirm").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.PasswordChange, handler.ConfirmMFAEnrollment))
+	r.Name("DisableMFA").Path("/api/v1/mfa/disable").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.PasswordChange, handler.DisableMFA))
+
+	// Sessions
+	r.Name("ListSessions").Path("/api/v1/sessions").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.SessionRead, handler.ListSessions))
+	r.Name("RevokeAllSessions").Path("/api/v1/sessions").Methods("DELETE").
+		HandlerFunc(middleware.EnforceAccess(policy.SessionWrite, handler.RevokeAllSessions))
+	r.Name("GetSessionDetails").Path("/api/v1/sessions/{sessionId}").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.SessionRead, handler.GetSessionDetails))
+	r.Name("RevokeSession").Path("/api/v1/sessions/{sessionId}").Methods("DELETE").
+		HandlerFunc(middleware.EnforceAccess(policy.SessionWrite, handler.RevokeSession))
+
+	// Encryption key rotation
+	r.Name("RotateEncryptionKey").Path("/api/v1/encryption-key/rotate").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.ClusterWrite, handler.RotateEncryptionKey))
+	r.Name("GetRotateEncryptionKeyStatus").Path("/api/v1/encryption-key/rotate/status").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.ClusterRead, handler.GetRotateEncryptionKeyStatus))
+
+	// Database backups
+	r.Name("CreateDBBackup").Path("/api/v1/db/backups").Methods("POST").
+		HandlerFunc(middleware.EnforceAccess(policy.ClusterWrite, handler.CreateDBBackup))
+	r.Name("ListDBBackups").Path("/api/v1/db/backups").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.ClusterWrite, handler.ListDBBackups))
+	r.Name("DownloadDBBackup").Path("/api/v1/db/backups/{name}/download").Methods("GET").
+		HandlerFunc(middleware.EnforceAccess(policy.ClusterWrite, handler.DownloadDBBackup))
+
 	// Upgrade service
 	r.Name("StartUpgradeService").Path("/api/v1/app/{appSlug}/start-upgrade-service").Methods("POST").
 		HandlerFunc(middleware.EnforceAccess(policy.AppUpdate, handler.StartUpgradeService))
@@ -90,12 +90,15 @@ type KOTSHandler interface {
 	GetAppRegistry(w http.ResponseWriter, r *http.Request)
 	ValidateAppRegistry(w http.ResponseWriter, r *http.Request)
 	GarbageCollectImages(w http.ResponseWriter, r *http.Request)
+	GarbageCollectVersions(w http.ResponseWriter, r *http.Request)
 
 	UpdateAppConfig(w http.ResponseWriter, r *http.Request)
 	CurrentAppConfig(w http.ResponseWriter, r *http.Request)
 	LiveAppConfig(w http.ResponseWriter, r *http.Request)
 	SetAppConfigValues(w http.ResponseWriter, r *http.Request)
+	GetAppConfigHistory(w http.ResponseWriter, r *http.Request)
 	DownloadFileFromConfig(w http.ResponseWriter, r *http.Request)
+	EvalAppTemplate(w http.ResponseWriter, r *http.Request)
 
 	SyncLicense(w http.ResponseWriter, r *http.Request)
 	ChangeLicense(w http.ResponseWriter, r *http.Request)
@@ -166,6 +169,27 @@ type KOTSHandler interface {
 	// Password change
 	ChangePassword(w http.ResponseWriter, r *http.Request)
 
+	// Multi-factor authentication
+	GetMFAStatus(w http.ResponseWriter, r *http.Request)
+	BeginMFAEnrollment(w http.ResponseWriter, r *http.Request)
+	ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request)
+	DisableMFA(w http.ResponseWriter, r *http.Request)
+
+	// Sessions
+	ListSessions(w http.ResponseWriter, r *http.Request)
+	GetSessionDetails(w http.ResponseWriter, r *http.Request)
+	RevokeSession(w http.ResponseWriter, r *http.Request)
+	RevokeAllSessions(w http.ResponseWriter, r *http.Request)
+
+	// Encryption key rotation
+	RotateEncryptionKey(w http.ResponseWriter, r *http.Request)
+	GetRotateEncryptionKeyStatus(w http.ResponseWriter, r *http.Request)
+
+	// Database backups
+	CreateDBBackup(w http.ResponseWriter, r *http.Request)
+	ListDBBackups(w http.ResponseWriter, r *http.Request)
+	DownloadDBBackup(w http.ResponseWriter, r *http.Request)
+
 	// Upgrade service
 	StartUpgradeService(w http.ResponseWriter, r *http.Request)
 	GetUpgradeServiceStatus(w http.ResponseWriter, r *http.Request)
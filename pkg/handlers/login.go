@@ -20,6 +20,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/k8sutil"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/session"
+	sessiontypes "github.com/replicatedhq/kots/pkg/session/types"
 	"github.com/replicatedhq/kots/pkg/store"
 	"github.com/replicatedhq/kots/pkg/user"
 	usertypes "github.com/replicatedhq/kots/pkg/user/types"
@@ -31,10 +32,12 @@ import (
 
 type LoginRequest struct {
 	Password string `json:"password"`
+	MFACode  string `json:"mfaCode,omitempty"`
 }
 
 type LoginResponse struct {
-	Error string `json:"error,omitempty"`
+	Error       string `json:"error,omitempty"`
+	MFARequired bool   `json:"mfaRequired,omitempty"`
 }
 
 type LoginMethod string
@@ -79,11 +82,21 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	foundUser, err := user.LogIn(loginRequest.Password)
+	foundUser, err := user.LogIn(loginRequest.Password, loginRequest.MFACode)
 	if err == user.ErrInvalidPassword {
 		loginResponse.Error = "Invalid password. Please try again."
 		JSON(w, http.StatusUnauthorized, loginResponse)
 		return
+	} else if err == user.ErrMFARequired {
+		loginResponse.Error = "Enter the code from your authenticator app or a recovery code."
+		loginResponse.MFARequired = true
+		JSON(w, http.StatusUnauthorized, loginResponse)
+		return
+	} else if err == user.ErrInvalidMFACode {
+		loginResponse.Error = "Invalid authentication code. Please try again."
+		loginResponse.MFARequired = true
+		JSON(w, http.StatusUnauthorized, loginResponse)
+		return
 	} else if err == user.ErrTooManyAttempts {
 		resetPasswordCmd := "kubectl kots reset-password"
 		if util.IsEmbeddedCluster() {
@@ -116,7 +129,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	roles := session.GetSessionRolesFromRBAC(nil, identity.DefaultGroups)
 
 	issuedAt, expiresAt := time.Now(), time.Now().Add(SessionTimeout)
-	createdSession, err := store.GetStore().CreateSession(foundUser, issuedAt, expiresAt, roles)
+	createdSession, err := store.GetStore().CreateSession(foundUser, issuedAt, expiresAt, roles, session.GetClientInfo(r, sessiontypes.AuthMethodPassword))
 	if err != nil {
 		logger.Error(err)
 		JSON(w, http.StatusInternalServerError, loginResponse)
@@ -370,7 +383,7 @@ func (h *Handler) OIDCLoginCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	issuedAt, expiresAt := time.Now(), time.Now().Add(SessionTimeout)
-	createdSession, err := store.GetStore().CreateSession(user, issuedAt, expiresAt, roles) // idToken.IssuedAt, idToken.Expiry
+	createdSession, err := store.GetStore().CreateSession(user, issuedAt, expiresAt, roles, session.GetClientInfo(r, sessiontypes.AuthMethodOIDC)) // idToken.IssuedAt, idToken.Expiry
 	if err != nil {
 		logger.Error(errors.Wrap(err, "failed to create session"))
 		w.WriteHeader(http.StatusInternalServerError)
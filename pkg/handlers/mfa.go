@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/handlers/types"
+	"github.com/replicatedhq/kots/pkg/identity"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/mfa"
+	mfatypes "github.com/replicatedhq/kots/pkg/mfa/types"
+	"github.com/replicatedhq/kots/pkg/util"
+)
+
+type GetMFAStatusResponse struct {
+	Error  string           `json:"error,omitempty"`
+	Status *mfatypes.Status `json:"status,omitempty"`
+}
+
+type BeginMFAEnrollmentResponse struct {
+	Error      string               `json:"error,omitempty"`
+	Enrollment *mfatypes.Enrollment `json:"enrollment,omitempty"`
+}
+
+type ConfirmMFAEnrollmentRequest struct {
+	Code string `json:"code"`
+}
+
+type ConfirmMFAEnrollmentResponse struct {
+	Error string `json:"error,omitempty"`
+	// RecoveryCodes are only returned once and can each be used once in place of an authentication code
+	RecoveryCodes []string `json:"recoveryCodes,omitempty"`
+}
+
+type DisableMFARequest struct {
+	Code string `json:"code"`
+}
+
+type DisableMFAResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (h *Handler) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	response := GetMFAStatusResponse{}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	status, err := mfa.GetStatus(clientset, util.PodNamespace)
+	if err != nil {
+		response.Error = "failed to get mfa status"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	response.Status = status
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) BeginMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	response := BeginMFAEnrollmentResponse{}
+
+	if !isPasswordAuthEnabled(w, r) {
+		return
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, err := mfa.BeginEnrollment(clientset, util.PodNamespace, r.Host)
+	if err != nil {
+		if errors.Is(err, mfa.ErrAlreadyEnabled) {
+			response.Error = err.Error()
+			JSON(w, http.StatusConflict, response)
+			return
+		}
+		response.Error = "failed to begin mfa enrollment"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	response.Enrollment = enrollment
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	response := ConfirmMFAEnrollmentResponse{}
+
+	request := ConfirmMFAEnrollmentRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	recoveryCodes, err := mfa.ConfirmEnrollment(clientset, util.PodNamespace, request.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, mfa.ErrInvalidCode), errors.Is(err, mfa.ErrNoPendingEnrollment):
+			response.Error = err.Error()
+			JSON(w, http.StatusBadRequest, response)
+		case errors.Is(err, mfa.ErrAlreadyEnabled):
+			response.Error = err.Error()
+			JSON(w, http.StatusConflict, response)
+		default:
+			response.Error = "failed to confirm mfa enrollment"
+			logger.Error(errors.Wrap(err, response.Error))
+			JSON(w, http.StatusInternalServerError, response)
+		}
+		return
+	}
+	response.RecoveryCodes = recoveryCodes
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	response := DisableMFAResponse{}
+
+	request := DisableMFARequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := mfa.Disable(clientset, util.PodNamespace, request.Code); err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, mfa.ErrNotEnabled) {
+			response.Error = err.Error()
+			JSON(w, http.StatusBadRequest, response)
+			return
+		}
+		response.Error = "failed to disable mfa"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	JSON(w, http.StatusOK, response)
+}
+
+// isPasswordAuthEnabled writes an error response and returns false if logging in with the shared password is disabled
+func isPasswordAuthEnabled(w http.ResponseWriter, r *http.Request) bool {
+	identityConfig, err := identity.GetConfig(r.Context(), util.PodNamespace)
+	if err != nil {
+		logger.Error(err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if identityConfig.Spec.Enabled && identityConfig.Spec.DisablePasswordAuth {
+		err := errors.New("password authentication disabled")
+		JSON(w, http.StatusForbidden, types.NewErrorResponse(err))
+		return false
+	}
+	return true
+}
@@ -70,6 +70,18 @@ func (mr *MockKOTSHandlerMockRecorder) AppUpdateCheck(w, r interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AppUpdateCheck", reflect.TypeOf((*MockKOTSHandler)(nil).AppUpdateCheck), w, r)
 }
 
+// BeginMFAEnrollment mocks base method.
+func (m *MockKOTSHandler) BeginMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "BeginMFAEnrollment", w, r)
+}
+
+// BeginMFAEnrollment indicates an expected call of BeginMFAEnrollment.
+func (mr *MockKOTSHandlerMockRecorder) BeginMFAEnrollment(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginMFAEnrollment", reflect.TypeOf((*MockKOTSHandler)(nil).BeginMFAEnrollment), w, r)
+}
+
 // CanInstallAppVersion mocks base method.
 func (m *MockKOTSHandler) CanInstallAppVersion(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -178,6 +190,18 @@ func (mr *MockKOTSHandlerMockRecorder) ConfirmEmbeddedClusterManagement(w, r int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEmbeddedClusterManagement", reflect.TypeOf((*MockKOTSHandler)(nil).ConfirmEmbeddedClusterManagement), w, r)
 }
 
+// ConfirmMFAEnrollment mocks base method.
+func (m *MockKOTSHandler) ConfirmMFAEnrollment(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ConfirmMFAEnrollment", w, r)
+}
+
+// ConfirmMFAEnrollment indicates an expected call of ConfirmMFAEnrollment.
+func (mr *MockKOTSHandlerMockRecorder) ConfirmMFAEnrollment(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmMFAEnrollment", reflect.TypeOf((*MockKOTSHandler)(nil).ConfirmMFAEnrollment), w, r)
+}
+
 // CreateAppFromAirgap mocks base method.
 func (m *MockKOTSHandler) CreateAppFromAirgap(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -214,6 +238,18 @@ func (mr *MockKOTSHandlerMockRecorder) CreateApplicationRestore(w, r interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateApplicationRestore", reflect.TypeOf((*MockKOTSHandler)(nil).CreateApplicationRestore), w, r)
 }
 
+// CreateDBBackup mocks base method.
+func (m *MockKOTSHandler) CreateDBBackup(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "CreateDBBackup", w, r)
+}
+
+// CreateDBBackup indicates an expected call of CreateDBBackup.
+func (mr *MockKOTSHandlerMockRecorder) CreateDBBackup(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDBBackup", reflect.TypeOf((*MockKOTSHandler)(nil).CreateDBBackup), w, r)
+}
+
 // CreateGitOps mocks base method.
 func (m *MockKOTSHandler) CreateGitOps(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -334,6 +370,18 @@ func (mr *MockKOTSHandlerMockRecorder) DisableAppGitOps(w, r interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableAppGitOps", reflect.TypeOf((*MockKOTSHandler)(nil).DisableAppGitOps), w, r)
 }
 
+// DisableMFA mocks base method.
+func (m *MockKOTSHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DisableMFA", w, r)
+}
+
+// DisableMFA indicates an expected call of DisableMFA.
+func (mr *MockKOTSHandlerMockRecorder) DisableMFA(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableMFA", reflect.TypeOf((*MockKOTSHandler)(nil).DisableMFA), w, r)
+}
+
 // DockerHubSecretUpdated mocks base method.
 func (m *MockKOTSHandler) DockerHubSecretUpdated(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -358,6 +406,18 @@ func (mr *MockKOTSHandlerMockRecorder) DownloadAppVersion(w, r interface{}) *gom
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadAppVersion", reflect.TypeOf((*MockKOTSHandler)(nil).DownloadAppVersion), w, r)
 }
 
+// DownloadDBBackup mocks base method.
+func (m *MockKOTSHandler) DownloadDBBackup(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "DownloadDBBackup", w, r)
+}
+
+// DownloadDBBackup indicates an expected call of DownloadDBBackup.
+func (mr *MockKOTSHandlerMockRecorder) DownloadDBBackup(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DownloadDBBackup", reflect.TypeOf((*MockKOTSHandler)(nil).DownloadDBBackup), w, r)
+}
+
 // DownloadFileFromConfig mocks base method.
 func (m *MockKOTSHandler) DownloadFileFromConfig(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -418,6 +478,18 @@ func (mr *MockKOTSHandlerMockRecorder) DrainKurlNode(w, r interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DrainKurlNode", reflect.TypeOf((*MockKOTSHandler)(nil).DrainKurlNode), w, r)
 }
 
+// EvalAppTemplate mocks base method.
+func (m *MockKOTSHandler) EvalAppTemplate(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "EvalAppTemplate", w, r)
+}
+
+// EvalAppTemplate indicates an expected call of EvalAppTemplate.
+func (mr *MockKOTSHandlerMockRecorder) EvalAppTemplate(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EvalAppTemplate", reflect.TypeOf((*MockKOTSHandler)(nil).EvalAppTemplate), w, r)
+}
+
 // ExchangePlatformLicense mocks base method.
 func (m *MockKOTSHandler) ExchangePlatformLicense(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -442,6 +514,18 @@ func (mr *MockKOTSHandlerMockRecorder) GarbageCollectImages(w, r interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollectImages", reflect.TypeOf((*MockKOTSHandler)(nil).GarbageCollectImages), w, r)
 }
 
+// GarbageCollectVersions mocks base method.
+func (m *MockKOTSHandler) GarbageCollectVersions(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GarbageCollectVersions", w, r)
+}
+
+// GarbageCollectVersions indicates an expected call of GarbageCollectVersions.
+func (mr *MockKOTSHandlerMockRecorder) GarbageCollectVersions(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GarbageCollectVersions", reflect.TypeOf((*MockKOTSHandler)(nil).GarbageCollectVersions), w, r)
+}
+
 // GenerateEmbeddedClusterNodeJoinCommand mocks base method.
 func (m *MockKOTSHandler) GenerateEmbeddedClusterNodeJoinCommand(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -550,6 +634,18 @@ func (mr *MockKOTSHandlerMockRecorder) GetApp(w, r interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetApp", reflect.TypeOf((*MockKOTSHandler)(nil).GetApp), w, r)
 }
 
+// GetAppConfigHistory mocks base method.
+func (m *MockKOTSHandler) GetAppConfigHistory(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetAppConfigHistory", w, r)
+}
+
+// GetAppConfigHistory indicates an expected call of GetAppConfigHistory.
+func (mr *MockKOTSHandlerMockRecorder) GetAppConfigHistory(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppConfigHistory", reflect.TypeOf((*MockKOTSHandler)(nil).GetAppConfigHistory), w, r)
+}
+
 // GetAppContents mocks base method.
 func (m *MockKOTSHandler) GetAppContents(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -874,6 +970,18 @@ func (mr *MockKOTSHandlerMockRecorder) GetLicense(w, r interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetLicense", reflect.TypeOf((*MockKOTSHandler)(nil).GetLicense), w, r)
 }
 
+// GetMFAStatus mocks base method.
+func (m *MockKOTSHandler) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetMFAStatus", w, r)
+}
+
+// GetMFAStatus indicates an expected call of GetMFAStatus.
+func (mr *MockKOTSHandlerMockRecorder) GetMFAStatus(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMFAStatus", reflect.TypeOf((*MockKOTSHandler)(nil).GetMFAStatus), w, r)
+}
+
 // GetOnlineInstallStatus mocks base method.
 func (m *MockKOTSHandler) GetOnlineInstallStatus(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -994,6 +1102,30 @@ func (mr *MockKOTSHandlerMockRecorder) GetRestoreStatus(w, r interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRestoreStatus", reflect.TypeOf((*MockKOTSHandler)(nil).GetRestoreStatus), w, r)
 }
 
+// GetRotateEncryptionKeyStatus mocks base method.
+func (m *MockKOTSHandler) GetRotateEncryptionKeyStatus(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetRotateEncryptionKeyStatus", w, r)
+}
+
+// GetRotateEncryptionKeyStatus indicates an expected call of GetRotateEncryptionKeyStatus.
+func (mr *MockKOTSHandlerMockRecorder) GetRotateEncryptionKeyStatus(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRotateEncryptionKeyStatus", reflect.TypeOf((*MockKOTSHandler)(nil).GetRotateEncryptionKeyStatus), w, r)
+}
+
+// GetSessionDetails mocks base method.
+func (m *MockKOTSHandler) GetSessionDetails(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "GetSessionDetails", w, r)
+}
+
+// GetSessionDetails indicates an expected call of GetSessionDetails.
+func (mr *MockKOTSHandlerMockRecorder) GetSessionDetails(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSessionDetails", reflect.TypeOf((*MockKOTSHandler)(nil).GetSessionDetails), w, r)
+}
+
 // GetSnapshotConfig mocks base method.
 func (m *MockKOTSHandler) GetSnapshotConfig(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -1138,6 +1270,18 @@ func (mr *MockKOTSHandlerMockRecorder) ListBackups(w, r interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBackups", reflect.TypeOf((*MockKOTSHandler)(nil).ListBackups), w, r)
 }
 
+// ListDBBackups mocks base method.
+func (m *MockKOTSHandler) ListDBBackups(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListDBBackups", w, r)
+}
+
+// ListDBBackups indicates an expected call of ListDBBackups.
+func (mr *MockKOTSHandlerMockRecorder) ListDBBackups(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListDBBackups", reflect.TypeOf((*MockKOTSHandler)(nil).ListDBBackups), w, r)
+}
+
 // ListInstanceBackups mocks base method.
 func (m *MockKOTSHandler) ListInstanceBackups(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -1162,6 +1306,18 @@ func (mr *MockKOTSHandlerMockRecorder) ListRedactors(w, r interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRedactors", reflect.TypeOf((*MockKOTSHandler)(nil).ListRedactors), w, r)
 }
 
+// ListSessions mocks base method.
+func (m *MockKOTSHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "ListSessions", w, r)
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockKOTSHandlerMockRecorder) ListSessions(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockKOTSHandler)(nil).ListSessions), w, r)
+}
+
 // ListSupportBundles mocks base method.
 func (m *MockKOTSHandler) ListSupportBundles(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
@@ -1282,6 +1438,42 @@ func (mr *MockKOTSHandlerMockRecorder) ResumeInstallOnline(w, r interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResumeInstallOnline", reflect.TypeOf((*MockKOTSHandler)(nil).ResumeInstallOnline), w, r)
 }
 
+// RevokeAllSessions mocks base method.
+func (m *MockKOTSHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RevokeAllSessions", w, r)
+}
+
+// RevokeAllSessions indicates an expected call of RevokeAllSessions.
+func (mr *MockKOTSHandlerMockRecorder) RevokeAllSessions(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeAllSessions", reflect.TypeOf((*MockKOTSHandler)(nil).RevokeAllSessions), w, r)
+}
+
+// RevokeSession mocks base method.
+func (m *MockKOTSHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RevokeSession", w, r)
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockKOTSHandlerMockRecorder) RevokeSession(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockKOTSHandler)(nil).RevokeSession), w, r)
+}
+
+// RotateEncryptionKey mocks base method.
+func (m *MockKOTSHandler) RotateEncryptionKey(w http.ResponseWriter, r *http.Request) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "RotateEncryptionKey", w, r)
+}
+
+// RotateEncryptionKey indicates an expected call of RotateEncryptionKey.
+func (mr *MockKOTSHandlerMockRecorder) RotateEncryptionKey(w, r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateEncryptionKey", reflect.TypeOf((*MockKOTSHandler)(nil).RotateEncryptionKey), w, r)
+}
+
 // SaveInstanceSnapshotRetention mocks base method.
 func (m *MockKOTSHandler) SaveInstanceSnapshotRetention(w http.ResponseWriter, r *http.Request) {
 	m.ctrl.T.Helper()
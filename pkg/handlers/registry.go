@@ -16,6 +16,7 @@ import (
 	apptypes "github.com/replicatedhq/kots/pkg/app/types"
 	dockerregistry "github.com/replicatedhq/kots/pkg/docker/registry"
 	"github.com/replicatedhq/kots/pkg/handlers/types"
+	"github.com/replicatedhq/kots/pkg/kotsadmconfig"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/preflight"
 	"github.com/replicatedhq/kots/pkg/registry"
@@ -106,7 +107,7 @@ func (h *Handler) DockerHubSecretUpdated(w http.ResponseWriter, r *http.Request)
 		isPrimaryVersion := true
 		skipPrefligths := false
 		deploy := false
-		resp, err := updateAppConfig(app, latestSequence, nil, createNewVersion, isPrimaryVersion, skipPrefligths, deploy)
+		resp, err := updateAppConfig(app, latestSequence, nil, createNewVersion, isPrimaryVersion, skipPrefligths, deploy, kotsadmconfig.ConfigChangeSource, "")
 		if err != nil {
 			logger.Error(err)
 			JSON(w, http.StatusInternalServerError, resp)
@@ -92,6 +92,16 @@ func requireValidSession(kotsStore store.Store, w http.ResponseWriter, r *http.R
 		return nil, err
 	}
 
+	if session.IsIdle(sess, time.Now()) {
+		if err := kotsStore.DeleteSession(sess.ID); err != nil {
+			logger.Error(errors.Wrapf(err, "session idle. failed to delete idle session %s", sess.ID))
+		}
+		err := errors.New("session expired due to inactivity")
+		response := types.ErrorResponse{Error: util.StrPointer(err.Error())}
+		JSON(w, http.StatusUnauthorized, response)
+		return nil, err
+	}
+
 	passwordUpdatedAt, err := kotsStore.GetPasswordUpdatedAt()
 	if err != nil {
 		response := types.ErrorResponse{Error: util.StrPointer("failed to validate session with current password")}
@@ -126,6 +136,13 @@ func requireValidSession(kotsStore store.Store, w http.ResponseWriter, r *http.R
 		}
 	}
 
+	if session.NeedsActivityUpdate(sess, time.Now()) {
+		sess.LastActivityAt = time.Now()
+		if err := kotsStore.UpdateSessionLastActivityAt(sess.ID, sess.LastActivityAt); err != nil {
+			logger.Error(errors.Wrapf(err, "failed to update session last activity %s", sess.ID))
+		}
+	}
+
 	return sess, nil
 }
 
@@ -34,9 +34,10 @@ func Test_requireValidSession(t *testing.T) {
 	mockStore := mock_store.NewMockStore(ctrl)
 
 	sess := &types.Session{
-		ID:        "session-id",
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(12 * time.Hour),
+		ID:             "session-id",
+		IssuedAt:       time.Now(),
+		ExpiresAt:      time.Now().Add(12 * time.Hour),
+		LastActivityAt: time.Now(),
 	}
 	sessionJWT := signJWT(t, sess)
 
@@ -296,9 +297,10 @@ func Test_requireValidSession_extendSession(t *testing.T) {
 	mockStore := mock_store.NewMockStore(ctrl)
 
 	extendSession := &types.Session{
-		ID:        "session-id",
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		ID:             "session-id",
+		IssuedAt:       time.Now(),
+		ExpiresAt:      time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		LastActivityAt: time.Now(),
 	}
 	extendSessionJWT := signJWT(t, extendSession)
 	extendedTokenCookie := http.Cookie{
@@ -332,9 +334,10 @@ func Test_requireValidSession_extendSession_withUpdateErr(t *testing.T) {
 	mockStore := mock_store.NewMockStore(ctrl)
 
 	extendSession := &types.Session{
-		ID:        "session-id",
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		ID:             "session-id",
+		IssuedAt:       time.Now(),
+		ExpiresAt:      time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		LastActivityAt: time.Now(),
 	}
 	extendSessionJWT := signJWT(t, extendSession)
 	extendedTokenCookie := http.Cookie{
@@ -373,9 +376,10 @@ func Test_requireValidSession_FailedToFetchPasswordUpdated_AfterSessionIssuedErr
 	mockStore := mock_store.NewMockStore(ctrl)
 
 	extendSession := &types.Session{
-		ID:        "session-id",
-		IssuedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		ID:             "session-id",
+		IssuedAt:       time.Now(),
+		ExpiresAt:      time.Now().Add(12 * time.Hour).Add(-1 * time.Hour), // simulate a scenario where user is still using the session after one hour
+		LastActivityAt: time.Now(),
 	}
 	extendSessionJWT := signJWT(t, extendSession)
 	extendedTokenCookie := http.Cookie{
@@ -478,3 +482,33 @@ func Test_requireValidSession_PasswordUpdated_AfterSessionIssuedErr_logDeleteSes
 	req.Equal(want, got)
 	req.Equal(401, w.Code)
 }
+
+func Test_requireValidSession_updateLastActivity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockStore := mock_store.NewMockStore(ctrl)
+
+	activeSession := &types.Session{
+		ID:             "session-id",
+		IssuedAt:       time.Now().Add(-10 * time.Minute),
+		ExpiresAt:      time.Now().Add(12 * time.Hour),
+		LastActivityAt: time.Now().Add(-5 * time.Minute),
+	}
+	activeSessionJWT := signJWT(t, activeSession)
+
+	w := httptest.NewRecorder()
+	r := &http.Request{
+		Header: http.Header{
+			"Authorization": []string{fmt.Sprintf("Bearer %v", activeSessionJWT)},
+		},
+	}
+
+	mockStore.EXPECT().GetSession(activeSession.ID).Return(activeSession, nil)
+	mockStore.EXPECT().GetPasswordUpdatedAt().Return(nil, nil)
+	mockStore.EXPECT().UpdateSessionLastActivityAt(activeSession.ID, gomock.Any()).Return(nil)
+
+	req := require.New(t)
+	got, err := requireValidSession(mockStore, w, r)
+	req.NoError(err)
+	req.WithinDuration(time.Now(), got.LastActivityAt, time.Minute)
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/session"
+	sessiontypes "github.com/replicatedhq/kots/pkg/session/types"
+	"github.com/replicatedhq/kots/pkg/store"
+)
+
+type ListSessionsResponse struct {
+	Error    string                     `json:"error,omitempty"`
+	Sessions []sessiontypes.SessionInfo `json:"sessions"`
+}
+
+type GetSessionResponse struct {
+	Error   string                    `json:"error,omitempty"`
+	Session *sessiontypes.SessionInfo `json:"session,omitempty"`
+}
+
+type RevokeSessionResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+type RevokeAllSessionsResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (h *Handler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	response := ListSessionsResponse{
+		Sessions: []sessiontypes.SessionInfo{},
+	}
+
+	sessions, err := store.GetStore().ListSessions()
+	if err != nil {
+		response.Error = "failed to list sessions"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	currentSessionID := getCurrentSessionID(r)
+	for _, sess := range sessions {
+		response.Sessions = append(response.Sessions, session.ToSessionInfo(sess, currentSessionID))
+	}
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) GetSessionDetails(w http.ResponseWriter, r *http.Request) {
+	response := GetSessionResponse{}
+
+	sessionID := mux.Vars(r)["sessionId"]
+
+	sess, err := store.GetStore().GetSession(sessionID)
+	if err != nil {
+		response.Error = "failed to get session"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	if sess == nil {
+		response.Error = "session not found"
+		JSON(w, http.StatusNotFound, response)
+		return
+	}
+
+	sessionInfo := session.ToSessionInfo(sess, getCurrentSessionID(r))
+	response.Session = &sessionInfo
+
+	JSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	response := RevokeSessionResponse{}
+
+	sessionID := mux.Vars(r)["sessionId"]
+
+	sess, err := store.GetStore().GetSession(sessionID)
+	if err != nil {
+		response.Error = "failed to get session"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	if sess == nil {
+		response.Error = "session not found"
+		JSON(w, http.StatusNotFound, response)
+		return
+	}
+
+	if err := store.GetStore().DeleteSession(sessionID); err != nil {
+		response.Error = "failed to revoke session"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	logger.Infof("session %s for user %q was revoked", sessionID, sess.UserID)
+
+	JSON(w, http.StatusOK, response)
+}
+
+// RevokeAllSessions logs out every session, including the one making the request
+func (h *Handler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	response := RevokeAllSessionsResponse{}
+
+	if err := store.GetStore().DeleteAllSessions(); err != nil {
+		response.Error = "failed to revoke sessions"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	logger.Info("all sessions were revoked")
+
+	JSON(w, http.StatusOK, response)
+}
+
+func getCurrentSessionID(r *http.Request) string {
+	sess := session.ContextGetSession(r)
+	if sess == nil {
+		return ""
+	}
+	return sess.ID
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/render"
+	rendertypes "github.com/replicatedhq/kots/pkg/render/types"
+	"github.com/replicatedhq/kots/pkg/store"
+	"github.com/replicatedhq/kots/pkg/template"
+	"github.com/replicatedhq/kots/pkg/util"
+)
+
+type EvalAppTemplateRequest struct {
+	// Sequence is the app version to evaluate the template against, the latest version if not set
+	Sequence *int64 `json:"sequence,omitempty"`
+	// Name identifies the template in errors, such as the name of the file it was read from
+	Name     string `json:"name,omitempty"`
+	Template string `json:"template"`
+	// ShowPasswords evaluates password config items to their values instead of a mask
+	ShowPasswords bool `json:"showPasswords"`
+}
+
+type EvalAppTemplateResponse struct {
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+	Sequence int64  `json:"sequence"`
+	Output   string `json:"output"`
+	// TemplateError is the location of the expression that failed to evaluate
+	TemplateError *TemplateErrorDetails `json:"templateError,omitempty"`
+}
+
+// TemplateErrorDetails point to the cause of a template error, so that it can be shown to the user
+type TemplateErrorDetails struct {
+	// Location is the location of the expression that failed
+	Location *template.TemplateError `json:"location,omitempty"`
+	// DependencyCycle is the path of config items that depend on each other
+	DependencyCycle []string `json:"dependencyCycle,omitempty"`
+}
+
+// getTemplateErrorDetails returns the details of a template error, or nil if the error is not caused by one
+func getTemplateErrorDetails(err error) *TemplateErrorDetails {
+	var templateErr *template.TemplateError
+	if errors.As(err, &templateErr) {
+		return &TemplateErrorDetails{Location: templateErr}
+	}
+
+	var cycleErr *template.DependencyCycleError
+	if errors.As(err, &cycleErr) {
+		return &TemplateErrorDetails{DependencyCycle: cycleErr.Cycle}
+	}
+
+	return nil
+}
+
+// EvalAppTemplate evaluates a template with the same template contexts that are used to render a version of the app
+func (h *Handler) EvalAppTemplate(w http.ResponseWriter, r *http.Request) {
+	response := EvalAppTemplateResponse{
+		Success: false,
+	}
+
+	request := EvalAppTemplateRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		response.Error = "failed to decode request body"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	foundApp, err := store.GetStore().GetAppFromSlug(mux.Vars(r)["appSlug"])
+	if err != nil {
+		response.Error = "failed to get app from app slug"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	sequence, err := store.GetStore().GetLatestAppSequence(foundApp.ID, true)
+	if err != nil {
+		response.Error = "failed to get latest app sequence"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	if request.Sequence != nil {
+		sequence = *request.Sequence
+	}
+	response.Sequence = sequence
+
+	archiveDir, err := os.MkdirTemp("", "kotsadm")
+	if err != nil {
+		response.Error = "failed to create temp dir"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+	defer os.RemoveAll(archiveDir)
+
+	if err := store.GetStore().GetAppVersionArchive(foundApp.ID, sequence, archiveDir); err != nil {
+		response.Error = "failed to get app version archive"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		response.Error = "failed to load kots kinds from path"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	registrySettings, err := store.GetStore().GetRegistryDetailsForApp(foundApp.ID)
+	if err != nil {
+		response.Error = "failed to get app registry info"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	output, err := render.EvalTemplate(rendertypes.EvalTemplateOptions{
+		KotsKinds:        kotsKinds,
+		RegistrySettings: registrySettings,
+		AppSlug:          foundApp.Slug,
+		Sequence:         sequence,
+		IsAirgap:         foundApp.IsAirgap,
+		Namespace:        util.AppNamespace(),
+		Name:             request.Name,
+		Template:         request.Template,
+		ShowPasswords:    request.ShowPasswords,
+	})
+	if err != nil {
+		// the error is returned as is so that it points to the failing expression
+		response.Error = err.Error()
+		response.TemplateError = getTemplateErrorDetails(err)
+		logger.Infof("failed to evaluate template for app %s: %v", foundApp.Slug, err)
+		JSON(w, http.StatusBadRequest, response)
+		return
+	}
+
+	response.Success = true
+	response.Output = output
+
+	JSON(w, http.StatusOK, response)
+}
@@ -256,6 +256,80 @@ func RestartKotsadm(ctx context.Context, clientset *kubernetes.Clientset, namesp
 	}
 }
 
+// ScaleKotsadm sets the number of kotsadm replicas, whether kotsadm runs as a deployment or a statefulset,
+// and returns the number of replicas before scaling
+func ScaleKotsadm(ctx context.Context, clientset kubernetes.Interface, namespace string, replicas int32) (int32, error) {
+	d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, "kotsadm", metav1.GetOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return 0, errors.Wrap(err, "failed to get kotsadm deployment")
+	}
+	if err == nil {
+		previous := int32(1)
+		if d.Spec.Replicas != nil {
+			previous = *d.Spec.Replicas
+		}
+		d.Spec.Replicas = &replicas
+		if _, err := clientset.AppsV1().Deployments(namespace).Update(ctx, d, metav1.UpdateOptions{}); err != nil {
+			return 0, errors.Wrap(err, "failed to update kotsadm deployment")
+		}
+		return previous, nil
+	}
+
+	s, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, "kotsadm", metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return 0, errors.New("unable to find kotsadm deployment or statefulset")
+		}
+		return 0, errors.Wrap(err, "failed to get kotsadm statefulset")
+	}
+	previous := int32(1)
+	if s.Spec.Replicas != nil {
+		previous = *s.Spec.Replicas
+	}
+	s.Spec.Replicas = &replicas
+	if _, err := clientset.AppsV1().StatefulSets(namespace).Update(ctx, s, metav1.UpdateOptions{}); err != nil {
+		return 0, errors.Wrap(err, "failed to update kotsadm statefulset")
+	}
+	return previous, nil
+}
+
+// WaitForKotsadmStopped waits until there are no kotsadm pods left
+func WaitForKotsadmStopped(ctx context.Context, clientset kubernetes.Interface, namespace string, timeout time.Duration) error {
+	start := time.Now()
+
+	for {
+		pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: "app=kotsadm"})
+		if err != nil {
+			return errors.Wrap(err, "failed to list pods")
+		}
+
+		if len(pods.Items) == 0 {
+			return nil
+		}
+
+		time.Sleep(time.Second)
+
+		if time.Since(start) > timeout {
+			return &types.ErrorTimeout{Message: "timeout waiting for kotsadm pods to stop"}
+		}
+	}
+}
+
+func FindRqlite(clientset *kubernetes.Clientset, namespace string) (string, error) {
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), metav1.ListOptions{LabelSelector: "app=kotsadm-rqlite"})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to list pods")
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning {
+			return pod.Name, nil
+		}
+	}
+
+	return "", errors.New("unable to find rqlite pod")
+}
+
 func DeleteKotsadm(ctx context.Context, clientset *kubernetes.Clientset, namespace string, isKurl bool) error {
 	selectorLabels := map[string]string{
 		kotsadmtypes.KotsadmKey: kotsadmtypes.KotsadmLabelValue,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"gopkg.in/go-playground/assert.v1"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
@@ -60,3 +61,47 @@ func TestGetKotsadmID(t *testing.T) {
 		})
 	}
 }
+
+func TestScaleKotsadm(t *testing.T) {
+	replicas := int32(1)
+	tests := []struct {
+		name      string
+		clientset kubernetes.Interface
+		wantErr   bool
+	}{
+		{
+			name: "deployment",
+			clientset: fake.NewSimpleClientset(&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "kotsadm", Namespace: "default"},
+				Spec:       appsv1.DeploymentSpec{Replicas: &replicas},
+			}),
+		},
+		{
+			name: "statefulset",
+			clientset: fake.NewSimpleClientset(&appsv1.StatefulSet{
+				ObjectMeta: metav1.ObjectMeta{Name: "kotsadm", Namespace: "default"},
+				Spec:       appsv1.StatefulSetSpec{Replicas: &replicas},
+			}),
+		},
+		{
+			name:      "not found",
+			clientset: fake.NewSimpleClientset(),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous, err := ScaleKotsadm(context.TODO(), tt.clientset, "default", 0)
+			if tt.wantErr {
+				assert.NotEqual(t, nil, err)
+				return
+			}
+			assert.Equal(t, nil, err)
+			assert.Equal(t, int32(1), previous)
+
+			previous, err = ScaleKotsadm(context.TODO(), tt.clientset, "default", 1)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, int32(0), previous)
+		})
+	}
+}
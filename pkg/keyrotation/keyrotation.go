@@ -0,0 +1,393 @@
+package keyrotation
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/filestore"
+	"github.com/replicatedhq/kots/pkg/gitops"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/store"
+	"github.com/replicatedhq/kots/pkg/tasks"
+	"github.com/replicatedhq/kots/pkg/util"
+	"go.yaml.in/yaml/v2"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	TaskID = "rotate-encryption-key"
+)
+
+var (
+	ErrRotationInProgress = errors.New("an encryption key rotation is already in progress")
+
+	mtx     sync.Mutex
+	running bool
+)
+
+// reencryptStep re-encrypts one kind of encrypted data with the current encryption key.
+// when dryRun is true, the data is only checked. returns the number of items that were not encrypted with the current key.
+type reencryptStep struct {
+	name string
+	run  func(ctx context.Context, dryRun bool, progressFn func(message string)) (int, error)
+}
+
+var reencryptSteps = []reencryptStep{
+	{
+		name: "registry credentials",
+		run: func(ctx context.Context, dryRun bool, progressFn func(message string)) (int, error) {
+			return store.GetStore().ReencryptRegistryPasswords(dryRun)
+		},
+	},
+	{
+		name: "GitOps credentials",
+		run: func(ctx context.Context, dryRun bool, progressFn func(message string)) (int, error) {
+			return gitops.ReencryptCredentials(dryRun)
+		},
+	},
+	{
+		name: "config values",
+		run:  reencryptConfigValues,
+	},
+	{
+		name: "version archives",
+		run: func(ctx context.Context, dryRun bool, progressFn func(message string)) (int, error) {
+			return filestore.ReencryptArchives(ctx, dryRun, func(done int, total int) {
+				progressFn(fmt.Sprintf("%d/%d archives", done, total))
+			})
+		},
+	},
+}
+
+// LoadPreviousKeys adds the keys that were replaced by an incomplete rotation to the list of decryption keys,
+// so that data that has not been re-encrypted yet can still be read.
+func LoadPreviousKeys() error {
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return errors.Wrap(err, "failed to get clientset")
+	}
+
+	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), crypto.EncryptionSecretName, metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "failed to get encryption secret")
+	}
+
+	for _, previousKey := range crypto.PreviousKeysFromSecret(secret) {
+		if err := crypto.InitFromString(previousKey); err != nil {
+			return errors.Wrap(err, "failed to load previous encryption key")
+		}
+	}
+
+	return nil
+}
+
+// Rotate generates a new encryption key and stores it in the encryption secret. the replaced key is kept in the secret
+// as a previous key until all encrypted data has been re-encrypted with the new key, which happens in the background.
+// if a previous rotation did not complete, it's resumed instead of generating another key. returns true if resumed.
+func Rotate() (bool, error) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	if running {
+		return false, ErrRotationInProgress
+	}
+
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get clientset")
+	}
+
+	resumed, err := rotateKeyInSecret(clientset, util.PodNamespace)
+	if err != nil {
+		return false, errors.Wrap(err, "failed to rotate key")
+	}
+
+	if err := tasks.SetTaskStatus(TaskID, "Re-encrypting data...", "running"); err != nil {
+		return false, errors.Wrap(err, "failed to set task status")
+	}
+
+	running = true
+	go reencryptAndRetire(clientset, util.PodNamespace)
+
+	return resumed, nil
+}
+
+// ResumeIfIncomplete continues re-encrypting data in the background if a previous rotation did not complete
+func ResumeIfIncomplete() {
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get clientset"))
+		return
+	}
+
+	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), crypto.EncryptionSecretName, metav1.GetOptions{})
+	if err != nil {
+		if !kuberneteserrors.IsNotFound(err) {
+			logger.Error(errors.Wrap(err, "failed to get encryption secret"))
+		}
+		return
+	}
+	if len(crypto.PreviousKeysFromSecret(secret)) == 0 {
+		return
+	}
+
+	logger.Info("resuming incomplete encryption key rotation")
+	if _, err := Rotate(); err != nil {
+		logger.Error(errors.Wrap(err, "failed to resume encryption key rotation"))
+	}
+}
+
+// rotateKeyInSecret replaces the encryption key in the secret and registers it for encryption.
+// if the secret already contains previous keys, the current key is kept. returns true in that case.
+func rotateKeyInSecret(clientset kubernetes.Interface, namespace string) (bool, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), crypto.EncryptionSecretName, metav1.GetOptions{})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get encryption secret")
+	}
+
+	currentKey := string(secret.Data[crypto.EncryptionKeySecretKey])
+	if currentKey == "" {
+		return false, errors.Errorf("%s secret does not have member %s", crypto.EncryptionSecretName, crypto.EncryptionKeySecretKey)
+	}
+
+	previousKeys := crypto.PreviousKeysFromSecret(secret)
+	for _, previousKey := range previousKeys {
+		if err := crypto.InitFromString(previousKey); err != nil {
+			return false, errors.Wrap(err, "failed to load previous encryption key")
+		}
+	}
+
+	if len(previousKeys) > 0 {
+		if err := crypto.SetEncryptionKey(currentKey); err != nil {
+			return false, errors.Wrap(err, "failed to load encryption key")
+		}
+		return true, nil
+	}
+
+	newKey, err := crypto.GenerateKey()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to generate encryption key")
+	}
+
+	// the secret is updated first so that the new key is not lost if kotsadm restarts before data is re-encrypted with it
+	secret.Data[crypto.EncryptionKeySecretKey] = []byte(newKey)
+	secret.Data[crypto.PreviousEncryptionKeysSecretKey] = []byte(currentKey)
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return false, errors.Wrap(err, "failed to update encryption secret")
+	}
+
+	if err := crypto.InitFromString(currentKey); err != nil {
+		return false, errors.Wrap(err, "failed to load previous encryption key")
+	}
+	if err := crypto.SetEncryptionKey(newKey); err != nil {
+		return false, errors.Wrap(err, "failed to load encryption key")
+	}
+
+	return false, nil
+}
+
+// retirePreviousKeys removes the previous keys from the secret
+func retirePreviousKeys(clientset kubernetes.Interface, namespace string) error {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), crypto.EncryptionSecretName, metav1.GetOptions{})
+	if err != nil {
+		return errors.Wrap(err, "failed to get encryption secret")
+	}
+
+	if _, ok := secret.Data[crypto.PreviousEncryptionKeysSecretKey]; !ok {
+		return nil
+	}
+
+	delete(secret.Data, crypto.PreviousEncryptionKeysSecretKey)
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), secret, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to update encryption secret")
+	}
+
+	return nil
+}
+
+func reencryptAndRetire(clientset kubernetes.Interface, namespace string) {
+	finishedChan := make(chan error)
+	defer close(finishedChan)
+
+	tasks.StartTaskMonitor(TaskID, finishedChan)
+
+	defer func() {
+		mtx.Lock()
+		running = false
+		mtx.Unlock()
+	}()
+
+	err := reencryptAll(context.Background())
+	if err == nil {
+		err = retirePreviousKeys(clientset, namespace)
+	}
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to rotate encryption key"))
+	} else {
+		logger.Info("encryption key rotation completed, previous keys have been retired")
+	}
+
+	finishedChan <- err
+}
+
+// reencryptAll re-encrypts all data that's not encrypted with the current key, then verifies that nothing references the previous keys anymore.
+// every step skips data that's already encrypted with the current key, so it can be safely resumed after an interruption.
+func reencryptAll(ctx context.Context) error {
+	for i, step := range reencryptSteps {
+		progressFn := func(message string) {
+			if err := tasks.SetTaskStatus(TaskID, fmt.Sprintf("Re-encrypting %s (step %d/%d): %s", step.name, i+1, len(reencryptSteps), message), "running"); err != nil {
+				logger.Error(errors.Wrap(err, "failed to set task status"))
+			}
+		}
+		progressFn("in progress")
+
+		count, err := step.run(ctx, false, progressFn)
+		if err != nil {
+			return errors.Wrapf(err, "failed to re-encrypt %s", step.name)
+		}
+		logger.Infof("re-encrypted %d %s", count, step.name)
+	}
+
+	if err := tasks.SetTaskStatus(TaskID, "Verifying that previous keys are no longer in use", "running"); err != nil {
+		logger.Error(errors.Wrap(err, "failed to set task status"))
+	}
+
+	for _, step := range reencryptSteps {
+		count, err := step.run(ctx, true, func(string) {})
+		if err != nil {
+			return errors.Wrapf(err, "failed to verify %s", step.name)
+		}
+		if count > 0 {
+			return errors.Errorf("%d %s are still encrypted with a previous key", count, step.name)
+		}
+	}
+
+	return nil
+}
+
+func reencryptConfigValues(ctx context.Context, dryRun bool, progressFn func(message string)) (int, error) {
+	apps, err := store.GetStore().ListInstalledApps()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to list installed apps")
+	}
+
+	count := 0
+	for _, app := range apps {
+		versions, err := store.GetStore().FindDownstreamVersions(app.ID, true)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to find versions for app %s", app.Slug)
+		}
+
+		for i, version := range versions.AllVersions {
+			if err := ctx.Err(); err != nil {
+				return 0, err
+			}
+
+			progressFn(fmt.Sprintf("%s (%d/%d versions)", app.Slug, i+1, len(versions.AllVersions)))
+
+			n, err := reencryptAppVersionConfigValues(app.ID, version.Sequence, dryRun)
+			if err != nil {
+				return 0, errors.Wrapf(err, "failed to re-encrypt config values for app %s sequence %d", app.Slug, version.Sequence)
+			}
+			count += n
+		}
+	}
+
+	return count, nil
+}
+
+func reencryptAppVersionConfigValues(appID string, sequence int64, dryRun bool) (int, error) {
+	archiveDir, err := os.MkdirTemp("", "kotsadm")
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(archiveDir)
+
+	if err := store.GetStore().GetAppVersionArchive(appID, sequence, archiveDir); err != nil {
+		return 0, errors.Wrap(err, "failed to get app version archive")
+	}
+
+	count, err := reencryptConfigValuesInArchive(archiveDir)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to re-encrypt config values in archive")
+	}
+
+	if count == 0 || dryRun {
+		return count, nil
+	}
+
+	if err := store.GetStore().UpdateAppVersionArchive(appID, sequence, archiveDir); err != nil {
+		return 0, errors.Wrap(err, "failed to update app version archive")
+	}
+
+	return count, nil
+}
+
+// reencryptConfigValuesInArchive re-encrypts the config values in both the upstream and the rendered kots kinds of an app version archive.
+// returns the number of values that were re-encrypted.
+func reencryptConfigValuesInArchive(archiveDir string) (int, error) {
+	count := 0
+	for _, dir := range []string{filepath.Join(archiveDir, "upstream", "userdata"), filepath.Join(archiveDir, "kotsKinds")} {
+		err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "failed to read file %s", path)
+			}
+
+			o := kotsutil.OverlySimpleGVK{}
+			if err := yaml.Unmarshal(contents, &o); err != nil {
+				return nil
+			}
+			if o.APIVersion != "kots.io/v1beta1" || o.Kind != "ConfigValues" {
+				return nil
+			}
+
+			configValues, err := kotsutil.LoadConfigValuesFromBytes(contents)
+			if err != nil {
+				return errors.Wrapf(err, "failed to load config values from %s", path)
+			}
+
+			n := kotsutil.ReencryptConfigValues(configValues)
+			if n == 0 {
+				return nil
+			}
+			count += n
+
+			updated, err := kotsutil.MarshalRuntimeObject(configValues)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal config values")
+			}
+			if err := os.WriteFile(path, updated, info.Mode()); err != nil {
+				return errors.Wrapf(err, "failed to write file %s", path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
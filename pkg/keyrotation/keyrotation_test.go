@@ -0,0 +1,124 @@
+package keyrotation
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRotateKeyInSecret(t *testing.T) {
+	req := require.New(t)
+
+	originalKey, err := crypto.GenerateKey()
+	req.NoError(err)
+	req.NoError(crypto.SetEncryptionKey(originalKey))
+	encryptedWithOriginalKey := crypto.Encrypt([]byte("secret"))
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kotsadm-encryption",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"encryptionKey": []byte(originalKey),
+		},
+	})
+
+	resumed, err := rotateKeyInSecret(clientset, "default")
+	req.NoError(err)
+	assert.False(t, resumed)
+
+	secret, err := clientset.CoreV1().Secrets("default").Get(context.TODO(), "kotsadm-encryption", metav1.GetOptions{})
+	req.NoError(err)
+	newKey := string(secret.Data["encryptionKey"])
+	assert.NotEqual(t, originalKey, newKey)
+	assert.Equal(t, []string{originalKey}, crypto.PreviousKeysFromSecret(secret))
+
+	// the new key is used for encryption and the previous one can still decrypt
+	assert.Equal(t, newKey, crypto.ToString())
+	assert.False(t, crypto.IsEncryptedWithCurrentKey(encryptedWithOriginalKey))
+	decrypted, err := crypto.Decrypt(encryptedWithOriginalKey)
+	req.NoError(err)
+	assert.Equal(t, "secret", string(decrypted))
+
+	// rotating again before the previous keys are retired resumes the incomplete rotation
+	resumed, err = rotateKeyInSecret(clientset, "default")
+	req.NoError(err)
+	assert.True(t, resumed)
+
+	secret, err = clientset.CoreV1().Secrets("default").Get(context.TODO(), "kotsadm-encryption", metav1.GetOptions{})
+	req.NoError(err)
+	assert.Equal(t, newKey, string(secret.Data["encryptionKey"]))
+
+	req.NoError(retirePreviousKeys(clientset, "default"))
+
+	secret, err = clientset.CoreV1().Secrets("default").Get(context.TODO(), "kotsadm-encryption", metav1.GetOptions{})
+	req.NoError(err)
+	assert.Empty(t, crypto.PreviousKeysFromSecret(secret))
+	assert.Equal(t, newKey, string(secret.Data["encryptionKey"]))
+}
+
+func TestReencryptConfigValuesInArchive(t *testing.T) {
+	req := require.New(t)
+
+	req.NoError(crypto.NewAESCipher())
+	encrypted := base64.StdEncoding.EncodeToString(crypto.Encrypt([]byte("secret")))
+
+	configValues := `apiVersion: kots.io/v1beta1
+kind: ConfigValues
+metadata:
+  name: app
+spec:
+  values:
+    password:
+      value: ` + encrypted + `
+    hostname:
+      value: example.com
+`
+
+	archiveDir := t.TempDir()
+	for _, dir := range []string{filepath.Join(archiveDir, "upstream", "userdata"), filepath.Join(archiveDir, "kotsKinds")} {
+		req.NoError(os.MkdirAll(dir, 0755))
+		req.NoError(os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(configValues), 0644))
+		req.NoError(os.WriteFile(filepath.Join(dir, "installation.yaml"), []byte("apiVersion: kots.io/v1beta1\nkind: Installation\n"), 0644))
+	}
+
+	count, err := reencryptConfigValuesInArchive(archiveDir)
+	req.NoError(err)
+	assert.Equal(t, 0, count)
+
+	newKey, err := crypto.GenerateKey()
+	req.NoError(err)
+	req.NoError(crypto.SetEncryptionKey(newKey))
+
+	count, err = reencryptConfigValuesInArchive(archiveDir)
+	req.NoError(err)
+	assert.Equal(t, 2, count)
+
+	for _, dir := range []string{filepath.Join(archiveDir, "upstream", "userdata"), filepath.Join(archiveDir, "kotsKinds")} {
+		updated, err := kotsutil.LoadConfigValuesFromFile(filepath.Join(dir, "config.yaml"))
+		req.NoError(err)
+		assert.Equal(t, "example.com", updated.Spec.Values["hostname"].Value)
+
+		decoded, err := base64.StdEncoding.DecodeString(updated.Spec.Values["password"].Value)
+		req.NoError(err)
+		assert.True(t, crypto.IsEncryptedWithCurrentKey(decoded))
+		decrypted, err := crypto.Decrypt(decoded)
+		req.NoError(err)
+		assert.Equal(t, "secret", string(decrypted))
+	}
+
+	count, err = reencryptConfigValuesInArchive(archiveDir)
+	req.NoError(err)
+	assert.Equal(t, 0, count)
+}
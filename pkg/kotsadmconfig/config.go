@@ -167,6 +167,15 @@ func UpdateAppConfigValues(values map[string]kotsv1beta1.ConfigValue, configGrou
 	return values
 }
 
+// RemoveReferencedConfigValues removes the values of the items that read their value from a reference,
+// since the referenced values are resolved at render time and must never be saved in the config values
+func RemoveReferencedConfigValues(values map[string]kotsv1beta1.ConfigValue, references map[string]kotsutil.ConfigValueReference) map[string]kotsv1beta1.ConfigValue {
+	for name := range references {
+		delete(values, name)
+	}
+	return values
+}
+
 // this is where config values that are passed to the install command are read from
 func ReadConfigValuesFromInClusterSecret() (string, error) {
 	log := logger.NewCLILogger(os.Stdout)
@@ -0,0 +1,131 @@
+package kotsadmconfig
+
+import (
+	"sort"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+)
+
+const (
+	// ConfigChangeSource is the version source of config changes in the admin console and with the kots cli.
+	// what made the change is recorded separately, see GetConfigChangedBy.
+	ConfigChangeSource = "Config Change"
+	// ConfigReferenceChangeSource is the version source of changes to secrets and config maps that config items reference
+	ConfigReferenceChangeSource = "Config Reference Change"
+
+	maskedValue = "********"
+)
+
+const (
+	ChangedByUser      = "user"
+	ChangedByCLI       = "cli"
+	ChangedByRestore   = "restore"
+	ChangedByReference = "reference"
+	ChangedByLicense   = "license"
+	ChangedByUpstream  = "upstream"
+	ChangedByInstall   = "install"
+	ChangedByOther     = "other"
+)
+
+// GetConfigChangedBy returns what changed the config of a version. changedBy is what was recorded for the version,
+// if anything, otherwise it's derived from the version source.
+func GetConfigChangedBy(versionSource string, changedBy string) string {
+	if changedBy != "" {
+		return changedBy
+	}
+
+	switch versionSource {
+	case ConfigChangeSource:
+		return ChangedByUser
+	case ConfigReferenceChangeSource:
+		return ChangedByReference
+	case "License Change":
+		return ChangedByLicense
+	case "Upstream Update", "Airgap Update", "KOTS Upload":
+		return ChangedByUpstream
+	case "Online Install", "Airgap Install":
+		return ChangedByInstall
+	default:
+		return ChangedByOther
+	}
+}
+
+// DiffConfigValues compares the config values of two versions item by item. the values of the masked items,
+// which are usually password items, are not included in the changes. values that were encrypted are expected
+// to be decrypted, since encrypting the same value twice has different results.
+func DiffConfigValues(from *kotsv1beta1.ConfigValues, to *kotsv1beta1.ConfigValues, maskedItems map[string]bool) []configtypes.ConfigItemChange {
+	fromValues := map[string]kotsv1beta1.ConfigValue{}
+	if from != nil {
+		fromValues = from.Spec.Values
+	}
+	toValues := map[string]kotsv1beta1.ConfigValue{}
+	if to != nil {
+		toValues = to.Spec.Values
+	}
+
+	names := map[string]bool{}
+	for name := range fromValues {
+		names[name] = true
+	}
+	for name := range toValues {
+		names[name] = true
+	}
+
+	changes := []configtypes.ConfigItemChange{}
+	for name := range names {
+		fromValue, fromOK := fromValues[name]
+		toValue, toOK := toValues[name]
+
+		change := configtypes.ConfigItemChange{
+			Name: name,
+			// values that were encrypted are secret, even if the config does not say that the item is a password
+			Masked: maskedItems[name] || fromValue.ValuePlaintext != "" || toValue.ValuePlaintext != "",
+		}
+		switch {
+		case !fromOK:
+			change.Change = configtypes.ConfigItemAdded
+		case !toOK:
+			change.Change = configtypes.ConfigItemRemoved
+		case configValueString(fromValue) != configValueString(toValue) || fromValue.Filename != toValue.Filename:
+			change.Change = configtypes.ConfigItemChanged
+		default:
+			continue
+		}
+		if fromOK {
+			change.From = describeConfigValue(fromValue, change.Masked)
+		}
+		if toOK {
+			change.To = describeConfigValue(toValue, change.Masked)
+		}
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// configValueString returns the effective value of the config value
+func configValueString(value kotsv1beta1.ConfigValue) string {
+	if value.ValuePlaintext != "" {
+		return value.ValuePlaintext
+	}
+	if value.Value != "" {
+		return value.Value
+	}
+	return value.Default
+}
+
+func describeConfigValue(value kotsv1beta1.ConfigValue, masked bool) string {
+	if value.Filename != "" {
+		return value.Filename
+	}
+	s := configValueString(value)
+	if masked && s != "" {
+		return maskedValue
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package kotsadmconfig
+
+import (
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DiffConfigValues(t *testing.T) {
+	configValues := func(values map[string]kotsv1beta1.ConfigValue) *kotsv1beta1.ConfigValues {
+		return &kotsv1beta1.ConfigValues{Spec: kotsv1beta1.ConfigValuesSpec{Values: values}}
+	}
+
+	tests := []struct {
+		name        string
+		from        *kotsv1beta1.ConfigValues
+		to          *kotsv1beta1.ConfigValues
+		maskedItems map[string]bool
+		want        []configtypes.ConfigItemChange
+	}{
+		{
+			name: "no changes",
+			from: configValues(map[string]kotsv1beta1.ConfigValue{
+				"hostname": {Value: "app.example.com"},
+				"replicas": {Default: "2"},
+			}),
+			to: configValues(map[string]kotsv1beta1.ConfigValue{
+				"hostname": {Value: "app.example.com"},
+				"replicas": {Default: "2"},
+			}),
+			want: []configtypes.ConfigItemChange{},
+		},
+		{
+			name: "added, removed and changed items",
+			from: configValues(map[string]kotsv1beta1.ConfigValue{
+				"hostname": {Value: "app.example.com"},
+				"replicas": {Default: "2"},
+				"old_item": {Value: "old"},
+			}),
+			to: configValues(map[string]kotsv1beta1.ConfigValue{
+				"hostname": {Value: "www.example.com"},
+				"replicas": {Value: "3", Default: "2"},
+				"new_item": {Default: "new"},
+			}),
+			want: []configtypes.ConfigItemChange{
+				{Name: "hostname", Change: configtypes.ConfigItemChanged, From: "app.example.com", To: "www.example.com"},
+				{Name: "new_item", Change: configtypes.ConfigItemAdded, To: "new"},
+				{Name: "old_item", Change: configtypes.ConfigItemRemoved, From: "old"},
+				{Name: "replicas", Change: configtypes.ConfigItemChanged, From: "2", To: "3"},
+			},
+		},
+		{
+			name: "password and file items",
+			from: configValues(map[string]kotsv1beta1.ConfigValue{
+				"db_password": {ValuePlaintext: "password-1"},
+				"api_token":   {Value: "token-1"},
+				"license":     {Value: "bGljZW5zZS0x", Filename: "license-1.yaml"},
+			}),
+			to: configValues(map[string]kotsv1beta1.ConfigValue{
+				"db_password": {ValuePlaintext: "password-2"},
+				"api_token":   {Value: "token-2"},
+				"license":     {Value: "bGljZW5zZS0y", Filename: "license-2.yaml"},
+			}),
+			maskedItems: map[string]bool{"api_token": true},
+			want: []configtypes.ConfigItemChange{
+				{Name: "api_token", Change: configtypes.ConfigItemChanged, From: maskedValue, To: maskedValue, Masked: true},
+				{Name: "db_password", Change: configtypes.ConfigItemChanged, From: maskedValue, To: maskedValue, Masked: true},
+				{Name: "license", Change: configtypes.ConfigItemChanged, From: "license-1.yaml", To: "license-2.yaml"},
+			},
+		},
+		{
+			name: "version without config values",
+			from: nil,
+			to: configValues(map[string]kotsv1beta1.ConfigValue{
+				"hostname": {Value: "app.example.com"},
+			}),
+			want: []configtypes.ConfigItemChange{
+				{Name: "hostname", Change: configtypes.ConfigItemAdded, To: "app.example.com"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DiffConfigValues(tt.from, tt.to, tt.maskedItems)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_GetConfigChangedBy(t *testing.T) {
+	require.Equal(t, ChangedByUser, GetConfigChangedBy(ConfigChangeSource, ""))
+	require.Equal(t, ChangedByCLI, GetConfigChangedBy(ConfigChangeSource, ChangedByCLI))
+	require.Equal(t, ChangedByRestore, GetConfigChangedBy(ConfigChangeSource, ChangedByRestore))
+	require.Equal(t, ChangedByReference, GetConfigChangedBy(ConfigReferenceChangeSource, ""))
+	require.Equal(t, ChangedByLicense, GetConfigChangedBy("License Change", ""))
+	require.Equal(t, ChangedByUpstream, GetConfigChangedBy("Airgap Update", ""))
+	require.Equal(t, ChangedByInstall, GetConfigChangedBy("Online Install", ""))
+	require.Equal(t, ChangedByOther, GetConfigChangedBy("Registry Change", ""))
+}
@@ -1,5 +1,11 @@
 package validation
 
+import (
+	"time"
+
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+)
+
 const (
 	EmptyItemType     = "" // when type is not set, it defaults to text
 	BoolItemType      = "bool"
@@ -29,3 +35,128 @@ type ConfigItemValidationError struct {
 type ValidationError struct {
 	Message string `json:"message"`
 }
+
+// ConfigItemValidation is the validation of a config item in the Config spec. it is a superset of the
+// kotskinds config item validation, which only has the regex validator.
+type ConfigItemValidation struct {
+	Regex       *kotsv1beta1.RegexValidator `json:"regex,omitempty"`
+	Number      *NumberValidator            `json:"number,omitempty"`
+	IP          *IPValidator                `json:"ip,omitempty"`
+	CIDR        *IPValidator                `json:"cidr,omitempty"`
+	Hostname    *HostnameValidator          `json:"hostname,omitempty"`
+	URL         *URLValidator               `json:"url,omitempty"`
+	Port        *MessageValidator           `json:"port,omitempty"`
+	Email       *MessageValidator           `json:"email,omitempty"`
+	Duration    *DurationValidator          `json:"duration,omitempty"`
+	Certificate *CertificateValidator       `json:"certificate,omitempty"`
+	PrivateKey  *PrivateKeyValidator        `json:"privateKey,omitempty"`
+	JSON        *MessageValidator           `json:"json,omitempty"`
+	YAML        *MessageValidator           `json:"yaml,omitempty"`
+	File        *FileValidator              `json:"file,omitempty"`
+}
+
+// MessageValidator is a validator without options. the message replaces the default validation error message.
+type MessageValidator struct {
+	Message string `json:"message,omitempty"`
+}
+
+type NumberValidator struct {
+	Message string   `json:"message,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	Integer bool     `json:"integer,omitempty"`
+}
+
+type IPValidator struct {
+	Message string `json:"message,omitempty"`
+	// Version is 4 or 6. any version is allowed when it is not set.
+	Version int `json:"version,omitempty"`
+}
+
+type HostnameValidator struct {
+	Message string `json:"message,omitempty"`
+	// FQDN requires a fully qualified domain name with at least two labels
+	FQDN bool `json:"fqdn,omitempty"`
+}
+
+type URLValidator struct {
+	Message string `json:"message,omitempty"`
+	// Schemes are the allowed url schemes. any scheme is allowed when it is empty.
+	Schemes []string `json:"schemes,omitempty"`
+}
+
+type DurationValidator struct {
+	Message string `json:"message,omitempty"`
+	Min     string `json:"min,omitempty"`
+	Max     string `json:"max,omitempty"`
+}
+
+type CertificateValidator struct {
+	Message string `json:"message,omitempty"`
+	// MinValidity is the duration that the certificates must remain valid for, such as 720h
+	MinValidity string `json:"minValidity,omitempty"`
+}
+
+type PrivateKeyValidator struct {
+	Message string `json:"message,omitempty"`
+	// CertificateItem is the name of the config item with the certificate that the key must match
+	CertificateItem string `json:"certificateItem,omitempty"`
+}
+
+type FileValidator struct {
+	Message string `json:"message,omitempty"`
+	// MaxSize is a quantity such as 512Ki or 10Mi
+	MaxSize string `json:"maxSize,omitempty"`
+	// Extensions are the allowed file name extensions, such as .pem
+	Extensions []string `json:"extensions,omitempty"`
+	// MediaTypes are the allowed media types of the content, such as application/zip
+	MediaTypes []string `json:"mediaTypes,omitempty"`
+}
+
+// ConfigValidations are the validations that are declared in the Config spec
+type ConfigValidations struct {
+	// ItemValidations are the validations of the config items by item name
+	ItemValidations map[string]ConfigItemValidation
+	// GroupRules are the validation rules of the config groups by group name
+	GroupRules map[string][]ConfigValidationRule
+	// Rules are the validation rules of the Config spec
+	Rules []ConfigValidationRule
+}
+
+// ConfigValidationRule validates the values of multiple config items
+type ConfigValidationRule struct {
+	// Rule is a template that renders to true when the values are valid, such as
+	// repl{{ ge (ConfigOption "max_replicas" | ParseInt) (ConfigOption "min_replicas" | ParseInt) }}
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+	// Items are the names of the config items that the validation error is attached to
+	Items []string `json:"items"`
+}
+
+const (
+	ConfigItemAdded   = "added"
+	ConfigItemRemoved = "removed"
+	ConfigItemChanged = "changed"
+)
+
+// ConfigItemChange is a change of the value of a config item between two versions
+type ConfigItemChange struct {
+	Name   string `json:"name"`
+	Change string `json:"change"`
+	// From and To are the values before and after the change. file items show the file name,
+	// and the values of password items are masked.
+	From   string `json:"from,omitempty"`
+	To     string `json:"to,omitempty"`
+	Masked bool   `json:"masked,omitempty"`
+}
+
+// ConfigVersionChanges are the config changes of a version compared to the version before it
+type ConfigVersionChanges struct {
+	Sequence int64 `json:"sequence"`
+	// Source is the source of the version, such as Config Change or Upstream Update
+	Source string `json:"source"`
+	// ChangedBy is what changed the config, such as user, cli, license or upstream
+	ChangedBy string             `json:"changedBy"`
+	CreatedOn *time.Time         `json:"createdOn,omitempty"`
+	Changes   []ConfigItemChange `json:"changes"`
+}
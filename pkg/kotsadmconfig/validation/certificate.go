@@ -0,0 +1,146 @@
+package validation
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+const (
+	certificateError        = "Value is not a valid PEM encoded certificate"
+	certificateExpiredError = "Certificate has expired"
+	certificateNotYetError  = "Certificate is not valid yet"
+	privateKeyError         = "Value is not a valid PEM encoded private key"
+	privateKeyMismatchError = "Private key does not match the certificate"
+)
+
+type certificateValidator struct {
+	*configtypes.CertificateValidator
+}
+
+func (v *certificateValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	var minValidity time.Duration
+	if v.MinValidity != "" {
+		d, err := time.ParseDuration(v.MinValidity)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse min validity")
+		}
+		minValidity = d
+	}
+
+	certs, err := parseCertificates(input)
+	if err != nil {
+		return newValidationError(v.Message, certificateError), nil
+	}
+
+	now := time.Now()
+	for _, cert := range certs {
+		if now.Before(cert.NotBefore) {
+			return newValidationError(v.Message, certificateNotYetError), nil
+		}
+		if now.After(cert.NotAfter) {
+			return newValidationError(v.Message, certificateExpiredError), nil
+		}
+		if now.Add(minValidity).After(cert.NotAfter) {
+			return newValidationError(v.Message, fmt.Sprintf("Certificate expires in less than %s", v.MinValidity)), nil
+		}
+	}
+
+	return nil, nil
+}
+
+type privateKeyValidator struct {
+	*configtypes.PrivateKeyValidator
+	// certificate is the value of the certificate item that the key must match
+	certificate string
+}
+
+func (v *privateKeyValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	key, err := parsePrivateKey(input)
+	if err != nil {
+		return newValidationError(v.Message, privateKeyError), nil
+	}
+
+	if v.certificate == "" {
+		return nil, nil
+	}
+	certs, err := parseCertificates(v.certificate)
+	if err != nil {
+		// the certificate item has its own validation
+		return nil, nil
+	}
+
+	publicKey, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publicKey.Equal(certs[0].PublicKey) {
+		return newValidationError(v.Message, privateKeyMismatchError), nil
+	}
+
+	return nil, nil
+}
+
+// parseCertificates returns the certificates in the PEM data. the first certificate is the leaf certificate.
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse certificate")
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("no certificates found")
+	}
+
+	return certs, nil
+}
+
+func parsePrivateKey(data string) (crypto.Signer, error) {
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			return nil, errors.New("no private key found")
+		}
+		if !strings.HasSuffix(block.Type, "PRIVATE KEY") {
+			continue
+		}
+
+		var key interface{}
+		var err error
+		switch block.Type {
+		case "RSA PRIVATE KEY":
+			key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+		case "EC PRIVATE KEY":
+			key, err = x509.ParseECPrivateKey(block.Bytes)
+		default:
+			key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse private key")
+		}
+
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, errors.Errorf("unsupported private key type %T", key)
+		}
+		return signer, nil
+	}
+}
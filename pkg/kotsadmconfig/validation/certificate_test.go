@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+// generateTestCertificate returns a PEM encoded self-signed certificate that expires at notAfter, and its private key
+func generateTestCertificate(notAfter time.Time) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "app.example.com"},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		panic(err)
+	}
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		panic(err)
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
+	return string(certPEM), string(keyPEM)
+}
+
+func Test_certificateValidator_Validate(t *testing.T) {
+	certificate, _ := generateTestCertificate(time.Now().Add(30 * 24 * time.Hour))
+	expiredCertificate, _ := generateTestCertificate(time.Now().Add(-time.Hour))
+
+	tests := []struct {
+		name      string
+		validator *configtypes.CertificateValidator
+		input     string
+		want      *configtypes.ValidationError
+		wantErr   bool
+	}{
+		{
+			name:      "valid certificate",
+			validator: &configtypes.CertificateValidator{},
+			input:     certificate,
+			want:      nil,
+		}, {
+			name:      "certificate chain",
+			validator: &configtypes.CertificateValidator{MinValidity: "24h"},
+			input:     certificate + certificate,
+			want:      nil,
+		}, {
+			name:      "not a certificate",
+			validator: &configtypes.CertificateValidator{},
+			input:     "-----BEGIN CERTIFICATE-----\nnot a certificate\n-----END CERTIFICATE-----\n",
+			want:      &configtypes.ValidationError{Message: certificateError},
+		}, {
+			name:      "expired certificate",
+			validator: &configtypes.CertificateValidator{},
+			input:     expiredCertificate,
+			want:      &configtypes.ValidationError{Message: certificateExpiredError},
+		}, {
+			name:      "certificate expires too soon",
+			validator: &configtypes.CertificateValidator{MinValidity: "2160h"},
+			input:     certificate,
+			want:      &configtypes.ValidationError{Message: "Certificate expires in less than 2160h"},
+		}, {
+			name:      "invalid min validity",
+			validator: &configtypes.CertificateValidator{MinValidity: "90 days"},
+			input:     certificate,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &certificateValidator{
+				CertificateValidator: tt.validator,
+			}
+			got, err := v.Validate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("certificateValidator.Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("certificateValidator.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_privateKeyValidator_Validate(t *testing.T) {
+	certificate, privateKey := generateTestCertificate(time.Now().Add(24 * time.Hour))
+	_, otherPrivateKey := generateTestCertificate(time.Now().Add(24 * time.Hour))
+
+	tests := []struct {
+		name        string
+		validator   *configtypes.PrivateKeyValidator
+		certificate string
+		input       string
+		want        *configtypes.ValidationError
+	}{
+		{
+			name:      "valid private key",
+			validator: &configtypes.PrivateKeyValidator{},
+			input:     privateKey,
+			want:      nil,
+		}, {
+			name:      "not a private key",
+			validator: &configtypes.PrivateKeyValidator{},
+			input:     certificate,
+			want:      &configtypes.ValidationError{Message: privateKeyError},
+		}, {
+			name:        "private key matches the certificate",
+			validator:   &configtypes.PrivateKeyValidator{CertificateItem: "tls_cert"},
+			certificate: certificate,
+			input:       privateKey,
+			want:        nil,
+		}, {
+			name:        "private key does not match the certificate",
+			validator:   &configtypes.PrivateKeyValidator{CertificateItem: "tls_cert", Message: "must be the key of the tls certificate"},
+			certificate: certificate,
+			input:       otherPrivateKey,
+			want:        &configtypes.ValidationError{Message: "must be the key of the tls certificate"},
+		}, {
+			name:        "certificate is not valid",
+			validator:   &configtypes.PrivateKeyValidator{CertificateItem: "tls_cert"},
+			certificate: "not a certificate",
+			input:       privateKey,
+			want:        nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &privateKeyValidator{
+				PrivateKeyValidator: tt.validator,
+				certificate:         tt.certificate,
+			}
+			got, err := v.Validate(tt.input)
+			if err != nil {
+				t.Errorf("privateKeyValidator.Validate() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("privateKeyValidator.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
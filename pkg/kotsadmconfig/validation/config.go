@@ -8,10 +8,18 @@ import (
 	"github.com/replicatedhq/kotskinds/multitype"
 )
 
-func ValidateConfigSpec(configSpec kotsv1beta1.ConfigSpec) ([]configtypes.ConfigGroupValidationError, error) {
+// ValidateConfigSpec validates the item values of the config spec. validations are the validations declared in
+// the Config spec, as returned by kotsutil.ParseConfigSpecExtras. the item validations take precedence over the
+// validations in the config spec, which only have the regex validator.
+func ValidateConfigSpec(configSpec kotsv1beta1.ConfigSpec, validations *configtypes.ConfigValidations) ([]configtypes.ConfigGroupValidationError, error) {
+	if validations == nil {
+		validations = &configtypes.ConfigValidations{}
+	}
+	itemValues := getValidatableItemValues(configSpec)
+
 	var configGroupErrors []configtypes.ConfigGroupValidationError
 	for _, configGroup := range configSpec.Groups {
-		configGroupError, err := validateConfigGroup(configGroup)
+		configGroupError, err := validateConfigGroup(configGroup, validations.ItemValidations, itemValues)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to validate config group %s", configGroup.Name)
 		}
@@ -19,15 +27,21 @@ func ValidateConfigSpec(configSpec kotsv1beta1.ConfigSpec) ([]configtypes.Config
 			configGroupErrors = append(configGroupErrors, *configGroupError)
 		}
 	}
+
+	configGroupErrors, err := validateConfigRules(configSpec, validations, configGroupErrors)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to validate config rules")
+	}
+
 	return configGroupErrors, nil
 }
 
-func validateConfigGroup(configGroup kotsv1beta1.ConfigGroup) (*configtypes.ConfigGroupValidationError, error) {
+func validateConfigGroup(configGroup kotsv1beta1.ConfigGroup, itemValidations map[string]configtypes.ConfigItemValidation, itemValues map[string]string) (*configtypes.ConfigGroupValidationError, error) {
 	if !isValidatableConfigGroup(configGroup) {
 		return nil, nil
 	}
 
-	configItemErrors, err := validateConfigItems(configGroup.Items)
+	configItemErrors, err := validateConfigItems(configGroup.Items, itemValidations, itemValues)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to validate config items")
 	}
@@ -42,10 +56,10 @@ func validateConfigGroup(configGroup kotsv1beta1.ConfigGroup) (*configtypes.Conf
 	}, nil
 }
 
-func validateConfigItems(configItems []kotsv1beta1.ConfigItem) ([]configtypes.ConfigItemValidationError, error) {
+func validateConfigItems(configItems []kotsv1beta1.ConfigItem, itemValidations map[string]configtypes.ConfigItemValidation, itemValues map[string]string) ([]configtypes.ConfigItemValidationError, error) {
 	var configItemErrors []configtypes.ConfigItemValidationError
 	for _, item := range configItems {
-		configItemErr, err := validateConfigItem(item)
+		configItemErr, err := validateConfigItem(item, itemValidations, itemValues)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to validate config item %s", item.Name)
 		}
@@ -58,8 +72,9 @@ func validateConfigItems(configItems []kotsv1beta1.ConfigItem) ([]configtypes.Co
 	return configItemErrors, nil
 }
 
-func validateConfigItem(item kotsv1beta1.ConfigItem) (*configtypes.ConfigItemValidationError, error) {
-	if !isValidatableConfigItem(item) {
+func validateConfigItem(item kotsv1beta1.ConfigItem, itemValidations map[string]configtypes.ConfigItemValidation, itemValues map[string]string) (*configtypes.ConfigItemValidationError, error) {
+	itemValidation := getItemValidation(item, itemValidations)
+	if !isValidatableConfigItem(item, itemValidation) {
 		return nil, nil
 	}
 
@@ -72,7 +87,11 @@ func validateConfigItem(item kotsv1beta1.ConfigItem) (*configtypes.ConfigItemVal
 		return nil, nil
 	}
 
-	validationErrors, err := validate(validatableValue, *item.Validation)
+	opts := validatorOptions{
+		filename:   item.Filename,
+		itemValues: itemValues,
+	}
+	validationErrors, err := validate(validatableValue, *itemValidation, opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to validate value")
 	}
@@ -88,6 +107,26 @@ func validateConfigItem(item kotsv1beta1.ConfigItem) (*configtypes.ConfigItemVal
 	return nil, nil
 }
 
+// getValidatableItemValues returns the values of the items that can be validated, so that validators
+// can compare an item to another item
+func getValidatableItemValues(configSpec kotsv1beta1.ConfigSpec) map[string]string {
+	itemValues := map[string]string{}
+	for _, configGroup := range configSpec.Groups {
+		for _, item := range configGroup.Items {
+			if item.Repeatable || !validatableItemTypesMap[item.Type] {
+				continue
+			}
+			value, err := getValidatableItemValue(item.Value, item.Type)
+			if err != nil {
+				// the item itself fails validation if it has validators
+				continue
+			}
+			itemValues[item.Name] = value
+		}
+	}
+	return itemValues
+}
+
 func getValidatableItemValue(value multitype.BoolOrString, itemType string) (string, error) {
 	switch itemType {
 	case configtypes.TextItemType, configtypes.TextAreaItemType, configtypes.EmptyItemType:
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/replicatedhq/kots/pkg/crypto"
 	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
@@ -273,7 +274,7 @@ func Test_validateConfigItem(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := validateConfigItem(tt.args.item)
+			got, err := validateConfigItem(tt.args.item, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateConfigItem() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -341,7 +342,7 @@ func Test_validateConfigItems(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := validateConfigItems(tt.args.configItems)
+			got, err := validateConfigItems(tt.args.configItems, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateConfigItems() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -459,7 +460,7 @@ func Test_validateConfigGroup(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := validateConfigGroup(tt.args.configGroup)
+			got, err := validateConfigGroup(tt.args.configGroup, nil, nil)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validateConfigGroup() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -472,8 +473,24 @@ func Test_validateConfigGroup(t *testing.T) {
 }
 
 func TestValidateConfigSpec(t *testing.T) {
+	certificate, privateKey := generateTestCertificate(time.Now().Add(24 * time.Hour))
+	_, otherPrivateKey := generateTestCertificate(time.Now().Add(24 * time.Hour))
+	certificateItems := func(key string) []kotsv1beta1.ConfigItem {
+		return []kotsv1beta1.ConfigItem{
+			{Name: "tls_cert", Type: "textarea", Value: multitype.BoolOrString{StrVal: certificate}},
+			{Name: "tls_key", Type: "textarea", Value: multitype.BoolOrString{StrVal: key}},
+		}
+	}
+	certificateValidations := &configtypes.ConfigValidations{
+		ItemValidations: map[string]configtypes.ConfigItemValidation{
+			"tls_cert": {Certificate: &configtypes.CertificateValidator{}},
+			"tls_key":  {PrivateKey: &configtypes.PrivateKeyValidator{CertificateItem: "tls_cert"}},
+		},
+	}
+
 	type args struct {
-		configSpec kotsv1beta1.ConfigSpec
+		configSpec  kotsv1beta1.ConfigSpec
+		validations *configtypes.ConfigValidations
 	}
 	tests := []struct {
 		name    string
@@ -562,11 +579,86 @@ func TestValidateConfigSpec(t *testing.T) {
 					},
 				},
 			},
+		}, {
+			name: "item validations declared in the config spec",
+			args: args{
+				configSpec: kotsv1beta1.ConfigSpec{
+					Groups: []kotsv1beta1.ConfigGroup{
+						{
+							Name: "test",
+							Items: []kotsv1beta1.ConfigItem{
+								{Name: "port", Type: "text", Value: multitype.BoolOrString{StrVal: "80000"}},
+								regexMatchFailedConfigItem,
+							},
+						},
+					},
+				},
+				validations: &configtypes.ConfigValidations{
+					ItemValidations: map[string]configtypes.ConfigItemValidation{
+						"port": {Port: &configtypes.MessageValidator{}},
+						// overrides the regex validation of the item
+						regexMatchFailedConfigItem.Name: {},
+					},
+				},
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "test",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name: "port",
+							Type: "text",
+							ValidationErrors: []configtypes.ValidationError{
+								{
+									Message: portError,
+								},
+							},
+						},
+					},
+				},
+			},
+		}, {
+			name: "private key matches the certificate item",
+			args: args{
+				configSpec: kotsv1beta1.ConfigSpec{
+					Groups: []kotsv1beta1.ConfigGroup{
+						{Name: "tls", Items: certificateItems(privateKey)},
+					},
+				},
+				validations: certificateValidations,
+			},
+			want: nil,
+		}, {
+			name: "private key does not match the certificate item",
+			args: args{
+				configSpec: kotsv1beta1.ConfigSpec{
+					Groups: []kotsv1beta1.ConfigGroup{
+						{Name: "tls", Items: certificateItems(otherPrivateKey)},
+					},
+				},
+				validations: certificateValidations,
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "tls",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name: "tls_key",
+							Type: "textarea",
+							ValidationErrors: []configtypes.ValidationError{
+								{
+									Message: privateKeyMismatchError,
+								},
+							},
+						},
+					},
+				},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ValidateConfigSpec(tt.args.configSpec)
+			got, err := ValidateConfigSpec(tt.args.configSpec, tt.args.validations)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ValidateConfigSpec() error = %v, wantErr %v", err, tt.wantErr)
 				return
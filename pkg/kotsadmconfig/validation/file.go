@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type fileValidator struct {
+	*configtypes.FileValidator
+	// filename is the name of the uploaded file
+	filename string
+}
+
+func (v *fileValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	if v.MaxSize != "" {
+		maxSize, err := resource.ParseQuantity(v.MaxSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse max size")
+		}
+		if int64(len(input)) > maxSize.Value() {
+			return newValidationError(v.Message, fmt.Sprintf("File must not be larger than %s", v.MaxSize)), nil
+		}
+	}
+
+	// the extension can only be checked when the file name is known
+	if len(v.Extensions) > 0 && v.filename != "" && !hasFileExtension(v.filename, v.Extensions) {
+		return newValidationError(v.Message, fmt.Sprintf("File extension must be one of: %s", strings.Join(v.Extensions, ", "))), nil
+	}
+
+	if len(v.MediaTypes) > 0 && !isMediaType(input, v.MediaTypes) {
+		return newValidationError(v.Message, fmt.Sprintf("File type must be one of: %s", strings.Join(v.MediaTypes, ", "))), nil
+	}
+
+	return nil, nil
+}
+
+func hasFileExtension(filename string, extensions []string) bool {
+	ext := filepath.Ext(filename)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(e, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isMediaType returns true if the content is one of the media types. media types can have a
+// wildcard subtype, such as text/*.
+func isMediaType(content string, mediaTypes []string) bool {
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType([]byte(content)))
+	if err != nil {
+		return false
+	}
+	for _, t := range mediaTypes {
+		t = strings.ToLower(t)
+		if t == mediaType {
+			return true
+		}
+		if strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+func Test_fileValidator_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator *configtypes.FileValidator
+		filename  string
+		input     string
+		want      *configtypes.ValidationError
+		wantErr   bool
+	}{
+		{
+			name:      "file within max size",
+			validator: &configtypes.FileValidator{MaxSize: "1Ki"},
+			input:     strings.Repeat("a", 1024),
+			want:      nil,
+		}, {
+			name:      "file larger than max size",
+			validator: &configtypes.FileValidator{MaxSize: "1Ki"},
+			input:     strings.Repeat("a", 1025),
+			want:      &configtypes.ValidationError{Message: "File must not be larger than 1Ki"},
+		}, {
+			name:      "invalid max size",
+			validator: &configtypes.FileValidator{MaxSize: "1 kilobyte"},
+			input:     "a",
+			wantErr:   true,
+		}, {
+			name:      "allowed extension",
+			validator: &configtypes.FileValidator{Extensions: []string{".crt", "pem"}},
+			filename:  "tls.PEM",
+			input:     "a",
+			want:      nil,
+		}, {
+			name:      "extension is not allowed",
+			validator: &configtypes.FileValidator{Extensions: []string{".crt", ".pem"}},
+			filename:  "tls.key",
+			input:     "a",
+			want:      &configtypes.ValidationError{Message: "File extension must be one of: .crt, .pem"},
+		}, {
+			name:      "unknown file name",
+			validator: &configtypes.FileValidator{Extensions: []string{".pem"}},
+			input:     "a",
+			want:      nil,
+		}, {
+			name:      "allowed media type",
+			validator: &configtypes.FileValidator{MediaTypes: []string{"application/zip", "text/*"}},
+			input:     "license: true\n",
+			want:      nil,
+		}, {
+			name:      "media type is not allowed",
+			validator: &configtypes.FileValidator{MediaTypes: []string{"application/zip"}, Message: "must be a zip file"},
+			input:     "license: true\n",
+			want:      &configtypes.ValidationError{Message: "must be a zip file"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &fileValidator{
+				FileValidator: tt.validator,
+				filename:      tt.filename,
+			}
+			got, err := v.Validate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("fileValidator.Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("fileValidator.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	durationError = "Value is not a valid duration"
+	jsonError     = "Value is not valid JSON"
+	yamlError     = "Value is not valid YAML"
+)
+
+type durationValidator struct {
+	*configtypes.DurationValidator
+}
+
+func (v *durationValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	duration, err := time.ParseDuration(strings.TrimSpace(input))
+	if err != nil {
+		return newValidationError(v.Message, durationError), nil
+	}
+
+	if v.Min != "" {
+		minDuration, err := time.ParseDuration(v.Min)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse min duration")
+		}
+		if duration < minDuration {
+			return newValidationError(v.Message, fmt.Sprintf("Duration must be at least %s", v.Min)), nil
+		}
+	}
+
+	if v.Max != "" {
+		maxDuration, err := time.ParseDuration(v.Max)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse max duration")
+		}
+		if duration > maxDuration {
+			return newValidationError(v.Message, fmt.Sprintf("Duration must be at most %s", v.Max)), nil
+		}
+	}
+
+	return nil, nil
+}
+
+type jsonValidator struct {
+	*configtypes.MessageValidator
+}
+
+func (v *jsonValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	if !json.Valid([]byte(input)) {
+		return newValidationError(v.Message, jsonError), nil
+	}
+	return nil, nil
+}
+
+type yamlValidator struct {
+	*configtypes.MessageValidator
+}
+
+func (v *yamlValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	var out interface{}
+	if err := yaml.Unmarshal([]byte(input), &out); err != nil {
+		return newValidationError(v.Message, yamlError), nil
+	}
+	return nil, nil
+}
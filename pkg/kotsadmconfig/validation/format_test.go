@@ -0,0 +1,77 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+func Test_formatValidators_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator
+		input     string
+		want      *configtypes.ValidationError
+		wantErr   bool
+	}{
+		{
+			name:      "duration",
+			validator: &durationValidator{&configtypes.DurationValidator{Min: "1m", Max: "24h"}},
+			input:     "1h30m",
+			want:      nil,
+		}, {
+			name:      "invalid duration",
+			validator: &durationValidator{&configtypes.DurationValidator{}},
+			input:     "1 hour",
+			want:      &configtypes.ValidationError{Message: durationError},
+		}, {
+			name:      "duration less than min",
+			validator: &durationValidator{&configtypes.DurationValidator{Min: "1m"}},
+			input:     "30s",
+			want:      &configtypes.ValidationError{Message: "Duration must be at least 1m"},
+		}, {
+			name:      "duration more than max",
+			validator: &durationValidator{&configtypes.DurationValidator{Max: "24h"}},
+			input:     "25h",
+			want:      &configtypes.ValidationError{Message: "Duration must be at most 24h"},
+		}, {
+			name:      "invalid min duration",
+			validator: &durationValidator{&configtypes.DurationValidator{Min: "1 minute"}},
+			input:     "30s",
+			wantErr:   true,
+		}, {
+			name:      "json",
+			validator: &jsonValidator{&configtypes.MessageValidator{}},
+			input:     `{"replicas": 2}`,
+			want:      nil,
+		}, {
+			name:      "invalid json",
+			validator: &jsonValidator{&configtypes.MessageValidator{}},
+			input:     `{"replicas": 2`,
+			want:      &configtypes.ValidationError{Message: jsonError},
+		}, {
+			name:      "yaml",
+			validator: &yamlValidator{&configtypes.MessageValidator{}},
+			input:     "replicas: 2\nimage:\n  tag: latest\n",
+			want:      nil,
+		}, {
+			name:      "invalid yaml",
+			validator: &yamlValidator{&configtypes.MessageValidator{Message: "must be helm values"}},
+			input:     "replicas: 2\n image: latest\n",
+			want:      &configtypes.ValidationError{Message: "must be helm values"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validator.Validate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package validation
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+const (
+	ipError       = "Value is not a valid IP address"
+	cidrError     = "Value is not a valid CIDR"
+	hostnameError = "Value is not a valid hostname"
+	fqdnError     = "Value is not a fully qualified domain name"
+	urlError      = "Value is not a valid URL"
+	portError     = "Value is not a valid port"
+	emailError    = "Value is not a valid email address"
+)
+
+var (
+	hostnameLabelRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	numericLabelRegex  = regexp.MustCompile(`^[0-9]+$`)
+)
+
+type ipValidator struct {
+	*configtypes.IPValidator
+}
+
+func (v *ipValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	ip := net.ParseIP(input)
+	if ip == nil {
+		return newValidationError(v.Message, ipError), nil
+	}
+
+	if ok, err := isIPVersion(ip, v.Version); err != nil {
+		return nil, err
+	} else if !ok {
+		return newValidationError(v.Message, fmt.Sprintf("Value is not a valid IPv%d address", v.Version)), nil
+	}
+
+	return nil, nil
+}
+
+type cidrValidator struct {
+	*configtypes.IPValidator
+}
+
+func (v *cidrValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	ip, _, err := net.ParseCIDR(input)
+	if err != nil {
+		return newValidationError(v.Message, cidrError), nil
+	}
+
+	if ok, err := isIPVersion(ip, v.Version); err != nil {
+		return nil, err
+	} else if !ok {
+		return newValidationError(v.Message, fmt.Sprintf("Value is not a valid IPv%d CIDR", v.Version)), nil
+	}
+
+	return nil, nil
+}
+
+func isIPVersion(ip net.IP, version int) (bool, error) {
+	switch version {
+	case 0:
+		return true, nil
+	case 4:
+		return ip.To4() != nil, nil
+	case 6:
+		return ip.To4() == nil, nil
+	default:
+		return false, errors.Errorf("unsupported ip version %d", version)
+	}
+}
+
+type hostnameValidator struct {
+	*configtypes.HostnameValidator
+}
+
+func (v *hostnameValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	// a trailing dot is the root of a fully qualified domain name
+	hostname := strings.TrimSuffix(input, ".")
+	if hostname == "" || len(hostname) > 253 {
+		return newValidationError(v.Message, hostnameError), nil
+	}
+
+	labels := strings.Split(hostname, ".")
+	for _, label := range labels {
+		if !hostnameLabelRegex.MatchString(label) {
+			return newValidationError(v.Message, hostnameError), nil
+		}
+	}
+
+	// the top level domain cannot be numeric, so that ip addresses are not domain names
+	if v.FQDN && (len(labels) < 2 || numericLabelRegex.MatchString(labels[len(labels)-1])) {
+		return newValidationError(v.Message, fqdnError), nil
+	}
+
+	return nil, nil
+}
+
+type urlValidator struct {
+	*configtypes.URLValidator
+}
+
+func (v *urlValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	u, err := url.Parse(input)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return newValidationError(v.Message, urlError), nil
+	}
+
+	if len(v.Schemes) == 0 {
+		return nil, nil
+	}
+	for _, scheme := range v.Schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return nil, nil
+		}
+	}
+
+	return newValidationError(v.Message, fmt.Sprintf("URL scheme must be one of: %s", strings.Join(v.Schemes, ", "))), nil
+}
+
+type portValidator struct {
+	*configtypes.MessageValidator
+}
+
+func (v *portValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	port, err := strconv.Atoi(input)
+	if err != nil || port < 1 || port > 65535 {
+		return newValidationError(v.Message, portError), nil
+	}
+	return nil, nil
+}
+
+type emailValidator struct {
+	*configtypes.MessageValidator
+}
+
+func (v *emailValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	// only the address is allowed, without a display name
+	address, err := mail.ParseAddress(input)
+	if err != nil || address.Address != input {
+		return newValidationError(v.Message, emailError), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,137 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+func Test_networkValidators_Validate(t *testing.T) {
+	tests := []struct {
+		name      string
+		validator validator
+		input     string
+		want      *configtypes.ValidationError
+		wantErr   bool
+	}{
+		{
+			name:      "ipv4 address",
+			validator: &ipValidator{&configtypes.IPValidator{}},
+			input:     "10.0.0.1",
+			want:      nil,
+		}, {
+			name:      "ipv6 address",
+			validator: &ipValidator{&configtypes.IPValidator{Version: 6}},
+			input:     "fd00::1",
+			want:      nil,
+		}, {
+			name:      "invalid ip address",
+			validator: &ipValidator{&configtypes.IPValidator{}},
+			input:     "10.0.0.256",
+			want:      &configtypes.ValidationError{Message: ipError},
+		}, {
+			name:      "ipv6 address when ipv4 is required",
+			validator: &ipValidator{&configtypes.IPValidator{Version: 4}},
+			input:     "fd00::1",
+			want:      &configtypes.ValidationError{Message: "Value is not a valid IPv4 address"},
+		}, {
+			name:      "unsupported ip version",
+			validator: &ipValidator{&configtypes.IPValidator{Version: 5}},
+			input:     "10.0.0.1",
+			wantErr:   true,
+		}, {
+			name:      "cidr",
+			validator: &cidrValidator{&configtypes.IPValidator{Version: 4}},
+			input:     "10.96.0.0/12",
+			want:      nil,
+		}, {
+			name:      "ip address is not a cidr",
+			validator: &cidrValidator{&configtypes.IPValidator{Message: "must be a pod cidr"}},
+			input:     "10.96.0.0",
+			want:      &configtypes.ValidationError{Message: "must be a pod cidr"},
+		}, {
+			name:      "hostname",
+			validator: &hostnameValidator{&configtypes.HostnameValidator{}},
+			input:     "postgres",
+			want:      nil,
+		}, {
+			name:      "invalid hostname",
+			validator: &hostnameValidator{&configtypes.HostnameValidator{}},
+			input:     "-postgres.example.com",
+			want:      &configtypes.ValidationError{Message: hostnameError},
+		}, {
+			name:      "fqdn",
+			validator: &hostnameValidator{&configtypes.HostnameValidator{FQDN: true}},
+			input:     "app.example.com.",
+			want:      nil,
+		}, {
+			name:      "hostname is not a fqdn",
+			validator: &hostnameValidator{&configtypes.HostnameValidator{FQDN: true}},
+			input:     "postgres",
+			want:      &configtypes.ValidationError{Message: fqdnError},
+		}, {
+			name:      "ip address is not a fqdn",
+			validator: &hostnameValidator{&configtypes.HostnameValidator{FQDN: true}},
+			input:     "10.0.0.1",
+			want:      &configtypes.ValidationError{Message: fqdnError},
+		}, {
+			name:      "url",
+			validator: &urlValidator{&configtypes.URLValidator{}},
+			input:     "postgres://db.example.com:5432/app",
+			want:      nil,
+		}, {
+			name:      "invalid url",
+			validator: &urlValidator{&configtypes.URLValidator{}},
+			input:     "example.com/app",
+			want:      &configtypes.ValidationError{Message: urlError},
+		}, {
+			name:      "url with allowed scheme",
+			validator: &urlValidator{&configtypes.URLValidator{Schemes: []string{"http", "https"}}},
+			input:     "HTTPS://example.com",
+			want:      nil,
+		}, {
+			name:      "url with scheme that is not allowed",
+			validator: &urlValidator{&configtypes.URLValidator{Schemes: []string{"https"}}},
+			input:     "http://example.com",
+			want:      &configtypes.ValidationError{Message: "URL scheme must be one of: https"},
+		}, {
+			name:      "port",
+			validator: &portValidator{&configtypes.MessageValidator{}},
+			input:     "8443",
+			want:      nil,
+		}, {
+			name:      "port out of range",
+			validator: &portValidator{&configtypes.MessageValidator{}},
+			input:     "65536",
+			want:      &configtypes.ValidationError{Message: portError},
+		}, {
+			name:      "email",
+			validator: &emailValidator{&configtypes.MessageValidator{}},
+			input:     "admin@example.com",
+			want:      nil,
+		}, {
+			name:      "email with display name",
+			validator: &emailValidator{&configtypes.MessageValidator{}},
+			input:     "Admin <admin@example.com>",
+			want:      &configtypes.ValidationError{Message: emailError},
+		}, {
+			name:      "invalid email",
+			validator: &emailValidator{&configtypes.MessageValidator{Message: "must be the admin email"}},
+			input:     "admin",
+			want:      &configtypes.ValidationError{Message: "must be the admin email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.validator.Validate(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
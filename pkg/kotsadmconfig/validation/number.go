@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+const (
+	numberError  = "Value is not a valid number"
+	integerError = "Value is not a whole number"
+)
+
+type numberValidator struct {
+	*configtypes.NumberValidator
+}
+
+func (v *numberValidator) Validate(input string) (*configtypes.ValidationError, error) {
+	number, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return newValidationError(v.Message, numberError), nil
+	}
+
+	if v.Integer && number != math.Trunc(number) {
+		return newValidationError(v.Message, integerError), nil
+	}
+
+	if v.Min != nil && number < *v.Min {
+		return newValidationError(v.Message, fmt.Sprintf("Value must be at least %v", *v.Min)), nil
+	}
+
+	if v.Max != nil && number > *v.Max {
+		return newValidationError(v.Message, fmt.Sprintf("Value must be at most %v", *v.Max)), nil
+	}
+
+	return nil, nil
+}
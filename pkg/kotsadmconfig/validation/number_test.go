@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+)
+
+func Test_numberValidator_Validate(t *testing.T) {
+	min, max := float64(1), float64(10)
+	tests := []struct {
+		name      string
+		validator *configtypes.NumberValidator
+		input     string
+		want      *configtypes.ValidationError
+	}{
+		{
+			name:      "valid number",
+			validator: &configtypes.NumberValidator{},
+			input:     "-1.5",
+			want:      nil,
+		}, {
+			name:      "not a number",
+			validator: &configtypes.NumberValidator{},
+			input:     "ten",
+			want:      &configtypes.ValidationError{Message: numberError},
+		}, {
+			name:      "not a number with message",
+			validator: &configtypes.NumberValidator{Message: "must be the number of replicas"},
+			input:     "NaN",
+			want:      &configtypes.ValidationError{Message: "must be the number of replicas"},
+		}, {
+			name:      "integer",
+			validator: &configtypes.NumberValidator{Integer: true},
+			input:     "3",
+			want:      nil,
+		}, {
+			name:      "not an integer",
+			validator: &configtypes.NumberValidator{Integer: true},
+			input:     "3.5",
+			want:      &configtypes.ValidationError{Message: integerError},
+		}, {
+			name:      "in range",
+			validator: &configtypes.NumberValidator{Min: &min, Max: &max},
+			input:     "10",
+			want:      nil,
+		}, {
+			name:      "less than min",
+			validator: &configtypes.NumberValidator{Min: &min, Max: &max},
+			input:     "0.5",
+			want:      &configtypes.ValidationError{Message: "Value must be at least 1"},
+		}, {
+			name:      "more than max",
+			validator: &configtypes.NumberValidator{Min: &min, Max: &max},
+			input:     "11",
+			want:      &configtypes.ValidationError{Message: "Value must be at most 10"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := &numberValidator{
+				NumberValidator: tt.validator,
+			}
+			got, err := v.Validate(tt.input)
+			if err != nil {
+				t.Errorf("numberValidator.Validate() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("numberValidator.Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
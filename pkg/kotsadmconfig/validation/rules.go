@@ -0,0 +1,161 @@
+package validation
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/template"
+	"github.com/replicatedhq/kots/pkg/util"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+)
+
+const (
+	ruleError = "Value does not satisfy the validation rule"
+)
+
+// validateConfigRules evaluates the validation rules of the config spec and of its groups. the errors of the
+// rules that fail are added to the errors of the items of the rules.
+func validateConfigRules(configSpec kotsv1beta1.ConfigSpec, validations *configtypes.ConfigValidations, configGroupErrors []configtypes.ConfigGroupValidationError) ([]configtypes.ConfigGroupValidationError, error) {
+	rules := append([]configtypes.ConfigValidationRule{}, validations.Rules...)
+	for _, configGroup := range configSpec.Groups {
+		if isValidatableConfigGroup(configGroup) {
+			rules = append(rules, validations.GroupRules[configGroup.Name]...)
+		}
+	}
+	if len(rules) == 0 {
+		return configGroupErrors, nil
+	}
+
+	builder, _, err := template.NewBuilder(template.BuilderOptions{
+		ConfigGroups:   configSpec.Groups,
+		ExistingValues: getRuleItemValues(configSpec),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create template builder")
+	}
+
+	for _, rule := range rules {
+		valid, err := evaluateRule(&builder, rule.Rule)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to evaluate rule %q", rule.Rule)
+		}
+		if valid {
+			continue
+		}
+
+		validationErr := *newValidationError(rule.Message, ruleError)
+		added := false
+		for _, itemName := range rule.Items {
+			configGroup, item := findConfigItem(configSpec, itemName)
+			if item == nil {
+				// the item is not in the request
+				continue
+			}
+			if !isValidatableConfigGroup(*configGroup) || item.Hidden || item.When == "false" {
+				// the value of the item cannot be changed
+				continue
+			}
+			configGroupErrors = addItemValidationError(configGroupErrors, *configGroup, *item, validationErr)
+			added = true
+		}
+		if !added {
+			// the values do not satisfy the rule, but there is no item to show the error on, so it must not pass
+			return nil, errors.Errorf("rule %q failed, but none of its items can be changed: %s", rule.Rule, validationErr.Message)
+		}
+	}
+
+	return configGroupErrors, nil
+}
+
+// evaluateRule renders the rule and returns true if it renders to true
+func evaluateRule(builder *template.Builder, rule string) (bool, error) {
+	rendered, err := builder.String(rule)
+	if err != nil {
+		// a function that fails is usually given a value that is empty or malformed, such as ParseInt,
+		// which means that the rule does not accept the values. other errors, such as syntax errors and
+		// unknown functions, are mistakes in the rule.
+		var templateErr *template.TemplateError
+		if errors.As(err, &templateErr) && strings.HasPrefix(templateErr.Message, "error calling ") {
+			return false, nil
+		}
+		return false, errors.Cause(err)
+	}
+
+	valid, err := strconv.ParseBool(strings.TrimSpace(rendered))
+	if err != nil {
+		return false, errors.Errorf("rule rendered to %q instead of true or false", rendered)
+	}
+
+	return valid, nil
+}
+
+// getRuleItemValues returns the values of the items for rendering the rules. password values are decrypted
+// so that rules can compare them.
+func getRuleItemValues(configSpec kotsv1beta1.ConfigSpec) map[string]template.ItemValue {
+	itemValues := map[string]template.ItemValue{}
+	for _, configGroup := range configSpec.Groups {
+		for _, item := range configGroup.Items {
+			if item.Repeatable {
+				continue
+			}
+			value := item.Value.String()
+			if item.Type == configtypes.PasswordItemType {
+				if decryptedValue, err := util.DecryptConfigValue(value); err == nil {
+					value = decryptedValue
+				}
+			}
+			itemValues[item.Name] = template.ItemValue{
+				Value:    value,
+				Filename: item.Filename,
+			}
+		}
+	}
+	return itemValues
+}
+
+func findConfigItem(configSpec kotsv1beta1.ConfigSpec, itemName string) (*kotsv1beta1.ConfigGroup, *kotsv1beta1.ConfigItem) {
+	for i, configGroup := range configSpec.Groups {
+		for j, item := range configGroup.Items {
+			if item.Name == itemName {
+				return &configSpec.Groups[i], &configSpec.Groups[i].Items[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// addItemValidationError adds the validation error to the errors of the item, keeping the order of the groups
+// and items in which they are added
+func addItemValidationError(configGroupErrors []configtypes.ConfigGroupValidationError, configGroup kotsv1beta1.ConfigGroup, item kotsv1beta1.ConfigItem, validationErr configtypes.ValidationError) []configtypes.ConfigGroupValidationError {
+	groupIndex := -1
+	for i, configGroupError := range configGroupErrors {
+		if configGroupError.Name == configGroup.Name {
+			groupIndex = i
+			break
+		}
+	}
+	if groupIndex == -1 {
+		configGroupErrors = append(configGroupErrors, configtypes.ConfigGroupValidationError{
+			Name:  configGroup.Name,
+			Title: configGroup.Title,
+		})
+		groupIndex = len(configGroupErrors) - 1
+	}
+
+	itemErrors := configGroupErrors[groupIndex].ItemErrors
+	for i, itemError := range itemErrors {
+		if itemError.Name == item.Name {
+			itemErrors[i].ValidationErrors = append(itemErrors[i].ValidationErrors, validationErr)
+			return configGroupErrors
+		}
+	}
+	configGroupErrors[groupIndex].ItemErrors = append(itemErrors, configtypes.ConfigItemValidationError{
+		Name:             item.Name,
+		Type:             item.Type,
+		ValidationErrors: []configtypes.ValidationError{validationErr},
+	})
+
+	return configGroupErrors
+}
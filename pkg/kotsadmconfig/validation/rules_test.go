@@ -0,0 +1,256 @@
+package validation
+
+import (
+	"reflect"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/replicatedhq/kotskinds/multitype"
+)
+
+func Test_validateConfigRules(t *testing.T) {
+	replicasRule := configtypes.ConfigValidationRule{
+		Rule:    `repl{{ ge (ConfigOption "max_replicas" | ParseInt) (ConfigOption "min_replicas" | ParseInt) }}`,
+		Message: "Max replicas must be at least min replicas",
+		Items:   []string{"max_replicas"},
+	}
+	tlsRule := configtypes.ConfigValidationRule{
+		Rule:    `repl{{ or (ConfigOptionEquals "tls_enabled" "0") (and (ConfigOptionNotEquals "tls_cert" "") (ConfigOptionNotEquals "tls_key" "")) }}`,
+		Message: "A certificate and key are required when TLS is enabled",
+		Items:   []string{"tls_cert", "tls_key"},
+	}
+	configSpec := func(minReplicas string, maxReplicas string, tlsEnabled string, tlsKeyHidden bool) kotsv1beta1.ConfigSpec {
+		return kotsv1beta1.ConfigSpec{
+			Groups: []kotsv1beta1.ConfigGroup{
+				{
+					Name: "scaling",
+					Items: []kotsv1beta1.ConfigItem{
+						{Name: "min_replicas", Type: "text", Default: multitype.BoolOrString{StrVal: "1"}, Value: multitype.BoolOrString{StrVal: minReplicas}},
+						{Name: "max_replicas", Type: "text", Value: multitype.BoolOrString{StrVal: maxReplicas}},
+					},
+				},
+				{
+					Name: "tls",
+					Items: []kotsv1beta1.ConfigItem{
+						{Name: "tls_enabled", Type: "bool", Value: multitype.BoolOrString{StrVal: tlsEnabled}},
+						{Name: "tls_cert", Type: "textarea"},
+						{Name: "tls_key", Type: "textarea", Hidden: tlsKeyHidden},
+					},
+				},
+			},
+		}
+	}
+
+	type args struct {
+		configSpec        kotsv1beta1.ConfigSpec
+		validations       *configtypes.ConfigValidations
+		configGroupErrors []configtypes.ConfigGroupValidationError
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    []configtypes.ConfigGroupValidationError
+		wantErr bool
+	}{
+		{
+			name: "rules pass",
+			args: args{
+				configSpec: configSpec("", "3", "0", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{tlsRule},
+					GroupRules: map[string][]configtypes.ConfigValidationRule{
+						"scaling": {replicasRule},
+					},
+				},
+			},
+			want: nil,
+		}, {
+			name: "group rule fails",
+			args: args{
+				configSpec: configSpec("4", "3", "0", false),
+				validations: &configtypes.ConfigValidations{
+					GroupRules: map[string][]configtypes.ConfigValidationRule{
+						"scaling": {replicasRule},
+					},
+				},
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "scaling",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name:             "max_replicas",
+							Type:             "text",
+							ValidationErrors: []configtypes.ValidationError{{Message: replicasRule.Message}},
+						},
+					},
+				},
+			},
+		}, {
+			name: "rule fails to render",
+			args: args{
+				configSpec: configSpec("1", "", "0", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: replicasRule.Rule, Items: replicasRule.Items},
+					},
+				},
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "scaling",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name:             "max_replicas",
+							Type:             "text",
+							ValidationErrors: []configtypes.ValidationError{{Message: ruleError}},
+						},
+					},
+				},
+			},
+		}, {
+			name: "rule fails and is added to the item errors",
+			args: args{
+				configSpec: configSpec("1", "3", "1", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{tlsRule},
+				},
+				configGroupErrors: []configtypes.ConfigGroupValidationError{
+					{
+						Name: "tls",
+						ItemErrors: []configtypes.ConfigItemValidationError{
+							{
+								Name:             "tls_key",
+								Type:             "textarea",
+								ValidationErrors: []configtypes.ValidationError{{Message: privateKeyError}},
+							},
+						},
+					},
+				},
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "tls",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name: "tls_key",
+							Type: "textarea",
+							ValidationErrors: []configtypes.ValidationError{
+								{Message: privateKeyError},
+								{Message: tlsRule.Message},
+							},
+						},
+						{
+							Name:             "tls_cert",
+							Type:             "textarea",
+							ValidationErrors: []configtypes.ValidationError{{Message: tlsRule.Message}},
+						},
+					},
+				},
+			},
+		}, {
+			name: "errors are not added to hidden items",
+			args: args{
+				configSpec: configSpec("1", "3", "1", true),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{tlsRule},
+				},
+			},
+			want: []configtypes.ConfigGroupValidationError{
+				{
+					Name: "tls",
+					ItemErrors: []configtypes.ConfigItemValidationError{
+						{
+							Name:             "tls_cert",
+							Type:             "textarea",
+							ValidationErrors: []configtypes.ValidationError{{Message: tlsRule.Message}},
+						},
+					},
+				},
+			},
+		}, {
+			name: "rule of group that is not validatable",
+			args: args{
+				configSpec: kotsv1beta1.ConfigSpec{
+					Groups: []kotsv1beta1.ConfigGroup{
+						{Name: "scaling", When: "false", Items: configSpec("4", "3", "0", false).Groups[0].Items},
+					},
+				},
+				validations: &configtypes.ConfigValidations{
+					GroupRules: map[string][]configtypes.ConfigValidationRule{
+						"scaling": {replicasRule},
+					},
+				},
+			},
+			want: nil,
+		}, {
+			name: "rule calls an unknown function",
+			args: args{
+				configSpec: configSpec("1", "3", "0", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: `repl{{ ge (ConfigOptoin "max_replicas" | ParseInt) 1 }}`, Items: []string{"max_replicas"}},
+					},
+				},
+			},
+			wantErr: true,
+		}, {
+			name: "rule calls a function with the wrong number of args",
+			args: args{
+				configSpec: configSpec("1", "3", "0", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: `repl{{ ConfigOptionEquals "tls_enabled" }}`, Items: []string{"tls_enabled"}},
+					},
+				},
+			},
+			wantErr: true,
+		}, {
+			name: "rule fails without items",
+			args: args{
+				configSpec: configSpec("1", "3", "1", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: tlsRule.Rule, Message: tlsRule.Message},
+					},
+				},
+			},
+			wantErr: true,
+		}, {
+			name: "rule fails and all of its items are hidden",
+			args: args{
+				configSpec: configSpec("1", "3", "1", true),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: tlsRule.Rule, Message: tlsRule.Message, Items: []string{"tls_key"}},
+					},
+				},
+			},
+			wantErr: true,
+		}, {
+			name: "rule does not render to a bool",
+			args: args{
+				configSpec: configSpec("1", "3", "0", false),
+				validations: &configtypes.ConfigValidations{
+					Rules: []configtypes.ConfigValidationRule{
+						{Rule: `repl{{ ConfigOption "max_replicas" }}`, Items: []string{"max_replicas"}},
+					},
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := validateConfigRules(tt.args.configSpec, tt.args.validations, tt.args.configGroupErrors)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateConfigRules() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateConfigRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
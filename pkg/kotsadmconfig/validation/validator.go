@@ -28,8 +28,31 @@ func isValidatableConfigGroup(group kotsv1beta1.ConfigGroup) bool {
 	return true
 }
 
-func isValidatableConfigItem(item kotsv1beta1.ConfigItem) bool {
-	if item.Validation == nil {
+// validatorOptions are the inputs of the validators other than the item value
+type validatorOptions struct {
+	// filename is the name of the uploaded file of file items
+	filename string
+	// itemValues are the validatable values of all config items by name, for validators that
+	// compare the item to another item
+	itemValues map[string]string
+}
+
+// getItemValidation returns the validation that is declared in the Config spec for the item, falling
+// back to the kotskinds validation, which only has the regex validator
+func getItemValidation(item kotsv1beta1.ConfigItem, itemValidations map[string]configtypes.ConfigItemValidation) *configtypes.ConfigItemValidation {
+	if itemValidation, ok := itemValidations[item.Name]; ok {
+		return &itemValidation
+	}
+	if item.Validation != nil {
+		return &configtypes.ConfigItemValidation{
+			Regex: item.Validation.Regex,
+		}
+	}
+	return nil
+}
+
+func isValidatableConfigItem(item kotsv1beta1.ConfigItem, itemValidation *configtypes.ConfigItemValidation) bool {
+	if itemValidation == nil {
 		return false
 	}
 
@@ -52,9 +75,9 @@ func isValidatableConfigItem(item kotsv1beta1.ConfigItem) bool {
 	return true
 }
 
-func validate(value string, itemValidation kotsv1beta1.ConfigItemValidation) ([]configtypes.ValidationError, error) {
+func validate(value string, itemValidation configtypes.ConfigItemValidation, opts validatorOptions) ([]configtypes.ValidationError, error) {
 	var validationErrs []configtypes.ValidationError
-	validators := buildValidators(itemValidation)
+	validators := buildValidators(itemValidation, opts)
 	for _, v := range validators {
 		validationErr, err := v.Validate(value)
 		if err != nil {
@@ -69,10 +92,64 @@ func validate(value string, itemValidation kotsv1beta1.ConfigItemValidation) ([]
 	return validationErrs, nil
 }
 
-func buildValidators(itemValidator kotsv1beta1.ConfigItemValidation) []validator {
+func buildValidators(itemValidator configtypes.ConfigItemValidation, opts validatorOptions) []validator {
 	var validators []validator
 	if itemValidator.Regex != nil {
 		validators = append(validators, &regexValidator{itemValidator.Regex})
 	}
+	if itemValidator.Number != nil {
+		validators = append(validators, &numberValidator{itemValidator.Number})
+	}
+	if itemValidator.IP != nil {
+		validators = append(validators, &ipValidator{itemValidator.IP})
+	}
+	if itemValidator.CIDR != nil {
+		validators = append(validators, &cidrValidator{itemValidator.CIDR})
+	}
+	if itemValidator.Hostname != nil {
+		validators = append(validators, &hostnameValidator{itemValidator.Hostname})
+	}
+	if itemValidator.URL != nil {
+		validators = append(validators, &urlValidator{itemValidator.URL})
+	}
+	if itemValidator.Port != nil {
+		validators = append(validators, &portValidator{itemValidator.Port})
+	}
+	if itemValidator.Email != nil {
+		validators = append(validators, &emailValidator{itemValidator.Email})
+	}
+	if itemValidator.Duration != nil {
+		validators = append(validators, &durationValidator{itemValidator.Duration})
+	}
+	if itemValidator.Certificate != nil {
+		validators = append(validators, &certificateValidator{itemValidator.Certificate})
+	}
+	if itemValidator.PrivateKey != nil {
+		certificate := ""
+		if itemValidator.PrivateKey.CertificateItem != "" {
+			certificate = opts.itemValues[itemValidator.PrivateKey.CertificateItem]
+		}
+		validators = append(validators, &privateKeyValidator{itemValidator.PrivateKey, certificate})
+	}
+	if itemValidator.JSON != nil {
+		validators = append(validators, &jsonValidator{itemValidator.JSON})
+	}
+	if itemValidator.YAML != nil {
+		validators = append(validators, &yamlValidator{itemValidator.YAML})
+	}
+	if itemValidator.File != nil {
+		validators = append(validators, &fileValidator{itemValidator.File, opts.filename})
+	}
 	return validators
 }
+
+// newValidationError returns a validation error with the message of the validator, or the default
+// message if the validator does not have one
+func newValidationError(message string, defaultMessage string) *configtypes.ValidationError {
+	if message == "" {
+		message = defaultMessage
+	}
+	return &configtypes.ValidationError{
+		Message: message,
+	}
+}
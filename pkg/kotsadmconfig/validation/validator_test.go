@@ -53,7 +53,7 @@ func Test_isValidatableConfigItem(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isValidatableConfigItem(tt.item); got != tt.want {
+			if got := isValidatableConfigItem(tt.item, getItemValidation(tt.item, nil)); got != tt.want {
 				t.Errorf("isValidatableConfigItem() = %v, want %v", got, tt.want)
 			}
 		})
@@ -63,7 +63,7 @@ func Test_isValidatableConfigItem(t *testing.T) {
 func Test_validate(t *testing.T) {
 	type args struct {
 		value     string
-		validator kotsv1beta1.ConfigItemValidation
+		validator configtypes.ConfigItemValidation
 	}
 	tests := []struct {
 		name    string
@@ -75,7 +75,7 @@ func Test_validate(t *testing.T) {
 			name: "valid regex",
 			args: args{
 				value: "foo",
-				validator: kotsv1beta1.ConfigItemValidation{
+				validator: configtypes.ConfigItemValidation{
 					Regex: &kotsv1beta1.RegexValidator{
 						Pattern: ".*",
 						Message: "must be a valid regex",
@@ -87,7 +87,7 @@ func Test_validate(t *testing.T) {
 			name: "invalid regex pattern",
 			args: args{
 				value: "foo",
-				validator: kotsv1beta1.ConfigItemValidation{
+				validator: configtypes.ConfigItemValidation{
 					Regex: &kotsv1beta1.RegexValidator{
 						Pattern: "[",
 						Message: "must be a valid regex",
@@ -100,7 +100,7 @@ func Test_validate(t *testing.T) {
 			name: "invalid value for regex pattern",
 			args: args{
 				value: "foo",
-				validator: kotsv1beta1.ConfigItemValidation{
+				validator: configtypes.ConfigItemValidation{
 					Regex: &kotsv1beta1.RegexValidator{
 						Pattern: "^[A-Z]+$",
 						Message: "must be a valid regex",
@@ -116,14 +116,14 @@ func Test_validate(t *testing.T) {
 			name: "empty item validators",
 			args: args{
 				value:     "foo",
-				validator: kotsv1beta1.ConfigItemValidation{},
+				validator: configtypes.ConfigItemValidation{},
 			},
 			want: nil,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := validate(tt.args.value, tt.args.validator)
+			got, err := validate(tt.args.value, tt.args.validator, validatorOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -137,8 +137,12 @@ func Test_validate(t *testing.T) {
 
 func Test_buildValidators(t *testing.T) {
 	regexpValidator := &kotsv1beta1.RegexValidator{Pattern: ".*"}
+	portValidatorOptions := &configtypes.MessageValidator{}
+	privateKeyValidatorOptions := &configtypes.PrivateKeyValidator{CertificateItem: "tls_cert"}
+	fileValidatorOptions := &configtypes.FileValidator{MaxSize: "1Ki"}
 	type args struct {
-		itemValidator kotsv1beta1.ConfigItemValidation
+		itemValidator configtypes.ConfigItemValidation
+		opts          validatorOptions
 	}
 	tests := []struct {
 		name string
@@ -148,7 +152,7 @@ func Test_buildValidators(t *testing.T) {
 		{
 			name: "regex",
 			args: args{
-				itemValidator: kotsv1beta1.ConfigItemValidation{
+				itemValidator: configtypes.ConfigItemValidation{
 					Regex: regexpValidator,
 				},
 			},
@@ -157,11 +161,31 @@ func Test_buildValidators(t *testing.T) {
 					regexpValidator,
 				},
 			},
+		}, {
+			name: "validators that use the item",
+			args: args{
+				itemValidator: configtypes.ConfigItemValidation{
+					Port:       portValidatorOptions,
+					PrivateKey: privateKeyValidatorOptions,
+					File:       fileValidatorOptions,
+				},
+				opts: validatorOptions{
+					filename: "tls.key",
+					itemValues: map[string]string{
+						"tls_cert": "certificate",
+					},
+				},
+			},
+			want: []validator{
+				&portValidator{portValidatorOptions},
+				&privateKeyValidator{privateKeyValidatorOptions, "certificate"},
+				&fileValidator{fileValidatorOptions, "tls.key"},
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := buildValidators(tt.args.itemValidator); !reflect.DeepEqual(got, tt.want) {
+			if got := buildValidators(tt.args.itemValidator, tt.args.opts); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("buildValidators() = %v, want %v", got, tt.want)
 			}
 		})
@@ -0,0 +1,115 @@
+package kotsutil
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/util"
+	"sigs.k8s.io/yaml"
+)
+
+// ConfigSpecExtras are the parts of the Config spec that the kotskinds Config does not have: the validators other
+// than regex, the validation rules and the value references. they are parsed from the Config spec itself.
+type ConfigSpecExtras struct {
+	Validations     *configtypes.ConfigValidations
+	ValueReferences map[string]ConfigValueReference
+}
+
+// configSpecExtras is the part of the Config spec with the extras
+type configSpecExtras struct {
+	Spec struct {
+		Groups []struct {
+			Name        string                             `json:"name"`
+			Validations []configtypes.ConfigValidationRule `json:"validations,omitempty"`
+			Items       []struct {
+				Name       string                            `json:"name"`
+				Validation *configtypes.ConfigItemValidation `json:"validation,omitempty"`
+				ValueFrom  *ConfigValueReference             `json:"valueFrom,omitempty"`
+			} `json:"items"`
+		} `json:"groups"`
+		Validations []configtypes.ConfigValidationRule `json:"validations,omitempty"`
+	} `json:"spec"`
+}
+
+// ParseConfigSpecExtras returns the extras of the Config spec in content. the item validations and value
+// references are by item name, and the group rules by group name.
+func ParseConfigSpecExtras(content []byte) (*ConfigSpecExtras, error) {
+	spec := configSpecExtras{}
+	if err := yaml.Unmarshal(content, &spec); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal config spec")
+	}
+
+	extras := &ConfigSpecExtras{
+		Validations: &configtypes.ConfigValidations{
+			ItemValidations: map[string]configtypes.ConfigItemValidation{},
+			GroupRules:      map[string][]configtypes.ConfigValidationRule{},
+			Rules:           spec.Spec.Validations,
+		},
+		ValueReferences: map[string]ConfigValueReference{},
+	}
+	for _, group := range spec.Spec.Groups {
+		if len(group.Validations) > 0 {
+			extras.Validations.GroupRules[group.Name] = group.Validations
+		}
+		for _, item := range group.Items {
+			if item.Validation != nil {
+				extras.Validations.ItemValidations[item.Name] = *item.Validation
+			}
+			if item.ValueFrom != nil {
+				extras.ValueReferences[item.Name] = *item.ValueFrom
+			}
+		}
+	}
+
+	return extras, nil
+}
+
+// FindConfigSpecExtrasInPath returns the extras of the Config spec in the given dir, which is usually the upstream
+// dir of a version archive. the extras are empty if there is no Config spec.
+func FindConfigSpecExtrasInPath(fromDir string) (*ConfigSpecExtras, error) {
+	extras := &ConfigSpecExtras{
+		Validations:     &configtypes.ConfigValidations{},
+		ValueReferences: map[string]ConfigValueReference{},
+	}
+	err := filepath.Walk(fromDir,
+		func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				return nil
+			}
+
+			if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+				return nil
+			}
+
+			contents, err := os.ReadFile(path)
+			if err != nil {
+				return errors.Wrap(err, "failed to read file")
+			}
+
+			for _, doc := range util.ConvertToSingleDocs(contents) {
+				if !IsApiVersionKind(doc, "kots.io/v1beta1", "Config") {
+					continue
+				}
+
+				// we only support having one config spec
+				extras, err = ParseConfigSpecExtras(doc)
+				if err != nil {
+					return errors.Wrapf(err, "failed to parse %s", path)
+				}
+				return filepath.SkipAll
+			}
+
+			return nil
+		})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to walk dir")
+	}
+
+	return extras, nil
+}
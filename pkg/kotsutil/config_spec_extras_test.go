@@ -0,0 +1,127 @@
+package kotsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const configSpecWithValidations = `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: config
+spec:
+  validations:
+  - rule: repl{{ ConfigOptionNotEquals "db_host" "" }}
+    message: A database is required
+    items:
+    - db_host
+  groups:
+  - name: database
+    validations:
+    - rule: repl{{ ge (ConfigOption "db_replicas" | ParseInt) 1 }}
+      items:
+      - db_replicas
+    items:
+    - name: db_host
+      type: text
+      validation:
+        hostname:
+          fqdn: true
+    - name: db_replicas
+      type: text
+      default: repl{{ ConfigOption "db_host" | len }}
+      validation:
+        regex:
+          pattern: ^[0-9]+$
+        number:
+          min: 1
+          integer: true
+    - name: db_password
+      type: password
+`
+
+func Test_ParseConfigSpecExtras(t *testing.T) {
+	min := float64(1)
+
+	extras, err := kotsutil.ParseConfigSpecExtras([]byte(configSpecWithValidations))
+	require.NoError(t, err)
+
+	assert.Equal(t, &configtypes.ConfigValidations{
+		ItemValidations: map[string]configtypes.ConfigItemValidation{
+			"db_host": {
+				Hostname: &configtypes.HostnameValidator{FQDN: true},
+			},
+			"db_replicas": {
+				Regex:  &kotsv1beta1.RegexValidator{Pattern: "^[0-9]+$"},
+				Number: &configtypes.NumberValidator{Min: &min, Integer: true},
+			},
+		},
+		GroupRules: map[string][]configtypes.ConfigValidationRule{
+			"database": {
+				{Rule: `repl{{ ge (ConfigOption "db_replicas" | ParseInt) 1 }}`, Items: []string{"db_replicas"}},
+			},
+		},
+		Rules: []configtypes.ConfigValidationRule{
+			{Rule: `repl{{ ConfigOptionNotEquals "db_host" "" }}`, Message: "A database is required", Items: []string{"db_host"}},
+		},
+	}, extras.Validations)
+	assert.Empty(t, extras.ValueReferences)
+
+	extras, err = kotsutil.ParseConfigSpecExtras([]byte(configSpecWithValueReferences))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]kotsutil.ConfigValueReference{
+		"db_password": {
+			SecretKeyRef: &kotsutil.ConfigValueKeyReference{
+				Name: `{{repl ConfigOption "db_secret_name"}}`,
+				Key:  "password",
+			},
+			TriggerUpdate: true,
+		},
+		"db_ca": {
+			File: &kotsutil.ConfigValueFileReference{
+				Path: "/etc/db/ca.crt",
+			},
+		},
+	}, extras.ValueReferences)
+	assert.Empty(t, extras.Validations.ItemValidations)
+}
+
+func Test_FindConfigSpecExtrasInPath(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, "app.yaml"), []byte("apiVersion: kots.io/v1beta1\nkind: Application\nmetadata:\n  name: app\n"), 0644)
+	require.NoError(t, err)
+
+	extras, err := kotsutil.FindConfigSpecExtrasInPath(dir)
+	require.NoError(t, err)
+	assert.Equal(t, &configtypes.ConfigValidations{}, extras.Validations)
+	assert.Empty(t, extras.ValueReferences)
+
+	// config specs are only read from yaml files
+	err = os.WriteFile(filepath.Join(dir, "config.txt"), []byte(configSpecWithValidations), 0644)
+	require.NoError(t, err)
+
+	extras, err = kotsutil.FindConfigSpecExtrasInPath(dir)
+	require.NoError(t, err)
+	assert.Empty(t, extras.Validations.ItemValidations)
+
+	// the config spec can be in a multi-doc file
+	err = os.WriteFile(filepath.Join(dir, "config.yaml"), []byte("apiVersion: apps/v1\nkind: Deployment\n---\n"+configSpecWithValueReferences), 0644)
+	require.NoError(t, err)
+
+	extras, err = kotsutil.FindConfigSpecExtrasInPath(dir)
+	require.NoError(t, err)
+	assert.Len(t, extras.ValueReferences, 2)
+
+	extras, err = kotsutil.FindConfigSpecExtrasInPath(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Equal(t, &configtypes.ConfigValidations{}, extras.Validations)
+	assert.Empty(t, extras.ValueReferences)
+}
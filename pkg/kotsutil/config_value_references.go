@@ -0,0 +1,128 @@
+package kotsutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ConfigValueReference is where the value of a config item is read from at render time instead of the config values.
+// the name, key and path can be templated. the referenced value is never written to the config values.
+type ConfigValueReference struct {
+	SecretKeyRef    *ConfigValueKeyReference  `json:"secretKeyRef,omitempty"`
+	ConfigMapKeyRef *ConfigValueKeyReference  `json:"configMapKeyRef,omitempty"`
+	File            *ConfigValueFileReference `json:"file,omitempty"`
+	// TriggerUpdate creates a new version of the app when the referenced secret or config map key changes
+	TriggerUpdate bool `json:"triggerUpdate,omitempty"`
+}
+
+type ConfigValueKeyReference struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// Namespace defaults to the namespace that the app is rendered for, which is also the only namespace that
+	// can be read from
+	Namespace string `json:"namespace,omitempty"`
+}
+
+type ConfigValueFileReference struct {
+	// Path is absolute or relative to ConfigValueFilesDir, and must be in ConfigValueFilesDir
+	Path string `json:"path"`
+}
+
+// ConfigValueFilesDir is the directory that file references can read from. files that config items reference
+// have to be mounted into it, so that a release can't read any other file in the kotsadm pod.
+var ConfigValueFilesDir = "/etc/kotsadm/config-values"
+
+// ResolveConfigValueReference reads the referenced value. the clientset is only used for secret and config map
+// references, which can only be read from the namespace the app is rendered for.
+func ResolveConfigValueReference(clientset kubernetes.Interface, namespace string, reference ConfigValueReference) (string, error) {
+	switch {
+	case reference.SecretKeyRef != nil:
+		ref := reference.SecretKeyRef
+		if ref.Namespace != "" && ref.Namespace != namespace {
+			return "", errors.Errorf("secret %s must be in namespace %s", ref.Name, namespace)
+		}
+		secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get secret %s", ref.Name)
+		}
+		value, ok := secret.Data[ref.Key]
+		if !ok {
+			return "", errors.Errorf("key %s not found in secret %s", ref.Key, ref.Name)
+		}
+		return string(value), nil
+
+	case reference.ConfigMapKeyRef != nil:
+		ref := reference.ConfigMapKeyRef
+		if ref.Namespace != "" && ref.Namespace != namespace {
+			return "", errors.Errorf("config map %s must be in namespace %s", ref.Name, namespace)
+		}
+		configMap, err := clientset.CoreV1().ConfigMaps(namespace).Get(context.TODO(), ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to get config map %s", ref.Name)
+		}
+		return getConfigMapKey(configMap, ref)
+
+	case reference.File != nil:
+		filePath, err := configValueFilePath(reference.File.Path)
+		if err != nil {
+			return "", err
+		}
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", errors.Wrapf(err, "failed to read file %s", reference.File.Path)
+		}
+		return string(content), nil
+	}
+
+	return "", errors.New("reference must have a secretKeyRef, configMapKeyRef or file")
+}
+
+// configValueFilePath returns the path of the referenced file if it's in ConfigValueFilesDir. symlinks are followed
+// because mounted secrets and config maps are symlinks, but they must not lead out of the dir either.
+func configValueFilePath(filePath string) (string, error) {
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(ConfigValueFilesDir, filePath)
+	}
+	if !isInDir(filePath, ConfigValueFilesDir) {
+		return "", errors.Errorf("file %s must be in %s", filePath, ConfigValueFilesDir)
+	}
+
+	resolvedPath, err := filepath.EvalSymlinks(filePath)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read file %s", filePath)
+	}
+	resolvedDir, err := filepath.EvalSymlinks(ConfigValueFilesDir)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to read dir %s", ConfigValueFilesDir)
+	}
+	if !isInDir(resolvedPath, resolvedDir) {
+		return "", errors.Errorf("file %s must be in %s", filePath, ConfigValueFilesDir)
+	}
+
+	return resolvedPath, nil
+}
+
+func isInDir(filePath string, dir string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(filePath))
+	if err != nil {
+		return false
+	}
+	return rel != "." && rel != ".." && !strings.HasPrefix(rel, "../")
+}
+
+func getConfigMapKey(configMap *corev1.ConfigMap, ref *ConfigValueKeyReference) (string, error) {
+	if value, ok := configMap.Data[ref.Key]; ok {
+		return value, nil
+	}
+	if value, ok := configMap.BinaryData[ref.Key]; ok {
+		return string(value), nil
+	}
+	return "", errors.Errorf("key %s not found in config map %s", ref.Key, ref.Name)
+}
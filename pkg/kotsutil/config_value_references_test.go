@@ -0,0 +1,163 @@
+package kotsutil_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const configSpecWithValueReferences = `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: config
+spec:
+  groups:
+  - name: database
+    items:
+    - name: db_host
+      type: text
+    - name: db_password
+      type: password
+      valueFrom:
+        secretKeyRef:
+          name: '{{repl ConfigOption "db_secret_name"}}'
+          key: password
+        triggerUpdate: true
+    - name: db_ca
+      type: textarea
+      valueFrom:
+        file:
+          path: /etc/db/ca.crt
+`
+
+func Test_ResolveConfigValueReference(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("s3cr3t")},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-creds", Namespace: "other"},
+			Data:       map[string][]byte{"password": []byte("other-s3cr3t")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "db-config", Namespace: "default"},
+			Data:       map[string]string{"host": "db.example.com"},
+			BinaryData: map[string][]byte{"port": []byte("5432")},
+		},
+	)
+
+	filesDir := kotsutil.ConfigValueFilesDir
+	kotsutil.ConfigValueFilesDir = t.TempDir()
+	t.Cleanup(func() {
+		kotsutil.ConfigValueFilesDir = filesDir
+	})
+
+	filePath := filepath.Join(kotsutil.ConfigValueFilesDir, "ca.crt")
+	require.NoError(t, os.WriteFile(filePath, []byte("ca-data"), 0644))
+
+	outsidePath := filepath.Join(t.TempDir(), "outside")
+	require.NoError(t, os.WriteFile(outsidePath, []byte("outside-data"), 0644))
+	require.NoError(t, os.Symlink(outsidePath, filepath.Join(kotsutil.ConfigValueFilesDir, "link")))
+
+	tests := []struct {
+		name      string
+		reference kotsutil.ConfigValueReference
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:      "secret key",
+			reference: kotsutil.ConfigValueReference{SecretKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-creds", Key: "password"}},
+			want:      "s3cr3t",
+		},
+		{
+			name:      "secret key in the app namespace",
+			reference: kotsutil.ConfigValueReference{SecretKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-creds", Key: "password", Namespace: "default"}},
+			want:      "s3cr3t",
+		},
+		{
+			name:      "secret key in another namespace",
+			reference: kotsutil.ConfigValueReference{SecretKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-creds", Key: "password", Namespace: "other"}},
+			wantErr:   true,
+		},
+		{
+			name:      "missing secret key",
+			reference: kotsutil.ConfigValueReference{SecretKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-creds", Key: "username"}},
+			wantErr:   true,
+		},
+		{
+			name:      "missing secret",
+			reference: kotsutil.ConfigValueReference{SecretKeyRef: &kotsutil.ConfigValueKeyReference{Name: "missing", Key: "password"}},
+			wantErr:   true,
+		},
+		{
+			name:      "config map key",
+			reference: kotsutil.ConfigValueReference{ConfigMapKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-config", Key: "host"}},
+			want:      "db.example.com",
+		},
+		{
+			name:      "config map binary key",
+			reference: kotsutil.ConfigValueReference{ConfigMapKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-config", Key: "port"}},
+			want:      "5432",
+		},
+		{
+			name:      "file",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: filePath}},
+			want:      "ca-data",
+		},
+		{
+			name:      "config map key in another namespace",
+			reference: kotsutil.ConfigValueReference{ConfigMapKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-config", Key: "host", Namespace: "other"}},
+			wantErr:   true,
+		},
+		{
+			name:      "relative file",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: "ca.crt"}},
+			want:      "ca-data",
+		},
+		{
+			name:      "missing file",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: "missing"}},
+			wantErr:   true,
+		},
+		{
+			name:      "file outside the files dir",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: outsidePath}},
+			wantErr:   true,
+		},
+		{
+			name:      "relative file outside the files dir",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: "../outside"}},
+			wantErr:   true,
+		},
+		{
+			name:      "symlink out of the files dir",
+			reference: kotsutil.ConfigValueReference{File: &kotsutil.ConfigValueFileReference{Path: "link"}},
+			wantErr:   true,
+		},
+		{
+			name:      "empty reference",
+			reference: kotsutil.ConfigValueReference{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := kotsutil.ResolveConfigValueReference(clientset, "default", tt.reference)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -100,6 +100,9 @@ type KotsKinds struct {
 
 	Config       *kotsv1beta1.Config
 	ConfigValues *kotsv1beta1.ConfigValues
+	// ConfigValueReferences are the value references of the config items by item name,
+	// which are read from the upstream Config spec since the rendered Config does not have them
+	ConfigValueReferences map[string]ConfigValueReference
 
 	Installation kotsv1beta1.Installation
 	License      *licensewrapper.LicenseWrapper // CHANGED: Supports both v1beta1 and v1beta2
@@ -248,6 +251,40 @@ func (k *KotsKinds) DecryptConfigValues() error {
 	return nil
 }
 
+// ReencryptConfigValues re-encrypts the encrypted config values that are not encrypted with the current encryption key.
+// returns the number of values that were re-encrypted.
+func ReencryptConfigValues(configValues *kotsv1beta1.ConfigValues) int {
+	if configValues == nil {
+		return 0
+	}
+
+	count := 0
+	for name, configValue := range configValues.Spec.Values {
+		if configValue.Value == "" {
+			continue
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(configValue.Value)
+		if err != nil {
+			continue
+		}
+		if crypto.IsEncryptedWithCurrentKey(decoded) {
+			continue
+		}
+		decrypted, err := crypto.Decrypt(decoded)
+		if err != nil {
+			// not an encrypted value
+			continue
+		}
+
+		configValue.Value = base64.StdEncoding.EncodeToString(crypto.Encrypt(decrypted))
+		configValues.Spec.Values[name] = configValue
+		count++
+	}
+
+	return count
+}
+
 func (k *KotsKinds) IsMultiNodeEnabled() bool {
 	if k == nil || !k.HasLicense() {
 		return false
@@ -793,6 +830,16 @@ func LoadKotsKindsWithOpts(archive string, opts LoadKotsKindsOptions) (*KotsKind
 		return nil, errors.Wrap(err, "failed to walk upstream dir")
 	}
 
+	if kotsKinds.Config != nil {
+		configSpecExtras, err := FindConfigSpecExtrasInPath(filepath.Join(archive, "upstream"))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to find config value references")
+		}
+		if len(configSpecExtras.ValueReferences) > 0 {
+			kotsKinds.ConfigValueReferences = configSpecExtras.ValueReferences
+		}
+	}
+
 	return &kotsKinds, nil
 }
 
@@ -1237,6 +1284,13 @@ type InstallationParams struct {
 	RequestedChannelSlug   string
 	AdditionalAnnotations  map[string]string
 	AdditionalLabels       map[string]string
+	VersionRetentionCount  int
+	VersionRetentionDays   int
+	DBBackupSchedule       string
+	DBBackupRetentionCount int
+	LoginMaxFailedAttempts int
+	SessionIdleTimeout     time.Duration
+	SessionTrustedProxies  []string
 }
 
 func GetInstallationParams(configMapName string) (InstallationParams, error) {
@@ -1279,6 +1333,17 @@ func GetInstallationParamsWithClientset(clientset kubernetes.Interface, configMa
 	autoConfig.WithMinio, _ = strconv.ParseBool(kotsadmConfigMap.Data["with-minio"])
 	autoConfig.AppVersionLabel = kotsadmConfigMap.Data["app-version-label"]
 	autoConfig.RequestedChannelSlug = kotsadmConfigMap.Data["requested-channel-slug"]
+	autoConfig.VersionRetentionCount, _ = strconv.Atoi(kotsadmConfigMap.Data["version-retention-count"])
+	autoConfig.VersionRetentionDays, _ = strconv.Atoi(kotsadmConfigMap.Data["version-retention-days"])
+	autoConfig.DBBackupSchedule = kotsadmConfigMap.Data["db-backup-schedule"]
+	autoConfig.DBBackupRetentionCount, _ = strconv.Atoi(kotsadmConfigMap.Data["db-backup-retention-count"])
+	autoConfig.LoginMaxFailedAttempts, _ = strconv.Atoi(kotsadmConfigMap.Data["login-max-failed-attempts"])
+	autoConfig.SessionIdleTimeout, _ = time.ParseDuration(kotsadmConfigMap.Data["session-idle-timeout"])
+	for _, proxy := range strings.Split(kotsadmConfigMap.Data["session-trusted-proxies"], ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			autoConfig.SessionTrustedProxies = append(autoConfig.SessionTrustedProxies, proxy)
+		}
+	}
 
 	if enableImageDeletion, ok := kotsadmConfigMap.Data["enable-image-deletion"]; ok {
 		autoConfig.EnableImageDeletion, _ = strconv.ParseBool(enableImageDeletion)
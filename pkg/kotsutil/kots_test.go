@@ -419,6 +419,40 @@ var _ = Describe("Kots", func() {
 		})
 	})
 
+	Describe("ReencryptConfigValues()", func() {
+		It("does not error when config values are missing", func() {
+			Expect(kotsutil.ReencryptConfigValues(nil)).To(Equal(0))
+		})
+
+		It("re-encrypts values that were encrypted with a previous key", func() {
+			Expect(crypto.NewAESCipher()).To(Succeed())
+			encryptedWithOldKey := base64.StdEncoding.EncodeToString(crypto.Encrypt([]byte("secret")))
+
+			newKey, err := crypto.GenerateKey()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(crypto.SetEncryptionKey(newKey)).To(Succeed())
+			encryptedWithNewKey := base64.StdEncoding.EncodeToString(crypto.Encrypt([]byte("secret")))
+
+			configValues := &kotsv1beta1.ConfigValues{
+				Spec: kotsv1beta1.ConfigValuesSpec{
+					Values: map[string]kotsv1beta1.ConfigValue{
+						"old-password": {Value: encryptedWithOldKey},
+						"new-password": {Value: encryptedWithNewKey},
+						"text":         {Value: "not encrypted"},
+						"default":      {Default: "value"},
+					},
+				},
+			}
+
+			Expect(kotsutil.ReencryptConfigValues(configValues)).To(Equal(1))
+			Expect(configValues.Spec.Values["old-password"].Value).To(Equal(encryptedWithNewKey))
+			Expect(configValues.Spec.Values["new-password"].Value).To(Equal(encryptedWithNewKey))
+			Expect(configValues.Spec.Values["text"].Value).To(Equal("not encrypted"))
+
+			Expect(kotsutil.ReencryptConfigValues(configValues)).To(Equal(0))
+		})
+	})
+
 	Describe("DecryptConfigValues()", func() {
 		It("does not error when config values are empty", func() {
 			kotsKind := &kotsutil.KotsKinds{
@@ -1342,17 +1376,24 @@ func TestGetInstallationParamsWithClientset(t *testing.T) {
 						"additional-annotations":    "abc/xyz=test-annotation1,test.annotation/two=test.value/two/test",
 						"additional-labels":         "xyz=label2,abc=123",
 						"app-version-label":         "",
+						"db-backup-retention-count": "7",
+						"db-backup-schedule":        "@daily",
 						"ensure-rbac":               "true",
 						"initial-app-images-pushed": "false",
 						"kots-install-id":           "2liAJUuyAi3Gnyhvi5Arv5BJRZ4",
+						"login-max-failed-attempts": "5",
 						"minio-enabled-snapshots":   "true",
 						"registry-is-read-only":     "false",
 						"requested-channel-slug":    "stable",
+						"session-idle-timeout":      "30m",
+						"session-trusted-proxies":   "10.0.0.0/8, 192.168.1.10",
 						"skip-compatibility-check":  "false",
 						"skip-preflights":           "false",
 						"skip-rbac-check":           "false",
 						"strict-security-context":   "false",
 						"use-minimal-rbac":          "false",
+						"version-retention-count":   "20",
+						"version-retention-days":    "30",
 						"wait-duration":             "2m0s",
 						"with-minio":                "true",
 					},
@@ -1375,6 +1416,13 @@ func TestGetInstallationParamsWithClientset(t *testing.T) {
 				WaitDuration:           time.Minute * 2,
 				WithMinio:              true,
 				RequestedChannelSlug:   "stable",
+				VersionRetentionCount:  20,
+				VersionRetentionDays:   30,
+				DBBackupSchedule:       "@daily",
+				DBBackupRetentionCount: 7,
+				LoginMaxFailedAttempts: 5,
+				SessionIdleTimeout:     30 * time.Minute,
+				SessionTrustedProxies:  []string{"10.0.0.0/8", "192.168.1.10"},
 			},
 		},
 		{
@@ -0,0 +1,216 @@
+package lint
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCrossReferenceKotsKinds = `apiVersion: kots.io/v1beta1
+kind: Application
+metadata:
+  name: app
+spec:
+  title: App
+  statusInformers:
+    - deployment/web
+    - worker/db
+    - statefulset/db
+    - other/service/web
+  additionalImages:
+    - nginx:1.25
+  additionalNamespaces:
+    - extra
+---
+apiVersion: kots.io/v1beta2
+kind: HelmChart
+metadata:
+  name: redis
+spec:
+  chart:
+    name: redis
+    chartVersion: 2.0.0
+  namespace: cache
+---
+apiVersion: velero.io/v1
+kind: Backup
+metadata:
+  name: backup
+spec:
+  includedNamespaces:
+    - data
+`
+
+const testCrossReferenceManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: docker.io/library/nginx:1.25
+      - name: sidecar
+        image: busybox:1.36
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: frontend
+`
+
+func testChartArchive(t *testing.T, name string, version string) string {
+	var buf bytes.Buffer
+	gzw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gzw)
+
+	chartYAML := []byte("apiVersion: v2\nname: " + name + "\nversion: " + version + "\n")
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: name + "/Chart.yaml", Mode: 0644, Size: int64(len(chartYAML)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(chartYAML)
+	require.NoError(t, err)
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gzw.Close())
+	return buf.String()
+}
+
+func TestValidateCrossReferences(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "kots.yaml", Path: "kots.yaml", Content: testCrossReferenceKotsKinds},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testCrossReferenceManifests},
+		{Name: "redis-1.0.0.tgz", Path: "redis-1.0.0.tgz", Content: testChartArchive(t, "redis", "1.0.0")},
+	}
+	renderedFiles, err := specFiles.Separate()
+	require.NoError(t, err)
+
+	lintExpressions, err := validators.ValidateCrossReferences(specFiles, renderedFiles, []types.RenderedManifest{
+		{Path: "manifests.yaml", DocIndex: 0, Content: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: docker.io/library/nginx:1.25
+      - name: sidecar
+        image: busybox:1.36
+`},
+		{Path: "manifests.yaml", DocIndex: 1, Content: "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n  namespace: frontend\n"},
+		{Path: "kots.yaml", DocIndex: 1, ChartPath: "redis/templates/statefulset.yaml", Content: "apiVersion: apps/v1\nkind: StatefulSet\nmetadata:\n  name: db\n"},
+	})
+	require.NoError(t, err)
+
+	line := func(line int) []types.LintExpressionItemPosition {
+		return []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: line}}}
+	}
+	assert.Equal(t, []types.LintExpression{
+		{
+			Rule:      "status-informer-not-found",
+			Type:      "warn",
+			Message:   `Status informer "worker/db" has an unsupported kind "worker"`,
+			Path:      "kots.yaml",
+			Positions: line(9),
+		},
+		{
+			Rule:      "status-informer-not-found",
+			Type:      "warn",
+			Message:   `Status informer "other/service/web" does not match a Service in the release`,
+			Path:      "kots.yaml",
+			Positions: line(11),
+		},
+		{
+			Rule:      "helm-chart-archive-not-found",
+			Type:      "error",
+			Message:   `No Helm chart archive found for chart "redis" version "2.0.0", the release has version(s) 1.0.0`,
+			Path:      "kots.yaml",
+			Positions: line(24),
+		},
+		{
+			Rule:      "unlisted-image",
+			Type:      "warn",
+			Message:   `Image "busybox:1.36" is not listed in the Application additionalImages or the Airgap savedImages`,
+			Path:      "manifests.yaml",
+			Positions: line(12),
+		},
+		{
+			Rule:      "backup-namespace-not-included",
+			Type:      "warn",
+			Message:   `Namespace "frontend" is not included in the Backup includedNamespaces or the Application additionalNamespaces (Backup in kots.yaml)`,
+			Path:      "manifests.yaml",
+			Positions: line(18),
+		},
+		{
+			Rule:      "backup-namespace-not-included",
+			Type:      "warn",
+			Message:   `Namespace "cache" is not included in the Backup includedNamespaces or the Application additionalNamespaces (Backup in kots.yaml)`,
+			Path:      "kots.yaml",
+			Positions: line(25),
+		},
+	}, lintExpressions)
+}
+
+func TestValidateCrossReferencesWithoutRenderedCharts(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "kots.yaml", Path: "kots.yaml", Content: testCrossReferenceKotsKinds},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testCrossReferenceManifests},
+		{Name: "redis-2.0.0.tgz", Path: "redis-2.0.0.tgz", Content: testChartArchive(t, "redis", "2.0.0")},
+	}
+	renderedFiles, err := specFiles.Separate()
+	require.NoError(t, err)
+
+	lintExpressions, err := validators.ValidateCrossReferences(specFiles, renderedFiles, nil)
+	require.NoError(t, err)
+
+	// the resources of the chart are not known without rendering it, so the status informers are not checked
+	rules := []string{}
+	for _, lintExpression := range lintExpressions {
+		rules = append(rules, lintExpression.Rule)
+	}
+	assert.Equal(t, []string{"unlisted-image", "backup-namespace-not-included", "backup-namespace-not-included"}, rules)
+}
+
+func TestLintAndFixStatusInformersNotFound(t *testing.T) {
+	require.NoError(t, InitOPA())
+
+	specFiles := types.SpecFiles{
+		{Name: "app.yaml", Path: "app.yaml", Content: `apiVersion: kots.io/v1beta1
+kind: Application
+metadata:
+  name: app
+spec:
+  title: App
+  statusInformers:
+    - deployment/web
+    # removed in the last release
+    - deployment/api
+    - service/web
+`},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testCrossReferenceManifests},
+	}
+
+	_, fixedFiles, err := LintAndFixSpecFiles(t.Context(), specFiles, LintOptions{})
+	require.NoError(t, err)
+	require.Len(t, fixedFiles, 1)
+	assert.Equal(t, []string{"status-informer-not-found"}, fixedFiles[0].Rules)
+	assert.Equal(t, `apiVersion: kots.io/v1beta1
+kind: Application
+metadata:
+  name: app
+spec:
+  title: App
+  statusInformers:
+    - deployment/web
+    # removed in the last release
+    - service/web
+`, fixedFiles[0].Fixed)
+}
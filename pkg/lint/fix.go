@@ -0,0 +1,555 @@
+package lint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/pmezard/go-difflib/difflib"
+	"github.com/replicatedhq/kots/pkg/appstate"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	log "github.com/sirupsen/logrus"
+	yamlv3 "go.yaml.in/yaml/v3"
+	"gopkg.in/yaml.v2"
+)
+
+// FixedFile is a spec file with the fixes for its lint findings applied
+type FixedFile struct {
+	Path     string   // Path of the file, like in the lint findings
+	Original string   // Content of the file before the fixes
+	Fixed    string   // Content of the file with the fixes applied
+	Rules    []string // Rules of the findings that were fixed
+}
+
+// Diff returns the fixes of the file as a unified diff
+func (f FixedFile) Diff() (string, error) {
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(f.Original),
+		B:        difflib.SplitLines(f.Fixed),
+		FromFile: "a/" + f.Path,
+		ToFile:   "b/" + f.Path,
+		Context:  3,
+	})
+}
+
+// lineEdit replaces the lines from start to end (1-based, inclusive) of a file.
+// an edit that ends before it starts inserts the lines before the start line.
+type lineEdit struct {
+	start int
+	end   int
+	lines []string
+}
+
+// fixer returns the edits that fix the findings of a rule in a yaml document of a file.
+// fixers edit the lines of the file instead of re-encoding the document so that comments,
+// ordering, quoting and indentation of everything else in the file are kept as is.
+// findings are the lint findings of the rule in the file.
+type fixer func(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit
+
+// fixers contains the rules that have automatic fixes
+var fixers = map[string]fixer{
+	"invalid-yaml":                            fixDuplicateKeys,
+	"deprecated-api-version":                  fixAPIVersion(validators.ReplacementAPIVersion),
+	"deprecated-kubernetes-installer-version": fixAPIVersion(replacementInstallerAPIVersion),
+	"annotation-value-type":                   fixAnnotationValues,
+	"application-statusInformers":             fixStatusInformers,
+	"status-informer-not-found":               fixStatusInformersNotFound,
+}
+
+// IsFixable returns true if findings of the rule can be fixed automatically
+func IsFixable(rule string) bool {
+	_, ok := fixers[rule]
+	return ok
+}
+
+// FixSpecFiles fixes the lint findings in the spec files that have automatic fixes and returns the files that changed.
+// Files in archives can not be fixed.
+func FixSpecFiles(specFiles types.SpecFiles, lintExpressions []types.LintExpression) ([]FixedFile, error) {
+	rulesByPath := map[string][]string{}
+	findingsByPathAndRule := map[string][]types.LintExpression{}
+	for _, lintExpression := range lintExpressions {
+		if !IsFixable(lintExpression.Rule) {
+			continue
+		}
+		if !containsString(rulesByPath[lintExpression.Path], lintExpression.Rule) {
+			rulesByPath[lintExpression.Path] = append(rulesByPath[lintExpression.Path], lintExpression.Rule)
+		}
+		key := lintExpression.Path + ":" + lintExpression.Rule
+		findingsByPathAndRule[key] = append(findingsByPathAndRule[key], lintExpression)
+	}
+
+	release, err := specFiles.Unnest().Separate()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to separate multi docs")
+	}
+
+	fixedFiles := []FixedFile{}
+	for _, specFile := range specFiles {
+		rules := rulesByPath[specFile.Path]
+		if len(rules) == 0 || !specFile.IsYAML() || len(specFile.Children) > 0 {
+			continue
+		}
+		sort.Strings(rules)
+
+		docs, err := decodeYAMLNodes(specFile.Content)
+		if err != nil {
+			log.Debugf("failed to parse %s to fix it: %v", specFile.Path, err)
+			continue
+		}
+
+		lines := strings.Split(specFile.Content, "\n")
+		edits := []lineEdit{}
+		fixedRules := []string{}
+		for _, rule := range rules {
+			ruleEdits := []lineEdit{}
+			for _, doc := range docs {
+				ruleEdits = append(ruleEdits, fixers[rule](release, lines, doc, findingsByPathAndRule[specFile.Path+":"+rule])...)
+			}
+			if len(ruleEdits) > 0 {
+				edits = append(edits, ruleEdits...)
+				fixedRules = append(fixedRules, rule)
+			}
+		}
+		if len(edits) == 0 {
+			continue
+		}
+
+		fixedFiles = append(fixedFiles, FixedFile{
+			Path:     specFile.Path,
+			Original: specFile.Content,
+			Fixed:    strings.Join(applyLineEdits(lines, edits), "\n"),
+			Rules:    fixedRules,
+		})
+	}
+
+	return fixedFiles, nil
+}
+
+// maxFixPasses limits how many times the spec files are linted and fixed again, fixes can make
+// findings that were hidden by other findings show up, like when invalid yaml skips the policy validators
+const maxFixPasses = 5
+
+// LintAndFixSpecFiles lints the spec files and fixes the findings that have automatic fixes until no more findings
+// can be fixed. It returns the lint result of the fixed spec files and the files that changed.
+func LintAndFixSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOptions) (*types.LintResult, []FixedFile, error) {
+	specFiles = append(types.SpecFiles{}, specFiles...)
+	fixedFiles := []FixedFile{}
+
+	for pass := 0; ; pass++ {
+		result, err := LintSpecFiles(ctx, specFiles, opts)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to lint spec files")
+		}
+		if pass == maxFixPasses {
+			return result, fixedFiles, nil
+		}
+
+		passFixedFiles, err := FixSpecFiles(specFiles, result.LintExpressions)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to fix spec files")
+		}
+		if len(passFixedFiles) == 0 {
+			return result, fixedFiles, nil
+		}
+
+		for _, passFixedFile := range passFixedFiles {
+			for i := range specFiles {
+				if specFiles[i].Path == passFixedFile.Path {
+					specFiles[i].Content = passFixedFile.Fixed
+				}
+			}
+			fixedFiles = mergeFixedFile(fixedFiles, passFixedFile)
+		}
+	}
+}
+
+// mergeFixedFile adds the fixes of a file to the files that were fixed in previous passes
+func mergeFixedFile(fixedFiles []FixedFile, fixedFile FixedFile) []FixedFile {
+	for i := range fixedFiles {
+		if fixedFiles[i].Path != fixedFile.Path {
+			continue
+		}
+		fixedFiles[i].Fixed = fixedFile.Fixed
+		for _, rule := range fixedFile.Rules {
+			if !containsString(fixedFiles[i].Rules, rule) {
+				fixedFiles[i].Rules = append(fixedFiles[i].Rules, rule)
+			}
+		}
+		sort.Strings(fixedFiles[i].Rules)
+		return fixedFiles
+	}
+	return append(fixedFiles, fixedFile)
+}
+
+func decodeYAMLNodes(content string) ([]*yamlv3.Node, error) {
+	docs := []*yamlv3.Node{}
+
+	decoder := yamlv3.NewDecoder(strings.NewReader(content))
+	for {
+		var doc yamlv3.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, &doc)
+	}
+
+	return docs, nil
+}
+
+// applyLineEdits applies the edits to the lines of a file. edits that overlap a previous edit are dropped,
+// they are fixed by that edit or can be fixed by running the fixes again.
+func applyLineEdits(lines []string, edits []lineEdit) []string {
+	sort.SliceStable(edits, func(i, j int) bool {
+		return edits[i].start < edits[j].start
+	})
+
+	applicable := []lineEdit{}
+	lastLine := 0
+	for _, edit := range edits {
+		if edit.start <= lastLine {
+			continue
+		}
+		applicable = append(applicable, edit)
+		lastLine = edit.end
+	}
+
+	result := append([]string{}, lines...)
+	for i := len(applicable) - 1; i >= 0; i-- {
+		edit := applicable[i]
+		tail := append([]string{}, result[edit.end:]...)
+		result = append(append(result[:edit.start-1], edit.lines...), tail...)
+	}
+
+	return result
+}
+
+// fixDuplicateKeys removes the keys of a mapping that are defined again later in the same mapping.
+// the last definition is kept because it is the one that is used when duplicate keys are allowed.
+func fixDuplicateKeys(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit {
+	edits := []lineEdit{}
+
+	if doc.Kind == yamlv3.MappingNode && doc.Style&yamlv3.FlowStyle == 0 {
+		for i := 0; i+2 < len(doc.Content); i += 2 {
+			key, next := doc.Content[i], doc.Content[i+2]
+			if key.Kind != yamlv3.ScalarNode || key.Value == "<<" || !isKeyRedefined(doc, i) {
+				continue
+			}
+			end := firstLine(next) - 1
+			for end > key.Line && strings.TrimSpace(lines[end-1]) == "" {
+				end--
+			}
+			if end < key.Line {
+				continue
+			}
+			edits = append(edits, lineEdit{start: key.Line, end: end})
+		}
+	}
+
+	for _, child := range doc.Content {
+		edits = append(edits, fixDuplicateKeys(release, lines, child, findings)...)
+	}
+
+	return edits
+}
+
+func isKeyRedefined(mapping *yamlv3.Node, keyIndex int) bool {
+	key := mapping.Content[keyIndex]
+	for i := keyIndex + 2; i < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Kind == yamlv3.ScalarNode && mapping.Content[i].Value == key.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// firstLine returns the first line of a node, including its head comment
+func firstLine(node *yamlv3.Node) int {
+	if node.HeadComment == "" {
+		return node.Line
+	}
+	return node.Line - strings.Count(node.HeadComment, "\n") - 1
+}
+
+// replacementInstallerAPIVersion returns the API version that replaces the deprecated kurl Installer API version
+func replacementInstallerAPIVersion(apiVersion string, kind string) (string, bool) {
+	if apiVersion == "kurl.sh/v1beta1" && kind == "Installer" {
+		return "cluster.kurl.sh/v1beta1", true
+	}
+	return "", false
+}
+
+// fixAPIVersion returns a fixer that replaces deprecated API versions
+func fixAPIVersion(replacementAPIVersion func(apiVersion string, kind string) (string, bool)) fixer {
+	return func(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit {
+		root := documentRoot(doc)
+		apiVersion, kind := mappingValue(root, "apiVersion"), mappingValue(root, "kind")
+		if apiVersion == nil || kind == nil {
+			return nil
+		}
+
+		replacement, ok := replacementAPIVersion(apiVersion.Value, kind.Value)
+		if !ok {
+			return nil
+		}
+
+		// resources without a selector are reported by a rule that has no fix, see validators.RequiresSelector
+		if validators.RequiresSelector(apiVersion.Value, kind.Value) {
+			selector := mappingValue(mappingValue(root, "spec"), "selector")
+			if selector == nil || selector.Kind != yamlv3.MappingNode || len(selector.Content) == 0 {
+				return nil
+			}
+		}
+
+		edit, ok := replaceScalar(lines, apiVersion, quoteLike(apiVersion, replacement))
+		if !ok {
+			return nil
+		}
+		return []lineEdit{edit}
+	}
+}
+
+// fixAnnotationValues quotes the values of annotations that would not be parsed as strings
+func fixAnnotationValues(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit {
+	annotations := mappingValue(mappingValue(documentRoot(doc), "metadata"), "annotations")
+	if annotations == nil || annotations.Kind != yamlv3.MappingNode {
+		return nil
+	}
+
+	edits := []lineEdit{}
+	for i := 1; i < len(annotations.Content); i += 2 {
+		value := annotations.Content[i]
+		if value.Kind != yamlv3.ScalarNode || value.Style != 0 || value.Tag == "!!null" {
+			continue
+		}
+
+		// annotations are parsed like yaml 1.1, where values such as "yes" are not strings either
+		var parsed interface{}
+		if err := yaml.Unmarshal([]byte(value.Value), &parsed); err != nil {
+			continue
+		}
+		if _, ok := parsed.(string); ok {
+			continue
+		}
+
+		if edit, ok := replaceScalar(lines, value, strconv.Quote(value.Value)); ok {
+			edits = append(edits, edit)
+		}
+	}
+
+	return edits
+}
+
+// fixStatusInformers adds status informers for the workloads in the release to an Application that has none
+func fixStatusInformers(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit {
+	root := documentRoot(doc)
+	apiVersion, kind := mappingValue(root, "apiVersion"), mappingValue(root, "kind")
+	if apiVersion == nil || kind == nil || apiVersion.Value != "kots.io/v1beta1" || kind.Value != "Application" {
+		return nil
+	}
+
+	spec := mappingValue(root, "spec")
+	if spec == nil || spec.Kind != yamlv3.MappingNode || spec.Style&yamlv3.FlowStyle != 0 || len(spec.Content) == 0 {
+		return nil
+	}
+	if mappingValue(spec, "statusInformers") != nil {
+		return nil
+	}
+
+	informers := workloadStatusInformers(release)
+	if len(informers) == 0 {
+		return nil
+	}
+
+	firstKey := spec.Content[0]
+	indent := strings.Repeat(" ", firstKey.Column-1)
+	itemIndent := indent + strings.Repeat(" ", sequenceIndent(root))
+
+	insert := []string{indent + "statusInformers:"}
+	for _, informer := range informers {
+		insert = append(insert, itemIndent+"- "+informer)
+	}
+
+	line := firstLine(firstKey)
+	return []lineEdit{{start: line, end: line - 1, lines: insert}}
+}
+
+// fixStatusInformersNotFound removes the status informers that do not match a resource in the release
+func fixStatusInformersNotFound(release types.SpecFiles, lines []string, doc *yamlv3.Node, findings []types.LintExpression) []lineEdit {
+	statusInformers := mappingValue(mappingValue(documentRoot(doc), "spec"), "statusInformers")
+	if statusInformers == nil || statusInformers.Kind != yamlv3.SequenceNode || statusInformers.Style&yamlv3.FlowStyle != 0 {
+		return nil
+	}
+
+	findingLines := map[int]bool{}
+	for _, finding := range findings {
+		for _, position := range finding.Positions {
+			findingLines[position.Start.Line] = true
+		}
+	}
+
+	edits := []lineEdit{}
+	for i, item := range statusInformers.Content {
+		if item.Kind != yamlv3.ScalarNode || !findingLines[item.Line] {
+			continue
+		}
+		// only items that are on their own line are removed
+		if (i > 0 && statusInformers.Content[i-1].Line == item.Line) || (i+1 < len(statusInformers.Content) && statusInformers.Content[i+1].Line == item.Line) {
+			continue
+		}
+		edits = append(edits, lineEdit{start: item.Line, end: item.Line})
+	}
+
+	return edits
+}
+
+// workloadStatusInformers returns status informers for the deployments, statefulsets and daemonsets in the release
+func workloadStatusInformers(release types.SpecFiles) []string {
+	informerKinds := map[string]string{
+		"Deployment":  appstate.DeploymentResourceKind,
+		"StatefulSet": appstate.StatefulSetResourceKind,
+		"DaemonSet":   appstate.DaemonSetResourceKind,
+	}
+
+	informers := []string{}
+	for _, specFile := range release {
+		if !specFile.IsYAML() {
+			continue
+		}
+
+		var doc types.GVKDoc
+		if err := yaml.Unmarshal([]byte(specFile.Content), &doc); err != nil {
+			continue
+		}
+
+		informerKind, ok := informerKinds[doc.Kind]
+		if !ok || doc.Metadata.Name == "" {
+			continue
+		}
+		// templated names can not be written as plain yaml safely
+		if strings.Contains(doc.Metadata.Name, "{{") || strings.Contains(doc.Metadata.Namespace, "{{") {
+			continue
+		}
+
+		informer := fmt.Sprintf("%s/%s", informerKind, doc.Metadata.Name)
+		if doc.Metadata.Namespace != "" {
+			informer = fmt.Sprintf("%s/%s", doc.Metadata.Namespace, informer)
+		}
+		if !containsString(informers, informer) {
+			informers = append(informers, informer)
+		}
+	}
+
+	return informers
+}
+
+// sequenceIndent returns how many spaces the block sequences of a document are indented relative to their key,
+// defaulting to two when the document has none
+func sequenceIndent(node *yamlv3.Node) int {
+	if indent, ok := findSequenceIndent(node); ok {
+		return indent
+	}
+	return 2
+}
+
+func findSequenceIndent(node *yamlv3.Node) (int, bool) {
+	if node.Kind == yamlv3.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yamlv3.SequenceNode && value.Style&yamlv3.FlowStyle == 0 && len(value.Content) > 0 && value.Line > key.Line {
+				return value.Column - key.Column, true
+			}
+		}
+	}
+	for _, child := range node.Content {
+		if indent, ok := findSequenceIndent(child); ok {
+			return indent, true
+		}
+	}
+	return 0, false
+}
+
+func documentRoot(doc *yamlv3.Node) *yamlv3.Node {
+	if doc == nil || doc.Kind != yamlv3.DocumentNode || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// mappingValue returns the value of a key in a mapping node, or nil if the node is not a mapping or has no such key
+func mappingValue(node *yamlv3.Node, key string) *yamlv3.Node {
+	if node == nil || node.Kind != yamlv3.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// replaceScalar returns an edit that replaces a single line scalar with the given text
+func replaceScalar(lines []string, node *yamlv3.Node, text string) (lineEdit, bool) {
+	if node.Line < 1 || node.Line > len(lines) {
+		return lineEdit{}, false
+	}
+
+	line := lines[node.Line-1]
+	start := node.Column - 1
+	if start < 0 || start >= len(line) {
+		return lineEdit{}, false
+	}
+
+	end := -1
+	switch node.Style {
+	case 0:
+		if strings.HasPrefix(line[start:], node.Value) {
+			end = start + len(node.Value)
+		}
+	case yamlv3.DoubleQuotedStyle, yamlv3.SingleQuotedStyle:
+		quote := line[start]
+		// only values without escaped characters are replaced
+		if closing := strings.IndexByte(line[start+1:], quote); closing == len(node.Value) && line[start+1:start+1+closing] == node.Value {
+			end = start + closing + 2
+		}
+	}
+	if end == -1 {
+		return lineEdit{}, false
+	}
+
+	return lineEdit{
+		start: node.Line,
+		end:   node.Line,
+		lines: []string{line[:start] + text + line[end:]},
+	}, true
+}
+
+// quoteLike quotes a value in the same style as a scalar node
+func quoteLike(node *yamlv3.Node, value string) string {
+	switch node.Style {
+	case yamlv3.DoubleQuotedStyle:
+		return `"` + value + `"`
+	case yamlv3.SingleQuotedStyle:
+		return `'` + value + `'`
+	default:
+		return value
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
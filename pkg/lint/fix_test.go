@@ -0,0 +1,232 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testFixApplication = `apiVersion: kots.io/v1beta1
+kind: Application
+metadata:
+  name: app
+spec:
+  # the title is shown in the admin console
+  title: App
+  icon: https://example.com/icon.png
+`
+
+const testFixManifests = `# deployment of the app
+apiVersion: apps/v1beta2 # migrated later
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    kots.io/creation-phase: 10
+    kots.io/exclude: "false"
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: app
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+---
+apiVersion: "policy/v1beta1"
+kind: PodDisruptionBudget
+metadata:
+  name: app
+  namespace: app
+spec:
+  selector:
+    matchLabels:
+      app: app
+  minAvailable: 1
+  minAvailable: 2
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: data
+spec:
+  serviceName: db
+  # replicas of the database
+  replicas: 1
+  replicas: 2
+`
+
+func TestFixSpecFiles(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "app.yaml", Path: "app.yaml", Content: testFixApplication},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testFixManifests},
+	}
+
+	lintExpressions := []types.LintExpression{
+		{Rule: "application-statusInformers", Path: "app.yaml"},
+		{Rule: "application-icon", Path: "app.yaml"},
+		{Rule: "deprecated-api-version", Path: "manifests.yaml"},
+		{Rule: "annotation-value-type", Path: "manifests.yaml"},
+		{Rule: "invalid-yaml", Path: "manifests.yaml"},
+	}
+
+	fixedFiles, err := FixSpecFiles(specFiles, lintExpressions)
+	require.NoError(t, err)
+	require.Len(t, fixedFiles, 2)
+
+	assert.Equal(t, "app.yaml", fixedFiles[0].Path)
+	assert.Equal(t, []string{"application-statusInformers"}, fixedFiles[0].Rules)
+	assert.Equal(t, `apiVersion: kots.io/v1beta1
+kind: Application
+metadata:
+  name: app
+spec:
+  statusInformers:
+    - deployment/app
+    - data/statefulset/db
+  # the title is shown in the admin console
+  title: App
+  icon: https://example.com/icon.png
+`, fixedFiles[0].Fixed)
+
+	assert.Equal(t, "manifests.yaml", fixedFiles[1].Path)
+	assert.Equal(t, []string{"annotation-value-type", "deprecated-api-version", "invalid-yaml"}, fixedFiles[1].Rules)
+	assert.Equal(t, `# deployment of the app
+apiVersion: apps/v1 # migrated later
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    kots.io/creation-phase: "10"
+    kots.io/exclude: "false"
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: app
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+---
+apiVersion: "policy/v1"
+kind: PodDisruptionBudget
+metadata:
+  name: app
+  namespace: app
+spec:
+  selector:
+    matchLabels:
+      app: app
+  minAvailable: 2
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+  namespace: data
+spec:
+  serviceName: db
+  # replicas of the database
+  replicas: 2
+`, fixedFiles[1].Fixed)
+
+	diff, err := fixedFiles[1].Diff()
+	require.NoError(t, err)
+	assert.Contains(t, diff, "--- a/manifests.yaml\n+++ b/manifests.yaml\n")
+	assert.Contains(t, diff, "-apiVersion: apps/v1beta2 # migrated later\n+apiVersion: apps/v1 # migrated later\n")
+}
+
+func TestFixSpecFilesWithoutSelector(t *testing.T) {
+	manifests := `apiVersion: apps/v1beta2
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+---
+apiVersion: policy/v1beta1
+kind: PodDisruptionBudget
+metadata:
+  name: app
+spec:
+  selector: {}
+  minAvailable: 1
+`
+	specFiles := types.SpecFiles{
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: manifests},
+	}
+
+	// the apiVersion can't be updated without changing the resources, so the findings have no fix
+	lintExpressions, err := validators.ValidateAPIVersions(specFiles)
+	require.NoError(t, err)
+	require.Len(t, lintExpressions, 2)
+	for _, lintExpression := range lintExpressions {
+		assert.Equal(t, "deprecated-api-version-selector", lintExpression.Rule)
+		assert.False(t, IsFixable(lintExpression.Rule))
+	}
+	assert.Equal(t, "API version 'apps/v1beta2' of Deployment is deprecated. Use 'apps/v1' instead and set spec.selector, which 'apps/v1' requires.", lintExpressions[0].Message)
+
+	fixedFiles, err := FixSpecFiles(specFiles, []types.LintExpression{
+		{Rule: "deprecated-api-version", Path: "manifests.yaml"},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, fixedFiles)
+}
+
+func TestFixSpecFilesOnlyFixesFilesWithFindings(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testFixManifests},
+		{Name: "installer.yaml", Path: "installer.yaml", Content: "apiVersion: kurl.sh/v1beta1\nkind: Installer\nmetadata:\n  name: installer\n"},
+	}
+
+	fixedFiles, err := FixSpecFiles(specFiles, []types.LintExpression{
+		{Rule: "deprecated-kubernetes-installer-version", Path: "installer.yaml"},
+	})
+	require.NoError(t, err)
+	require.Len(t, fixedFiles, 1)
+	assert.Equal(t, "installer.yaml", fixedFiles[0].Path)
+	assert.Equal(t, "apiVersion: cluster.kurl.sh/v1beta1\nkind: Installer\nmetadata:\n  name: installer\n", fixedFiles[0].Fixed)
+}
+
+func TestLintAndFixSpecFiles(t *testing.T) {
+	require.NoError(t, InitOPA())
+
+	specFiles := types.SpecFiles{
+		{Name: "app.yaml", Path: "app.yaml", Content: testFixApplication},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testFixManifests},
+	}
+
+	// duplicate keys skip the policy validators, so the missing status informers are only found
+	// and fixed after the first pass has fixed the duplicate keys
+	result, fixedFiles, err := LintAndFixSpecFiles(t.Context(), specFiles, LintOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, fixedFiles, 2)
+	assert.Equal(t, "manifests.yaml", fixedFiles[0].Path)
+	assert.Equal(t, testFixManifests, fixedFiles[0].Original)
+	assert.Equal(t, []string{"annotation-value-type", "deprecated-api-version", "invalid-yaml"}, fixedFiles[0].Rules)
+	assert.Equal(t, "app.yaml", fixedFiles[1].Path)
+	assert.Equal(t, []string{"application-statusInformers"}, fixedFiles[1].Rules)
+
+	for _, lintExpression := range result.LintExpressions {
+		assert.False(t, IsFixable(lintExpression.Rule), "finding %s was not fixed", lintExpression.Rule)
+	}
+
+	// the spec files passed in are not changed
+	assert.Equal(t, testFixManifests, specFiles[1].Content)
+}
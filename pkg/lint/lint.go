@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 
+	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/lint/types"
 	"github.com/replicatedhq/kots/pkg/lint/validators"
 	log "github.com/sirupsen/logrus"
@@ -11,8 +12,13 @@ import (
 
 // LintOptions contains options for linting
 type LintOptions struct {
-	SkipNetworkChecks bool // Skip checks that require network (e.g., version validation)
-	Verbose           bool // Show detailed progress for each validator
+	SkipNetworkChecks bool     // Skip checks that require network (e.g., version validation)
+	Verbose           bool     // Show detailed progress for each validator
+	PolicyDirs        []string // Directories with custom rego policies to evaluate in addition to the built-in ones
+	Render            bool     // Render the release like it is deployed and validate the rendered manifests against the Kubernetes schemas
+	ConfigValuesFile  string   // ConfigValues file to render the release with
+	LicenseFile       string   // License file to render the release with
+	KubernetesVersion string   // Kubernetes version to render the release for and to validate the rendered manifests against
 }
 
 // InitOPA initializes the OPA linting engine
@@ -80,9 +86,14 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 		}
 	}
 
+	totalSteps := 8
+	if opts.Render {
+		totalSteps = 9
+	}
+
 	// Step 1: YAML Syntax Validation
 	if opts.Verbose {
-		log.Info("Running validator 1/5: YAML Syntax...")
+		log.Infof("Running validator 1/%d: YAML Syntax...", totalSteps)
 	}
 	yamlLintExpressions := validators.ValidateYAML(yamlFiles)
 	if opts.Verbose {
@@ -94,7 +105,7 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 	opaNonRenderedLintExpressions := []types.LintExpression{}
 	if !hasErrors(yamlLintExpressions) {
 		if opts.Verbose {
-			log.Info("Running validator 2/5: OPA Policies...")
+			log.Infof("Running validator 2/%d: OPA Policies...", totalSteps)
 		}
 		var err error
 		opaNonRenderedLintExpressions, err = validators.ValidateOPANonRendered(yamlFiles)
@@ -105,7 +116,7 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 			log.Infof("  ✓ OPA Policies: %d issue(s)", len(opaNonRenderedLintExpressions))
 		}
 	} else if opts.Verbose {
-		log.Info("Skipping validator 2/5: OPA Policies (invalid YAML)")
+		log.Infof("Skipping validator 2/%d: OPA Policies (invalid YAML)", totalSteps)
 	}
 
 	// Step 3: Template Rendering Validation
@@ -114,7 +125,7 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 	renderedFiles := yamlFiles // Default to original files
 	if !hasErrors(yamlLintExpressions) {
 		if opts.Verbose {
-			log.Info("Running validator 3/5: Template Rendering...")
+			log.Infof("Running validator 3/%d: Template Rendering...", totalSteps)
 		}
 		var err error
 		renderContentLintExpressions, renderedFiles, err = validators.ValidateRendering(yamlFiles)
@@ -125,12 +136,12 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 			log.Infof("  ✓ Template Rendering: %d issue(s)", len(renderContentLintExpressions))
 		}
 	} else if opts.Verbose {
-		log.Info("Skipping validator 3/5: Template Rendering (invalid YAML)")
+		log.Infof("Skipping validator 3/%d: Template Rendering (invalid YAML)", totalSteps)
 	}
 
 	// Step 4: Rendered YAML Validity
 	if opts.Verbose {
-		log.Info("Running validator 4/5: Rendered YAML Validity...")
+		log.Infof("Running validator 4/%d: Rendered YAML Validity...", totalSteps)
 	}
 	renderedYAMLLintExpressions := validators.ValidateRenderedYAML(renderedFiles)
 	if opts.Verbose {
@@ -139,7 +150,7 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 
 	// Step 5: Resource Annotations Validation
 	if opts.Verbose {
-		log.Info("Running validator 5/5: Resource Annotations...")
+		log.Infof("Running validator 5/%d: Resource Annotations...", totalSteps)
 	}
 	resourceAnnotationsLintExpressions, err := validators.ValidateAnnotations(renderedFiles)
 	if err != nil {
@@ -150,6 +161,106 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 		log.Infof("  ✓ Resource Annotations: %d issue(s)", len(resourceAnnotationsLintExpressions))
 	}
 
+	// Step 6: API Versions Validation
+	if opts.Verbose {
+		log.Infof("Running validator 6/%d: API Versions...", totalSteps)
+	}
+	apiVersionsLintExpressions, err := validators.ValidateAPIVersions(yamlFiles)
+	if err != nil {
+		log.Warnf("API Versions validator failed: %v", err)
+		apiVersionsLintExpressions = []types.LintExpression{}
+	}
+	if opts.Verbose {
+		log.Infof("  ✓ API Versions: %d issue(s)", len(apiVersionsLintExpressions))
+	}
+
+	// Step 7: Custom Policies
+	// Skip if YAML is invalid (can't parse)
+	lintConfig := findLintConfig(yamlFiles)
+	customPolicyLintExpressions := []types.LintExpression{}
+	if !hasErrors(yamlLintExpressions) {
+		policies := policiesFromSpecFiles(unnestedFiles, lintConfig.Policies)
+		for _, policyDir := range opts.PolicyDirs {
+			dirPolicies, err := LoadPoliciesFromDirectory(policyDir)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to load policies from %s", policyDir)
+			}
+			for name, content := range dirPolicies {
+				policies[name] = content
+			}
+		}
+
+		if opts.Verbose {
+			log.Infof("Running validator 7/%d: Custom Policies (%d module(s))...", totalSteps, len(policies))
+		}
+		customPolicyLintExpressions, err = validators.ValidateOPACustom(policies, yamlFiles, renderedFiles)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to validate custom policies")
+		}
+		if opts.Verbose {
+			log.Infof("  ✓ Custom Policies: %d issue(s)", len(customPolicyLintExpressions))
+		}
+	} else if opts.Verbose {
+		log.Infof("Skipping validator 7/%d: Custom Policies (invalid YAML)", totalSteps)
+	}
+
+	// Step 8: Release Rendering and Kubernetes Schemas
+	// Skip if YAML is invalid or templates can't be rendered
+	kubernetesSchemaLintExpressions := []types.LintExpression{}
+	var manifests []types.RenderedManifest
+	if opts.Render && !hasErrors(yamlLintExpressions) && !hasErrors(renderContentLintExpressions) {
+		kubernetesVersion := opts.KubernetesVersion
+		if kubernetesVersion == "" {
+			kubernetesVersion = validators.DefaultKubernetesVersion
+		}
+		if _, err := validators.KubernetesSchemaVersion(kubernetesVersion); err != nil {
+			return nil, err
+		}
+
+		if opts.Verbose {
+			log.Infof("Running validator 8/%d: Kubernetes Schemas (Kubernetes %s)...", totalSteps, kubernetesVersion)
+		}
+		manifests, err = renderRelease(unnestedFiles, opts, kubernetesVersion)
+		if err != nil {
+			manifests = nil
+			kubernetesSchemaLintExpressions = append(kubernetesSchemaLintExpressions, types.LintExpression{
+				Rule:    "unable-to-render-release",
+				Type:    "error",
+				Message: err.Error(),
+			})
+		} else {
+			kubernetesSchemaLintExpressions, err = validators.ValidateKubernetesSchemas(manifests, yamlFiles, kubernetesVersion)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to validate kubernetes schemas")
+			}
+		}
+		if opts.Verbose {
+			log.Infof("  ✓ Kubernetes Schemas: %d issue(s)", len(kubernetesSchemaLintExpressions))
+		}
+	} else if opts.Render && opts.Verbose {
+		log.Infof("Skipping validator 8/%d: Kubernetes Schemas (invalid YAML or templates)", totalSteps)
+	}
+
+	// Last step: Cross References, against the rendered release when it was rendered
+	// Skip if YAML is invalid (can't parse)
+	step := totalSteps
+	crossReferenceLintExpressions := []types.LintExpression{}
+	if !hasErrors(yamlLintExpressions) {
+		if opts.Verbose {
+			log.Infof("Running validator %d/%d: Cross References...", step, totalSteps)
+		}
+		crossReferenceLintExpressions, err = validators.ValidateCrossReferences(unnestedFiles, renderedFiles, manifests)
+		if err != nil {
+			log.Warnf("Cross References validator failed: %v", err)
+			crossReferenceLintExpressions = []types.LintExpression{}
+		}
+		if opts.Verbose {
+			log.Infof("  ✓ Cross References: %d issue(s)", len(crossReferenceLintExpressions))
+		}
+	} else if opts.Verbose {
+		log.Infof("Skipping validator %d/%d: Cross References (invalid YAML)", step, totalSteps)
+	}
+
 	// Collect all lint expressions
 	allLintExpressions := []types.LintExpression{}
 	allLintExpressions = append(allLintExpressions, yamlLintExpressions...)
@@ -157,6 +268,13 @@ func LintSpecFiles(ctx context.Context, specFiles types.SpecFiles, opts LintOpti
 	allLintExpressions = append(allLintExpressions, renderContentLintExpressions...)
 	allLintExpressions = append(allLintExpressions, renderedYAMLLintExpressions...)
 	allLintExpressions = append(allLintExpressions, resourceAnnotationsLintExpressions...)
+	allLintExpressions = append(allLintExpressions, apiVersionsLintExpressions...)
+	allLintExpressions = append(allLintExpressions, customPolicyLintExpressions...)
+	allLintExpressions = append(allLintExpressions, kubernetesSchemaLintExpressions...)
+	allLintExpressions = append(allLintExpressions, crossReferenceLintExpressions...)
+
+	// Apply LintConfig rules, including the ones for specific files
+	allLintExpressions = applyLintConfigRules(allLintExpressions, lintConfig.Rules)
 
 	result.LintExpressions = allLintExpressions
 	result.IsComplete = true
@@ -0,0 +1,125 @@
+package lint
+
+import (
+	"path"
+	"strings"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"sigs.k8s.io/yaml"
+)
+
+// lintConfig is the part of the LintConfig spec that is applied outside of the built-in rego policy
+type lintConfig struct {
+	Rules []lintConfigRule `json:"rules"`
+	// Policies are the paths of custom rego policies in the application, or of directories with custom rego policies
+	Policies []string `json:"policies"`
+}
+
+type lintConfigRule struct {
+	Name  string `json:"name"`
+	Level string `json:"level,omitempty"`
+	// Paths are glob patterns of the files that the rule applies to. the rule applies to all files if there are none.
+	Paths []string `json:"paths,omitempty"`
+}
+
+// findLintConfig returns the LintConfig spec in the files, or an empty one if there is none
+func findLintConfig(files types.SpecFiles) lintConfig {
+	separatedFiles, err := files.Separate()
+	if err != nil {
+		return lintConfig{}
+	}
+
+	for _, file := range separatedFiles {
+		if !file.IsYAML() {
+			continue
+		}
+
+		doc := struct {
+			APIVersion string     `json:"apiVersion"`
+			Kind       string     `json:"kind"`
+			Spec       lintConfig `json:"spec"`
+		}{}
+		if err := yaml.Unmarshal([]byte(file.Content), &doc); err != nil {
+			continue
+		}
+		if doc.APIVersion == "kots.io/v1beta1" && doc.Kind == "LintConfig" {
+			return doc.Spec
+		}
+	}
+
+	return lintConfig{}
+}
+
+// policiesFromSpecFiles returns the rego modules in the files that are at or under one of the policy paths
+func policiesFromSpecFiles(files types.SpecFiles, policyPaths []string) map[string]string {
+	policies := map[string]string{}
+	for _, file := range files {
+		if path.Ext(file.Path) != ".rego" {
+			continue
+		}
+		for _, policyPath := range policyPaths {
+			policyPath = strings.TrimSuffix(path.Clean(strings.TrimPrefix(policyPath, "./")), "/")
+			if file.Path == policyPath || strings.HasPrefix(file.Path, policyPath+"/") || policyPath == "." {
+				policies[file.Path] = file.Content
+				break
+			}
+		}
+	}
+	return policies
+}
+
+// applyLintConfigRules overrides the levels of lint expressions with the LintConfig rules, and removes the
+// lint expressions of rules that are off. rules for specific files take precedence over rules for all files.
+func applyLintConfigRules(lintExpressions []types.LintExpression, rules []lintConfigRule) []types.LintExpression {
+	if len(rules) == 0 {
+		return lintExpressions
+	}
+
+	applied := []types.LintExpression{}
+	for _, lintExpression := range lintExpressions {
+		level := ""
+		for _, rule := range rules {
+			if rule.Name != lintExpression.Rule || !isValidLintLevel(rule.Level) {
+				continue
+			}
+			if len(rule.Paths) == 0 {
+				if level == "" {
+					level = rule.Level
+				}
+				continue
+			}
+			if matchesAnyPath(lintExpression.Path, rule.Paths) {
+				level = rule.Level
+				break
+			}
+		}
+
+		if level == "off" {
+			continue
+		}
+		if level != "" {
+			lintExpression.Type = level
+		}
+		applied = append(applied, lintExpression)
+	}
+
+	return applied
+}
+
+func isValidLintLevel(level string) bool {
+	switch level {
+	case "error", "warn", "info", "off":
+		return true
+	default:
+		return false
+	}
+}
+
+func matchesAnyPath(filePath string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(strings.TrimPrefix(pattern, "./"), filePath); matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,190 @@
+package lint
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLintConfig = `apiVersion: kots.io/v1beta1
+kind: LintConfig
+metadata:
+  name: lint-config
+spec:
+  policies:
+  - ./policies
+  rules:
+  - name: preflight-spec
+    level: "off"
+  - name: image-tag
+    level: warn
+  - name: image-tag
+    level: "off"
+    paths:
+    - legacy/*.yaml
+`
+
+const testImageTagPolicy = `package kots.custom.images
+
+lint[output] {
+  file := input[_]
+  file.rendered
+  doc := yaml.unmarshal(file.content)
+  container := doc.spec.template.spec.containers[_]
+  endswith(container.image, ":latest")
+  output := {
+    "rule": "image-tag",
+    "type": "error",
+    "message": sprintf("image %s uses the latest tag", [container.image]),
+    "path": file.path,
+    "docIndex": file.docIndex
+  }
+}
+`
+
+const testDeployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx:latest
+`
+
+func Test_findLintConfig(t *testing.T) {
+	config := findLintConfig(types.SpecFiles{
+		{Name: "deployment.yaml", Path: "deployment.yaml", Content: testDeployment},
+		{Name: "lintconfig.yaml", Path: "lintconfig.yaml", Content: testLintConfig},
+	})
+
+	assert.Equal(t, []string{"./policies"}, config.Policies)
+	require.Len(t, config.Rules, 3)
+	assert.Equal(t, lintConfigRule{Name: "image-tag", Level: "off", Paths: []string{"legacy/*.yaml"}}, config.Rules[2])
+
+	assert.Equal(t, lintConfig{}, findLintConfig(types.SpecFiles{
+		{Name: "deployment.yaml", Path: "deployment.yaml", Content: testDeployment},
+	}))
+}
+
+func Test_policiesFromSpecFiles(t *testing.T) {
+	files := types.SpecFiles{
+		{Path: "policies/images.rego", Content: "images"},
+		{Path: "policies/nested/labels.rego", Content: "labels"},
+		{Path: "policies/readme.md", Content: "readme"},
+		{Path: "other/other.rego", Content: "other"},
+		{Path: "single.rego", Content: "single"},
+	}
+
+	assert.Equal(t, map[string]string{
+		"policies/images.rego":        "images",
+		"policies/nested/labels.rego": "labels",
+		"single.rego":                 "single",
+	}, policiesFromSpecFiles(files, []string{"./policies/", "single.rego"}))
+
+	assert.Empty(t, policiesFromSpecFiles(files, nil))
+}
+
+func Test_applyLintConfigRules(t *testing.T) {
+	rules := findLintConfig(types.SpecFiles{{Path: "lintconfig.yaml", Content: testLintConfig}}).Rules
+
+	lintExpressions := []types.LintExpression{
+		{Rule: "image-tag", Type: "error", Path: "deployment.yaml"},
+		{Rule: "image-tag", Type: "error", Path: "legacy/deployment.yaml"},
+		{Rule: "preflight-spec", Type: "warn"},
+		{Rule: "invalid-yaml", Type: "error", Path: "legacy/deployment.yaml"},
+	}
+
+	assert.Equal(t, []types.LintExpression{
+		{Rule: "image-tag", Type: "warn", Path: "deployment.yaml"},
+		{Rule: "invalid-yaml", Type: "error", Path: "legacy/deployment.yaml"},
+	}, applyLintConfigRules(lintExpressions, rules))
+}
+
+func Test_ValidateOPACustom(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "deployment.yaml", Path: "deployment.yaml", Content: testDeployment},
+	}
+	renderedFiles, err := specFiles.Separate()
+	require.NoError(t, err)
+
+	policies := map[string]string{"policies/images.rego": testImageTagPolicy}
+
+	lintExpressions, err := validators.ValidateOPACustom(policies, specFiles, renderedFiles)
+	require.NoError(t, err)
+	require.Len(t, lintExpressions, 1)
+	assert.Equal(t, "image-tag", lintExpressions[0].Rule)
+	assert.Equal(t, "error", lintExpressions[0].Type)
+	assert.Equal(t, "deployment.yaml", lintExpressions[0].Path)
+	assert.Equal(t, "image nginx:latest uses the latest tag", lintExpressions[0].Message)
+
+	// no policies
+	lintExpressions, err = validators.ValidateOPACustom(nil, specFiles, renderedFiles)
+	require.NoError(t, err)
+	assert.Empty(t, lintExpressions)
+
+	// invalid policy
+	_, err = validators.ValidateOPACustom(map[string]string{"invalid.rego": "package kots.custom.invalid\nlint["}, specFiles, renderedFiles)
+	assert.Error(t, err)
+
+	// policies can't reach the network or read the environment
+	for _, call := range []string{
+		`http.send({"method": "get", "url": "http://169.254.169.254/"})`,
+		`net.lookup_ip_addr("example.com")`,
+		`opa.runtime()`,
+	} {
+		policy := fmt.Sprintf("package kots.custom.denied\n\nlint[output] {\n  x := %s\n  output := {\"rule\": \"denied\", \"type\": \"error\", \"message\": sprintf(\"%%v\", [x])}\n}\n", call)
+		_, err = validators.ValidateOPACustom(map[string]string{"denied.rego": policy}, specFiles, renderedFiles)
+		assert.ErrorContains(t, err, "undefined function", call)
+	}
+}
+
+func TestLoadPoliciesFromDirectory(t *testing.T) {
+	tmpDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "images.rego"), []byte(testImageTagPolicy), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "README.md"), []byte("policies"), 0644))
+
+	policies, err := LoadPoliciesFromDirectory(tmpDir)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		filepath.ToSlash(filepath.Join(tmpDir, "images.rego")): testImageTagPolicy,
+	}, policies)
+
+	_, err = LoadPoliciesFromDirectory(filepath.Join(tmpDir, "missing"))
+	assert.Error(t, err)
+}
+
+func Test_builtInRulesWithPathsAreNotGlobal(t *testing.T) {
+	require.NoError(t, InitOPA())
+
+	lintConfigWithPaths := `apiVersion: kots.io/v1beta1
+kind: LintConfig
+metadata:
+  name: lint-config
+spec:
+  rules:
+  - name: preflight-spec
+    level: "off"
+    paths:
+    - legacy/*.yaml
+`
+	lintExpressions, err := validators.ValidateOPANonRendered(types.SpecFiles{
+		{Name: "deployment.yaml", Path: "deployment.yaml", Content: testDeployment},
+		{Name: "lintconfig.yaml", Path: "lintconfig.yaml", Content: lintConfigWithPaths},
+	})
+	require.NoError(t, err)
+
+	rules := []string{}
+	for _, lintExpression := range lintExpressions {
+		rules = append(rules, lintExpression.Rule)
+	}
+	assert.Contains(t, rules, "preflight-spec")
+}
@@ -104,3 +104,32 @@ func LoadFiles(path string) (types.SpecFiles, error) {
 
 	return types.SpecFiles{specFile}, nil
 }
+
+// LoadPoliciesFromDirectory loads the rego modules in a directory on the filesystem, by their path
+func LoadPoliciesFromDirectory(dirPath string) (map[string]string, error) {
+	policies := map[string]string{}
+
+	err := filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".rego" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "failed to read file %s", path)
+		}
+
+		policies[filepath.ToSlash(path)] = string(content)
+		return nil
+	})
+
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to walk directory %s", dirPath)
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,335 @@
+package lint
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/apparchive"
+	"github.com/replicatedhq/kots/pkg/base"
+	imagetypes "github.com/replicatedhq/kots/pkg/image/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/logger"
+	upstreamtypes "github.com/replicatedhq/kots/pkg/upstream/types"
+	"github.com/replicatedhq/kots/pkg/util"
+	"k8s.io/apimachinery/pkg/util/version"
+	k8sversion "k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// releaseRenderDownstream is the downstream that v1beta2 Helm charts are rendered for
+	releaseRenderDownstream = "this-cluster"
+)
+
+var (
+	// releaseDocPathRegexp matches the upstream paths of the release documents, including the ones that were split into
+	// multiple base files when rendering, such as doc-3-2.yaml
+	releaseDocPathRegexp = regexp.MustCompile(`^doc-(\d+)(?:-\d+)?\.yaml$`)
+	chartSourceRegexp    = regexp.MustCompile(`(?m)^# Source: (.+)$`)
+)
+
+// releaseDoc is a document of a spec file in the release
+type releaseDoc struct {
+	Path     string
+	DocIndex int
+}
+
+// renderRelease renders the release the same way that it is rendered when deployed, with the sample config values and
+// license, and returns the rendered manifests along with the spec files they were rendered from. cluster lookups in
+// the templates are made against an empty cluster of the Kubernetes version, and Helm charts are rendered offline.
+func renderRelease(specFiles types.SpecFiles, opts LintOptions, kubernetesVersion string) ([]types.RenderedManifest, error) {
+	separatedFiles, err := specFiles.Separate()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to separate multi docs")
+	}
+
+	// each document is rendered as its own upstream file so that the rendered manifests can be mapped back to it
+	docs := []releaseDoc{}
+	u := &upstreamtypes.Upstream{
+		Name:  "lint",
+		Type:  "replicated",
+		Files: []upstreamtypes.UpstreamFile{},
+	}
+	for _, file := range separatedFiles {
+		if file.IsTarGz() {
+			content, err := base64.StdEncoding.DecodeString(file.Content)
+			if err != nil {
+				// the content of archives that are loaded from directories is not base64 encoded
+				content = []byte(file.Content)
+			}
+			u.Files = append(u.Files, upstreamtypes.UpstreamFile{Path: file.Path, Content: content})
+			continue
+		}
+		if !file.IsYAML() {
+			continue
+		}
+		u.Files = append(u.Files, upstreamtypes.UpstreamFile{
+			Path:    fmt.Sprintf("doc-%d.yaml", len(docs)),
+			Content: []byte(file.Content),
+		})
+		docs = append(docs, releaseDoc{Path: file.Path, DocIndex: file.DocIndex})
+	}
+
+	for _, userdataFile := range []string{opts.ConfigValuesFile, opts.LicenseFile} {
+		if userdataFile == "" {
+			continue
+		}
+		content, err := os.ReadFile(userdataFile)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s", userdataFile)
+		}
+		u.Files = append(u.Files, upstreamtypes.UpstreamFile{
+			Path:    path.Join("userdata", filepath.Base(userdataFile)),
+			Content: content,
+		})
+	}
+
+	clientset, err := newReleaseRenderClientset(kubernetesVersion)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	log := logger.NewCLILogger(io.Discard)
+	renderOptions := base.RenderOptions{
+		SplitMultiDocYAML: true,
+		ExcludeKotsKinds:  true,
+		Log:               log,
+		AppSlug:           "app-slug",
+		Clientset:         clientset,
+	}
+
+	renderedKotsKindsMap, err := base.RenderKotsKinds(u, &renderOptions)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render kots kinds")
+	}
+	renderedKotsKinds, err := kotsutil.KotsKindsFromMap(renderedKotsKindsMap)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load rendered kots kinds")
+	}
+
+	commonBase, helmBases, err := base.RenderUpstream(u, &renderOptions, renderedKotsKinds)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render upstream")
+	}
+
+	chartDocs := helmChartDocs(renderedKotsKindsMap, docs)
+
+	manifests := []types.RenderedManifest{}
+	for _, baseFile := range commonBase.Files {
+		doc, ok := releaseDocForPath(baseFile.Path, docs)
+		if !ok {
+			continue
+		}
+		manifests = append(manifests, types.RenderedManifest{
+			Path:     doc.Path,
+			DocIndex: doc.DocIndex,
+			Content:  string(baseFile.Content),
+		})
+	}
+
+	// v1beta1 charts are rendered as bases, in the common base unless they use helm install
+	chartBases := append([]base.Base{}, helmBases...)
+	for _, b := range commonBase.Bases {
+		chartBases = append(chartBases, b)
+		chartBases = append(chartBases, nestedBases(b)...)
+	}
+	for _, b := range chartBases {
+		// the path of a chart base is charts/<release name>, or charts/<release name>/charts/<subchart> for subcharts
+		parts := strings.Split(b.Path, "/")
+		if len(parts) < 2 {
+			continue
+		}
+		doc, ok := chartDocs[parts[1]]
+		if !ok {
+			continue
+		}
+		for _, baseFile := range b.Files {
+			manifests = append(manifests, types.RenderedManifest{
+				Path:      doc.Path,
+				DocIndex:  doc.DocIndex,
+				Content:   string(baseFile.Content),
+				ChartPath: path.Join(b.Path, baseFile.Path),
+			})
+		}
+	}
+
+	v1Beta2Manifests, err := renderV1Beta2HelmCharts(u, &renderOptions, renderedKotsKinds, chartDocs)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to render v1beta2 helm charts")
+	}
+	manifests = append(manifests, v1Beta2Manifests...)
+
+	return manifests, nil
+}
+
+// renderV1Beta2HelmCharts templates the v1beta2 charts with their rendered values, like they are templated for the
+// rendered directory of a version
+func renderV1Beta2HelmCharts(u *upstreamtypes.Upstream, renderOptions *base.RenderOptions, renderedKotsKinds *kotsutil.KotsKinds, chartDocs map[string]releaseDoc) ([]types.RenderedManifest, error) {
+	if renderedKotsKinds.V1Beta2HelmCharts == nil || len(renderedKotsKinds.V1Beta2HelmCharts.Items) == 0 {
+		return nil, nil
+	}
+
+	rootDir, err := os.MkdirTemp("", "kots-lint")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(rootDir)
+
+	writeUpstreamOptions := upstreamtypes.WriteOptions{
+		RootDir: rootDir,
+	}
+	if err := apparchive.WriteV1Beta2HelmCharts(apparchive.WriteV1Beta2HelmChartsOptions{
+		Upstream:             u,
+		WriteUpstreamOptions: writeUpstreamOptions,
+		RenderOptions:        renderOptions,
+		ProcessImageOptions:  imagetypes.ProcessImageOptions{},
+		KotsKinds:            renderedKotsKinds,
+		Clientset:            renderOptions.Clientset,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to write helm charts")
+	}
+
+	renderedDir := u.GetRenderedDir(writeUpstreamOptions)
+	if err := apparchive.WriteRenderedV1Beta2HelmCharts(apparchive.WriteRenderedV1Beta2HelmChartsOptions{
+		HelmDir:     u.GetHelmDir(writeUpstreamOptions),
+		RenderedDir: renderedDir,
+		Log:         renderOptions.Log,
+		Downstreams: []string{releaseRenderDownstream},
+		KotsKinds:   renderedKotsKinds,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to template helm charts")
+	}
+
+	manifests := []types.RenderedManifest{}
+	for _, helmChart := range renderedKotsKinds.V1Beta2HelmCharts.Items {
+		doc, ok := chartDocs[helmChart.GetDirName()]
+		if !ok {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(renderedDir, releaseRenderDownstream, "helm", helmChart.GetDirName(), "all.yaml"))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// the chart is excluded
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to read rendered chart %s", helmChart.GetDirName())
+		}
+
+		seen := map[string]bool{}
+		for _, chartDoc := range util.ConvertToSingleDocs(content) {
+			// hooks are written twice to the rendered chart
+			if seen[string(chartDoc)] {
+				continue
+			}
+			seen[string(chartDoc)] = true
+
+			chartPath := ""
+			if matches := chartSourceRegexp.FindSubmatch(chartDoc); len(matches) == 2 {
+				chartPath = strings.TrimSpace(string(matches[1]))
+			}
+			if chartPath == "" {
+				chartPath = helmChart.GetChartName()
+			}
+
+			manifests = append(manifests, types.RenderedManifest{
+				Path:      doc.Path,
+				DocIndex:  doc.DocIndex,
+				Content:   string(chartDoc),
+				ChartPath: chartPath,
+			})
+		}
+	}
+
+	return manifests, nil
+}
+
+// newReleaseRenderClientset returns a clientset of an empty cluster that runs the Kubernetes version
+func newReleaseRenderClientset(kubernetesVersion string) (kubernetes.Interface, error) {
+	v, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse kubernetes version %q", kubernetesVersion)
+	}
+
+	clientset := fake.NewSimpleClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).FakedServerVersion = &k8sversion.Info{
+		Major:      strconv.Itoa(int(v.Major())),
+		Minor:      strconv.Itoa(int(v.Minor())),
+		GitVersion: fmt.Sprintf("v%d.%d.%d", v.Major(), v.Minor(), v.Patch()),
+	}
+	return clientset, nil
+}
+
+// helmChartDocs maps the release names of the rendered HelmChart custom resources to the documents they were rendered from
+func helmChartDocs(renderedKotsKindsMap map[string][]byte, docs []releaseDoc) map[string]releaseDoc {
+	chartDocs := map[string]releaseDoc{}
+
+	upstreamPaths := []string{}
+	for upstreamPath := range renderedKotsKindsMap {
+		upstreamPaths = append(upstreamPaths, upstreamPath)
+	}
+	sort.Strings(upstreamPaths)
+
+	for _, upstreamPath := range upstreamPaths {
+		doc, ok := releaseDocForPath(upstreamPath, docs)
+		if !ok {
+			continue
+		}
+
+		helmChart := struct {
+			Kind string `json:"kind"`
+			Spec struct {
+				Chart struct {
+					Name string `json:"name"`
+				} `json:"chart"`
+				ReleaseName string `json:"releaseName"`
+			} `json:"spec"`
+		}{}
+		if err := yaml.Unmarshal(renderedKotsKindsMap[upstreamPath], &helmChart); err != nil || helmChart.Kind != "HelmChart" {
+			continue
+		}
+
+		releaseName := helmChart.Spec.ReleaseName
+		if releaseName == "" {
+			releaseName = helmChart.Spec.Chart.Name
+		}
+		chartDocs[releaseName] = doc
+	}
+
+	return chartDocs
+}
+
+func releaseDocForPath(upstreamPath string, docs []releaseDoc) (releaseDoc, bool) {
+	matches := releaseDocPathRegexp.FindStringSubmatch(upstreamPath)
+	if len(matches) != 2 {
+		return releaseDoc{}, false
+	}
+	i, err := strconv.Atoi(matches[1])
+	if err != nil || i >= len(docs) {
+		return releaseDoc{}, false
+	}
+	return docs[i], true
+}
+
+func nestedBases(b base.Base) []base.Base {
+	bases := []base.Base{}
+	for _, sub := range b.Bases {
+		sub.Path = path.Join(b.Path, sub.Path)
+		bases = append(bases, sub)
+		bases = append(bases, nestedBases(sub)...)
+	}
+	return bases
+}
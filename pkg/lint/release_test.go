@@ -0,0 +1,156 @@
+package lint
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testRenderConfig = `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: config
+spec:
+  groups:
+  - name: settings
+    items:
+    - name: replicas
+      type: text
+      default: "1"
+    - name: port
+      type: text
+      default: "8080"
+`
+
+const testRenderManifests = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: repl{{ ConfigOption "replicas" | ParseInt }}
+  selector:
+    matchLabels:
+      app: app
+  template:
+    metadata:
+      labels:
+        app: app
+    spec:
+      containers:
+      - name: app
+        image: nginx:1.25
+        imagePullPolicyy: Always
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+spec:
+  ports:
+  - port: '{{repl ConfigOption "port"}}'
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app
+data:
+  kubernetesVersion: repl{{ KubernetesVersion }}
+---
+apiVersion: policy/v1beta1
+kind: PodSecurityPolicy
+metadata:
+  name: app
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: app
+spec:
+  anything: goes
+`
+
+const testRenderConfigValues = `apiVersion: kots.io/v1beta1
+kind: ConfigValues
+metadata:
+  name: config-values
+spec:
+  values:
+    replicas:
+      value: "3"
+`
+
+func Test_renderRelease(t *testing.T) {
+	configValuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	require.NoError(t, os.WriteFile(configValuesFile, []byte(testRenderConfigValues), 0644))
+
+	specFiles := types.SpecFiles{
+		{Name: "config.yaml", Path: "config.yaml", Content: testRenderConfig},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testRenderManifests},
+	}
+
+	manifests, err := renderRelease(specFiles, LintOptions{ConfigValuesFile: configValuesFile}, "1.27")
+	require.NoError(t, err)
+	require.Len(t, manifests, 5)
+
+	for i, manifest := range manifests {
+		assert.Equal(t, "manifests.yaml", manifest.Path)
+		assert.Equal(t, i, manifest.DocIndex)
+		assert.Empty(t, manifest.ChartPath)
+	}
+	assert.Contains(t, manifests[0].Content, "replicas: 3")
+	assert.Contains(t, manifests[2].Content, "kubernetesVersion: 1.27.0")
+
+	lintExpressions, err := validators.ValidateKubernetesSchemas(manifests, specFiles, "1.27")
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.LintExpression{
+		{
+			Rule:      "invalid-kubernetes-manifest",
+			Type:      "error",
+			Message:   `Deployment "app": spec.template.spec.containers.0.imagePullPolicyy: unknown field`,
+			Path:      "manifests.yaml",
+			Positions: []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: 18}}},
+		},
+		{
+			Rule:      "invalid-kubernetes-manifest",
+			Type:      "error",
+			Message:   `Service "app": spec.ports.0.port: expected integer, got string`,
+			Path:      "manifests.yaml",
+			Positions: []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: 26}}},
+		},
+		{
+			Rule:      "unsupported-kubernetes-api",
+			Type:      "error",
+			Message:   "policy/v1beta1 PodSecurityPolicy is not available in Kubernetes 1.27",
+			Path:      "manifests.yaml",
+			Positions: []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: 35}}},
+		},
+	}, lintExpressions)
+}
+
+func TestLintSpecFilesRender(t *testing.T) {
+	require.NoError(t, InitOPA())
+
+	specFiles := types.SpecFiles{
+		{Name: "config.yaml", Path: "config.yaml", Content: testRenderConfig},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: testRenderManifests},
+	}
+
+	result, err := LintSpecFiles(t.Context(), specFiles, LintOptions{Render: true})
+	require.NoError(t, err)
+
+	rules := []string{}
+	for _, lintExpression := range result.LintExpressions {
+		rules = append(rules, lintExpression.Rule)
+	}
+	assert.Contains(t, rules, "invalid-kubernetes-manifest")
+	assert.Contains(t, rules, "unsupported-kubernetes-api")
+
+	_, err = LintSpecFiles(t.Context(), specFiles, LintOptions{Render: true, KubernetesVersion: "1.10"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,66 @@
+package lint
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/validators"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRenderingTemplateErrors(t *testing.T) {
+	specFiles := types.SpecFiles{
+		{Name: "config.yaml", Path: "config.yaml", Content: `apiVersion: kots.io/v1beta1
+kind: Config
+metadata:
+  name: config
+spec:
+  groups:
+    - name: settings
+      items:
+        - name: hostname
+          type: text
+          default: repl{{ ConfigOption "url" }}
+        - name: url
+          type: text
+          default: https://repl{{ ConfigOption "hostname" }}
+`},
+		{Name: "manifests.yaml", Path: "manifests.yaml", Content: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: first
+data:
+  a: b
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
+data:
+  # the hostname of the app
+  host: repl{{ ConfigOption "hostname" }}
+  port: repl{{ index "" 2 }}
+`},
+	}
+
+	lintExpressions, _, err := validators.ValidateRendering(specFiles)
+	require.NoError(t, err)
+
+	assert.Equal(t, []types.LintExpression{
+		{
+			Rule:      "config-is-invalid",
+			Type:      "error",
+			Path:      "config.yaml",
+			Message:   `config items have a dependency cycle: "hostname" -> "url" -> "hostname"`,
+			Positions: []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: 9}}},
+		},
+		{
+			Rule:      "unable-to-render",
+			Type:      "error",
+			Path:      "manifests.yaml",
+			Message:   `error calling index: index out of range: 2 in repl{{ index "" 2 }}`,
+			Positions: []types.LintExpressionItemPosition{{Start: types.LintExpressionItemLinePosition{Line: 15}}},
+		},
+	}, lintExpressions)
+}
@@ -28,6 +28,16 @@ type SpecFile struct {
 	Children        SpecFiles `json:"children"`
 }
 
+// RenderedManifest is a manifest rendered from a release, along with the document of the spec file it was rendered from
+type RenderedManifest struct {
+	Path     string `json:"path"`
+	DocIndex int    `json:"docIndex"`
+	Content  string `json:"content"`
+	// ChartPath is the path of the manifest in its Helm chart, for manifests rendered from a Helm chart.
+	// Path and DocIndex are of the HelmChart custom resource in that case.
+	ChartPath string `json:"chartPath,omitempty"`
+}
+
 // GVKDoc represents a Kubernetes resource with basic GVK info
 type GVKDoc struct {
 	Kind       string      `yaml:"kind" json:"kind" validate:"required"`
@@ -36,6 +36,18 @@ func ValidateAnnotations(specFiles types.SpecFiles) ([]types.LintExpression, err
 			continue
 		}
 		for k, v := range annotations {
+			// annotation values that are not strings are rejected by the kubernetes api server
+			if _, ok := v.(string); !ok && v != nil {
+				lintExpression := types.LintExpression{
+					Rule:    "annotation-value-type",
+					Type:    "error",
+					Path:    spec.Path,
+					Message: fmt.Sprintf("Resource annotation %v should be a string, quote the value", k),
+				}
+				lintExpressions = append(lintExpressions, lintExpression)
+				continue
+			}
+
 			// convert the key and value to strings
 			key, value := fmt.Sprintf("%v", k), fmt.Sprintf("%v", v)
 			switch key {
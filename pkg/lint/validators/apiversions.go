@@ -0,0 +1,160 @@
+package validators
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/util"
+	"gopkg.in/yaml.v2"
+)
+
+// deprecatedAPIVersions maps deprecated API versions of built-in kinds to the API version that replaces them.
+// kinds are included if they can be migrated by updating the apiVersion, at least once they have a selector (see RequiresSelector).
+var deprecatedAPIVersions = map[string]map[string]string{
+	"extensions/v1beta1": {
+		"DaemonSet":  "apps/v1",
+		"Deployment": "apps/v1",
+		"ReplicaSet": "apps/v1",
+	},
+	"apps/v1beta1": {
+		"Deployment":  "apps/v1",
+		"StatefulSet": "apps/v1",
+	},
+	"apps/v1beta2": {
+		"DaemonSet":   "apps/v1",
+		"Deployment":  "apps/v1",
+		"ReplicaSet":  "apps/v1",
+		"StatefulSet": "apps/v1",
+	},
+	"batch/v1beta1": {
+		"CronJob": "batch/v1",
+	},
+	"autoscaling/v2beta2": {
+		"HorizontalPodAutoscaler": "autoscaling/v2",
+	},
+	"policy/v1beta1": {
+		"PodDisruptionBudget": "policy/v1",
+	},
+	"rbac.authorization.k8s.io/v1alpha1": {
+		"ClusterRole":        "rbac.authorization.k8s.io/v1",
+		"ClusterRoleBinding": "rbac.authorization.k8s.io/v1",
+		"Role":               "rbac.authorization.k8s.io/v1",
+		"RoleBinding":        "rbac.authorization.k8s.io/v1",
+	},
+	"rbac.authorization.k8s.io/v1beta1": {
+		"ClusterRole":        "rbac.authorization.k8s.io/v1",
+		"ClusterRoleBinding": "rbac.authorization.k8s.io/v1",
+		"Role":               "rbac.authorization.k8s.io/v1",
+		"RoleBinding":        "rbac.authorization.k8s.io/v1",
+	},
+	"scheduling.k8s.io/v1alpha1": {
+		"PriorityClass": "scheduling.k8s.io/v1",
+	},
+	"scheduling.k8s.io/v1beta1": {
+		"PriorityClass": "scheduling.k8s.io/v1",
+	},
+	"storage.k8s.io/v1beta1": {
+		"CSIDriver":          "storage.k8s.io/v1",
+		"CSINode":            "storage.k8s.io/v1",
+		"CSIStorageCapacity": "storage.k8s.io/v1",
+		"StorageClass":       "storage.k8s.io/v1",
+		"VolumeAttachment":   "storage.k8s.io/v1",
+	},
+	"coordination.k8s.io/v1beta1": {
+		"Lease": "coordination.k8s.io/v1",
+	},
+	"networking.k8s.io/v1beta1": {
+		"IngressClass": "networking.k8s.io/v1",
+	},
+}
+
+// ReplacementAPIVersion returns the API version that replaces the deprecated API version of a kind
+func ReplacementAPIVersion(apiVersion string, kind string) (string, bool) {
+	replacement, ok := deprecatedAPIVersions[apiVersion][kind]
+	return replacement, ok
+}
+
+// selectorKinds are the kinds that can only be migrated by updating the apiVersion if they have a selector. the deprecated
+// API versions of workloads default spec.selector to the labels of the pod template, while apps/v1 requires it, and an
+// empty PodDisruptionBudget selector matches no pods in policy/v1beta1 but every pod in the namespace in policy/v1.
+var selectorKinds = map[string]bool{
+	"DaemonSet":           true,
+	"Deployment":          true,
+	"ReplicaSet":          true,
+	"StatefulSet":         true,
+	"PodDisruptionBudget": true,
+}
+
+// RequiresSelector returns true if the deprecated API version of a kind can only be replaced when spec.selector is not empty
+func RequiresSelector(apiVersion string, kind string) bool {
+	_, ok := ReplacementAPIVersion(apiVersion, kind)
+	return ok && selectorKinds[kind]
+}
+
+// ValidateAPIVersions checks for resources that use a deprecated API version of a built-in kind
+func ValidateAPIVersions(specFiles types.SpecFiles) ([]types.LintExpression, error) {
+	lintExpressions := []types.LintExpression{}
+
+	separatedSpecFiles, err := specFiles.Separate()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to separate multi docs")
+	}
+
+	for _, spec := range separatedSpecFiles {
+		if !spec.IsYAML() {
+			continue
+		}
+
+		var doc struct {
+			types.GVKDoc `yaml:",inline"`
+			Spec         struct {
+				Selector map[string]interface{} `yaml:"selector"`
+			} `yaml:"spec"`
+		}
+		if err := yaml.Unmarshal([]byte(spec.Content), &doc); err != nil {
+			// templates that are not valid yaml before rendering are reported by other validators
+			continue
+		}
+
+		replacement, ok := ReplacementAPIVersion(doc.APIVersion, doc.Kind)
+		if !ok {
+			continue
+		}
+
+		lintExpression := types.LintExpression{
+			Rule:    "deprecated-api-version",
+			Type:    "warn",
+			Path:    spec.Path,
+			Message: fmt.Sprintf("API version '%s' of %s is deprecated. Use '%s' instead.", doc.APIVersion, doc.Kind, replacement),
+		}
+
+		// without a selector, updating the apiVersion would change the resource, so it can't be fixed automatically
+		if RequiresSelector(doc.APIVersion, doc.Kind) && len(doc.Spec.Selector) == 0 {
+			lintExpression.Rule = "deprecated-api-version-selector"
+			if doc.Kind == "PodDisruptionBudget" {
+				lintExpression.Message = fmt.Sprintf("API version '%s' of %s is deprecated. Use '%s' instead and set spec.selector, since an empty selector matches no pods in '%s' but every pod in the namespace in '%s'.", doc.APIVersion, doc.Kind, replacement, doc.APIVersion, replacement)
+			} else {
+				lintExpression.Message = fmt.Sprintf("API version '%s' of %s is deprecated. Use '%s' instead and set spec.selector, which '%s' requires.", doc.APIVersion, doc.Kind, replacement, replacement)
+			}
+		}
+
+		// we need to get the line number for the original file content not the separated document
+		if foundSpecFile, err := specFiles.GetFile(spec.Path); err == nil {
+			line, _ := util.GetLineNumberFromYamlPath(foundSpecFile.Content, "apiVersion", spec.DocIndex)
+			if line > -1 {
+				lintExpression.Positions = []types.LintExpressionItemPosition{
+					{
+						Start: types.LintExpressionItemLinePosition{
+							Line: line,
+						},
+					},
+				}
+			}
+		}
+
+		lintExpressions = append(lintExpressions, lintExpression)
+	}
+
+	return lintExpressions, nil
+}
@@ -0,0 +1,421 @@
+package validators
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/distribution/reference"
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/base"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/util"
+	"sigs.k8s.io/yaml"
+)
+
+// statusInformerKinds maps the kinds that status informers support, by all of their names, to the kinds of the resources
+var statusInformerKinds = map[string]string{
+	"deployment":             "Deployment",
+	"deployments":            "Deployment",
+	"deploy":                 "Deployment",
+	"statefulset":            "StatefulSet",
+	"statefulsets":           "StatefulSet",
+	"sts":                    "StatefulSet",
+	"daemonset":              "DaemonSet",
+	"daemonsets":             "DaemonSet",
+	"ds":                     "DaemonSet",
+	"service":                "Service",
+	"services":               "Service",
+	"svc":                    "Service",
+	"ingress":                "Ingress",
+	"ingresses":              "Ingress",
+	"ing":                    "Ingress",
+	"persistentvolumeclaim":  "PersistentVolumeClaim",
+	"persistentvolumeclaims": "PersistentVolumeClaim",
+	"pvc":                    "PersistentVolumeClaim",
+}
+
+// releaseResource is a kubernetes resource in a rendered document of the release
+type releaseResource struct {
+	types.GVKDoc
+	manifest types.RenderedManifest
+}
+
+type crossReferenceApplication struct {
+	Spec struct {
+		StatusInformers      []string `json:"statusInformers"`
+		AdditionalImages     []string `json:"additionalImages"`
+		AdditionalNamespaces []string `json:"additionalNamespaces"`
+	} `json:"spec"`
+}
+
+type crossReferenceHelmChart struct {
+	Spec struct {
+		Chart struct {
+			Name         string `json:"name"`
+			ChartVersion string `json:"chartVersion"`
+		} `json:"chart"`
+		Namespace string `json:"namespace"`
+	} `json:"spec"`
+}
+
+type crossReferenceAirgap struct {
+	Spec struct {
+		SavedImages []string `json:"savedImages"`
+	} `json:"spec"`
+}
+
+type crossReferenceBackup struct {
+	Spec struct {
+		IncludedNamespaces []string `json:"includedNamespaces"`
+	} `json:"spec"`
+}
+
+type chartArchiveMetadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ValidateCrossReferences checks that the KOTS kinds in the release agree with each other and with the manifests.
+// The KOTS kinds are read from the rendered files. Manifests rendered from the release, including Helm charts, are
+// checked when they are available, otherwise only the manifests in the rendered files are checked. The spec files
+// are used to find the Helm chart archives and to map findings back to their lines.
+func ValidateCrossReferences(specFiles types.SpecFiles, renderedFiles types.SpecFiles, manifests []types.RenderedManifest) ([]types.LintExpression, error) {
+	kotsKinds := []releaseResource{}
+	resources := []releaseResource{}
+	hasHelmCharts := false
+	for _, renderedFile := range renderedFiles {
+		if !renderedFile.IsYAML() {
+			continue
+		}
+		resource, ok := parseReleaseResource(types.RenderedManifest{
+			Path:     renderedFile.Path,
+			DocIndex: renderedFile.DocIndex,
+			Content:  renderedFile.Content,
+		})
+		if !ok {
+			continue
+		}
+		if kotsutil.IsKotsKind(resource.APIVersion, resource.Kind) {
+			kotsKinds = append(kotsKinds, resource)
+			hasHelmCharts = hasHelmCharts || resource.Kind == "HelmChart"
+		} else if manifests == nil {
+			resources = append(resources, resource)
+		}
+	}
+	for _, manifest := range manifests {
+		if resource, ok := parseReleaseResource(manifest); ok && !kotsutil.IsKotsKind(resource.APIVersion, resource.Kind) {
+			resources = append(resources, resource)
+		}
+	}
+
+	chartArchives, err := findChartArchives(specFiles)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find helm chart archives")
+	}
+
+	lintExpressions := []types.LintExpression{}
+
+	// status informers can only be checked against the resources in Helm charts when the charts are rendered
+	if manifests != nil || !hasHelmCharts {
+		lintExpressions = append(lintExpressions, validateStatusInformers(kotsKinds, resources, specFiles)...)
+	}
+	lintExpressions = append(lintExpressions, validateHelmChartArchives(kotsKinds, chartArchives, specFiles)...)
+	lintExpressions = append(lintExpressions, validateListedImages(kotsKinds, resources, specFiles)...)
+	lintExpressions = append(lintExpressions, validateBackupNamespaces(kotsKinds, resources, specFiles)...)
+
+	return lintExpressions, nil
+}
+
+func parseReleaseResource(manifest types.RenderedManifest) (releaseResource, bool) {
+	resource := releaseResource{manifest: manifest}
+	if err := yaml.Unmarshal([]byte(manifest.Content), &resource.GVKDoc); err != nil {
+		return resource, false
+	}
+	if resource.APIVersion == "" || resource.Kind == "" {
+		return resource, false
+	}
+	return resource, true
+}
+
+// findChartArchives returns the name and version of the Helm chart archives in the spec files
+func findChartArchives(specFiles types.SpecFiles) ([]chartArchiveMetadata, error) {
+	chartArchives := []chartArchiveMetadata{}
+	for _, specFile := range specFiles {
+		if !specFile.IsTarGz() {
+			continue
+		}
+		files, err := types.SpecFilesFromTarGz(specFile)
+		if err != nil {
+			// archives that are not helm charts are not considered
+			continue
+		}
+		for _, file := range files {
+			if file.Path != "Chart.yaml" {
+				continue
+			}
+			var metadata chartArchiveMetadata
+			if err := yaml.Unmarshal([]byte(file.Content), &metadata); err != nil {
+				return nil, errors.Wrapf(err, "failed to parse Chart.yaml in %s", specFile.Path)
+			}
+			chartArchives = append(chartArchives, metadata)
+		}
+	}
+	return chartArchives, nil
+}
+
+// validateStatusInformers checks that the status informers of the Application refer to resources in the release
+func validateStatusInformers(kotsKinds []releaseResource, resources []releaseResource, specFiles types.SpecFiles) []types.LintExpression {
+	lintExpressions := []types.LintExpression{}
+	for _, kotsKind := range kotsKinds {
+		if kotsKind.APIVersion != "kots.io/v1beta1" || kotsKind.Kind != "Application" {
+			continue
+		}
+		var app crossReferenceApplication
+		if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &app); err != nil {
+			continue
+		}
+
+		for i, informer := range app.Spec.StatusInformers {
+			if strings.Contains(informer, "{{") {
+				continue
+			}
+
+			message := ""
+			namespace, kind, name, err := parseStatusInformer(informer)
+			if err != nil {
+				message = fmt.Sprintf("Status informer %q is not in the format [namespace/]kind/name", informer)
+			} else if _, ok := statusInformerKinds[kind]; !ok {
+				message = fmt.Sprintf("Status informer %q has an unsupported kind %q", informer, kind)
+			} else if !hasStatusInformerResource(resources, namespace, statusInformerKinds[kind], name) {
+				message = fmt.Sprintf("Status informer %q does not match a %s in the release", informer, statusInformerKinds[kind])
+			}
+			if message == "" {
+				continue
+			}
+
+			lintExpressions = append(lintExpressions, manifestLintExpression(kotsKind.manifest, specFiles, types.LintExpression{
+				Rule:    "status-informer-not-found",
+				Type:    "warn",
+				Message: message,
+			}, fmt.Sprintf("spec.statusInformers.%d", i)))
+		}
+	}
+	return lintExpressions
+}
+
+func parseStatusInformer(informer string) (string, string, string, error) {
+	parts := strings.Split(informer, "/")
+	switch {
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return "", strings.ToLower(parts[0]), parts[1], nil
+	case len(parts) == 3 && parts[0] != "" && parts[1] != "" && parts[2] != "":
+		return parts[0], strings.ToLower(parts[1]), parts[2], nil
+	default:
+		return "", "", "", errors.New("invalid status informer")
+	}
+}
+
+// hasStatusInformerResource returns true if a resource matches a status informer. resources and informers without a
+// namespace are in the namespace of the app, which is not known, so namespaces only have to match when both are set.
+func hasStatusInformerResource(resources []releaseResource, namespace string, kind string, name string) bool {
+	for _, resource := range resources {
+		if resource.Kind != kind || resource.Metadata.Name != name {
+			continue
+		}
+		if namespace != "" && resource.Metadata.Namespace != "" && namespace != resource.Metadata.Namespace {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// validateHelmChartArchives checks that there is a Helm chart archive in the release for every HelmChart
+func validateHelmChartArchives(kotsKinds []releaseResource, chartArchives []chartArchiveMetadata, specFiles types.SpecFiles) []types.LintExpression {
+	lintExpressions := []types.LintExpression{}
+	for _, kotsKind := range kotsKinds {
+		if kotsKind.Kind != "HelmChart" {
+			continue
+		}
+		var helmChart crossReferenceHelmChart
+		if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &helmChart); err != nil {
+			continue
+		}
+		chartName, chartVersion := helmChart.Spec.Chart.Name, helmChart.Spec.Chart.ChartVersion
+		if chartName == "" {
+			continue
+		}
+
+		versions := []string{}
+		found := false
+		for _, chartArchive := range chartArchives {
+			if chartArchive.Name != chartName {
+				continue
+			}
+			if chartArchive.Version == chartVersion {
+				found = true
+				break
+			}
+			versions = append(versions, chartArchive.Version)
+		}
+		if found {
+			continue
+		}
+
+		lintExpression := types.LintExpression{
+			Rule:    "helm-chart-archive-not-found",
+			Type:    "error",
+			Message: fmt.Sprintf("No Helm chart archive found for chart %q version %q", chartName, chartVersion),
+		}
+		field := "spec.chart.name"
+		if len(versions) > 0 {
+			sort.Strings(versions)
+			lintExpression.Message = fmt.Sprintf("No Helm chart archive found for chart %q version %q, the release has version(s) %s", chartName, chartVersion, strings.Join(versions, ", "))
+			field = "spec.chart.chartVersion"
+		}
+		lintExpressions = append(lintExpressions, manifestLintExpression(kotsKind.manifest, specFiles, lintExpression, field))
+	}
+	return lintExpressions
+}
+
+// validateListedImages checks that the images in the manifests are listed in the Application additionalImages or the
+// Airgap savedImages. images only have to be listed when the release lists images in either of them.
+func validateListedImages(kotsKinds []releaseResource, resources []releaseResource, specFiles types.SpecFiles) []types.LintExpression {
+	listedImages := map[string]bool{}
+	for _, kotsKind := range kotsKinds {
+		images := []string{}
+		switch {
+		case kotsKind.APIVersion == "kots.io/v1beta1" && kotsKind.Kind == "Application":
+			var app crossReferenceApplication
+			if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &app); err == nil {
+				images = app.Spec.AdditionalImages
+			}
+		case kotsKind.APIVersion == "kots.io/v1beta1" && kotsKind.Kind == "Airgap":
+			var airgap crossReferenceAirgap
+			if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &airgap); err == nil {
+				images = airgap.Spec.SavedImages
+			}
+		}
+		for _, image := range images {
+			listedImages[normalizeImage(image)] = true
+		}
+	}
+	if len(listedImages) == 0 {
+		return []types.LintExpression{}
+	}
+
+	lintExpressions := []types.LintExpression{}
+	reported := map[string]bool{}
+	for _, resource := range resources {
+		images, _, err := base.FindImages(&base.Base{
+			Files: []base.BaseFile{{Path: resource.manifest.Path, Content: []byte(resource.manifest.Content)}},
+		})
+		if err != nil {
+			continue
+		}
+		for _, image := range images {
+			if listedImages[normalizeImage(image)] || reported[image] {
+				continue
+			}
+			reported[image] = true
+
+			lintExpression := manifestLintExpression(resource.manifest, specFiles, types.LintExpression{
+				Rule:    "unlisted-image",
+				Type:    "warn",
+				Message: fmt.Sprintf("Image %q is not listed in the Application additionalImages or the Airgap savedImages", image),
+			}, "")
+			if resource.manifest.ChartPath == "" {
+				if foundSpecFile, err := specFiles.GetFile(resource.manifest.Path); err == nil {
+					if line, _ := util.GetLineNumberFromMatch(foundSpecFile.Content, image, resource.manifest.DocIndex); line > -1 {
+						lintExpression.Positions = []types.LintExpressionItemPosition{
+							{
+								Start: types.LintExpressionItemLinePosition{
+									Line: line,
+								},
+							},
+						}
+					}
+				}
+			}
+			lintExpressions = append(lintExpressions, lintExpression)
+		}
+	}
+	return lintExpressions
+}
+
+// normalizeImage returns the fully qualified name of an image so that images can be compared however they are written
+func normalizeImage(image string) string {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return image
+	}
+	return reference.TagNameOnly(named).String()
+}
+
+// validateBackupNamespaces checks that the namespaces used by the manifests and the Helm charts are backed up by
+// the Backup. the namespace of the app and the additional namespaces of the Application are always backed up.
+func validateBackupNamespaces(kotsKinds []releaseResource, resources []releaseResource, specFiles types.SpecFiles) []types.LintExpression {
+	var backup *releaseResource
+	backedUpNamespaces := map[string]bool{}
+	for i, kotsKind := range kotsKinds {
+		switch {
+		case kotsKind.APIVersion == "velero.io/v1" && kotsKind.Kind == "Backup":
+			var spec crossReferenceBackup
+			if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &spec); err != nil {
+				continue
+			}
+			backup = &kotsKinds[i]
+			for _, namespace := range spec.Spec.IncludedNamespaces {
+				backedUpNamespaces[namespace] = true
+			}
+		case kotsKind.APIVersion == "kots.io/v1beta1" && kotsKind.Kind == "Application":
+			var app crossReferenceApplication
+			if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &app); err != nil {
+				continue
+			}
+			for _, namespace := range app.Spec.AdditionalNamespaces {
+				backedUpNamespaces[namespace] = true
+			}
+		}
+	}
+	if backup == nil || backedUpNamespaces["*"] {
+		return []types.LintExpression{}
+	}
+
+	type namespaceUse struct {
+		namespace string
+		manifest  types.RenderedManifest
+		field     string
+	}
+	uses := []namespaceUse{}
+	for _, resource := range resources {
+		uses = append(uses, namespaceUse{resource.Metadata.Namespace, resource.manifest, "metadata.namespace"})
+	}
+	for _, kotsKind := range kotsKinds {
+		if kotsKind.Kind != "HelmChart" {
+			continue
+		}
+		var helmChart crossReferenceHelmChart
+		if err := yaml.Unmarshal([]byte(kotsKind.manifest.Content), &helmChart); err == nil {
+			uses = append(uses, namespaceUse{helmChart.Spec.Namespace, kotsKind.manifest, "spec.namespace"})
+		}
+	}
+
+	lintExpressions := []types.LintExpression{}
+	reported := map[string]bool{}
+	for _, use := range uses {
+		if use.namespace == "" || strings.Contains(use.namespace, "{{") || backedUpNamespaces[use.namespace] || reported[use.namespace] {
+			continue
+		}
+		reported[use.namespace] = true
+
+		lintExpressions = append(lintExpressions, manifestLintExpression(use.manifest, specFiles, types.LintExpression{
+			Rule:    "backup-namespace-not-included",
+			Type:    "warn",
+			Message: fmt.Sprintf("Namespace %q is not included in the Backup includedNamespaces or the Application additionalNamespaces (Backup in %s)", use.namespace, backup.manifest.Path),
+		}, use.field))
+	}
+	return lintExpressions
+}
@@ -3,8 +3,10 @@ package validators
 import (
 	"context"
 	_ "embed"
+	"fmt"
 
 	"github.com/mitchellh/mapstructure"
+	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/lint/types"
@@ -121,3 +123,122 @@ func opaResultsToLintExpressions(results rego.ResultSet, specFiles types.SpecFil
 
 	return lintExpressions, nil
 }
+
+// customRegoQuery queries the lint rules of custom policies. custom policies must be in a package under kots.custom,
+// such as "package kots.custom.images", and add their findings to a "lint" set with the same fields as the built-in rules.
+const customRegoQuery = "data.kots.custom[_].lint"
+
+// customPolicyDeniedBuiltins are the built-in functions that custom policies can't call. policies can come from the
+// release that is linted, so they must not be able to reach the network or read the environment of the linter.
+var customPolicyDeniedBuiltins = map[string]bool{
+	"http.send":          true,
+	"net.lookup_ip_addr": true,
+	"opa.runtime":        true,
+}
+
+// customPolicyCapabilities returns the capabilities of this version of OPA without the denied built-in functions
+func customPolicyCapabilities() *ast.Capabilities {
+	capabilities := ast.CapabilitiesForThisVersion()
+
+	builtins := []*ast.Builtin{}
+	for _, builtin := range capabilities.Builtins {
+		if !customPolicyDeniedBuiltins[builtin.Name] {
+			builtins = append(builtins, builtin)
+		}
+	}
+	capabilities.Builtins = builtins
+	capabilities.AllowNet = []string{}
+
+	return capabilities
+}
+
+// policyInputFile is a file in the input of custom policies. custom policies are evaluated against both the
+// non-rendered and the rendered files, which can be told apart with the rendered field.
+type policyInputFile struct {
+	types.SpecFile
+	// DocIndex is always set so that policies do not need a default for it
+	DocIndex int  `json:"docIndex"`
+	Rendered bool `json:"rendered"`
+}
+
+// ValidateOPACustom validates the non-rendered and the rendered files using custom OPA policies, which map module names
+// to their rego content. findings that are the same for both the non-rendered and the rendered files are only reported once.
+func ValidateOPACustom(policies map[string]string, specFiles types.SpecFiles, renderedFiles types.SpecFiles) ([]types.LintExpression, error) {
+	lintExpressions := []types.LintExpression{}
+	if len(policies) == 0 {
+		return lintExpressions, nil
+	}
+
+	ctx := context.Background()
+
+	options := []func(*rego.Rego){
+		rego.Query(customRegoQuery),
+		rego.Capabilities(customPolicyCapabilities()),
+	}
+	for name, content := range policies {
+		options = append(options, rego.Module(name, content))
+	}
+	query, err := rego.New(options...).PrepareForEval(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to prepare custom policies for eval")
+	}
+
+	separatedSpecFiles, err := specFiles.Separate()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to separate multi docs")
+	}
+
+	seen := map[string]bool{}
+	for _, files := range []struct {
+		files    types.SpecFiles
+		rendered bool
+	}{
+		{files: separatedSpecFiles, rendered: false},
+		{files: renderedFiles, rendered: true},
+	} {
+		input := []policyInputFile{}
+		for _, file := range files.files {
+			if !file.IsYAML() {
+				continue
+			}
+			input = append(input, policyInputFile{SpecFile: file, DocIndex: file.DocIndex, Rendered: files.rendered})
+		}
+
+		results, err := query.Eval(ctx, rego.EvalInput(input))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to evaluate custom policies")
+		}
+
+		// each package under kots.custom has its own result
+		for _, result := range results {
+			expressions, err := opaResultsToLintExpressions(rego.ResultSet{result}, specFiles)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to convert custom policy results")
+			}
+
+			for _, expression := range expressions {
+				expression.Type = validateLintType(expression.Type)
+
+				key := fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%v", expression.Rule, expression.Type, expression.Path, expression.Message, expression.Positions)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+
+				lintExpressions = append(lintExpressions, expression)
+			}
+		}
+	}
+
+	return lintExpressions, nil
+}
+
+// validateLintType returns the lint type if it is valid, or "warn" if it is not
+func validateLintType(lintType string) string {
+	switch lintType {
+	case "error", "warn", "info":
+		return lintType
+	default:
+		return "warn"
+	}
+}
@@ -1021,7 +1021,7 @@ lint[output] {
 
   file := input[_]
 
-  expression := "(ConfigOption|ConfigOptionName|ConfigOptionEquals|ConfigOptionNotEquals)\\W+?(repl\\W+?)?([\\w\\d_-]+)"
+  expression := "(ConfigOption|ConfigOptionName|ConfigOptionIndex|ConfigOptionData|ConfigOptionFilename|ConfigOptionEquals|ConfigOptionNotEquals)\\W+?(repl\\W+?)?([\\w\\d_-]+)"
   expression_matches := regex.find_all_string_submatch_n(expression, file.content, -1)
 
   capture_groups := expression_matches[_]
@@ -1216,6 +1216,8 @@ lint_rule_config(lint_rule_name, default_level) = lint_rule_config {
   lintconfig := files[_].content.spec
   lint_rule = lintconfig.rules[_]
   lint_rule.name == lint_rule_name
+  # rules that only apply to some files are applied after linting
+  not lint_rule.paths
   rule_level := validate_lint_rule_level(default_level, lint_rule.level)
   lint_rule_config := {
     "off": lint_rule.level == "off",
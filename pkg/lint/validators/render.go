@@ -1,8 +1,7 @@
 package validators
 
 import (
-	"strconv"
-	"strings"
+	"fmt"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/config"
@@ -44,18 +43,44 @@ func ValidateRendering(specFiles types.SpecFiles) ([]types.LintExpression, types
 	}
 
 	// check if config is valid
-	kotsConfig, path, err := findAndValidateConfig(separatedSpecFiles)
-	if err != nil {
+	kotsConfig, configFile, configErr := findAndValidateConfig(separatedSpecFiles)
+	if configErr != nil {
 		lintExpression := types.LintExpression{
 			Rule:    "config-is-invalid",
 			Type:    "error",
-			Path:    path,
-			Message: err.Error(),
+			Path:    configFile.Path,
+			Message: configErr.Error(),
+		}
+
+		// point to the failing expression or the first config item of a dependency cycle
+		match := ""
+		var templateErr *template.TemplateError
+		var cycleErr *template.DependencyCycleError
+		if errors.As(configErr, &templateErr) {
+			lintExpression.Message = renderTemplateErrorMessage(templateErr)
+			match = templateErr.Expression
+		} else if errors.As(configErr, &cycleErr) {
+			lintExpression.Message = cycleErr.Error()
+			match = fmt.Sprintf("name: %s", cycleErr.Cycle[0])
 		}
+		if line := findLine(specFiles, configFile, match); line > -1 {
+			lintExpression.Positions = []types.LintExpressionItemPosition{
+				{
+					Start: types.LintExpressionItemLinePosition{
+						Line: line,
+					},
+				},
+			}
+		}
+
 		lintExpressions = append(lintExpressions, lintExpression)
 	}
 
 	builder, err := getTemplateBuilder(kotsConfig)
+	if err != nil && configErr != nil {
+		// the config is reported as invalid already, render the other files without it
+		builder, err = getTemplateBuilder(nil)
+	}
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "failed to get template builder")
 	}
@@ -80,19 +105,7 @@ func ValidateRendering(specFiles types.SpecFiles) ([]types.LintExpression, types
 				Message: renderErr.Error(),
 			}
 
-			if renderErr.Match() != "" {
-				// we need to get the line number for the original file content not the separated document
-				foundSpecFile, err := specFiles.GetFile(file.Path)
-				if err != nil {
-					lintExpressions = append(lintExpressions, lintExpression)
-					continue
-				}
-
-				line, err := util.GetLineNumberFromMatch(foundSpecFile.Content, renderErr.Match(), file.DocIndex)
-				if err != nil || line == -1 {
-					lintExpressions = append(lintExpressions, lintExpression)
-					continue
-				}
+			if line := findLine(specFiles, file, renderErr.Match()); line > -1 {
 				lintExpression.Positions = []types.LintExpressionItemPosition{
 					{
 						Start: types.LintExpressionItemLinePosition{
@@ -112,9 +125,28 @@ func ValidateRendering(specFiles types.SpecFiles) ([]types.LintExpression, types
 	return lintExpressions, renderedFiles, nil
 }
 
-func findAndValidateConfig(files types.SpecFiles) (*kotsv1beta1.Config, string, error) {
+// findLine returns the line of the match in the original content of a separated spec file, or -1 if it is not found
+func findLine(specFiles types.SpecFiles, file types.SpecFile, match string) int {
+	if match == "" {
+		return -1
+	}
+
+	// we need to get the line number for the original file content not the separated document
+	foundSpecFile, err := specFiles.GetFile(file.Path)
+	if err != nil {
+		return -1
+	}
+
+	line, err := util.GetLineNumberFromMatch(foundSpecFile.Content, match, file.DocIndex)
+	if err != nil {
+		return -1
+	}
+	return line
+}
+
+func findAndValidateConfig(files types.SpecFiles) (*kotsv1beta1.Config, types.SpecFile, error) {
 	var kotsConfig *kotsv1beta1.Config
-	var path string
+	var configFile types.SpecFile
 
 	for _, file := range files {
 		document := &types.GVKDoc{}
@@ -129,12 +161,12 @@ func findAndValidateConfig(files types.SpecFiles) (*kotsv1beta1.Config, string,
 		decode := scheme.Codecs.UniversalDeserializer().Decode
 		obj, gvk, err := decode([]byte(file.Content), nil, nil)
 		if err != nil {
-			return nil, file.Path, errors.Wrap(err, "failed to decode config content")
+			return nil, file, errors.Wrap(err, "failed to decode config content")
 		}
 
 		if gvk.Group == "kots.io" && gvk.Version == "v1beta1" && gvk.Kind == "Config" {
 			kotsConfig = obj.(*kotsv1beta1.Config)
-			path = file.Path
+			configFile = file
 		}
 	}
 
@@ -142,11 +174,11 @@ func findAndValidateConfig(files types.SpecFiles) (*kotsv1beta1.Config, string,
 		// if config was found, validate that it renders successfully
 		configCopy := kotsConfig.DeepCopy()
 		if _, err := renderConfig(configCopy); err != nil {
-			return kotsConfig, path, errors.Wrap(err, "failed to render config")
+			return kotsConfig, configFile, errors.Wrap(err, "failed to render config")
 		}
 	}
 
-	return kotsConfig, path, nil
+	return kotsConfig, configFile, nil
 }
 
 func renderConfig(kotsConfig *kotsv1beta1.Config) ([]byte, error) {
@@ -205,17 +237,11 @@ func renderContent(file types.SpecFile, builder *template.Builder) ([]byte, erro
 		return []byte(file.Content), nil
 	}
 
-	// add new line so that parsing the render template error is easier (possible)
-	content := file.Content + "\n"
-
-	rendered, err := builder.RenderTemplate(content, content)
+	rendered, err := builder.RenderTemplate(file.Path, file.Content)
 	if err != nil {
-		return nil, parseRenderTemplateError(file, err.Error())
+		return nil, newRenderTemplateError(err)
 	}
 
-	// remove the new line that was added to make parsing template error easier (possible)
-	rendered = strings.TrimSuffix(rendered, "\n")
-
 	return []byte(rendered), nil
 }
 
@@ -234,64 +260,26 @@ func shouldBeRendered(file types.SpecFile) (bool, error) {
 	return true, nil
 }
 
-func parseRenderTemplateError(file types.SpecFile, value string) RenderTemplateError {
-	/*
-		** SAMPLE **
-		failed to get template: template: apiVersion: v1
-		data:
-			ENV_VAR_1: fake
-			ENV_VAR_2: '{{repl ConfigOptionEquals "test}}'
-		kind: ConfigMap
-		metadata:
-			name: example-config
-		:4: unterminated quoted string
-	*/
-
-	renderTemplateError := RenderTemplateError{
-		match:   "",
-		message: value,
-	}
-
-	parts := strings.Split(value, "\n:")
-	if len(parts) == 1 {
-		return renderTemplateError
-	}
-
-	lineAndMsg := parts[len(parts)-1]
-	lineAndMsgParts := strings.SplitN(lineAndMsg, ":", 2)
-
-	if len(lineAndMsgParts) == 1 {
-		return renderTemplateError
-	}
-
-	// in some cases, the message contains the whole file content which is noisy and difficult to read
-	msg := lineAndMsgParts[1]
-	if i := strings.Index(msg, `\n"`); i != -1 {
-		msg = msg[i+len(`\n"`):]
-	}
-	renderTemplateError.message = strings.TrimSpace(msg)
-
-	// get the line number in the remarshalled (keys rearranged) data
-	lineNumber, err := strconv.Atoi(lineAndMsgParts[0])
-	if err != nil {
-		return renderTemplateError
+// newRenderTemplateError returns a RenderTemplateError that matches the failing expression of a template error
+func newRenderTemplateError(err error) RenderTemplateError {
+	var templateErr *template.TemplateError
+	if !errors.As(err, &templateErr) {
+		return RenderTemplateError{
+			message: err.Error(),
+		}
 	}
 
-	// try to find the data after it's been remarshalled (keys rearranged)
-	data := util.GetStringInBetween(value, ": template: ", "\n:")
-	if data == "" {
-		return renderTemplateError
+	return RenderTemplateError{
+		message: renderTemplateErrorMessage(templateErr),
+		match:   templateErr.Expression,
 	}
+}
 
-	// find error line from data
-	match := ""
-	for index, line := range strings.Split(data, "\n") {
-		if index == lineNumber-1 {
-			match = line
-			break
-		}
+// renderTemplateErrorMessage returns the message of a template error without its location, which is
+// reported as the position of the lint expression
+func renderTemplateErrorMessage(templateErr *template.TemplateError) string {
+	if templateErr.Expression == "" {
+		return templateErr.Message
 	}
-	renderTemplateError.match = match
-
-	return renderTemplateError
+	return fmt.Sprintf("%s in %s", templateErr.Message, templateErr.Expression)
 }
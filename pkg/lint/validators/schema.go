@@ -0,0 +1,410 @@
+package validators
+
+import (
+	"bytes"
+	"compress/gzip"
+	"embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/replicatedhq/kots/pkg/lint/util"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/version"
+	"sigs.k8s.io/yaml"
+)
+
+// the schemas are the definitions of the Kubernetes OpenAPI v2 specs, without descriptions
+//
+//go:embed schemas/*.json.gz
+var kubernetesSchemaFiles embed.FS
+
+// DefaultKubernetesVersion is the Kubernetes version that rendered manifests are validated against by default
+const DefaultKubernetesVersion = "1.34"
+
+const (
+	definitionRefPrefix = "#/definitions/"
+	quantityDefinition  = "io.k8s.apimachinery.pkg.api.resource.Quantity"
+)
+
+var (
+	kubernetesSchemasMtx sync.Mutex
+	kubernetesSchemas    = map[string]*kubernetesSchema{}
+)
+
+type kubernetesSchema struct {
+	Definitions map[string]*schemaNode `json:"definitions"`
+
+	// definitionsByGVK maps the group, version and kind of the top level definitions to their name
+	definitionsByGVK map[schema.GroupVersionKind]string
+	// groups are the API groups in the schema, whose versions and kinds are all known
+	groups map[string]bool
+}
+
+type schemaNode struct {
+	Ref                   string                 `json:"$ref"`
+	Type                  string                 `json:"type"`
+	Format                string                 `json:"format"`
+	Enum                  []interface{}          `json:"enum"`
+	Required              []string               `json:"required"`
+	Properties            map[string]*schemaNode `json:"properties"`
+	AdditionalProperties  *schemaNode            `json:"additionalProperties"`
+	Items                 *schemaNode            `json:"items"`
+	IntOrString           bool                   `json:"x-kubernetes-int-or-string"`
+	PreserveUnknownFields bool                   `json:"x-kubernetes-preserve-unknown-fields"`
+	GroupVersionKinds     []struct {
+		Group   string `json:"group"`
+		Version string `json:"version"`
+		Kind    string `json:"kind"`
+	} `json:"x-kubernetes-group-version-kind"`
+}
+
+// schemaError is a manifest field that does not match the schema. the field is a yaml path, such as spec.ports.0.port
+type schemaError struct {
+	Field   string
+	Message string
+}
+
+// SupportedKubernetesVersions returns the Kubernetes versions that have bundled schemas, such as 1.34
+func SupportedKubernetesVersions() []string {
+	versions := []string{}
+	entries, err := kubernetesSchemaFiles.ReadDir("schemas")
+	if err != nil {
+		return versions
+	}
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".json.gz")
+		versions = append(versions, strings.TrimPrefix(name, "kubernetes-"))
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return version.MustParseGeneric(versions[i]).LessThan(version.MustParseGeneric(versions[j]))
+	})
+	return versions
+}
+
+// KubernetesSchemaVersion returns the version of the bundled schema for a Kubernetes version, such as 1.34 for v1.34.2
+func KubernetesSchemaVersion(kubernetesVersion string) (string, error) {
+	v, err := version.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to parse kubernetes version %q", kubernetesVersion)
+	}
+	schemaVersion := fmt.Sprintf("%d.%d", v.Major(), v.Minor())
+	for _, supported := range SupportedKubernetesVersions() {
+		if supported == schemaVersion {
+			return schemaVersion, nil
+		}
+	}
+	return "", errors.Errorf("kubernetes version %s is not supported, supported versions are %s", kubernetesVersion, strings.Join(SupportedKubernetesVersions(), ", "))
+}
+
+func loadKubernetesSchema(schemaVersion string) (*kubernetesSchema, error) {
+	kubernetesSchemasMtx.Lock()
+	defer kubernetesSchemasMtx.Unlock()
+
+	if s, ok := kubernetesSchemas[schemaVersion]; ok {
+		return s, nil
+	}
+
+	content, err := kubernetesSchemaFiles.ReadFile(path.Join("schemas", fmt.Sprintf("kubernetes-%s.json.gz", schemaVersion)))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read schema")
+	}
+	gzr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gzip reader")
+	}
+	defer gzr.Close()
+	uncompressed, err := io.ReadAll(gzr)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decompress schema")
+	}
+
+	s := &kubernetesSchema{}
+	if err := json.Unmarshal(uncompressed, s); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal schema")
+	}
+
+	s.definitionsByGVK = map[schema.GroupVersionKind]string{}
+	s.groups = map[string]bool{}
+	for name, definition := range s.Definitions {
+		for _, gvk := range definition.GroupVersionKinds {
+			// the meta types such as DeleteOptions are listed for every group and version, and are not manifests
+			if strings.HasPrefix(name, "io.k8s.apimachinery.pkg.apis.meta.") {
+				continue
+			}
+			s.definitionsByGVK[schema.GroupVersionKind{Group: gvk.Group, Version: gvk.Version, Kind: gvk.Kind}] = name
+			s.groups[gvk.Group] = true
+		}
+	}
+
+	kubernetesSchemas[schemaVersion] = s
+	return s, nil
+}
+
+// ValidateKubernetesSchemas validates the rendered manifests against the schema of the Kubernetes version.
+// manifests of custom resources are not validated, and findings are mapped back to the spec files the
+// manifests were rendered from.
+func ValidateKubernetesSchemas(manifests []types.RenderedManifest, specFiles types.SpecFiles, kubernetesVersion string) ([]types.LintExpression, error) {
+	schemaVersion, err := KubernetesSchemaVersion(kubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+	s, err := loadKubernetesSchema(schemaVersion)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to load schema for kubernetes %s", schemaVersion)
+	}
+
+	lintExpressions := []types.LintExpression{}
+	for _, manifest := range manifests {
+		var obj map[string]interface{}
+		if err := yaml.Unmarshal([]byte(manifest.Content), &obj); err != nil || obj == nil {
+			continue
+		}
+
+		apiVersion, _ := obj["apiVersion"].(string)
+		kind, _ := obj["kind"].(string)
+		if apiVersion == "" || kind == "" {
+			continue
+		}
+		gv, err := schema.ParseGroupVersion(apiVersion)
+		if err != nil {
+			continue
+		}
+		name := ""
+		if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+			name, _ = metadata["name"].(string)
+		}
+
+		definitionName, ok := s.definitionsByGVK[gv.WithKind(kind)]
+		if !ok {
+			if !s.groups[gv.Group] {
+				// custom resources are not part of the schema
+				continue
+			}
+			lintExpressions = append(lintExpressions, manifestLintExpression(manifest, specFiles, types.LintExpression{
+				Rule:    "unsupported-kubernetes-api",
+				Type:    "error",
+				Message: fmt.Sprintf("%s %s is not available in Kubernetes %s", apiVersion, kind, schemaVersion),
+			}, ""))
+			continue
+		}
+
+		for _, schemaErr := range s.validate(s.Definitions[definitionName], obj, "") {
+			lintExpressions = append(lintExpressions, manifestLintExpression(manifest, specFiles, types.LintExpression{
+				Rule:    "invalid-kubernetes-manifest",
+				Type:    "error",
+				Message: fmt.Sprintf("%s %q: %s: %s", kind, name, schemaErr.Field, schemaErr.Message),
+			}, schemaErr.Field))
+		}
+	}
+
+	return lintExpressions, nil
+}
+
+// manifestLintExpression sets the path and the position of the lint expression to the document that the
+// manifest was rendered from. the field can only be found in that document if it is not in a Helm chart.
+func manifestLintExpression(manifest types.RenderedManifest, specFiles types.SpecFiles, lintExpression types.LintExpression, field string) types.LintExpression {
+	lintExpression.Path = manifest.Path
+	if manifest.ChartPath != "" {
+		lintExpression.Message = fmt.Sprintf("%s (rendered from %s)", lintExpression.Message, manifest.ChartPath)
+	}
+
+	foundSpecFile, err := specFiles.GetFile(manifest.Path)
+	if err != nil {
+		return lintExpression
+	}
+
+	line := -1
+	if field != "" && manifest.ChartPath == "" {
+		line, _ = util.GetLineNumberFromYamlPath(foundSpecFile.Content, field, manifest.DocIndex)
+	}
+	if line == -1 {
+		line, _ = util.GetLineNumberForDoc(foundSpecFile.Content, manifest.DocIndex)
+	}
+	if line == -1 {
+		return lintExpression
+	}
+
+	lintExpression.Positions = []types.LintExpressionItemPosition{
+		{
+			Start: types.LintExpressionItemLinePosition{
+				Line: line,
+			},
+		},
+	}
+	return lintExpression
+}
+
+func (s *kubernetesSchema) validate(node *schemaNode, value interface{}, field string) []schemaError {
+	// null values are the same as unset values when applied
+	if node == nil || value == nil {
+		return nil
+	}
+
+	if node.Ref != "" {
+		definitionName := strings.TrimPrefix(node.Ref, definitionRefPrefix)
+		if definitionName == quantityDefinition {
+			// quantities can be numbers too, such as cpu: 1
+			switch value.(type) {
+			case string, float64:
+				return nil
+			}
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected quantity, got %s", jsonType(value))}}
+		}
+		return s.validate(s.Definitions[definitionName], value, field)
+	}
+
+	if node.PreserveUnknownFields {
+		return nil
+	}
+
+	if node.IntOrString || node.Format == "int-or-string" {
+		if _, ok := value.(string); ok || isInteger(value) {
+			return nil
+		}
+		return []schemaError{{Field: field, Message: fmt.Sprintf("expected integer or string, got %s", jsonType(value))}}
+	}
+
+	nodeType := node.Type
+	if nodeType == "" && len(node.Properties) > 0 {
+		nodeType = "object"
+	}
+
+	switch nodeType {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected object, got %s", jsonType(value))}}
+		}
+		return s.validateObject(node, obj, field)
+
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected array, got %s", jsonType(value))}}
+		}
+		schemaErrs := []schemaError{}
+		for i, item := range arr {
+			schemaErrs = append(schemaErrs, s.validate(node.Items, item, joinField(field, fmt.Sprintf("%d", i)))...)
+		}
+		return schemaErrs
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected string, got %s", jsonType(value))}}
+		}
+		if node.Format == "byte" {
+			if _, err := base64.StdEncoding.DecodeString(str); err != nil {
+				return []schemaError{{Field: field, Message: "expected base64 encoded data"}}
+			}
+		}
+		if len(node.Enum) > 0 && !containsEnumValue(node.Enum, str) {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("unsupported value %q, expected one of %s", str, enumValues(node.Enum))}}
+		}
+
+	case "integer":
+		if !isInteger(value) {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected integer, got %s", jsonType(value))}}
+		}
+
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected number, got %s", jsonType(value))}}
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []schemaError{{Field: field, Message: fmt.Sprintf("expected boolean, got %s", jsonType(value))}}
+		}
+	}
+
+	return nil
+}
+
+func (s *kubernetesSchema) validateObject(node *schemaNode, obj map[string]interface{}, field string) []schemaError {
+	schemaErrs := []schemaError{}
+
+	for _, required := range node.Required {
+		if _, ok := obj[required]; !ok {
+			schemaErrs = append(schemaErrs, schemaError{Field: joinField(field, required), Message: "required field is missing"})
+		}
+	}
+
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if property, ok := node.Properties[key]; ok {
+			schemaErrs = append(schemaErrs, s.validate(property, obj[key], joinField(field, key))...)
+			continue
+		}
+		if node.AdditionalProperties != nil {
+			schemaErrs = append(schemaErrs, s.validate(node.AdditionalProperties, obj[key], joinField(field, key))...)
+			continue
+		}
+		if len(node.Properties) > 0 {
+			// unknown fields are rejected by kubectl's default strict field validation
+			schemaErrs = append(schemaErrs, schemaError{Field: joinField(field, key), Message: "unknown field"})
+		}
+	}
+
+	return schemaErrs
+}
+
+func joinField(field string, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+func isInteger(value interface{}) bool {
+	f, ok := value.(float64)
+	return ok && f == math.Trunc(f)
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func containsEnumValue(enum []interface{}, value string) bool {
+	for _, e := range enum {
+		if e == value {
+			return true
+		}
+	}
+	return false
+}
+
+func enumValues(enum []interface{}) string {
+	values := []string{}
+	for _, e := range enum {
+		values = append(values, fmt.Sprintf("%q", e))
+	}
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,329 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/mfa/types"
+	"github.com/replicatedhq/kots/pkg/util"
+	"github.com/rqlite/gorqlite"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	SecretName = "kotsadm-mfa"
+
+	issuer = "Admin Console"
+
+	recoveryCodeCount    = 10
+	recoveryCodeLength   = 10
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var (
+	ErrAlreadyEnabled      = errors.New("multi-factor authentication is already enabled")
+	ErrNotEnabled          = errors.New("multi-factor authentication is not enabled")
+	ErrNoPendingEnrollment = errors.New("multi-factor authentication enrollment has not been started")
+	ErrInvalidCode         = errors.New("invalid authentication code")
+)
+
+// mfaLock - mutex to prevent a code from being used twice by concurrent requests
+var mfaLock = sync.Mutex{}
+
+// now is overridden in tests
+var now = time.Now
+
+// GetStatus returns the enrollment status of the admin console
+func GetStatus(clientset kubernetes.Interface, namespace string) (*types.Status, error) {
+	secret, err := getSecret(clientset, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mfa secret")
+	}
+
+	status := &types.Status{}
+	if secret == nil || len(secret.Data["totpSecret"]) == 0 {
+		return status, nil
+	}
+
+	status.Enabled = true
+	status.RecoveryCodesRemaining = len(recoveryCodeHashes(secret))
+	if enabledAt, err := time.Parse(time.RFC3339, string(secret.Data["enabledAt"])); err == nil {
+		status.EnabledAt = &enabledAt
+	}
+
+	return status, nil
+}
+
+// IsEnabled returns true if a second factor is required to log in
+func IsEnabled(clientset kubernetes.Interface, namespace string) (bool, error) {
+	status, err := GetStatus(clientset, namespace)
+	if err != nil {
+		return false, err
+	}
+	return status.Enabled, nil
+}
+
+// BeginEnrollment generates a new totp secret that becomes active once a code generated from it is confirmed
+func BeginEnrollment(clientset kubernetes.Interface, namespace string, accountName string) (*types.Enrollment, error) {
+	mfaLock.Lock()
+	defer mfaLock.Unlock()
+
+	secret, err := getSecret(clientset, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mfa secret")
+	}
+	if secret != nil && len(secret.Data["totpSecret"]) > 0 {
+		return nil, ErrAlreadyEnabled
+	}
+
+	totpSecret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate totp secret")
+	}
+
+	data := map[string][]byte{
+		"pendingTotpSecret": []byte(totpSecret),
+	}
+	if err := saveSecret(clientset, namespace, secret, data); err != nil {
+		return nil, errors.Wrap(err, "failed to save mfa secret")
+	}
+
+	return &types.Enrollment{
+		Secret:          totpSecret,
+		ProvisioningURI: provisioningURI(totpSecret, issuer, accountName),
+	}, nil
+}
+
+// ConfirmEnrollment enables multi-factor authentication if the code was generated from the pending secret,
+// and returns the recovery codes that can be used in place of a code if the authenticator is lost
+func ConfirmEnrollment(clientset kubernetes.Interface, namespace string, code string) ([]string, error) {
+	mfaLock.Lock()
+	defer mfaLock.Unlock()
+
+	secret, err := getSecret(clientset, namespace)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get mfa secret")
+	}
+	if secret != nil && len(secret.Data["totpSecret"]) > 0 {
+		return nil, ErrAlreadyEnabled
+	}
+	if secret == nil || len(secret.Data["pendingTotpSecret"]) == 0 {
+		return nil, ErrNoPendingEnrollment
+	}
+
+	totpSecret := string(secret.Data["pendingTotpSecret"])
+	step, ok := validateTOTPCode(totpSecret, normalizeCode(code), now(), 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	recoveryCodes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate recovery codes")
+	}
+
+	data := map[string][]byte{
+		"totpSecret":    []byte(totpSecret),
+		"recoveryCodes": []byte(strings.Join(hashes, "\n")),
+		"lastUsedStep":  []byte(strconv.FormatInt(step, 10)),
+		"enabledAt":     []byte(now().UTC().Format(time.RFC3339)),
+	}
+	if err := saveSecret(clientset, namespace, secret, data); err != nil {
+		return nil, errors.Wrap(err, "failed to save mfa secret")
+	}
+
+	return recoveryCodes, nil
+}
+
+// Verify checks a totp code or a recovery code. a recovery code can only be used once.
+func Verify(clientset kubernetes.Interface, namespace string, code string) error {
+	mfaLock.Lock()
+	defer mfaLock.Unlock()
+
+	secret, err := getSecret(clientset, namespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to get mfa secret")
+	}
+	if secret == nil || len(secret.Data["totpSecret"]) == 0 {
+		return ErrNotEnabled
+	}
+
+	code = normalizeCode(code)
+
+	if isTOTPCode(code) {
+		lastUsedStep, _ := strconv.ParseInt(string(secret.Data["lastUsedStep"]), 10, 64)
+		step, ok := validateTOTPCode(string(secret.Data["totpSecret"]), code, now(), lastUsedStep)
+		if !ok {
+			return ErrInvalidCode
+		}
+
+		secret.Data["lastUsedStep"] = []byte(strconv.FormatInt(step, 10))
+		if err := saveSecret(clientset, namespace, secret, secret.Data); err != nil {
+			return errors.Wrap(err, "failed to save mfa secret")
+		}
+		return nil
+	}
+
+	hashes := recoveryCodeHashes(secret)
+	for i, hash := range hashes {
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) != nil {
+			continue
+		}
+
+		remaining := append(hashes[:i:i], hashes[i+1:]...)
+		secret.Data["recoveryCodes"] = []byte(strings.Join(remaining, "\n"))
+		if err := saveSecret(clientset, namespace, secret, secret.Data); err != nil {
+			return errors.Wrap(err, "failed to save mfa secret")
+		}
+		return nil
+	}
+
+	return ErrInvalidCode
+}
+
+// Disable turns off multi-factor authentication after verifying a code
+func Disable(clientset kubernetes.Interface, namespace string, code string) error {
+	if err := Verify(clientset, namespace, code); err != nil {
+		return err
+	}
+
+	if err := Reset(clientset, namespace); err != nil {
+		return errors.Wrap(err, "failed to reset mfa")
+	}
+
+	return nil
+}
+
+// Reset turns off multi-factor authentication without a code and unlocks the admin console.
+// this is used by the cli when access to the authenticator has been lost, together with ResetFailedLoginCount.
+func Reset(clientset kubernetes.Interface, namespace string) error {
+	mfaLock.Lock()
+	defer mfaLock.Unlock()
+
+	err := clientset.CoreV1().Secrets(namespace).Delete(context.TODO(), SecretName, metav1.DeleteOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete mfa secret")
+	}
+
+	passwordSecret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), util.PasswordSecretName, metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, "failed to get password secret")
+	}
+
+	delete(passwordSecret.Labels, "numAttempts")
+	delete(passwordSecret.Labels, "lastFailure")
+
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), passwordSecret, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to update password secret")
+	}
+
+	return nil
+}
+
+// ResetFailedLoginCount unlocks the admin console when the failed logins are counted in the database
+// instead of the password secret
+func ResetFailedLoginCount(db *gorqlite.Connection) error {
+	wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     `delete from kotsadm_params where key = ?`,
+		Arguments: []interface{}{"failed.login.count"},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete failed login count: %v: %v", err, wr.Err)
+	}
+
+	return nil
+}
+
+func getSecret(clientset kubernetes.Interface, namespace string) (*corev1.Secret, error) {
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), SecretName, metav1.GetOptions{})
+	if err != nil {
+		if kuberneteserrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return secret, nil
+}
+
+func saveSecret(clientset kubernetes.Interface, namespace string, existing *corev1.Secret, data map[string][]byte) error {
+	if existing == nil {
+		secret := &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "v1",
+				Kind:       "Secret",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      SecretName,
+				Namespace: namespace,
+				Labels:    kotsadmtypes.GetKotsadmLabels(),
+			},
+			Data: data,
+		}
+		if _, err := clientset.CoreV1().Secrets(namespace).Create(context.TODO(), secret, metav1.CreateOptions{}); err != nil {
+			return errors.Wrap(err, "failed to create secret")
+		}
+		return nil
+	}
+
+	existing.Data = data
+	if _, err := clientset.CoreV1().Secrets(namespace).Update(context.TODO(), existing, metav1.UpdateOptions{}); err != nil {
+		return errors.Wrap(err, "failed to update secret")
+	}
+	return nil
+}
+
+func recoveryCodeHashes(secret *corev1.Secret) []string {
+	hashes := []string{}
+	for _, hash := range strings.Split(string(secret.Data["recoveryCodes"]), "\n") {
+		if hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := []string{}
+	hashes := []string{}
+	for i := 0; i < recoveryCodeCount; i++ {
+		code := make([]byte, recoveryCodeLength)
+		for j := range code {
+			n, err := rand.Int(rand.Reader, big.NewInt(int64(len(recoveryCodeAlphabet))))
+			if err != nil {
+				return nil, nil, errors.Wrap(err, "failed to generate random number")
+			}
+			code[j] = recoveryCodeAlphabet[n.Int64()]
+		}
+
+		hash, err := bcrypt.GenerateFromPassword(code, 10)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "failed to hash recovery code")
+		}
+
+		half := recoveryCodeLength / 2
+		codes = append(codes, string(code[:half])+"-"+string(code[half:]))
+		hashes = append(hashes, string(hash))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode strips the separators users may type and the dash in recovery codes
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
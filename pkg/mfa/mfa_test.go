@@ -0,0 +1,159 @@
+package mfa
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rqlite/gorqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestEnrollment(t *testing.T) {
+	req := require.New(t)
+
+	current := time.Unix(1700000000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	clientset := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "kotsadm-password",
+			Namespace: "default",
+			Labels:    map[string]string{"numAttempts": "11", "lastFailure": "1700000000"},
+		},
+	})
+
+	enabled, err := IsEnabled(clientset, "default")
+	req.NoError(err)
+	assert.False(t, enabled)
+
+	err = Verify(clientset, "default", "123456")
+	assert.ErrorIs(t, err, ErrNotEnabled)
+
+	_, err = ConfirmEnrollment(clientset, "default", "123456")
+	assert.ErrorIs(t, err, ErrNoPendingEnrollment)
+
+	enrollment, err := BeginEnrollment(clientset, "default", "kotsadm.example.com")
+	req.NoError(err)
+	assert.Contains(t, enrollment.ProvisioningURI, enrollment.Secret)
+
+	// enrollment is not active until confirmed
+	enabled, err = IsEnabled(clientset, "default")
+	req.NoError(err)
+	assert.False(t, enabled)
+
+	_, err = ConfirmEnrollment(clientset, "default", "000000")
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	code, err := generateTOTPCode(enrollment.Secret, timeStep(current))
+	req.NoError(err)
+	recoveryCodes, err := ConfirmEnrollment(clientset, "default", code)
+	req.NoError(err)
+	assert.Len(t, recoveryCodes, recoveryCodeCount)
+
+	status, err := GetStatus(clientset, "default")
+	req.NoError(err)
+	assert.True(t, status.Enabled)
+	assert.Equal(t, recoveryCodeCount, status.RecoveryCodesRemaining)
+
+	_, err = BeginEnrollment(clientset, "default", "kotsadm.example.com")
+	assert.ErrorIs(t, err, ErrAlreadyEnabled)
+
+	// the code used to confirm enrollment can't be used to log in
+	err = Verify(clientset, "default", code)
+	assert.ErrorIs(t, err, ErrInvalidCode)
+
+	current = current.Add(totpPeriod * time.Second)
+	code, err = generateTOTPCode(enrollment.Secret, timeStep(current))
+	req.NoError(err)
+	req.NoError(Verify(clientset, "default", code))
+	assert.ErrorIs(t, Verify(clientset, "default", code), ErrInvalidCode)
+
+	// recovery codes can be used once, with or without the dash
+	req.NoError(Verify(clientset, "default", recoveryCodes[0]))
+	assert.ErrorIs(t, Verify(clientset, "default", recoveryCodes[0]), ErrInvalidCode)
+	req.NoError(Verify(clientset, "default", " "+removeDash(recoveryCodes[1])+" "))
+
+	status, err = GetStatus(clientset, "default")
+	req.NoError(err)
+	assert.Equal(t, recoveryCodeCount-2, status.RecoveryCodesRemaining)
+
+	// resetting turns off mfa and unlocks the admin console
+	req.NoError(Reset(clientset, "default"))
+
+	enabled, err = IsEnabled(clientset, "default")
+	req.NoError(err)
+	assert.False(t, enabled)
+
+	passwordSecret, err := clientset.CoreV1().Secrets("default").Get(context.TODO(), "kotsadm-password", metav1.GetOptions{})
+	req.NoError(err)
+	assert.Empty(t, passwordSecret.Labels["numAttempts"])
+	assert.Empty(t, passwordSecret.Labels["lastFailure"])
+}
+
+func TestDisable(t *testing.T) {
+	req := require.New(t)
+
+	current := time.Unix(1700000000, 0)
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	clientset := fake.NewSimpleClientset()
+
+	enrollment, err := BeginEnrollment(clientset, "default", "kotsadm.example.com")
+	req.NoError(err)
+	code, err := generateTOTPCode(enrollment.Secret, timeStep(current))
+	req.NoError(err)
+	_, err = ConfirmEnrollment(clientset, "default", code)
+	req.NoError(err)
+
+	assert.ErrorIs(t, Disable(clientset, "default", "000000"), ErrInvalidCode)
+
+	enabled, err := IsEnabled(clientset, "default")
+	req.NoError(err)
+	assert.True(t, enabled)
+
+	current = current.Add(totpPeriod * time.Second)
+	code, err = generateTOTPCode(enrollment.Secret, timeStep(current))
+	req.NoError(err)
+	req.NoError(Disable(clientset, "default", code))
+
+	enabled, err = IsEnabled(clientset, "default")
+	req.NoError(err)
+	assert.False(t, enabled)
+}
+
+func TestResetFailedLoginCount(t *testing.T) {
+	req := require.New(t)
+
+	statements := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/db/execute" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		statements = append(statements, string(body))
+		w.Write([]byte(`{"results":[{"rows_affected":1}]}`))
+	}))
+	defer server.Close()
+
+	db, err := gorqlite.Open(server.URL + "?disableClusterDiscovery=true")
+	req.NoError(err)
+
+	err = ResetFailedLoginCount(&db)
+	req.NoError(err)
+	assert.Equal(t, []string{`[["delete from kotsadm_params where key = ?","failed.login.count"]]`}, statements)
+}
+
+func removeDash(code string) string {
+	return code[:recoveryCodeLength/2] + code[recoveryCodeLength/2+1:]
+}
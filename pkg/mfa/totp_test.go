@@ -0,0 +1,79 @@
+package mfa
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateTOTPCode(t *testing.T) {
+	// test vectors from RFC 6238 appendix B, truncated to 6 digits
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{unix: 59, want: "287082"},
+		{unix: 1111111109, want: "081804"},
+		{unix: 1111111111, want: "050471"},
+		{unix: 1234567890, want: "005924"},
+		{unix: 2000000000, want: "279037"},
+	}
+	for _, tt := range tests {
+		got, err := generateTOTPCode(secret, timeStep(time.Unix(tt.unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.unix)
+	}
+}
+
+func TestValidateTOTPCode(t *testing.T) {
+	secret, err := generateTOTPSecret()
+	require.NoError(t, err)
+
+	now := time.Unix(1700000000, 0)
+	current := timeStep(now)
+
+	code, err := generateTOTPCode(secret, current)
+	require.NoError(t, err)
+
+	step, ok := validateTOTPCode(secret, code, now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, current, step)
+
+	// codes from adjacent steps are accepted to allow for clock skew
+	previous, err := generateTOTPCode(secret, current-1)
+	require.NoError(t, err)
+	_, ok = validateTOTPCode(secret, previous, now, 0)
+	assert.True(t, ok)
+
+	old, err := generateTOTPCode(secret, current-2)
+	require.NoError(t, err)
+	_, ok = validateTOTPCode(secret, old, now, 0)
+	assert.False(t, ok)
+
+	// a code can't be used again
+	_, ok = validateTOTPCode(secret, code, now, current)
+	assert.False(t, ok)
+
+	_, ok = validateTOTPCode(secret, "12345", now, 0)
+	assert.False(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := provisioningURI("JBSWY3DPEHPK3PXP", "Admin Console", "kotsadm.example.com:8800")
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Admin Console:kotsadm.example.com:8800", u.Path)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", u.Query().Get("secret"))
+	assert.Equal(t, "Admin Console", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
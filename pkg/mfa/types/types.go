@@ -0,0 +1,17 @@
+package types
+
+import "time"
+
+// Status describes the multi-factor authentication enrollment of the admin console
+type Status struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabledAt,omitempty"`
+	RecoveryCodesRemaining int        `json:"recoveryCodesRemaining"`
+}
+
+// Enrollment is returned when enrollment is started. the provisioning uri is meant to be shown as a qr code
+// and scanned by an authenticator app, the secret can be entered manually instead.
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
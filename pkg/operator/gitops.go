@@ -0,0 +1,165 @@
+package operator
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	apptypes "github.com/replicatedhq/kots/pkg/app/types"
+	"github.com/replicatedhq/kots/pkg/gitops"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	storetypes "github.com/replicatedhq/kots/pkg/store/types"
+)
+
+// gitOpsSyncStatusLoop updates the version history and app status of gitops apps from the flux or argo cd
+// objects that deploy the repo, since kots does not deploy these apps itself. the state of the pull requests
+// that were opened for versions is refreshed as well.
+func (o *Operator) gitOpsSyncStatusLoop() {
+	apps, err := o.store.ListAppsForDownstream(o.clusterID)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to list installed apps for downstream"))
+		return
+	}
+
+	for _, a := range apps {
+		if err := o.syncGitOpsStatusForApp(a); err != nil {
+			logger.Error(errors.Wrapf(err, "failed to sync gitops status for app %s", a.ID))
+		}
+	}
+}
+
+func (o *Operator) syncGitOpsStatusForApp(a *apptypes.App) error {
+	if !a.IsGitOps || a.RestoreInProgressName != "" {
+		return nil
+	}
+
+	gitOpsConfig, err := gitops.GetDownstreamGitOpsConfig(o.k8sClientset, a.ID, o.clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get gitops config")
+	}
+	if gitOpsConfig == nil || !gitOpsConfig.IsConnected {
+		return nil
+	}
+
+	if gitOpsConfig.Action == gitops.ActionPullRequest {
+		if err := o.refreshGitOpsPullRequests(a.ID, gitOpsConfig); err != nil {
+			logger.Error(errors.Wrapf(err, "failed to refresh gitops pull requests for app %s", a.ID))
+		}
+	}
+
+	if !gitOpsConfig.SyncStatus {
+		return nil
+	}
+
+	dynamicClient, err := k8sutil.GetDynamicClient()
+	if err != nil {
+		return errors.Wrap(err, "failed to get dynamic client")
+	}
+
+	syncStatus, err := gitops.GetSyncStatus(context.TODO(), dynamicClient, gitOpsConfig)
+	if err != nil {
+		return errors.Wrap(err, "failed to get sync status")
+	}
+	if syncStatus == nil {
+		// nothing deploys the repo yet
+		return nil
+	}
+
+	versions, err := o.store.GetDownstreamVersions(a.ID, o.clusterID, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to get downstream versions")
+	}
+
+	version := findGitOpsCommitVersion(versions.AllVersions, syncStatus.Revision)
+	if version != nil {
+		if err := o.setGitOpsVersionStatus(a.ID, versions.CurrentVersion, version, syncStatus); err != nil {
+			return errors.Wrap(err, "failed to set version status")
+		}
+	} else {
+		// the revision is not a commit that kots made or a pull request that kots opened, such as a commit that was
+		// pushed by hand, so the status applies to the current version
+		version = versions.CurrentVersion
+	}
+	if version == nil {
+		return nil
+	}
+
+	if err := o.store.SetAppStatus(a.ID, syncStatus.ResourceStates, time.Now(), version.ParentSequence); err != nil {
+		return errors.Wrap(err, "failed to set app status")
+	}
+
+	return nil
+}
+
+// refreshGitOpsPullRequests updates the state of the open pull requests, which can be merged or closed on the provider at any time
+func (o *Operator) refreshGitOpsPullRequests(appID string, gitOpsConfig *gitops.GitOpsConfig) error {
+	openPullRequests, err := o.store.ListOpenGitOpsPullRequests(appID, o.clusterID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list open pull requests")
+	}
+
+	changed, err := gitops.RefreshPullRequests(gitOpsConfig, openPullRequests)
+	if err != nil {
+		return errors.Wrap(err, "failed to refresh pull requests")
+	}
+
+	if err := o.store.SetGitOpsPullRequestStates(appID, o.clusterID, changed); err != nil {
+		return errors.Wrap(err, "failed to set pull request states")
+	}
+
+	return nil
+}
+
+func (o *Operator) setGitOpsVersionStatus(appID string, currentVersion *downstreamtypes.DownstreamVersion, version *downstreamtypes.DownstreamVersion, syncStatus *gitops.SyncStatus) error {
+	switch syncStatus.State {
+	case gitops.SyncStateDeployed:
+		if version.Status != storetypes.VersionDeployed {
+			if err := o.store.SetDownstreamVersionStatus(appID, version.Sequence, storetypes.VersionDeployed, ""); err != nil {
+				return errors.Wrap(err, "failed to set downstream version status")
+			}
+		}
+		if currentVersion == nil || currentVersion.Sequence != version.Sequence {
+			if err := o.store.MarkAsCurrentDownstreamVersion(appID, version.Sequence); err != nil {
+				return errors.Wrap(err, "failed to mark as current downstream version")
+			}
+		}
+
+	case gitops.SyncStateFailed:
+		if version.Status != storetypes.VersionFailed {
+			if err := o.store.SetDownstreamVersionStatus(appID, version.Sequence, storetypes.VersionFailed, syncStatus.Message); err != nil {
+				return errors.Wrap(err, "failed to set downstream version status")
+			}
+		}
+
+	case gitops.SyncStatePending:
+		// versions stay pending until they are applied, and a version that is being re-applied keeps its last status
+	}
+
+	return nil
+}
+
+// findGitOpsCommitVersion returns the version that was committed as the given revision,
+// or whose pull request was merged as the given revision
+func findGitOpsCommitVersion(versions []*downstreamtypes.DownstreamVersion, revision string) *downstreamtypes.DownstreamVersion {
+	if revision == "" {
+		return nil
+	}
+	for _, v := range versions {
+		if v.CommitHash == revision {
+			return v
+		}
+		if v.PullRequest != nil && v.PullRequest.MergeCommitHash == revision {
+			return v
+		}
+	}
+	// versions that were committed before the commit hash was recorded only have the commit url
+	for _, v := range versions {
+		if v.CommitHash == "" && v.CommitURL != "" && strings.Contains(v.CommitURL, revision) {
+			return v
+		}
+	}
+	return nil
+}
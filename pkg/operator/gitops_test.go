@@ -0,0 +1,43 @@
+package operator
+
+import (
+	"testing"
+
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_findGitOpsCommitVersion(t *testing.T) {
+	versions := []*downstreamtypes.DownstreamVersion{
+		{Sequence: 3, CommitHash: "ccc", PullRequest: &gitopstypes.PullRequest{Number: 2, MergeCommitHash: "ddd"}},
+		{Sequence: 2, CommitHash: "bbb"},
+		{Sequence: 1, CommitURL: "https://github.com/org/repo/commit/aaa"},
+		{Sequence: 0},
+	}
+
+	tests := []struct {
+		name     string
+		revision string
+		want     int64
+	}{
+		{name: "pushed commit", revision: "bbb", want: 2},
+		{name: "pull request branch commit", revision: "ccc", want: 3},
+		{name: "pull request merge commit", revision: "ddd", want: 3},
+		{name: "commit url of an older version", revision: "aaa", want: 1},
+		{name: "commit that kots did not make", revision: "eee", want: -1},
+		{name: "no revision", revision: "", want: -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := findGitOpsCommitVersion(versions, tt.revision)
+			if tt.want == -1 {
+				assert.Nil(t, got)
+				return
+			}
+			if assert.NotNil(t, got) {
+				assert.Equal(t, tt.want, got.Sequence)
+			}
+		})
+	}
+}
@@ -107,6 +107,7 @@ func (o *Operator) Start() error {
 	go o.resumeDeployments()
 	o.watchDeployments()
 	startLoop(o.restoreLoop, 2)
+	startLoop(o.gitOpsSyncStatusLoop, 30)
 
 	return nil
 }
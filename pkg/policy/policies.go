@@ -63,6 +63,13 @@ var (
 	PasswordChange = Must(NewPolicy(ActionWrite, "passwordupdate."))
 )
 
+// Sessions
+
+var (
+	SessionRead  = Must(NewPolicy(ActionRead, "session."))
+	SessionWrite = Must(NewPolicy(ActionWrite, "session."))
+)
+
 // Kotsadm Identity Service
 
 var (
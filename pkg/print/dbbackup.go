@@ -0,0 +1,35 @@
+package print
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	units "github.com/docker/go-units"
+	dbbackuptypes "github.com/replicatedhq/kots/pkg/dbbackup/types"
+)
+
+func DBBackups(backups []dbbackuptypes.Backup, format string) {
+	switch format {
+	case "json":
+		printDBBackupsJSON(backups)
+	default:
+		printDBBackupsTable(backups)
+	}
+}
+
+func printDBBackupsJSON(backups []dbbackuptypes.Backup) {
+	str, _ := json.MarshalIndent(backups, "", "    ")
+	fmt.Println(string(str))
+}
+
+func printDBBackupsTable(backups []dbbackuptypes.Backup) {
+	w := NewTabWriter()
+	defer w.Flush()
+
+	fmtColumns := "%s\t%s\t%s\n"
+	fmt.Fprintf(w, fmtColumns, "NAME", "SIZE", "CREATED")
+	for _, b := range backups {
+		fmt.Fprintf(w, fmtColumns, b.Name, units.HumanSize(float64(b.Size)), b.CreatedAt.Format(time.RFC3339))
+	}
+}
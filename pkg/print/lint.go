@@ -26,10 +26,14 @@ func LintResult(result *types.LintResult, format string, verbose bool) error {
 		return printLintJSON(filteredResult)
 	case "yaml":
 		return printLintYAML(filteredResult)
+	case "sarif":
+		return printLintSARIF(filteredResult)
+	case "junit":
+		return printLintJUnit(filteredResult)
 	case "table", "":
 		return printLintTable(filteredResult)
 	default:
-		return errors.Errorf("unsupported output format: %s (supported: table, json, yaml)", format)
+		return errors.Errorf("unsupported output format: %s (supported: table, json, yaml, sarif, junit)", format)
 	}
 }
 
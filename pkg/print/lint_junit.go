@@ -0,0 +1,122 @@
+package print
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	ClassName  string           `xml:"classname,attr"`
+	File       string           `xml:"file,attr,omitempty"`
+	Line       int              `xml:"line,attr,omitempty"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func printLintJUnit(result *types.LintResult) error {
+	output, err := xml.MarshalIndent(lintResultToJUnit(result), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal JUnit XML")
+	}
+	fmt.Println(xml.Header + string(output))
+	return nil
+}
+
+// lintResultToJUnit reports each lint finding as a test case. only errors are failures, since warnings
+// and info messages do not fail linting by default. a result without findings has a single passing test case.
+func lintResultToJUnit(result *types.LintResult) junitTestSuites {
+	suite := junitTestSuite{
+		Name:      "kots lint",
+		TestCases: []junitTestCase{},
+	}
+
+	fingerprints := lintFingerprints(result.LintExpressions)
+	for i, expr := range result.LintExpressions {
+		line := lintExpressionLine(expr)
+
+		name := expr.Rule
+		if expr.Path != "" {
+			location := expr.Path
+			if line > 0 {
+				location = fmt.Sprintf("%s:%d", expr.Path, line)
+			}
+			name = fmt.Sprintf("%s %s", expr.Rule, location)
+		}
+
+		testCase := junitTestCase{
+			Name:      name,
+			ClassName: expr.Rule,
+			File:      expr.Path,
+			Line:      line,
+			Properties: &junitProperties{
+				Properties: []junitProperty{
+					{Name: "severity", Value: expr.Type},
+					{Name: "fingerprint", Value: fingerprints[i]},
+				},
+			},
+		}
+
+		if expr.Type == "error" {
+			testCase.Failure = &junitFailure{
+				Message: expr.Message,
+				Type:    expr.Type,
+				Text:    expr.Message,
+			}
+			suite.Failures++
+		} else {
+			testCase.SystemOut = fmt.Sprintf("%s: %s", expr.Type, expr.Message)
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	if len(suite.TestCases) == 0 {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      "kots lint",
+			ClassName: "kots-lint",
+		})
+	}
+	suite.Tests = len(suite.TestCases)
+
+	return junitTestSuites{
+		Name:     suite.Name,
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+}
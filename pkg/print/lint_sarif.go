@@ -0,0 +1,188 @@
+package print
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/lint/types"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+
+	// lintFingerprintKey is the key of the lint fingerprint in the SARIF partial fingerprints
+	lintFingerprintKey = "kotsLintFingerprint/v1"
+)
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string                 `json:"id"`
+	ShortDescription     sarifMessage           `json:"shortDescription"`
+	DefaultConfiguration sarifRuleConfiguration `json:"defaultConfiguration"`
+}
+
+type sarifRuleConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifMessage      `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+func printLintSARIF(result *types.LintResult) error {
+	output, err := json.MarshalIndent(lintResultToSARIF(result), "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal SARIF")
+	}
+	fmt.Println(string(output))
+	return nil
+}
+
+func lintResultToSARIF(result *types.LintResult) sarifLog {
+	rules := []sarifRule{}
+	ruleIndexes := map[string]int{}
+	results := []sarifResult{}
+
+	fingerprints := lintFingerprints(result.LintExpressions)
+	for i, expr := range result.LintExpressions {
+		level := sarifLevel(expr.Type)
+
+		ruleIndex, ok := ruleIndexes[expr.Rule]
+		if !ok {
+			ruleIndex = len(rules)
+			ruleIndexes[expr.Rule] = ruleIndex
+			rules = append(rules, sarifRule{
+				ID:                   expr.Rule,
+				ShortDescription:     sarifMessage{Text: expr.Rule},
+				DefaultConfiguration: sarifRuleConfiguration{Level: level},
+			})
+		}
+
+		res := sarifResult{
+			RuleID:    expr.Rule,
+			RuleIndex: ruleIndex,
+			Level:     level,
+			Message:   sarifMessage{Text: expr.Message},
+			PartialFingerprints: map[string]string{
+				lintFingerprintKey: fingerprints[i],
+			},
+		}
+
+		if expr.Path != "" {
+			location := sarifLocation{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: expr.Path},
+				},
+			}
+			if line := lintExpressionLine(expr); line > 0 {
+				location.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+			}
+			res.Locations = []sarifLocation{location}
+		}
+
+		results = append(results, res)
+	}
+
+	return sarifLog{
+		Schema:  sarifSchema,
+		Version: sarifVersion,
+		Runs: []sarifRun{
+			{
+				Tool: sarifTool{
+					Driver: sarifDriver{
+						Name:           "kots-lint",
+						InformationURI: "https://github.com/replicatedhq/kots",
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+func sarifLevel(lintType string) string {
+	switch lintType {
+	case "error":
+		return "error"
+	case "warn":
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+// lintExpressionLine returns the line of the first position of the lint expression, or 0 if it does not have one
+func lintExpressionLine(expr types.LintExpression) int {
+	if len(expr.Positions) == 0 {
+		return 0
+	}
+	return expr.Positions[0].Start.Line
+}
+
+// lintFingerprints returns a fingerprint for each lint expression so that findings can be tracked across runs.
+// lines are not part of the fingerprint since they change when unrelated lines are added or removed. findings
+// that are otherwise the same are told apart by the order they appear in.
+func lintFingerprints(expressions []types.LintExpression) []string {
+	fingerprints := make([]string, 0, len(expressions))
+	occurrences := map[string]int{}
+	for _, expr := range expressions {
+		key := fmt.Sprintf("%s\x00%s\x00%s", expr.Rule, expr.Path, expr.Message)
+		occurrence := occurrences[key]
+		occurrences[key]++
+
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s\x00%d", key, occurrence)))
+		fingerprints = append(fingerprints, hex.EncodeToString(sum[:]))
+	}
+	return fingerprints
+}
@@ -0,0 +1,115 @@
+package print
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/lint/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testLintResult() *types.LintResult {
+	return &types.LintResult{
+		LintExpressions: []types.LintExpression{
+			{
+				Rule:    "invalid-yaml",
+				Type:    "error",
+				Message: "yaml: line 3: mapping values are not allowed in this context",
+				Path:    "deployment.yaml",
+				Positions: []types.LintExpressionItemPosition{
+					{Start: types.LintExpressionItemLinePosition{Line: 3}},
+				},
+			},
+			{
+				Rule:    "deployment-phase-annotation",
+				Type:    "warn",
+				Message: "deployment phase annotation must be an integer",
+				Path:    "service.yaml",
+			},
+			{
+				Rule:    "invalid-yaml",
+				Type:    "error",
+				Message: "yaml: line 3: mapping values are not allowed in this context",
+				Path:    "deployment.yaml",
+				Positions: []types.LintExpressionItemPosition{
+					{Start: types.LintExpressionItemLinePosition{Line: 9}},
+				},
+			},
+		},
+		IsComplete: true,
+	}
+}
+
+func Test_lintResultToSARIF(t *testing.T) {
+	log := lintResultToSARIF(testLintResult())
+
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, "invalid-yaml", run.Tool.Driver.Rules[0].ID)
+	assert.Equal(t, "error", run.Tool.Driver.Rules[0].DefaultConfiguration.Level)
+	assert.Equal(t, "deployment-phase-annotation", run.Tool.Driver.Rules[1].ID)
+	assert.Equal(t, "warning", run.Tool.Driver.Rules[1].DefaultConfiguration.Level)
+
+	require.Len(t, run.Results, 3)
+	assert.Equal(t, 0, run.Results[0].RuleIndex)
+	assert.Equal(t, "error", run.Results[0].Level)
+	assert.Equal(t, "deployment.yaml", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, &sarifRegion{StartLine: 3}, run.Results[0].Locations[0].PhysicalLocation.Region)
+
+	assert.Equal(t, 1, run.Results[1].RuleIndex)
+	assert.Equal(t, "warning", run.Results[1].Level)
+	assert.Nil(t, run.Results[1].Locations[0].PhysicalLocation.Region)
+
+	// the same finding twice in a file has different fingerprints
+	assert.NotEqual(t, run.Results[0].PartialFingerprints[lintFingerprintKey], run.Results[2].PartialFingerprints[lintFingerprintKey])
+}
+
+func Test_lintFingerprints(t *testing.T) {
+	result := testLintResult()
+	fingerprints := lintFingerprints(result.LintExpressions)
+	require.Len(t, fingerprints, 3)
+
+	// fingerprints are stable across runs
+	assert.Equal(t, fingerprints, lintFingerprints(result.LintExpressions))
+
+	// fingerprints do not change when lines move
+	result.LintExpressions[0].Positions[0].Start.Line = 5
+	assert.Equal(t, fingerprints, lintFingerprints(result.LintExpressions))
+
+	// fingerprints of other findings do not change when a finding is fixed
+	assert.Equal(t, fingerprints[1], lintFingerprints(result.LintExpressions[1:2])[0])
+}
+
+func Test_lintResultToJUnit(t *testing.T) {
+	suites := lintResultToJUnit(testLintResult())
+
+	assert.Equal(t, 3, suites.Tests)
+	assert.Equal(t, 2, suites.Failures)
+	require.Len(t, suites.Suites, 1)
+
+	testCases := suites.Suites[0].TestCases
+	require.Len(t, testCases, 3)
+	assert.Equal(t, "invalid-yaml deployment.yaml:3", testCases[0].Name)
+	assert.Equal(t, 3, testCases[0].Line)
+	require.NotNil(t, testCases[0].Failure)
+	assert.Equal(t, "error", testCases[0].Failure.Type)
+
+	assert.Equal(t, "deployment-phase-annotation service.yaml", testCases[1].Name)
+	assert.Nil(t, testCases[1].Failure)
+	assert.Equal(t, "warn: deployment phase annotation must be an integer", testCases[1].SystemOut)
+
+	_, err := xml.Marshal(suites)
+	require.NoError(t, err)
+}
+
+func Test_lintResultToJUnitNoFindings(t *testing.T) {
+	suites := lintResultToJUnit(&types.LintResult{IsComplete: true})
+
+	assert.Equal(t, 1, suites.Tests)
+	assert.Equal(t, 0, suites.Failures)
+	assert.Nil(t, suites.Suites[0].TestCases[0].Failure)
+}
@@ -0,0 +1,34 @@
+package print
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	sessiontypes "github.com/replicatedhq/kots/pkg/session/types"
+)
+
+func Sessions(sessions []sessiontypes.SessionInfo, format string) {
+	switch format {
+	case "json":
+		printSessionsJSON(sessions)
+	default:
+		printSessionsTable(sessions)
+	}
+}
+
+func printSessionsJSON(sessions []sessiontypes.SessionInfo) {
+	str, _ := json.MarshalIndent(sessions, "", "    ")
+	fmt.Println(string(str))
+}
+
+func printSessionsTable(sessions []sessiontypes.SessionInfo) {
+	w := NewTabWriter()
+	defer w.Flush()
+
+	fmtColumns := "%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+	fmt.Fprintf(w, fmtColumns, "ID", "USER", "METHOD", "IP ADDRESS", "ISSUED", "LAST ACTIVITY", "USER AGENT")
+	for _, s := range sessions {
+		fmt.Fprintf(w, fmtColumns, s.ID, s.UserID, s.AuthMethod, s.IPAddress, s.IssuedAt.Format(time.RFC3339), s.LastActivityAt.Format(time.RFC3339), s.UserAgent)
+	}
+}
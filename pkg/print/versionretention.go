@@ -0,0 +1,46 @@
+package print
+
+import (
+	"encoding/json"
+	"fmt"
+
+	units "github.com/docker/go-units"
+	versionretentiontypes "github.com/replicatedhq/kots/pkg/versionretention/types"
+)
+
+func GarbageCollectVersionsReport(report *versionretentiontypes.GarbageCollectReport, format string) {
+	if report == nil {
+		return
+	}
+
+	switch format {
+	case "json":
+		printGarbageCollectVersionsReportJSON(report)
+	default:
+		printGarbageCollectVersionsReportTable(report)
+	}
+}
+
+func printGarbageCollectVersionsReportJSON(report *versionretentiontypes.GarbageCollectReport) {
+	str, _ := json.MarshalIndent(report, "", "    ")
+	fmt.Println(string(str))
+}
+
+func printGarbageCollectVersionsReportTable(report *versionretentiontypes.GarbageCollectReport) {
+	w := NewTabWriter()
+
+	fmtColumns := "%s\t%s\t%v\t%s\n"
+	fmt.Fprintf(w, fmtColumns, "APP", "VERSION", "SEQUENCE", "ARCHIVE SIZE")
+	for _, app := range report.Apps {
+		for _, version := range app.PrunedVersions {
+			fmt.Fprintf(w, fmtColumns, app.AppSlug, version.VersionLabel, version.Sequence, units.HumanSize(float64(version.ArchiveSize)))
+		}
+	}
+	w.Flush()
+
+	if report.DryRun {
+		fmt.Printf("\n%s can be reclaimed\n", units.HumanSize(float64(report.TotalReclaimableBytes)))
+	} else {
+		fmt.Printf("\n%s reclaimed\n", units.HumanSize(float64(report.TotalReclaimableBytes)))
+	}
+}
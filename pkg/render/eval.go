@@ -0,0 +1,102 @@
+package render
+
+import (
+	"github.com/pkg/errors"
+	configtypes "github.com/replicatedhq/kots/pkg/kotsadmconfig/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	types "github.com/replicatedhq/kots/pkg/render/types"
+	"github.com/replicatedhq/kots/pkg/template"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+)
+
+// MaskedPassword is what password config items evaluate to when passwords are not shown
+const MaskedPassword = "********"
+
+// EvalTemplate evaluates a template with the same template contexts that are used to render the app version.
+// the template may be a single expression or the contents of a whole file.
+func EvalTemplate(opts types.EvalTemplateOptions) (string, error) {
+	kotsKinds := opts.KotsKinds
+	if !opts.ShowPasswords {
+		kotsKinds = maskPasswords(kotsKinds)
+	}
+
+	builderOptions, err := getBuilderOptions(kotsKinds, opts.RegistrySettings, opts.AppSlug, opts.Sequence, opts.IsAirgap, opts.Namespace)
+	if err != nil {
+		return "", err
+	}
+	// the template is supplied by the user, so it can't read from the cluster with the service account of kotsadm
+	// or persist generated values
+	builderOptions.Eval = true
+
+	builder, _, err := template.NewBuilder(builderOptions)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to create builder")
+	}
+
+	name := opts.Name
+	if name == "" {
+		name = "template"
+	}
+
+	// template errors already include the name, line and column of the failing expression
+	rendered, err := builder.RenderTemplate(name, opts.Template)
+	if err != nil {
+		return "", errors.Cause(err)
+	}
+
+	return rendered, nil
+}
+
+// maskPasswords returns a copy of the kots kinds where every password config item that has a value is
+// replaced with a mask. the values are replaced before the builder is created so that functions applied to
+// a password, such as Base64Encode, do not reveal it either.
+func maskPasswords(kotsKinds *kotsutil.KotsKinds) *kotsutil.KotsKinds {
+	if kotsKinds.Config == nil {
+		return kotsKinds
+	}
+
+	masked := *kotsKinds
+
+	values := map[string]kotsv1beta1.ConfigValue{}
+	if kotsKinds.ConfigValues != nil {
+		masked.ConfigValues = kotsKinds.ConfigValues.DeepCopy()
+		values = masked.ConfigValues.Spec.Values
+	} else {
+		masked.ConfigValues = &kotsv1beta1.ConfigValues{}
+	}
+
+	references := map[string]kotsutil.ConfigValueReference{}
+	for name, reference := range kotsKinds.ConfigValueReferences {
+		references[name] = reference
+	}
+
+	for _, group := range kotsKinds.Config.Spec.Groups {
+		for _, item := range group.Items {
+			if item.Type != configtypes.PasswordItemType {
+				continue
+			}
+
+			value := values[item.Name]
+			_, hasReference := references[item.Name]
+			hasValue := value.Value != "" || value.ValuePlaintext != "" || value.Default != "" || hasReference ||
+				item.Value.String() != "" || item.Default.String() != ""
+			if !hasValue {
+				continue
+			}
+
+			value.Value = MaskedPassword
+			value.ValuePlaintext = ""
+			value.Default = MaskedPassword
+			if values == nil {
+				values = map[string]kotsv1beta1.ConfigValue{}
+			}
+			values[item.Name] = value
+			delete(references, item.Name)
+		}
+	}
+
+	masked.ConfigValues.Spec.Values = values
+	masked.ConfigValueReferences = references
+
+	return &masked
+}
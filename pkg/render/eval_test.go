@@ -0,0 +1,116 @@
+package render
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/crypto"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	types "github.com/replicatedhq/kots/pkg/render/types"
+	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvalKotsKinds() *kotsutil.KotsKinds {
+	return &kotsutil.KotsKinds{
+		Config: &kotsv1beta1.Config{
+			Spec: kotsv1beta1.ConfigSpec{
+				Groups: []kotsv1beta1.ConfigGroup{
+					{
+						Name: "settings",
+						Items: []kotsv1beta1.ConfigItem{
+							{Name: "hostname", Type: "text"},
+							{Name: "db_password", Type: "password"},
+							{Name: "api_token", Type: "password"},
+						},
+					},
+				},
+			},
+		},
+		ConfigValues: &kotsv1beta1.ConfigValues{
+			Spec: kotsv1beta1.ConfigValuesSpec{
+				Values: map[string]kotsv1beta1.ConfigValue{
+					"hostname":    {Value: "example.com"},
+					"db_password": {Value: base64.StdEncoding.EncodeToString(crypto.Encrypt([]byte("hunter2")))},
+				},
+			},
+		},
+	}
+}
+
+func TestEvalTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		template      string
+		showPasswords bool
+		want          string
+		wantErr       string
+	}{
+		{
+			name:     "config option",
+			template: `repl{{ ConfigOption "hostname" }}`,
+			want:     "example.com",
+		},
+		{
+			name:     "file with both delimiters",
+			template: "host: '{{repl ConfigOption \"hostname\" }}'\nport: repl{{ add 8000 80 }}\n",
+			want:     "host: 'example.com'\nport: 8080\n",
+		},
+		{
+			name:     "password is masked",
+			template: `repl{{ ConfigOption "db_password" }} repl{{ ConfigOption "db_password" | Base64Encode }}`,
+			want:     "******** " + base64.StdEncoding.EncodeToString([]byte(MaskedPassword)),
+		},
+		{
+			name:          "password is shown",
+			template:      `repl{{ ConfigOption "db_password" }}`,
+			showPasswords: true,
+			want:          "hunter2",
+		},
+		{
+			name:     "empty password is not masked",
+			template: `repl{{ ConfigOption "api_token" }}`,
+			want:     "",
+		},
+		{
+			name:     "error includes the location",
+			template: "a: b\nc: repl{{ ConfigOption }}\n",
+			wantErr:  `values.yaml:2:11: wrong number of args for ConfigOption: want 1 got 0 in repl{{ ConfigOption }}`,
+		},
+		{
+			name:     "kotsadm secrets can not be looked up",
+			template: `repl{{ Lookup "v1" "Secret" "default" "kotsadm-encryption" }}`,
+			wantErr:  `values.yaml:1:8: error calling Lookup: Lookup is not available when evaluating templates in repl{{ Lookup "v1" "Secret" "default" "kotsadm-encryption" }}`,
+		},
+		{
+			name:     "generated values are not persisted",
+			template: `repl{{ GenerateOnce "app-credentials" "token" "value" }}`,
+			wantErr:  `values.yaml:1:8: error calling GenerateOnce: GenerateOnce is not available when evaluating templates in repl{{ GenerateOnce "app-credentials" "token" "value" }}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kotsKinds := testEvalKotsKinds()
+
+			got, err := EvalTemplate(types.EvalTemplateOptions{
+				KotsKinds:     kotsKinds,
+				AppSlug:       "my-app",
+				Sequence:      1,
+				Name:          "values.yaml",
+				Template:      tt.template,
+				ShowPasswords: tt.showPasswords,
+			})
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			// the kots kinds passed in are not changed
+			assert.Equal(t, testEvalKotsKinds().ConfigValues.Spec.Values["hostname"], kotsKinds.ConfigValues.Spec.Values["hostname"])
+			assert.NotEqual(t, MaskedPassword, kotsKinds.ConfigValues.Spec.Values["db_password"].Value)
+		})
+	}
+}
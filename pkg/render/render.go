@@ -53,6 +53,15 @@ func RenderContent(opts types.RenderFileOptions) ([]byte, error) {
 }
 
 func NewBuilder(kotsKinds *kotsutil.KotsKinds, registrySettings registrytypes.RegistrySettings, appSlug string, sequence int64, isAirgap bool, namespace string) (*template.Builder, error) {
+	builderOptions, err := getBuilderOptions(kotsKinds, registrySettings, appSlug, sequence, isAirgap, namespace)
+	if err != nil {
+		return nil, err
+	}
+	builder, _, err := template.NewBuilder(builderOptions)
+	return &builder, errors.Wrap(err, "failed to create builder")
+}
+
+func getBuilderOptions(kotsKinds *kotsutil.KotsKinds, registrySettings registrytypes.RegistrySettings, appSlug string, sequence int64, isAirgap bool, namespace string) (template.BuilderOptions, error) {
 	templateContextValues := make(map[string]template.ItemValue)
 	if kotsKinds.ConfigValues != nil {
 		for k, v := range kotsKinds.ConfigValues.Spec.Values {
@@ -65,7 +74,7 @@ func NewBuilder(kotsKinds *kotsutil.KotsKinds, registrySettings registrytypes.Re
 
 	err := crypto.InitFromString(kotsKinds.Installation.Spec.EncryptionKey)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to load encryption cipher")
+		return template.BuilderOptions{}, errors.Wrap(err, "failed to load encryption cipher")
 	}
 
 	configGroups := []kotsv1beta1.ConfigGroup{}
@@ -90,9 +99,9 @@ func NewBuilder(kotsKinds *kotsutil.KotsKinds, registrySettings registrytypes.Re
 		IdentityConfig:  kotsKinds.IdentityConfig,
 		Namespace:       namespace,
 		DecryptValues:   true,
+		ValueReferences: kotsKinds.ConfigValueReferences,
 	}
-	builder, _, err := template.NewBuilder(builderOptions)
-	return &builder, errors.Wrap(err, "failed to create builder")
+	return builderOptions, nil
 }
 
 // RenderDir renders an app archive dir
@@ -18,6 +18,20 @@ type RenderFileOptions struct {
 	InputContent     []byte
 }
 
+type EvalTemplateOptions struct {
+	KotsKinds        *kotsutil.KotsKinds
+	RegistrySettings registrytypes.RegistrySettings
+	AppSlug          string
+	Sequence         int64
+	IsAirgap         bool
+	Namespace        string
+	// Name is used to identify the template in errors, such as the name of the file it was read from
+	Name     string
+	Template string
+	// ShowPasswords evaluates password config items to their values instead of a mask
+	ShowPasswords bool
+}
+
 type RenderDirOptions struct {
 	ArchiveDir           string
 	App                  *apptypes.App
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/session/types"
+)
+
+const (
+	// activityUpdateInterval - how stale the last activity of a session can get before it is written to the store again.
+	// this keeps the session secret from being updated on every request.
+	activityUpdateInterval = time.Minute
+)
+
+var (
+	// idleTimeout - sessions that have not been used for this long expire. 0 means sessions only expire at ExpiresAt.
+	idleTimeout time.Duration
+
+	// trustedProxies - the forwarded headers of requests from these networks are used for the client address of sessions
+	trustedProxies []*net.IPNet
+)
+
+// InitSettings reads the idle timeout from the "session-idle-timeout" key of the kotsadm config map, and the proxies
+// that are trusted to set the address of the client from the "session-trusted-proxies" key
+func InitSettings() error {
+	installParams, err := kotsutil.GetInstallationParams(kotsadmtypes.KotsadmConfigMap)
+	if err != nil {
+		return errors.Wrap(err, "failed to get installation params")
+	}
+
+	idleTimeout = installParams.SessionIdleTimeout
+	if idleTimeout > 0 {
+		logger.Debugf("sessions will expire after %s of inactivity", idleTimeout)
+	}
+
+	trustedProxies, err = parseTrustedProxies(installParams.SessionTrustedProxies)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse trusted proxies")
+	}
+
+	return nil
+}
+
+// parseTrustedProxies parses the networks of the trusted proxies, which are either CIDRs or single addresses
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	networks := []*net.IPNet{}
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, errors.Errorf("invalid address %q", proxy)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cidr %q", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// IsIdle returns true if the session has not been used for longer than the idle timeout
+func IsIdle(sess *types.Session, now time.Time) bool {
+	if idleTimeout <= 0 || sess.AuthMethod == types.AuthMethodToken {
+		return false
+	}
+	return now.Sub(sess.LastActivityAt) > idleTimeout
+}
+
+// NeedsActivityUpdate returns true if the last activity of the session should be written to the store
+func NeedsActivityUpdate(sess *types.Session, now time.Time) bool {
+	if sess.AuthMethod == types.AuthMethodToken {
+		return false
+	}
+	return now.Sub(sess.LastActivityAt) > activityUpdateInterval
+}
+
+// GetClientInfo returns the details of the client that are recorded when a session is created
+func GetClientInfo(r *http.Request, authMethod types.AuthMethod) types.ClientInfo {
+	return types.ClientInfo{
+		AuthMethod: authMethod,
+		IPAddress:  getClientIP(r),
+		UserAgent:  r.UserAgent(),
+	}
+}
+
+// getClientIP returns the address the request came from. forwarded headers can be set by any client, so they are
+// only used when the request comes from a trusted proxy, such as the ingress in front of the admin console.
+func getClientIP(r *http.Request) string {
+	remoteIP, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteIP = r.RemoteAddr
+	}
+	if !isTrustedProxy(remoteIP) {
+		return remoteIP
+	}
+
+	// each proxy appends the address it received the request from, so the client is the last address
+	// that was not added by a trusted proxy
+	if forwardedFor := r.Header.Get("X-Forwarded-For"); forwardedFor != "" {
+		addresses := strings.Split(forwardedFor, ",")
+		for i := len(addresses) - 1; i >= 0; i-- {
+			address := strings.TrimSpace(addresses[i])
+			if i == 0 || !isTrustedProxy(address) {
+				return address
+			}
+		}
+	}
+	if realIP := r.Header.Get("X-Real-Ip"); realIP != "" {
+		return strings.TrimSpace(realIP)
+	}
+	return remoteIP
+}
+
+func isTrustedProxy(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToSessionInfo returns the representation of the session returned by the sessions api
+func ToSessionInfo(sess *types.Session, currentSessionID string) types.SessionInfo {
+	return types.SessionInfo{
+		ID:             sess.ID,
+		UserID:         sess.UserID,
+		AuthMethod:     sess.AuthMethod,
+		IPAddress:      sess.IPAddress,
+		UserAgent:      sess.UserAgent,
+		Roles:          sess.Roles,
+		IssuedAt:       sess.IssuedAt,
+		ExpiresAt:      sess.ExpiresAt,
+		LastActivityAt: sess.LastActivityAt,
+		Current:        sess.ID == currentSessionID,
+	}
+}
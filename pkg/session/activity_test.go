@@ -0,0 +1,91 @@
+package session
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/kots/pkg/session/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsIdle(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		idleTimeout time.Duration
+		session     types.Session
+		want        bool
+	}{
+		{
+			name:        "no idle timeout",
+			idleTimeout: 0,
+			session:     types.Session{LastActivityAt: now.Add(-24 * time.Hour)},
+			want:        false,
+		},
+		{
+			name:        "active",
+			idleTimeout: 30 * time.Minute,
+			session:     types.Session{LastActivityAt: now.Add(-10 * time.Minute)},
+			want:        false,
+		},
+		{
+			name:        "idle",
+			idleTimeout: 30 * time.Minute,
+			session:     types.Session{LastActivityAt: now.Add(-31 * time.Minute)},
+			want:        true,
+		},
+		{
+			name:        "token sessions are never idle",
+			idleTimeout: 30 * time.Minute,
+			session:     types.Session{AuthMethod: types.AuthMethodToken, LastActivityAt: now.Add(-31 * time.Minute)},
+			want:        false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idleTimeout = tt.idleTimeout
+			defer func() { idleTimeout = 0 }()
+
+			assert.Equal(t, tt.want, IsIdle(&tt.session, now))
+		})
+	}
+}
+
+func TestGetClientInfo(t *testing.T) {
+	req := httptest.NewRequest("POST", "http://example.com/api/v1/login", nil)
+	req.RemoteAddr = "10.0.0.5:43210"
+	req.Header.Set("User-Agent", "Mozilla/5.0")
+
+	clientInfo := GetClientInfo(req, types.AuthMethodPassword)
+	assert.Equal(t, types.AuthMethodPassword, clientInfo.AuthMethod)
+	assert.Equal(t, "10.0.0.5", clientInfo.IPAddress)
+	assert.Equal(t, "Mozilla/5.0", clientInfo.UserAgent)
+
+	// forwarded headers are ignored unless the request comes from a trusted proxy
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	clientInfo = GetClientInfo(req, types.AuthMethodOIDC)
+	assert.Equal(t, "10.0.0.5", clientInfo.IPAddress)
+
+	proxies, err := parseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"})
+	require.NoError(t, err)
+	trustedProxies = proxies
+	defer func() { trustedProxies = nil }()
+
+	clientInfo = GetClientInfo(req, types.AuthMethodOIDC)
+	assert.Equal(t, "203.0.113.7", clientInfo.IPAddress)
+
+	// addresses that the client added before the proxies are not used
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 192.168.1.10")
+	clientInfo = GetClientInfo(req, types.AuthMethodOIDC)
+	assert.Equal(t, "203.0.113.7", clientInfo.IPAddress)
+
+	req.RemoteAddr = "203.0.113.9:43210"
+	clientInfo = GetClientInfo(req, types.AuthMethodOIDC)
+	assert.Equal(t, "203.0.113.9", clientInfo.IPAddress)
+
+	_, err = parseTrustedProxies([]string{"not-an-address"})
+	assert.Error(t, err)
+}
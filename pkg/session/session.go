@@ -59,12 +59,14 @@ func Parse(kotsStore store.Store, signedToken string) (*types.Session, error) {
 		}
 
 		s := types.Session{
-			ID:        "kots-cli",
-			IssuedAt:  time.Now(),
-			ExpiresAt: time.Now().Add(time.Minute),
+			ID:             "kots-cli",
+			IssuedAt:       time.Now(),
+			ExpiresAt:      time.Now().Add(time.Minute),
+			LastActivityAt: time.Now(),
 			// TODO: super user permissions
-			Roles:   GetSessionRolesFromRBAC(nil, identity.DefaultGroups),
-			HasRBAC: true,
+			Roles:      GetSessionRolesFromRBAC(nil, identity.DefaultGroups),
+			HasRBAC:    true,
+			AuthMethod: types.AuthMethodToken,
 		}
 
 		return &s, nil
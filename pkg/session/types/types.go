@@ -2,10 +2,44 @@ package types
 
 import "time"
 
+type AuthMethod string
+
+const (
+	AuthMethodPassword AuthMethod = "password"
+	AuthMethodOIDC     AuthMethod = "oidc"
+	AuthMethodToken    AuthMethod = "token"
+)
+
 type Session struct {
-	ID        string
-	IssuedAt  time.Time
-	ExpiresAt time.Time
-	Roles     []string
-	HasRBAC   bool
+	ID             string
+	UserID         string
+	IssuedAt       time.Time
+	ExpiresAt      time.Time
+	LastActivityAt time.Time
+	Roles          []string
+	HasRBAC        bool
+	AuthMethod     AuthMethod
+	IPAddress      string
+	UserAgent      string
+}
+
+// ClientInfo describes where a session was created from
+type ClientInfo struct {
+	AuthMethod AuthMethod
+	IPAddress  string
+	UserAgent  string
+}
+
+// SessionInfo is the representation of a session returned by the sessions api
+type SessionInfo struct {
+	ID             string     `json:"id"`
+	UserID         string     `json:"userId,omitempty"`
+	AuthMethod     AuthMethod `json:"authMethod,omitempty"`
+	IPAddress      string     `json:"ipAddress,omitempty"`
+	UserAgent      string     `json:"userAgent,omitempty"`
+	Roles          []string   `json:"roles"`
+	IssuedAt       time.Time  `json:"issuedAt"`
+	ExpiresAt      time.Time  `json:"expiresAt"`
+	LastActivityAt time.Time  `json:"lastActivityAt"`
+	Current        bool       `json:"current"`
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
 	"github.com/replicatedhq/kots/pkg/cursor"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
 	"github.com/replicatedhq/kots/pkg/kotsutil"
 	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/persistence"
@@ -220,6 +221,42 @@ func (s *KOTSStore) GetDownstreamVersionSource(appID string, sequence int64) (st
 	return source.String, nil
 }
 
+func (s *KOTSStore) GetDownstreamVersionConfigChangedBy(appID string, sequence int64) (string, error) {
+	db := persistence.MustGetDBSession()
+	query := `select config_changed_by from app_downstream_version where app_id = ? and sequence = ?`
+	rows, err := db.QueryOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{appID, sequence},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+	}
+	if !rows.Next() {
+		return "", nil
+	}
+
+	var changedBy gorqlite.NullString
+	if err := rows.Scan(&changedBy); err != nil {
+		return "", errors.Wrap(err, "failed to scan")
+	}
+
+	return changedBy.String, nil
+}
+
+func (s *KOTSStore) SetDownstreamVersionConfigChangedBy(appID string, sequence int64, changedBy string) error {
+	db := persistence.MustGetDBSession()
+	query := `update app_downstream_version set config_changed_by = ? where app_id = ? and sequence = ?`
+	wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{changedBy, appID, sequence},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write: %v: %v", err, wr.Err)
+	}
+
+	return nil
+}
+
 func (s *KOTSStore) GetIgnoreRBACErrors(appID string, sequence int64) (bool, error) {
 	db := persistence.MustGetDBSession()
 	query := `SELECT preflight_ignore_permissions FROM app_downstream_version WHERE app_id = ? and sequence = ? LIMIT 1`
@@ -265,7 +302,12 @@ func (s *KOTSStore) GetCurrentDownstreamVersion(appID string, clusterID string)
 	adv.source,
 	adv.preflight_skipped,
 	adv.git_commit_url,
+	adv.git_commit_hash,
 	adv.git_deployable,
+	adv.git_pr_url,
+	adv.git_pr_number,
+	adv.git_pr_state,
+	adv.git_pr_merge_commit_hash,
 	ado.is_error,
 	av.upstream_released_at,
 	av.version_label,
@@ -360,7 +402,12 @@ func (s *KOTSStore) GetDownstreamVersions(appID string, clusterID string, downlo
 	adv.source,
 	adv.preflight_skipped,
 	adv.git_commit_url,
+	adv.git_commit_hash,
 	adv.git_deployable,
+	adv.git_pr_url,
+	adv.git_pr_number,
+	adv.git_pr_state,
+	adv.git_pr_merge_commit_hash,
 	ado.is_error,
 	av.upstream_released_at,
 	av.version_label,
@@ -779,7 +826,12 @@ func (s *KOTSStore) downstreamVersionFromRow(appID string, row gorqlite.QueryRes
 	var source gorqlite.NullString
 	var preflightSkipped gorqlite.NullBool
 	var commitURL gorqlite.NullString
+	var commitHash gorqlite.NullString
 	var gitDeployable gorqlite.NullBool
+	var prURL gorqlite.NullString
+	var prNumber gorqlite.NullInt64
+	var prState gorqlite.NullString
+	var prMergeCommitHash gorqlite.NullString
 	var hasError gorqlite.NullBool
 	var upstreamReleasedAt gorqlite.NullTime
 
@@ -792,7 +844,12 @@ func (s *KOTSStore) downstreamVersionFromRow(appID string, row gorqlite.QueryRes
 		&source,
 		&preflightSkipped,
 		&commitURL,
+		&commitHash,
 		&gitDeployable,
+		&prURL,
+		&prNumber,
+		&prState,
+		&prMergeCommitHash,
 		&hasError,
 		&upstreamReleasedAt,
 		&versionLabel,
@@ -829,7 +886,16 @@ func (s *KOTSStore) downstreamVersionFromRow(appID string, row gorqlite.QueryRes
 	v.Source = source.String
 	v.PreflightSkipped = preflightSkipped.Bool
 	v.CommitURL = commitURL.String
+	v.CommitHash = commitHash.String
 	v.GitDeployable = gitDeployable.Bool
+	if prURL.Valid && prURL.String != "" {
+		v.PullRequest = &gitopstypes.PullRequest{
+			Number:          prNumber.Int64,
+			URL:             prURL.String,
+			State:           gitopstypes.PullRequestState(prState.String),
+			MergeCommitHash: prMergeCommitHash.String,
+		}
+	}
 
 	if upstreamReleasedAt.Valid {
 		v.UpstreamReleasedAt = &upstreamReleasedAt.Time
@@ -1249,3 +1315,93 @@ func (s *KOTSStore) DeleteDownstreamDeployStatus(appID string, clusterID string,
 
 	return nil
 }
+
+func (s *KOTSStore) ListOpenGitOpsPullRequests(appID string, clusterID string) ([]gitopstypes.PullRequest, error) {
+	db := persistence.MustGetDBSession()
+	query := `select git_pr_number, git_pr_url from app_downstream_version where app_id = ? and cluster_id = ? and git_pr_state = ? order by sequence`
+	rows, err := db.QueryOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{appID, clusterID, gitopstypes.PullRequestStateOpen},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+	}
+
+	pullRequests := []gitopstypes.PullRequest{}
+	for rows.Next() {
+		var number gorqlite.NullInt64
+		var url gorqlite.NullString
+		if err := rows.Scan(&number, &url); err != nil {
+			return nil, errors.Wrap(err, "failed to scan")
+		}
+		pullRequests = append(pullRequests, gitopstypes.PullRequest{
+			Number: number.Int64,
+			URL:    url.String,
+			State:  gitopstypes.PullRequestStateOpen,
+		})
+	}
+
+	return pullRequests, nil
+}
+
+func (s *KOTSStore) SetDownstreamVersionGitOpsPullRequest(appID string, clusterID string, sequence int64, pr gitopstypes.PullRequest) error {
+	db := persistence.MustGetDBSession()
+
+	query := `update app_downstream_version set git_pr_url = ?, git_pr_number = ?, git_pr_state = ? where app_id = ? and cluster_id = ? and sequence = ?`
+	wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{pr.URL, pr.Number, pr.State, appID, clusterID, sequence},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write: %v: %v", err, wr.Err)
+	}
+
+	return nil
+}
+
+func (s *KOTSStore) SetDownstreamVersionGitOpsCommitHash(appID string, clusterID string, sequence int64, commitHash string) error {
+	db := persistence.MustGetDBSession()
+
+	query := `update app_downstream_version set git_commit_hash = ? where app_id = ? and cluster_id = ? and sequence = ?`
+	wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     query,
+		Arguments: []interface{}{commitHash, appID, clusterID, sequence},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write: %v: %v", err, wr.Err)
+	}
+
+	return nil
+}
+
+func (s *KOTSStore) SetGitOpsPullRequestStates(appID string, clusterID string, prs []gitopstypes.PullRequest) error {
+	if len(prs) == 0 {
+		return nil
+	}
+
+	db := persistence.MustGetDBSession()
+
+	statements := gitOpsPullRequestStatesStatements(appID, clusterID, prs)
+	if wrs, err := db.WriteParameterized(statements); err != nil {
+		wrErrs := []error{}
+		for _, wr := range wrs {
+			wrErrs = append(wrErrs, wr.Err)
+		}
+		return fmt.Errorf("failed to write: %v: %v", err, wrErrs)
+	}
+
+	return nil
+}
+
+// gitOpsPullRequestStatesStatements sets the state and merge commit of the pull requests by number. pull requests are only
+// marked as closed once they were closed on the provider, so that the state matches what the provider shows.
+func gitOpsPullRequestStatesStatements(appID string, clusterID string, prs []gitopstypes.PullRequest) []gorqlite.ParameterizedStatement {
+	statements := []gorqlite.ParameterizedStatement{}
+	for _, pr := range prs {
+		statements = append(statements, gorqlite.ParameterizedStatement{
+			Query:     `update app_downstream_version set git_pr_state = ?, git_pr_merge_commit_hash = ? where app_id = ? and cluster_id = ? and git_pr_number = ?`,
+			Arguments: []interface{}{pr.State, pr.MergeCommitHash, appID, clusterID, pr.Number},
+		})
+	}
+	return statements
+}
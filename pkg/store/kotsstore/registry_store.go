@@ -117,3 +117,55 @@ func (s *KOTSStore) GetAppIDsFromRegistry(hostname string) ([]string, error) {
 
 	return appIDs, nil
 }
+
+// ReencryptRegistryPasswords re-encrypts the registry passwords that are not encrypted with the current encryption key.
+// when dryRun is true, the passwords are only checked. returns the number of passwords that were not encrypted with the current key.
+func (s *KOTSStore) ReencryptRegistryPasswords(dryRun bool) (int, error) {
+	db := persistence.MustGetDBSession()
+	query := `select id, registry_password_enc from app where registry_password_enc is not null and registry_password_enc != ''`
+	rows, err := db.QueryOne(query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query: %v: %v", err, rows.Err)
+	}
+
+	statements := []gorqlite.ParameterizedStatement{}
+	for rows.Next() {
+		var appID string
+		var registryPasswordEnc string
+		if err := rows.Scan(&appID, &registryPasswordEnc); err != nil {
+			return 0, errors.Wrap(err, "failed to scan")
+		}
+
+		decodedPassword, err := base64.StdEncoding.DecodeString(registryPasswordEnc)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to decode registry password for app %s", appID)
+		}
+		if crypto.IsEncryptedWithCurrentKey(decodedPassword) {
+			continue
+		}
+
+		decryptedPassword, err := crypto.Decrypt(decodedPassword)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to decrypt registry password for app %s", appID)
+		}
+
+		statements = append(statements, gorqlite.ParameterizedStatement{
+			Query:     `update app set registry_password_enc = ? where id = ?`,
+			Arguments: []interface{}{base64.StdEncoding.EncodeToString(crypto.Encrypt(decryptedPassword)), appID},
+		})
+	}
+
+	if dryRun || len(statements) == 0 {
+		return len(statements), nil
+	}
+
+	if wrs, err := db.WriteParameterized(statements); err != nil {
+		wrErrs := []error{}
+		for _, wr := range wrs {
+			wrErrs = append(wrErrs, wr.Err)
+		}
+		return 0, fmt.Errorf("failed to write: %v: %v", err, wrErrs)
+	}
+
+	return len(statements), nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -105,7 +106,7 @@ func (s *KOTSStore) migrateSessionsFromRqlite() error {
 
 }
 
-func (s *KOTSStore) CreateSession(forUser *usertypes.User, issuedAt time.Time, expiresAt time.Time, roles []string) (*sessiontypes.Session, error) {
+func (s *KOTSStore) CreateSession(forUser *usertypes.User, issuedAt time.Time, expiresAt time.Time, roles []string, clientInfo sessiontypes.ClientInfo) (*sessiontypes.Session, error) {
 	sessionLock.Lock()
 	defer sessionLock.Unlock()
 
@@ -124,11 +125,18 @@ func (s *KOTSStore) CreateSession(forUser *usertypes.User, issuedAt time.Time, e
 	}
 
 	session := sessiontypes.Session{
-		ID:        id,
-		IssuedAt:  issuedAt,
-		ExpiresAt: expiresAt,
-		Roles:     roles,
-		HasRBAC:   true,
+		ID:             id,
+		IssuedAt:       issuedAt,
+		ExpiresAt:      expiresAt,
+		LastActivityAt: issuedAt,
+		Roles:          roles,
+		HasRBAC:        true,
+		AuthMethod:     clientInfo.AuthMethod,
+		IPAddress:      clientInfo.IPAddress,
+		UserAgent:      clientInfo.UserAgent,
+	}
+	if forUser != nil {
+		session.UserID = forUser.ID
 	}
 
 	b, err := json.Marshal(session)
@@ -163,9 +171,48 @@ func (s *KOTSStore) GetSession(id string) (*sessiontypes.Session, error) {
 		return nil, nil
 	}
 
+	session, err := unmarshalSession(data)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal session")
+	}
+
+	return session, nil
+}
+
+func (s *KOTSStore) ListSessions() ([]*sessiontypes.Session, error) {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	secret, err := s.getSessionSecret()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get session secret")
+	}
+
+	now := time.Now()
+	sessions := []*sessiontypes.Session{}
+	for id, data := range secret.Data {
+		session, err := unmarshalSession(data)
+		if err != nil {
+			logger.Error(errors.Wrapf(err, "failed to unmarshal session %s while listing sessions", id))
+			continue
+		}
+		if !session.ExpiresAt.After(now) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivityAt.After(sessions[j].LastActivityAt)
+	})
+
+	return sessions, nil
+}
+
+func unmarshalSession(data []byte) (*sessiontypes.Session, error) {
 	session := sessiontypes.Session{}
 	if err := json.Unmarshal(data, &session); err != nil {
-		return nil, errors.Wrap(err, "failed to unmarshal session")
+		return nil, err
 	}
 
 	// sessions created before this change will not have IssuedAt
@@ -173,6 +220,11 @@ func (s *KOTSStore) GetSession(id string) (*sessiontypes.Session, error) {
 		session.IssuedAt = session.ExpiresAt.AddDate(0, 0, -14)
 	}
 
+	// sessions created before activity was tracked will not have LastActivityAt
+	if session.LastActivityAt.IsZero() {
+		session.LastActivityAt = session.IssuedAt
+	}
+
 	return &session, nil
 }
 
@@ -302,6 +354,38 @@ func (s *KOTSStore) UpdateSessionExpiresAt(id string, expiresAt time.Time) error
 	return nil
 }
 
+func (s *KOTSStore) UpdateSessionLastActivityAt(id string, lastActivityAt time.Time) error {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	secret, err := s.getSessionSecret()
+	if err != nil {
+		return errors.Wrap(err, "failed to get session secret")
+	}
+
+	data, ok := secret.Data[id]
+	if !ok {
+		return nil
+	}
+
+	session := sessiontypes.Session{}
+	if err := json.Unmarshal(data, &session); err != nil {
+		return errors.Wrap(err, "failed to unmarshal session")
+	}
+
+	session.LastActivityAt = lastActivityAt
+	b, err := json.Marshal(session)
+	if err != nil {
+		return errors.Wrap(err, "failed to encoded session")
+	}
+
+	secret.Data[id] = b
+	if err := s.saveSessionSecret(secret); err != nil {
+		return errors.Wrap(err, "failed to update session secret")
+	}
+	return nil
+}
+
 func (s *KOTSStore) DeleteExpiredSessions() error {
 	sessionLock.Lock()
 	defer sessionLock.Unlock()
@@ -332,3 +416,23 @@ func (s *KOTSStore) DeleteExpiredSessions() error {
 
 	return nil
 }
+
+func (s *KOTSStore) DeleteAllSessions() error {
+	sessionLock.Lock()
+	defer sessionLock.Unlock()
+
+	s.sessionSecret = nil
+
+	secret, err := s.getSessionSecret()
+	if err != nil {
+		return errors.Wrap(err, "failed to get session secret")
+	}
+
+	secret.Data = map[string][]byte{}
+
+	if err := s.saveSessionSecret(secret); err != nil {
+		return errors.Wrap(err, "failed to update session secret")
+	}
+
+	return nil
+}
@@ -9,6 +9,9 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
 	"github.com/replicatedhq/kots/pkg/persistence"
 	"github.com/replicatedhq/kots/pkg/util"
 	"github.com/rqlite/gorqlite"
@@ -21,6 +24,24 @@ var (
 	passwordSecretName = "kotsadm-password"
 )
 
+const (
+	// defaultMaxFailedLoginAttempts - the number of failed logins allowed before the admin console is locked
+	// when "login-max-failed-attempts" is not set in the kotsadm config map
+	defaultMaxFailedLoginAttempts = 10
+)
+
+func maxFailedLoginAttempts() int {
+	installParams, err := kotsutil.GetInstallationParams(kotsadmtypes.KotsadmConfigMap)
+	if err != nil {
+		logger.Error(errors.Wrap(err, "failed to get installation params"))
+		return defaultMaxFailedLoginAttempts
+	}
+	if installParams.LoginMaxFailedAttempts <= 0 {
+		return defaultMaxFailedLoginAttempts
+	}
+	return installParams.LoginMaxFailedAttempts
+}
+
 // GetSharedPasswordBcrypt will return the hash of the current password
 // that can be used to validate an auth request. This is in the store pkg,
 // but the data may be in the cluster or the database, depending on the
@@ -43,7 +64,7 @@ func (s *KOTSStore) GetSharedPasswordBcrypt() ([]byte, error) {
 		}
 
 		numAttempts, _ := strconv.Atoi(passwordSecret.Labels["numAttempts"])
-		if numAttempts > 10 {
+		if numAttempts > maxFailedLoginAttempts() {
 			return nil, ErrTooManyAttempts
 		}
 
@@ -80,7 +101,7 @@ func (s *KOTSStore) getSharedPaswordBcryptFromDatabase() ([]byte, error) {
 		return nil, errors.Wrap(err, "failed to parse failed login count")
 	}
 
-	if i > 10 {
+	if i > maxFailedLoginAttempts() {
 		return nil, ErrTooManyAttempts
 	}
 
@@ -20,6 +20,7 @@ import (
 	"github.com/replicatedhq/kots/pkg/cursor"
 	"github.com/replicatedhq/kots/pkg/filestore"
 	"github.com/replicatedhq/kots/pkg/gitops"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
 	kotsadmconfig "github.com/replicatedhq/kots/pkg/kotsadmconfig"
 	"github.com/replicatedhq/kots/pkg/kotsutil"
@@ -256,6 +257,96 @@ func (s *KOTSStore) GetAppVersionArchive(appID string, sequence int64, dstPath s
 	return nil
 }
 
+// GetAppVersionArchiveSize returns the size in bytes of the stored archive for the given sequence
+func (s *KOTSStore) GetAppVersionArchiveSize(appID string, sequence int64) (int64, error) {
+	path := fmt.Sprintf("%s/%d.tar.gz", appID, sequence)
+	size, err := filestore.GetStore().GetArchiveSize(path)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get archive size")
+	}
+	return size, nil
+}
+
+func (s *KOTSStore) GetAppVersionArchivesReclaimableSize(appID string, sequences []int64) (int64, error) {
+	paths := []string{}
+	for _, sequence := range sequences {
+		paths = append(paths, fmt.Sprintf("%s/%d.tar.gz", appID, sequence))
+	}
+	size, err := filestore.GetReclaimableSize(filestore.GetStore(), paths)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get reclaimable size")
+	}
+	return size, nil
+}
+
+// DeleteAppVersion removes all records for the given sequence across all downstreams, as well as its archive.
+// it's the caller's responsibility to make sure that the version is not deployed or needed for a rollback.
+func (s *KOTSStore) DeleteAppVersion(appID string, sequence int64) error {
+	db := persistence.MustGetDBSession()
+	statements := []gorqlite.ParameterizedStatement{}
+
+	statements = append(statements, gorqlite.ParameterizedStatement{
+		Query:     `delete from app_downstream_output where app_id = ? and downstream_sequence = ?`,
+		Arguments: []interface{}{appID, sequence},
+	})
+
+	statements = append(statements, gorqlite.ParameterizedStatement{
+		Query:     `delete from app_downstream_version where app_id = ? and sequence = ?`,
+		Arguments: []interface{}{appID, sequence},
+	})
+
+	statements = append(statements, gorqlite.ParameterizedStatement{
+		Query:     `delete from app_version where app_id = ? and sequence = ?`,
+		Arguments: []interface{}{appID, sequence},
+	})
+
+	if wrs, err := db.WriteParameterized(statements); err != nil {
+		wrErrs := []error{}
+		for _, wr := range wrs {
+			wrErrs = append(wrErrs, wr.Err)
+		}
+		return fmt.Errorf("failed to write: %v: %v", err, wrErrs)
+	}
+
+	// the records are removed first so that a failure here only leaves an orphaned archive behind
+	// rather than a version that can no longer be read.
+	path := fmt.Sprintf("%s/%d.tar.gz", appID, sequence)
+	if err := filestore.GetStore().DeleteArchive(path); err != nil {
+		return errors.Wrap(err, "failed to delete archive")
+	}
+
+	return nil
+}
+
+// UpdateAppVersionArchive replaces the archive of an existing version with the contents of archiveDir and refreshes the config values cached in the app version table.
+// unlike UpdateAppVersion, the downstream version status, diff summary and gitops commits are left untouched.
+func (s *KOTSStore) UpdateAppVersionArchive(appID string, sequence int64, archiveDir string) error {
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to read kots kinds")
+	}
+
+	configValuesSpec, err := kotsKinds.Marshal("kots.io", "v1beta1", "ConfigValues")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal configvalues spec")
+	}
+
+	if err := apparchive.CreateAppVersionArchive(archiveDir, fmt.Sprintf("%s/%d.tar.gz", appID, sequence)); err != nil {
+		return errors.Wrap(err, "failed to create app version archive")
+	}
+
+	db := persistence.MustGetDBSession()
+	wr, err := db.WriteOneParameterized(gorqlite.ParameterizedStatement{
+		Query:     `update app_version set config_values = ? where app_id = ? and sequence = ?`,
+		Arguments: []interface{}{configValuesSpec, appID, sequence},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write: %v: %v", err, wr.Err)
+	}
+
+	return nil
+}
+
 // GetAppVersionBaseSequence returns the base sequence for a given version label.
 // if the "versionLabel" param is empty or is not a valid semver, the sequence of the latest version will be returned.
 func (s *KOTSStore) GetAppVersionBaseSequence(appID string, versionLabel string) (int64, error) {
@@ -381,7 +472,7 @@ func (s *KOTSStore) CreatePendingDownloadAppVersion(appID string, update upstrea
 	for _, d := range downstreams {
 		downstreamVersionStatements, err := s.upsertAppDownstreamVersionStatements(a.ID, d.ClusterID, newSequence,
 			kotsKinds.Installation.Spec.VersionLabel, types.VersionPendingDownload,
-			"Upstream Update", "", "", "", false, false)
+			"Upstream Update", "", "", "", "", false, nil, false)
 		if err != nil {
 			return 0, errors.Wrap(err, "failed to construct app downstream version statements")
 		}
@@ -532,9 +623,10 @@ func (s *KOTSStore) upsertAppVersionStatements(appID string, sequence int64, bas
 		}
 
 		diffSummary, diffSummaryError := "", ""
+		var diff *apparchive.Diff
 		if baseSequence != nil {
 			// diff this release from the last release
-			diff, err := apparchive.DiffAppVersionsForDownstream(d.Name, filesInDir, previousArchiveDir, kustomizeBinPath)
+			diff, err = apparchive.DiffAppVersionsForDownstream(d.Name, filesInDir, previousArchiveDir, kustomizeBinPath)
 			if err != nil {
 				diffSummaryError = errors.Wrap(err, "failed to diff").Error()
 			} else {
@@ -546,18 +638,30 @@ func (s *KOTSStore) upsertAppVersionStatements(appID string, sequence int64, bas
 			}
 		}
 
-		commitURL, err := gitops.CreateGitOpsDownstreamCommit(a, d.ClusterID, int(sequence), filesInDir, d.Name)
+		openPullRequests, err := s.ListOpenGitOpsPullRequests(appID, d.ClusterID)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list open gitops pull requests")
+		}
+
+		commitResult, err := gitops.CreateGitOpsDownstreamCommit(a, d.ClusterID, int(sequence), filesInDir, d.Name, gitops.CommitOptions{
+			VersionLabel:           kotsKinds.Installation.Spec.VersionLabel,
+			ReleaseNotes:           kotsKinds.Installation.Spec.ReleaseNotes,
+			DiffSummary:            diff,
+			SupersededPullRequests: openPullRequests,
+		})
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to create gitops commit")
 		}
 
 		downstreamVersionStatements, err := s.upsertAppDownstreamVersionStatements(appID, d.ClusterID, sequence,
 			kotsKinds.Installation.Spec.VersionLabel, downstreamStatus,
-			source, diffSummary, diffSummaryError, commitURL, commitURL != "", skipPreflights)
+			source, diffSummary, diffSummaryError, commitResult.CommitURL, commitResult.CommitHash, commitResult.CommitURL != "", commitResult.PullRequest, skipPreflights)
 		if err != nil {
 			return nil, errors.Wrap(err, "failed to construct app downstream version statements")
 		}
 		statements = append(statements, downstreamVersionStatements...)
+
+		statements = append(statements, gitOpsPullRequestStatesStatements(appID, d.ClusterID, commitResult.ClosedPullRequests)...)
 	}
 
 	return statements, nil
@@ -756,11 +860,16 @@ func determineDownstreamVersionStatus(s store.Store, a *apptypes.App, sequence i
 	return types.VersionPending, nil
 }
 
-func (s *KOTSStore) upsertAppDownstreamVersionStatements(appID string, clusterID string, sequence int64, versionLabel string, status types.DownstreamVersionStatus, source string, diffSummary string, diffSummaryError string, commitURL string, gitDeployable bool, preflightsSkipped bool) ([]gorqlite.ParameterizedStatement, error) {
+func (s *KOTSStore) upsertAppDownstreamVersionStatements(appID string, clusterID string, sequence int64, versionLabel string, status types.DownstreamVersionStatus, source string, diffSummary string, diffSummaryError string, commitURL string, commitHash string, gitDeployable bool, pullRequest *gitopstypes.PullRequest, preflightsSkipped bool) ([]gorqlite.ParameterizedStatement, error) {
 	statements := []gorqlite.ParameterizedStatement{}
 
-	query := `insert into app_downstream_version (app_id, cluster_id, sequence, parent_sequence, created_at, version_label, status, source, diff_summary, diff_summary_error, git_commit_url, git_deployable, preflight_skipped)
-		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	prURL, prNumber, prState := "", int64(0), ""
+	if pullRequest != nil {
+		prURL, prNumber, prState = pullRequest.URL, pullRequest.Number, string(pullRequest.State)
+	}
+
+	query := `insert into app_downstream_version (app_id, cluster_id, sequence, parent_sequence, created_at, version_label, status, source, diff_summary, diff_summary_error, git_commit_url, git_commit_hash, git_deployable, git_pr_url, git_pr_number, git_pr_state, git_pr_merge_commit_hash, preflight_skipped)
+		values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(app_id, cluster_id, sequence) DO UPDATE SET
 		created_at = EXCLUDED.created_at,
 		version_label = EXCLUDED.version_label,
@@ -769,7 +878,12 @@ func (s *KOTSStore) upsertAppDownstreamVersionStatements(appID string, clusterID
 		diff_summary = EXCLUDED.diff_summary,
 		diff_summary_error = EXCLUDED.diff_summary_error,
 		git_commit_url = EXCLUDED.git_commit_url,
+		git_commit_hash = EXCLUDED.git_commit_hash,
 		git_deployable = EXCLUDED.git_deployable,
+		git_pr_url = EXCLUDED.git_pr_url,
+		git_pr_number = EXCLUDED.git_pr_number,
+		git_pr_state = EXCLUDED.git_pr_state,
+		git_pr_merge_commit_hash = EXCLUDED.git_pr_merge_commit_hash,
 		preflight_skipped= EXCLUDED.preflight_skipped`
 
 	statements = append(statements, gorqlite.ParameterizedStatement{
@@ -786,7 +900,12 @@ func (s *KOTSStore) upsertAppDownstreamVersionStatements(appID string, clusterID
 			diffSummary,
 			diffSummaryError,
 			commitURL,
+			commitHash,
 			gitDeployable,
+			prURL,
+			prNumber,
+			prState,
+			"",
 			preflightsSkipped,
 		},
 	})
@@ -17,16 +17,17 @@ import (
 	types2 "github.com/replicatedhq/kots/pkg/api/version/types"
 	types3 "github.com/replicatedhq/kots/pkg/app/types"
 	types4 "github.com/replicatedhq/kots/pkg/appstate/types"
-	types5 "github.com/replicatedhq/kots/pkg/kotsadmsnapshot/types"
-	types6 "github.com/replicatedhq/kots/pkg/online/types"
-	types7 "github.com/replicatedhq/kots/pkg/preflight/types"
-	types8 "github.com/replicatedhq/kots/pkg/registry/types"
-	types9 "github.com/replicatedhq/kots/pkg/render/types"
-	types10 "github.com/replicatedhq/kots/pkg/session/types"
-	types11 "github.com/replicatedhq/kots/pkg/store/types"
-	types12 "github.com/replicatedhq/kots/pkg/supportbundle/types"
-	types13 "github.com/replicatedhq/kots/pkg/upstream/types"
-	types14 "github.com/replicatedhq/kots/pkg/user/types"
+	types5 "github.com/replicatedhq/kots/pkg/gitops/types"
+	types6 "github.com/replicatedhq/kots/pkg/kotsadmsnapshot/types"
+	types7 "github.com/replicatedhq/kots/pkg/online/types"
+	types8 "github.com/replicatedhq/kots/pkg/preflight/types"
+	types9 "github.com/replicatedhq/kots/pkg/registry/types"
+	types10 "github.com/replicatedhq/kots/pkg/render/types"
+	types11 "github.com/replicatedhq/kots/pkg/session/types"
+	types12 "github.com/replicatedhq/kots/pkg/store/types"
+	types13 "github.com/replicatedhq/kots/pkg/supportbundle/types"
+	types14 "github.com/replicatedhq/kots/pkg/upstream/types"
+	types15 "github.com/replicatedhq/kots/pkg/user/types"
 	v1beta10 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	licensewrapper "github.com/replicatedhq/kotskinds/pkg/licensewrapper"
 	redact "github.com/replicatedhq/troubleshoot/pkg/redact"
@@ -128,7 +129,7 @@ func (mr *MockStoreMockRecorder) CreateAppVersion(appID, baseSequence, filesInDi
 }
 
 // CreateInProgressSupportBundle mocks base method.
-func (m *MockStore) CreateInProgressSupportBundle(supportBundle *types12.SupportBundle) error {
+func (m *MockStore) CreateInProgressSupportBundle(supportBundle *types13.SupportBundle) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateInProgressSupportBundle", supportBundle)
 	ret0, _ := ret[0].(error)
@@ -172,7 +173,7 @@ func (mr *MockStoreMockRecorder) CreateNewCluster(userID, isAllUsers, title, tok
 }
 
 // CreatePendingDownloadAppVersion mocks base method.
-func (m *MockStore) CreatePendingDownloadAppVersion(appID string, update types13.Update, kotsApplication *v1beta10.Application, license *licensewrapper.LicenseWrapper) (int64, error) {
+func (m *MockStore) CreatePendingDownloadAppVersion(appID string, update types14.Update, kotsApplication *v1beta10.Application, license *licensewrapper.LicenseWrapper) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePendingDownloadAppVersion", appID, update, kotsApplication, license)
 	ret0, _ := ret[0].(int64)
@@ -215,25 +216,25 @@ func (mr *MockStoreMockRecorder) CreateScheduledSnapshot(snapshotID, appID, time
 }
 
 // CreateSession mocks base method.
-func (m *MockStore) CreateSession(user *types14.User, issuedAt, expiresAt time.Time, roles []string) (*types10.Session, error) {
+func (m *MockStore) CreateSession(user *types15.User, issuedAt, expiresAt time.Time, roles []string, clientInfo types11.ClientInfo) (*types11.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSession", user, issuedAt, expiresAt, roles)
-	ret0, _ := ret[0].(*types10.Session)
+	ret := m.ctrl.Call(m, "CreateSession", user, issuedAt, expiresAt, roles, clientInfo)
+	ret0, _ := ret[0].(*types11.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateSession indicates an expected call of CreateSession.
-func (mr *MockStoreMockRecorder) CreateSession(user, issuedAt, expiresAt, roles interface{}) *gomock.Call {
+func (mr *MockStoreMockRecorder) CreateSession(user, issuedAt, expiresAt, roles, clientInfo interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), user, issuedAt, expiresAt, roles)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockStore)(nil).CreateSession), user, issuedAt, expiresAt, roles, clientInfo)
 }
 
 // CreateSupportBundle mocks base method.
-func (m *MockStore) CreateSupportBundle(bundleID, appID, archivePath string, marshalledTree []byte) (*types12.SupportBundle, error) {
+func (m *MockStore) CreateSupportBundle(bundleID, appID, archivePath string, marshalledTree []byte) (*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateSupportBundle", bundleID, appID, archivePath, marshalledTree)
-	ret0, _ := ret[0].(*types12.SupportBundle)
+	ret0, _ := ret[0].(*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -244,6 +245,34 @@ func (mr *MockStoreMockRecorder) CreateSupportBundle(bundleID, appID, archivePat
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSupportBundle", reflect.TypeOf((*MockStore)(nil).CreateSupportBundle), bundleID, appID, archivePath, marshalledTree)
 }
 
+// DeleteAllSessions mocks base method.
+func (m *MockStore) DeleteAllSessions() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAllSessions")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAllSessions indicates an expected call of DeleteAllSessions.
+func (mr *MockStoreMockRecorder) DeleteAllSessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllSessions", reflect.TypeOf((*MockStore)(nil).DeleteAllSessions))
+}
+
+// DeleteAppVersion mocks base method.
+func (m *MockStore) DeleteAppVersion(appID string, sequence int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAppVersion", appID, sequence)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAppVersion indicates an expected call of DeleteAppVersion.
+func (mr *MockStoreMockRecorder) DeleteAppVersion(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAppVersion", reflect.TypeOf((*MockStore)(nil).DeleteAppVersion), appID, sequence)
+}
+
 // DeleteDownstreamDeployStatus mocks base method.
 func (m *MockStore) DeleteDownstreamDeployStatus(appID, clusterID string, sequence int64) error {
 	m.ctrl.T.Helper()
@@ -505,6 +534,36 @@ func (mr *MockStoreMockRecorder) GetAppVersionArchive(appID, sequence, dstPath i
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchive", reflect.TypeOf((*MockStore)(nil).GetAppVersionArchive), appID, sequence, dstPath)
 }
 
+// GetAppVersionArchiveSize mocks base method.
+func (m *MockStore) GetAppVersionArchiveSize(appID string, sequence int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAppVersionArchiveSize", appID, sequence)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAppVersionArchiveSize indicates an expected call of GetAppVersionArchiveSize.
+func (mr *MockStoreMockRecorder) GetAppVersionArchiveSize(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchiveSize", reflect.TypeOf((*MockStore)(nil).GetAppVersionArchiveSize), appID, sequence)
+}
+
+// GetAppVersionArchivesReclaimableSize mocks base method.
+func (m *MockStore) GetAppVersionArchivesReclaimableSize(appID string, sequences []int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAppVersionArchivesReclaimableSize", appID, sequences)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAppVersionArchivesReclaimableSize indicates an expected call of GetAppVersionArchivesReclaimableSize.
+func (mr *MockStoreMockRecorder) GetAppVersionArchivesReclaimableSize(appID, sequences interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchivesReclaimableSize", reflect.TypeOf((*MockStore)(nil).GetAppVersionArchivesReclaimableSize), appID, sequences)
+}
+
 // GetAppVersionBaseArchive mocks base method.
 func (m *MockStore) GetAppVersionBaseArchive(appID, versionLabel string) (string, int64, error) {
 	m.ctrl.T.Helper()
@@ -656,6 +715,21 @@ func (mr *MockStoreMockRecorder) GetDownstreamOutput(appID, clusterID, sequence
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDownstreamOutput", reflect.TypeOf((*MockStore)(nil).GetDownstreamOutput), appID, clusterID, sequence)
 }
 
+// GetDownstreamVersionConfigChangedBy mocks base method.
+func (m *MockStore) GetDownstreamVersionConfigChangedBy(appID string, sequence int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDownstreamVersionConfigChangedBy", appID, sequence)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDownstreamVersionConfigChangedBy indicates an expected call of GetDownstreamVersionConfigChangedBy.
+func (mr *MockStoreMockRecorder) GetDownstreamVersionConfigChangedBy(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDownstreamVersionConfigChangedBy", reflect.TypeOf((*MockStore)(nil).GetDownstreamVersionConfigChangedBy), appID, sequence)
+}
+
 // GetDownstreamVersionHistory mocks base method.
 func (m *MockStore) GetDownstreamVersionHistory(appID, clusterID string, currentPage, pageSize int, pinLatest, pinLatestDeployable bool) (*types0.DownstreamVersionHistory, error) {
 	m.ctrl.T.Helper()
@@ -687,10 +761,10 @@ func (mr *MockStoreMockRecorder) GetDownstreamVersionSource(appID, sequence inte
 }
 
 // GetDownstreamVersionStatus mocks base method.
-func (m *MockStore) GetDownstreamVersionStatus(appID string, sequence int64) (types11.DownstreamVersionStatus, error) {
+func (m *MockStore) GetDownstreamVersionStatus(appID string, sequence int64) (types12.DownstreamVersionStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetDownstreamVersionStatus", appID, sequence)
-	ret0, _ := ret[0].(types11.DownstreamVersionStatus)
+	ret0, _ := ret[0].(types12.DownstreamVersionStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -944,10 +1018,10 @@ func (mr *MockStoreMockRecorder) GetPendingAirgapUploadApp() *gomock.Call {
 }
 
 // GetPendingInstallationStatus mocks base method.
-func (m *MockStore) GetPendingInstallationStatus() (*types6.InstallStatus, error) {
+func (m *MockStore) GetPendingInstallationStatus() (*types7.InstallStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetPendingInstallationStatus")
-	ret0, _ := ret[0].(*types6.InstallStatus)
+	ret0, _ := ret[0].(*types7.InstallStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -974,10 +1048,10 @@ func (mr *MockStoreMockRecorder) GetPreflightProgress(appID, sequence interface{
 }
 
 // GetPreflightResults mocks base method.
-func (m *MockStore) GetPreflightResults(appID string, sequence int64) (*types7.PreflightResult, error) {
+func (m *MockStore) GetPreflightResults(appID string, sequence int64) (*types8.PreflightResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetPreflightResults", appID, sequence)
-	ret0, _ := ret[0].(*types7.PreflightResult)
+	ret0, _ := ret[0].(*types8.PreflightResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1034,10 +1108,10 @@ func (mr *MockStoreMockRecorder) GetRedactions(bundleID interface{}) *gomock.Cal
 }
 
 // GetRegistryDetailsForApp mocks base method.
-func (m *MockStore) GetRegistryDetailsForApp(appID string) (types8.RegistrySettings, error) {
+func (m *MockStore) GetRegistryDetailsForApp(appID string) (types9.RegistrySettings, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetRegistryDetailsForApp", appID)
-	ret0, _ := ret[0].(types8.RegistrySettings)
+	ret0, _ := ret[0].(types9.RegistrySettings)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1049,10 +1123,10 @@ func (mr *MockStoreMockRecorder) GetRegistryDetailsForApp(appID interface{}) *go
 }
 
 // GetSession mocks base method.
-func (m *MockStore) GetSession(sessionID string) (*types10.Session, error) {
+func (m *MockStore) GetSession(sessionID string) (*types11.Session, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSession", sessionID)
-	ret0, _ := ret[0].(*types10.Session)
+	ret0, _ := ret[0].(*types11.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1079,10 +1153,10 @@ func (mr *MockStoreMockRecorder) GetSharedPasswordBcrypt() *gomock.Call {
 }
 
 // GetStatusForVersion mocks base method.
-func (m *MockStore) GetStatusForVersion(appID, clusterID string, sequence int64) (types11.DownstreamVersionStatus, error) {
+func (m *MockStore) GetStatusForVersion(appID, clusterID string, sequence int64) (types12.DownstreamVersionStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetStatusForVersion", appID, clusterID, sequence)
-	ret0, _ := ret[0].(types11.DownstreamVersionStatus)
+	ret0, _ := ret[0].(types12.DownstreamVersionStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1094,10 +1168,10 @@ func (mr *MockStoreMockRecorder) GetStatusForVersion(appID, clusterID, sequence
 }
 
 // GetSupportBundle mocks base method.
-func (m *MockStore) GetSupportBundle(bundleID string) (*types12.SupportBundle, error) {
+func (m *MockStore) GetSupportBundle(bundleID string) (*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSupportBundle", bundleID)
-	ret0, _ := ret[0].(*types12.SupportBundle)
+	ret0, _ := ret[0].(*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1109,10 +1183,10 @@ func (mr *MockStoreMockRecorder) GetSupportBundle(bundleID interface{}) *gomock.
 }
 
 // GetSupportBundleAnalysis mocks base method.
-func (m *MockStore) GetSupportBundleAnalysis(bundleID string) (*types12.SupportBundleAnalysis, error) {
+func (m *MockStore) GetSupportBundleAnalysis(bundleID string) (*types13.SupportBundleAnalysis, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSupportBundleAnalysis", bundleID)
-	ret0, _ := ret[0].(*types12.SupportBundleAnalysis)
+	ret0, _ := ret[0].(*types13.SupportBundleAnalysis)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1288,7 +1362,7 @@ func (mr *MockStoreMockRecorder) IsRollbackSupportedForVersion(appID, sequence i
 }
 
 // IsSnapshotsSupportedForVersion mocks base method.
-func (m *MockStore) IsSnapshotsSupportedForVersion(a *types3.App, sequence int64, renderer types9.Renderer) (bool, error) {
+func (m *MockStore) IsSnapshotsSupportedForVersion(a *types3.App, sequence int64, renderer types10.Renderer) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IsSnapshotsSupportedForVersion", a, sequence, renderer)
 	ret0, _ := ret[0].(bool)
@@ -1392,11 +1466,26 @@ func (mr *MockStoreMockRecorder) ListInstalledApps() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInstalledApps", reflect.TypeOf((*MockStore)(nil).ListInstalledApps))
 }
 
+// ListOpenGitOpsPullRequests mocks base method.
+func (m *MockStore) ListOpenGitOpsPullRequests(appID, clusterID string) ([]types5.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpenGitOpsPullRequests", appID, clusterID)
+	ret0, _ := ret[0].([]types5.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpenGitOpsPullRequests indicates an expected call of ListOpenGitOpsPullRequests.
+func (mr *MockStoreMockRecorder) ListOpenGitOpsPullRequests(appID, clusterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpenGitOpsPullRequests", reflect.TypeOf((*MockStore)(nil).ListOpenGitOpsPullRequests), appID, clusterID)
+}
+
 // ListPendingScheduledInstanceSnapshots mocks base method.
-func (m *MockStore) ListPendingScheduledInstanceSnapshots(clusterID string) ([]types5.ScheduledInstanceSnapshot, error) {
+func (m *MockStore) ListPendingScheduledInstanceSnapshots(clusterID string) ([]types6.ScheduledInstanceSnapshot, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListPendingScheduledInstanceSnapshots", clusterID)
-	ret0, _ := ret[0].([]types5.ScheduledInstanceSnapshot)
+	ret0, _ := ret[0].([]types6.ScheduledInstanceSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1408,10 +1497,10 @@ func (mr *MockStoreMockRecorder) ListPendingScheduledInstanceSnapshots(clusterID
 }
 
 // ListPendingScheduledSnapshots mocks base method.
-func (m *MockStore) ListPendingScheduledSnapshots(appID string) ([]types5.ScheduledSnapshot, error) {
+func (m *MockStore) ListPendingScheduledSnapshots(appID string) ([]types6.ScheduledSnapshot, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListPendingScheduledSnapshots", appID)
-	ret0, _ := ret[0].([]types5.ScheduledSnapshot)
+	ret0, _ := ret[0].([]types6.ScheduledSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1422,11 +1511,26 @@ func (mr *MockStoreMockRecorder) ListPendingScheduledSnapshots(appID interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPendingScheduledSnapshots", reflect.TypeOf((*MockStore)(nil).ListPendingScheduledSnapshots), appID)
 }
 
+// ListSessions mocks base method.
+func (m *MockStore) ListSessions() ([]*types11.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions")
+	ret0, _ := ret[0].([]*types11.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockStoreMockRecorder) ListSessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockStore)(nil).ListSessions))
+}
+
 // ListSupportBundles mocks base method.
-func (m *MockStore) ListSupportBundles(appID string) ([]*types12.SupportBundle, error) {
+func (m *MockStore) ListSupportBundles(appID string) ([]*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListSupportBundles", appID)
-	ret0, _ := ret[0].([]*types12.SupportBundle)
+	ret0, _ := ret[0].([]*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -1451,6 +1555,21 @@ func (mr *MockStoreMockRecorder) MarkAsCurrentDownstreamVersion(appID, sequence
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAsCurrentDownstreamVersion", reflect.TypeOf((*MockStore)(nil).MarkAsCurrentDownstreamVersion), appID, sequence)
 }
 
+// ReencryptRegistryPasswords mocks base method.
+func (m *MockStore) ReencryptRegistryPasswords(dryRun bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReencryptRegistryPasswords", dryRun)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReencryptRegistryPasswords indicates an expected call of ReencryptRegistryPasswords.
+func (mr *MockStoreMockRecorder) ReencryptRegistryPasswords(dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReencryptRegistryPasswords", reflect.TypeOf((*MockStore)(nil).ReencryptRegistryPasswords), dryRun)
+}
+
 // RemoveApp mocks base method.
 func (m *MockStore) RemoveApp(appID string) error {
 	m.ctrl.T.Helper()
@@ -1589,8 +1708,50 @@ func (mr *MockStoreMockRecorder) SetAutoDeploy(appID, autoDeploy interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAutoDeploy", reflect.TypeOf((*MockStore)(nil).SetAutoDeploy), appID, autoDeploy)
 }
 
+// SetDownstreamVersionConfigChangedBy mocks base method.
+func (m *MockStore) SetDownstreamVersionConfigChangedBy(appID string, sequence int64, changedBy string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionConfigChangedBy", appID, sequence, changedBy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionConfigChangedBy indicates an expected call of SetDownstreamVersionConfigChangedBy.
+func (mr *MockStoreMockRecorder) SetDownstreamVersionConfigChangedBy(appID, sequence, changedBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionConfigChangedBy", reflect.TypeOf((*MockStore)(nil).SetDownstreamVersionConfigChangedBy), appID, sequence, changedBy)
+}
+
+// SetDownstreamVersionGitOpsCommitHash mocks base method.
+func (m *MockStore) SetDownstreamVersionGitOpsCommitHash(appID, clusterID string, sequence int64, commitHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionGitOpsCommitHash", appID, clusterID, sequence, commitHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionGitOpsCommitHash indicates an expected call of SetDownstreamVersionGitOpsCommitHash.
+func (mr *MockStoreMockRecorder) SetDownstreamVersionGitOpsCommitHash(appID, clusterID, sequence, commitHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionGitOpsCommitHash", reflect.TypeOf((*MockStore)(nil).SetDownstreamVersionGitOpsCommitHash), appID, clusterID, sequence, commitHash)
+}
+
+// SetDownstreamVersionGitOpsPullRequest mocks base method.
+func (m *MockStore) SetDownstreamVersionGitOpsPullRequest(appID, clusterID string, sequence int64, pr types5.PullRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionGitOpsPullRequest", appID, clusterID, sequence, pr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionGitOpsPullRequest indicates an expected call of SetDownstreamVersionGitOpsPullRequest.
+func (mr *MockStoreMockRecorder) SetDownstreamVersionGitOpsPullRequest(appID, clusterID, sequence, pr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionGitOpsPullRequest", reflect.TypeOf((*MockStore)(nil).SetDownstreamVersionGitOpsPullRequest), appID, clusterID, sequence, pr)
+}
+
 // SetDownstreamVersionStatus mocks base method.
-func (m *MockStore) SetDownstreamVersionStatus(appID string, sequence int64, status types11.DownstreamVersionStatus, statusInfo string) error {
+func (m *MockStore) SetDownstreamVersionStatus(appID string, sequence int64, status types12.DownstreamVersionStatus, statusInfo string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetDownstreamVersionStatus", appID, sequence, status, statusInfo)
 	ret0, _ := ret[0].(error)
@@ -1632,6 +1793,20 @@ func (mr *MockStoreMockRecorder) SetEmbeddedClusterInstallCommandRoles(roles int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEmbeddedClusterInstallCommandRoles", reflect.TypeOf((*MockStore)(nil).SetEmbeddedClusterInstallCommandRoles), roles)
 }
 
+// SetGitOpsPullRequestStates mocks base method.
+func (m *MockStore) SetGitOpsPullRequestStates(appID, clusterID string, prs []types5.PullRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGitOpsPullRequestStates", appID, clusterID, prs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetGitOpsPullRequestStates indicates an expected call of SetGitOpsPullRequestStates.
+func (mr *MockStoreMockRecorder) SetGitOpsPullRequestStates(appID, clusterID, prs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGitOpsPullRequestStates", reflect.TypeOf((*MockStore)(nil).SetGitOpsPullRequestStates), appID, clusterID, prs)
+}
+
 // SetIgnorePreflightPermissionErrors mocks base method.
 func (m *MockStore) SetIgnorePreflightPermissionErrors(appID string, sequence int64) error {
 	m.ctrl.T.Helper()
@@ -1801,7 +1976,7 @@ func (mr *MockStoreMockRecorder) SetUpdateCheckerSpec(appID, updateCheckerSpec i
 }
 
 // UpdateAppLicense mocks base method.
-func (m *MockStore) UpdateAppLicense(appID string, sequence int64, archiveDir string, newLicense *licensewrapper.LicenseWrapper, originalLicenseData string, channelChanged, failOnVersionCreate bool, renderer types9.Renderer, reportingInfo *types1.ReportingInfo) (int64, error) {
+func (m *MockStore) UpdateAppLicense(appID string, sequence int64, archiveDir string, newLicense *licensewrapper.LicenseWrapper, originalLicenseData string, channelChanged, failOnVersionCreate bool, renderer types10.Renderer, reportingInfo *types1.ReportingInfo) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAppLicense", appID, sequence, archiveDir, newLicense, originalLicenseData, channelChanged, failOnVersionCreate, renderer, reportingInfo)
 	ret0, _ := ret[0].(int64)
@@ -1843,6 +2018,20 @@ func (mr *MockStoreMockRecorder) UpdateAppVersion(appID, sequence, baseSequence,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAppVersion", reflect.TypeOf((*MockStore)(nil).UpdateAppVersion), appID, sequence, baseSequence, filesInDir, source, skipPreflights)
 }
 
+// UpdateAppVersionArchive mocks base method.
+func (m *MockStore) UpdateAppVersionArchive(appID string, sequence int64, archiveDir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAppVersionArchive", appID, sequence, archiveDir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAppVersionArchive indicates an expected call of UpdateAppVersionArchive.
+func (mr *MockStoreMockRecorder) UpdateAppVersionArchive(appID, sequence, archiveDir interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAppVersionArchive", reflect.TypeOf((*MockStore)(nil).UpdateAppVersionArchive), appID, sequence, archiveDir)
+}
+
 // UpdateAppVersionDemotion mocks base method.
 func (m *MockStore) UpdateAppVersionDemotion(appID, channelID, cursor string, isDemoted bool) error {
 	m.ctrl.T.Helper()
@@ -1858,7 +2047,7 @@ func (mr *MockStoreMockRecorder) UpdateAppVersionDemotion(appID, channelID, curs
 }
 
 // UpdateAppVersionMetadata mocks base method.
-func (m *MockStore) UpdateAppVersionMetadata(appID string, update types13.Update) error {
+func (m *MockStore) UpdateAppVersionMetadata(appID string, update types14.Update) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAppVersionMetadata", appID, update)
 	ret0, _ := ret[0].(error)
@@ -1955,8 +2144,22 @@ func (mr *MockStoreMockRecorder) UpdateSessionExpiresAt(sessionID, expiresAt int
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionExpiresAt", reflect.TypeOf((*MockStore)(nil).UpdateSessionExpiresAt), sessionID, expiresAt)
 }
 
+// UpdateSessionLastActivityAt mocks base method.
+func (m *MockStore) UpdateSessionLastActivityAt(sessionID string, lastActivityAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionLastActivityAt", sessionID, lastActivityAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionLastActivityAt indicates an expected call of UpdateSessionLastActivityAt.
+func (mr *MockStoreMockRecorder) UpdateSessionLastActivityAt(sessionID, lastActivityAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionLastActivityAt", reflect.TypeOf((*MockStore)(nil).UpdateSessionLastActivityAt), sessionID, lastActivityAt)
+}
+
 // UpdateSupportBundle mocks base method.
-func (m *MockStore) UpdateSupportBundle(bundle *types12.SupportBundle) error {
+func (m *MockStore) UpdateSupportBundle(bundle *types13.SupportBundle) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateSupportBundle", bundle)
 	ret0, _ := ret[0].(error)
@@ -2071,10 +2274,10 @@ func (mr *MockRegistryStoreMockRecorder) GetAppIDsFromRegistry(hostname interfac
 }
 
 // GetRegistryDetailsForApp mocks base method.
-func (m *MockRegistryStore) GetRegistryDetailsForApp(appID string) (types8.RegistrySettings, error) {
+func (m *MockRegistryStore) GetRegistryDetailsForApp(appID string) (types9.RegistrySettings, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetRegistryDetailsForApp", appID)
-	ret0, _ := ret[0].(types8.RegistrySettings)
+	ret0, _ := ret[0].(types9.RegistrySettings)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2085,6 +2288,21 @@ func (mr *MockRegistryStoreMockRecorder) GetRegistryDetailsForApp(appID interfac
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRegistryDetailsForApp", reflect.TypeOf((*MockRegistryStore)(nil).GetRegistryDetailsForApp), appID)
 }
 
+// ReencryptRegistryPasswords mocks base method.
+func (m *MockRegistryStore) ReencryptRegistryPasswords(dryRun bool) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReencryptRegistryPasswords", dryRun)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReencryptRegistryPasswords indicates an expected call of ReencryptRegistryPasswords.
+func (mr *MockRegistryStoreMockRecorder) ReencryptRegistryPasswords(dryRun interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReencryptRegistryPasswords", reflect.TypeOf((*MockRegistryStore)(nil).ReencryptRegistryPasswords), dryRun)
+}
+
 // UpdateRegistry mocks base method.
 func (m *MockRegistryStore) UpdateRegistry(appID, hostname, username, password, namespace string, isReadOnly bool) error {
 	m.ctrl.T.Helper()
@@ -2123,7 +2341,7 @@ func (m *MockSupportBundleStore) EXPECT() *MockSupportBundleStoreMockRecorder {
 }
 
 // CreateInProgressSupportBundle mocks base method.
-func (m *MockSupportBundleStore) CreateInProgressSupportBundle(supportBundle *types12.SupportBundle) error {
+func (m *MockSupportBundleStore) CreateInProgressSupportBundle(supportBundle *types13.SupportBundle) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateInProgressSupportBundle", supportBundle)
 	ret0, _ := ret[0].(error)
@@ -2137,10 +2355,10 @@ func (mr *MockSupportBundleStoreMockRecorder) CreateInProgressSupportBundle(supp
 }
 
 // CreateSupportBundle mocks base method.
-func (m *MockSupportBundleStore) CreateSupportBundle(bundleID, appID, archivePath string, marshalledTree []byte) (*types12.SupportBundle, error) {
+func (m *MockSupportBundleStore) CreateSupportBundle(bundleID, appID, archivePath string, marshalledTree []byte) (*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreateSupportBundle", bundleID, appID, archivePath, marshalledTree)
-	ret0, _ := ret[0].(*types12.SupportBundle)
+	ret0, _ := ret[0].(*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2181,10 +2399,10 @@ func (mr *MockSupportBundleStoreMockRecorder) GetRedactions(bundleID interface{}
 }
 
 // GetSupportBundle mocks base method.
-func (m *MockSupportBundleStore) GetSupportBundle(bundleID string) (*types12.SupportBundle, error) {
+func (m *MockSupportBundleStore) GetSupportBundle(bundleID string) (*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSupportBundle", bundleID)
-	ret0, _ := ret[0].(*types12.SupportBundle)
+	ret0, _ := ret[0].(*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2196,10 +2414,10 @@ func (mr *MockSupportBundleStoreMockRecorder) GetSupportBundle(bundleID interfac
 }
 
 // GetSupportBundleAnalysis mocks base method.
-func (m *MockSupportBundleStore) GetSupportBundleAnalysis(bundleID string) (*types12.SupportBundleAnalysis, error) {
+func (m *MockSupportBundleStore) GetSupportBundleAnalysis(bundleID string) (*types13.SupportBundleAnalysis, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSupportBundleAnalysis", bundleID)
-	ret0, _ := ret[0].(*types12.SupportBundleAnalysis)
+	ret0, _ := ret[0].(*types13.SupportBundleAnalysis)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2226,10 +2444,10 @@ func (mr *MockSupportBundleStoreMockRecorder) GetSupportBundleArchive(bundleID i
 }
 
 // ListSupportBundles mocks base method.
-func (m *MockSupportBundleStore) ListSupportBundles(appID string) ([]*types12.SupportBundle, error) {
+func (m *MockSupportBundleStore) ListSupportBundles(appID string) ([]*types13.SupportBundle, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListSupportBundles", appID)
-	ret0, _ := ret[0].([]*types12.SupportBundle)
+	ret0, _ := ret[0].([]*types13.SupportBundle)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2269,7 +2487,7 @@ func (mr *MockSupportBundleStoreMockRecorder) SetSupportBundleAnalysis(bundleID,
 }
 
 // UpdateSupportBundle mocks base method.
-func (m *MockSupportBundleStore) UpdateSupportBundle(bundle *types12.SupportBundle) error {
+func (m *MockSupportBundleStore) UpdateSupportBundle(bundle *types13.SupportBundle) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateSupportBundle", bundle)
 	ret0, _ := ret[0].(error)
@@ -2335,10 +2553,10 @@ func (mr *MockPreflightStoreMockRecorder) GetPreflightProgress(appID, sequence i
 }
 
 // GetPreflightResults mocks base method.
-func (m *MockPreflightStore) GetPreflightResults(appID string, sequence int64) (*types7.PreflightResult, error) {
+func (m *MockPreflightStore) GetPreflightResults(appID string, sequence int64) (*types8.PreflightResult, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetPreflightResults", appID, sequence)
-	ret0, _ := ret[0].(*types7.PreflightResult)
+	ret0, _ := ret[0].(*types8.PreflightResult)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2562,18 +2780,32 @@ func (m *MockSessionStore) EXPECT() *MockSessionStoreMockRecorder {
 }
 
 // CreateSession mocks base method.
-func (m *MockSessionStore) CreateSession(user *types14.User, issuedAt, expiresAt time.Time, roles []string) (*types10.Session, error) {
+func (m *MockSessionStore) CreateSession(user *types15.User, issuedAt, expiresAt time.Time, roles []string, clientInfo types11.ClientInfo) (*types11.Session, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateSession", user, issuedAt, expiresAt, roles)
-	ret0, _ := ret[0].(*types10.Session)
+	ret := m.ctrl.Call(m, "CreateSession", user, issuedAt, expiresAt, roles, clientInfo)
+	ret0, _ := ret[0].(*types11.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateSession indicates an expected call of CreateSession.
-func (mr *MockSessionStoreMockRecorder) CreateSession(user, issuedAt, expiresAt, roles interface{}) *gomock.Call {
+func (mr *MockSessionStoreMockRecorder) CreateSession(user, issuedAt, expiresAt, roles, clientInfo interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockSessionStore)(nil).CreateSession), user, issuedAt, expiresAt, roles)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSession", reflect.TypeOf((*MockSessionStore)(nil).CreateSession), user, issuedAt, expiresAt, roles, clientInfo)
+}
+
+// DeleteAllSessions mocks base method.
+func (m *MockSessionStore) DeleteAllSessions() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAllSessions")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAllSessions indicates an expected call of DeleteAllSessions.
+func (mr *MockSessionStoreMockRecorder) DeleteAllSessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAllSessions", reflect.TypeOf((*MockSessionStore)(nil).DeleteAllSessions))
 }
 
 // DeleteExpiredSessions mocks base method.
@@ -2605,10 +2837,10 @@ func (mr *MockSessionStoreMockRecorder) DeleteSession(sessionID interface{}) *go
 }
 
 // GetSession mocks base method.
-func (m *MockSessionStore) GetSession(sessionID string) (*types10.Session, error) {
+func (m *MockSessionStore) GetSession(sessionID string) (*types11.Session, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetSession", sessionID)
-	ret0, _ := ret[0].(*types10.Session)
+	ret0, _ := ret[0].(*types11.Session)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -2619,6 +2851,21 @@ func (mr *MockSessionStoreMockRecorder) GetSession(sessionID interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSession", reflect.TypeOf((*MockSessionStore)(nil).GetSession), sessionID)
 }
 
+// ListSessions mocks base method.
+func (m *MockSessionStore) ListSessions() ([]*types11.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSessions")
+	ret0, _ := ret[0].([]*types11.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSessions indicates an expected call of ListSessions.
+func (mr *MockSessionStoreMockRecorder) ListSessions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSessions", reflect.TypeOf((*MockSessionStore)(nil).ListSessions))
+}
+
 // UpdateSessionExpiresAt mocks base method.
 func (m *MockSessionStore) UpdateSessionExpiresAt(sessionID string, expiresAt time.Time) error {
 	m.ctrl.T.Helper()
@@ -2633,6 +2880,20 @@ func (mr *MockSessionStoreMockRecorder) UpdateSessionExpiresAt(sessionID, expire
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionExpiresAt", reflect.TypeOf((*MockSessionStore)(nil).UpdateSessionExpiresAt), sessionID, expiresAt)
 }
 
+// UpdateSessionLastActivityAt mocks base method.
+func (m *MockSessionStore) UpdateSessionLastActivityAt(sessionID string, lastActivityAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSessionLastActivityAt", sessionID, lastActivityAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateSessionLastActivityAt indicates an expected call of UpdateSessionLastActivityAt.
+func (mr *MockSessionStoreMockRecorder) UpdateSessionLastActivityAt(sessionID, lastActivityAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSessionLastActivityAt", reflect.TypeOf((*MockSessionStore)(nil).UpdateSessionLastActivityAt), sessionID, lastActivityAt)
+}
+
 // MockAppStatusStore is a mock of AppStatusStore interface.
 type MockAppStatusStore struct {
 	ctrl     *gomock.Controller
@@ -3139,6 +3400,21 @@ func (mr *MockDownstreamStoreMockRecorder) GetDownstreamOutput(appID, clusterID,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDownstreamOutput", reflect.TypeOf((*MockDownstreamStore)(nil).GetDownstreamOutput), appID, clusterID, sequence)
 }
 
+// GetDownstreamVersionConfigChangedBy mocks base method.
+func (m *MockDownstreamStore) GetDownstreamVersionConfigChangedBy(appID string, sequence int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDownstreamVersionConfigChangedBy", appID, sequence)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDownstreamVersionConfigChangedBy indicates an expected call of GetDownstreamVersionConfigChangedBy.
+func (mr *MockDownstreamStoreMockRecorder) GetDownstreamVersionConfigChangedBy(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDownstreamVersionConfigChangedBy", reflect.TypeOf((*MockDownstreamStore)(nil).GetDownstreamVersionConfigChangedBy), appID, sequence)
+}
+
 // GetDownstreamVersionHistory mocks base method.
 func (m *MockDownstreamStore) GetDownstreamVersionHistory(appID, clusterID string, currentPage, pageSize int, pinLatest, pinLatestDeployable bool) (*types0.DownstreamVersionHistory, error) {
 	m.ctrl.T.Helper()
@@ -3170,10 +3446,10 @@ func (mr *MockDownstreamStoreMockRecorder) GetDownstreamVersionSource(appID, seq
 }
 
 // GetDownstreamVersionStatus mocks base method.
-func (m *MockDownstreamStore) GetDownstreamVersionStatus(appID string, sequence int64) (types11.DownstreamVersionStatus, error) {
+func (m *MockDownstreamStore) GetDownstreamVersionStatus(appID string, sequence int64) (types12.DownstreamVersionStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetDownstreamVersionStatus", appID, sequence)
-	ret0, _ := ret[0].(types11.DownstreamVersionStatus)
+	ret0, _ := ret[0].(types12.DownstreamVersionStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -3262,10 +3538,10 @@ func (mr *MockDownstreamStoreMockRecorder) GetPreviouslyDeployedSequence(appID,
 }
 
 // GetStatusForVersion mocks base method.
-func (m *MockDownstreamStore) GetStatusForVersion(appID, clusterID string, sequence int64) (types11.DownstreamVersionStatus, error) {
+func (m *MockDownstreamStore) GetStatusForVersion(appID, clusterID string, sequence int64) (types12.DownstreamVersionStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetStatusForVersion", appID, clusterID, sequence)
-	ret0, _ := ret[0].(types11.DownstreamVersionStatus)
+	ret0, _ := ret[0].(types12.DownstreamVersionStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -3307,6 +3583,21 @@ func (mr *MockDownstreamStoreMockRecorder) IsDownstreamDeploySuccessful(appID, c
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsDownstreamDeploySuccessful", reflect.TypeOf((*MockDownstreamStore)(nil).IsDownstreamDeploySuccessful), appID, clusterID, sequence)
 }
 
+// ListOpenGitOpsPullRequests mocks base method.
+func (m *MockDownstreamStore) ListOpenGitOpsPullRequests(appID, clusterID string) ([]types5.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListOpenGitOpsPullRequests", appID, clusterID)
+	ret0, _ := ret[0].([]types5.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListOpenGitOpsPullRequests indicates an expected call of ListOpenGitOpsPullRequests.
+func (mr *MockDownstreamStoreMockRecorder) ListOpenGitOpsPullRequests(appID, clusterID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListOpenGitOpsPullRequests", reflect.TypeOf((*MockDownstreamStore)(nil).ListOpenGitOpsPullRequests), appID, clusterID)
+}
+
 // MarkAsCurrentDownstreamVersion mocks base method.
 func (m *MockDownstreamStore) MarkAsCurrentDownstreamVersion(appID string, sequence int64) error {
 	m.ctrl.T.Helper()
@@ -3321,8 +3612,50 @@ func (mr *MockDownstreamStoreMockRecorder) MarkAsCurrentDownstreamVersion(appID,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkAsCurrentDownstreamVersion", reflect.TypeOf((*MockDownstreamStore)(nil).MarkAsCurrentDownstreamVersion), appID, sequence)
 }
 
+// SetDownstreamVersionConfigChangedBy mocks base method.
+func (m *MockDownstreamStore) SetDownstreamVersionConfigChangedBy(appID string, sequence int64, changedBy string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionConfigChangedBy", appID, sequence, changedBy)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionConfigChangedBy indicates an expected call of SetDownstreamVersionConfigChangedBy.
+func (mr *MockDownstreamStoreMockRecorder) SetDownstreamVersionConfigChangedBy(appID, sequence, changedBy interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionConfigChangedBy", reflect.TypeOf((*MockDownstreamStore)(nil).SetDownstreamVersionConfigChangedBy), appID, sequence, changedBy)
+}
+
+// SetDownstreamVersionGitOpsCommitHash mocks base method.
+func (m *MockDownstreamStore) SetDownstreamVersionGitOpsCommitHash(appID, clusterID string, sequence int64, commitHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionGitOpsCommitHash", appID, clusterID, sequence, commitHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionGitOpsCommitHash indicates an expected call of SetDownstreamVersionGitOpsCommitHash.
+func (mr *MockDownstreamStoreMockRecorder) SetDownstreamVersionGitOpsCommitHash(appID, clusterID, sequence, commitHash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionGitOpsCommitHash", reflect.TypeOf((*MockDownstreamStore)(nil).SetDownstreamVersionGitOpsCommitHash), appID, clusterID, sequence, commitHash)
+}
+
+// SetDownstreamVersionGitOpsPullRequest mocks base method.
+func (m *MockDownstreamStore) SetDownstreamVersionGitOpsPullRequest(appID, clusterID string, sequence int64, pr types5.PullRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetDownstreamVersionGitOpsPullRequest", appID, clusterID, sequence, pr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetDownstreamVersionGitOpsPullRequest indicates an expected call of SetDownstreamVersionGitOpsPullRequest.
+func (mr *MockDownstreamStoreMockRecorder) SetDownstreamVersionGitOpsPullRequest(appID, clusterID, sequence, pr interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionGitOpsPullRequest", reflect.TypeOf((*MockDownstreamStore)(nil).SetDownstreamVersionGitOpsPullRequest), appID, clusterID, sequence, pr)
+}
+
 // SetDownstreamVersionStatus mocks base method.
-func (m *MockDownstreamStore) SetDownstreamVersionStatus(appID string, sequence int64, status types11.DownstreamVersionStatus, statusInfo string) error {
+func (m *MockDownstreamStore) SetDownstreamVersionStatus(appID string, sequence int64, status types12.DownstreamVersionStatus, statusInfo string) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "SetDownstreamVersionStatus", appID, sequence, status, statusInfo)
 	ret0, _ := ret[0].(error)
@@ -3335,6 +3668,20 @@ func (mr *MockDownstreamStoreMockRecorder) SetDownstreamVersionStatus(appID, seq
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetDownstreamVersionStatus", reflect.TypeOf((*MockDownstreamStore)(nil).SetDownstreamVersionStatus), appID, sequence, status, statusInfo)
 }
 
+// SetGitOpsPullRequestStates mocks base method.
+func (m *MockDownstreamStore) SetGitOpsPullRequestStates(appID, clusterID string, prs []types5.PullRequest) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetGitOpsPullRequestStates", appID, clusterID, prs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetGitOpsPullRequestStates indicates an expected call of SetGitOpsPullRequestStates.
+func (mr *MockDownstreamStoreMockRecorder) SetGitOpsPullRequestStates(appID, clusterID, prs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetGitOpsPullRequestStates", reflect.TypeOf((*MockDownstreamStore)(nil).SetGitOpsPullRequestStates), appID, clusterID, prs)
+}
+
 // UpdateDownstreamDeployStatus mocks base method.
 func (m *MockDownstreamStore) UpdateDownstreamDeployStatus(appID, clusterID string, sequence int64, isError bool, output types0.DownstreamOutput) error {
 	m.ctrl.T.Helper()
@@ -3429,10 +3776,10 @@ func (mr *MockSnapshotStoreMockRecorder) DeletePendingScheduledSnapshots(appID i
 }
 
 // ListPendingScheduledInstanceSnapshots mocks base method.
-func (m *MockSnapshotStore) ListPendingScheduledInstanceSnapshots(clusterID string) ([]types5.ScheduledInstanceSnapshot, error) {
+func (m *MockSnapshotStore) ListPendingScheduledInstanceSnapshots(clusterID string) ([]types6.ScheduledInstanceSnapshot, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListPendingScheduledInstanceSnapshots", clusterID)
-	ret0, _ := ret[0].([]types5.ScheduledInstanceSnapshot)
+	ret0, _ := ret[0].([]types6.ScheduledInstanceSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -3444,10 +3791,10 @@ func (mr *MockSnapshotStoreMockRecorder) ListPendingScheduledInstanceSnapshots(c
 }
 
 // ListPendingScheduledSnapshots mocks base method.
-func (m *MockSnapshotStore) ListPendingScheduledSnapshots(appID string) ([]types5.ScheduledSnapshot, error) {
+func (m *MockSnapshotStore) ListPendingScheduledSnapshots(appID string) ([]types6.ScheduledSnapshot, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListPendingScheduledSnapshots", appID)
-	ret0, _ := ret[0].([]types5.ScheduledSnapshot)
+	ret0, _ := ret[0].([]types6.ScheduledSnapshot)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -3525,7 +3872,7 @@ func (mr *MockVersionStoreMockRecorder) CreateAppVersion(appID, baseSequence, fi
 }
 
 // CreatePendingDownloadAppVersion mocks base method.
-func (m *MockVersionStore) CreatePendingDownloadAppVersion(appID string, update types13.Update, kotsApplication *v1beta10.Application, license *licensewrapper.LicenseWrapper) (int64, error) {
+func (m *MockVersionStore) CreatePendingDownloadAppVersion(appID string, update types14.Update, kotsApplication *v1beta10.Application, license *licensewrapper.LicenseWrapper) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "CreatePendingDownloadAppVersion", appID, update, kotsApplication, license)
 	ret0, _ := ret[0].(int64)
@@ -3539,6 +3886,20 @@ func (mr *MockVersionStoreMockRecorder) CreatePendingDownloadAppVersion(appID, u
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePendingDownloadAppVersion", reflect.TypeOf((*MockVersionStore)(nil).CreatePendingDownloadAppVersion), appID, update, kotsApplication, license)
 }
 
+// DeleteAppVersion mocks base method.
+func (m *MockVersionStore) DeleteAppVersion(appID string, sequence int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteAppVersion", appID, sequence)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteAppVersion indicates an expected call of DeleteAppVersion.
+func (mr *MockVersionStoreMockRecorder) DeleteAppVersion(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteAppVersion", reflect.TypeOf((*MockVersionStore)(nil).DeleteAppVersion), appID, sequence)
+}
+
 // GetAppVersion mocks base method.
 func (m *MockVersionStore) GetAppVersion(appID string, sequence int64) (*types2.AppVersion, error) {
 	m.ctrl.T.Helper()
@@ -3568,6 +3929,36 @@ func (mr *MockVersionStoreMockRecorder) GetAppVersionArchive(appID, sequence, ds
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchive", reflect.TypeOf((*MockVersionStore)(nil).GetAppVersionArchive), appID, sequence, dstPath)
 }
 
+// GetAppVersionArchiveSize mocks base method.
+func (m *MockVersionStore) GetAppVersionArchiveSize(appID string, sequence int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAppVersionArchiveSize", appID, sequence)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAppVersionArchiveSize indicates an expected call of GetAppVersionArchiveSize.
+func (mr *MockVersionStoreMockRecorder) GetAppVersionArchiveSize(appID, sequence interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchiveSize", reflect.TypeOf((*MockVersionStore)(nil).GetAppVersionArchiveSize), appID, sequence)
+}
+
+// GetAppVersionArchivesReclaimableSize mocks base method.
+func (m *MockVersionStore) GetAppVersionArchivesReclaimableSize(appID string, sequences []int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAppVersionArchivesReclaimableSize", appID, sequences)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAppVersionArchivesReclaimableSize indicates an expected call of GetAppVersionArchivesReclaimableSize.
+func (mr *MockVersionStoreMockRecorder) GetAppVersionArchivesReclaimableSize(appID, sequences interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAppVersionArchivesReclaimableSize", reflect.TypeOf((*MockVersionStore)(nil).GetAppVersionArchivesReclaimableSize), appID, sequences)
+}
+
 // GetAppVersionBaseArchive mocks base method.
 func (m *MockVersionStore) GetAppVersionBaseArchive(appID, versionLabel string) (string, int64, error) {
 	m.ctrl.T.Helper()
@@ -3720,7 +4111,7 @@ func (mr *MockVersionStoreMockRecorder) IsRollbackSupportedForVersion(appID, seq
 }
 
 // IsSnapshotsSupportedForVersion mocks base method.
-func (m *MockVersionStore) IsSnapshotsSupportedForVersion(a *types3.App, sequence int64, renderer types9.Renderer) (bool, error) {
+func (m *MockVersionStore) IsSnapshotsSupportedForVersion(a *types3.App, sequence int64, renderer types10.Renderer) (bool, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "IsSnapshotsSupportedForVersion", a, sequence, renderer)
 	ret0, _ := ret[0].(bool)
@@ -3748,6 +4139,20 @@ func (mr *MockVersionStoreMockRecorder) UpdateAppVersion(appID, sequence, baseSe
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAppVersion", reflect.TypeOf((*MockVersionStore)(nil).UpdateAppVersion), appID, sequence, baseSequence, filesInDir, source, skipPreflights)
 }
 
+// UpdateAppVersionArchive mocks base method.
+func (m *MockVersionStore) UpdateAppVersionArchive(appID string, sequence int64, archiveDir string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateAppVersionArchive", appID, sequence, archiveDir)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateAppVersionArchive indicates an expected call of UpdateAppVersionArchive.
+func (mr *MockVersionStoreMockRecorder) UpdateAppVersionArchive(appID, sequence, archiveDir interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateAppVersionArchive", reflect.TypeOf((*MockVersionStore)(nil).UpdateAppVersionArchive), appID, sequence, archiveDir)
+}
+
 // UpdateAppVersionDemotion mocks base method.
 func (m *MockVersionStore) UpdateAppVersionDemotion(appID, channelID, cursor string, isDemoted bool) error {
 	m.ctrl.T.Helper()
@@ -3763,7 +4168,7 @@ func (mr *MockVersionStoreMockRecorder) UpdateAppVersionDemotion(appID, channelI
 }
 
 // UpdateAppVersionMetadata mocks base method.
-func (m *MockVersionStore) UpdateAppVersionMetadata(appID string, update types13.Update) error {
+func (m *MockVersionStore) UpdateAppVersionMetadata(appID string, update types14.Update) error {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAppVersionMetadata", appID, update)
 	ret0, _ := ret[0].(error)
@@ -3859,7 +4264,7 @@ func (mr *MockLicenseStoreMockRecorder) GetLicenseForAppVersion(appID, sequence
 }
 
 // UpdateAppLicense mocks base method.
-func (m *MockLicenseStore) UpdateAppLicense(appID string, sequence int64, archiveDir string, newLicense *licensewrapper.LicenseWrapper, originalLicenseData string, channelChanged, failOnVersionCreate bool, renderer types9.Renderer, reportingInfo *types1.ReportingInfo) (int64, error) {
+func (m *MockLicenseStore) UpdateAppLicense(appID string, sequence int64, archiveDir string, newLicense *licensewrapper.LicenseWrapper, originalLicenseData string, channelChanged, failOnVersionCreate bool, renderer types10.Renderer, reportingInfo *types1.ReportingInfo) (int64, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "UpdateAppLicense", appID, sequence, archiveDir, newLicense, originalLicenseData, channelChanged, failOnVersionCreate, renderer, reportingInfo)
 	ret0, _ := ret[0].(int64)
@@ -4103,10 +4508,10 @@ func (m *MockInstallationStore) EXPECT() *MockInstallationStoreMockRecorder {
 }
 
 // GetPendingInstallationStatus mocks base method.
-func (m *MockInstallationStore) GetPendingInstallationStatus() (*types6.InstallStatus, error) {
+func (m *MockInstallationStore) GetPendingInstallationStatus() (*types7.InstallStatus, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetPendingInstallationStatus")
-	ret0, _ := ret[0].(*types6.InstallStatus)
+	ret0, _ := ret[0].(*types7.InstallStatus)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -11,6 +11,7 @@ import (
 	versiontypes "github.com/replicatedhq/kots/pkg/api/version/types"
 	apptypes "github.com/replicatedhq/kots/pkg/app/types"
 	appstatetypes "github.com/replicatedhq/kots/pkg/appstate/types"
+	gitopstypes "github.com/replicatedhq/kots/pkg/gitops/types"
 	snapshottypes "github.com/replicatedhq/kots/pkg/kotsadmsnapshot/types"
 	installationtypes "github.com/replicatedhq/kots/pkg/online/types"
 	preflighttypes "github.com/replicatedhq/kots/pkg/preflight/types"
@@ -61,6 +62,7 @@ type RegistryStore interface {
 	GetRegistryDetailsForApp(appID string) (registrytypes.RegistrySettings, error)
 	UpdateRegistry(appID string, hostname string, username string, password string, namespace string, isReadOnly bool) error
 	GetAppIDsFromRegistry(hostname string) ([]string, error)
+	ReencryptRegistryPasswords(dryRun bool) (int, error)
 }
 
 type SupportBundleStore interface {
@@ -100,11 +102,14 @@ type AirgapStore interface {
 }
 
 type SessionStore interface {
-	CreateSession(user *usertypes.User, issuedAt time.Time, expiresAt time.Time, roles []string) (*sessiontypes.Session, error)
+	CreateSession(user *usertypes.User, issuedAt time.Time, expiresAt time.Time, roles []string, clientInfo sessiontypes.ClientInfo) (*sessiontypes.Session, error)
 	DeleteSession(sessionID string) error
 	GetSession(sessionID string) (*sessiontypes.Session, error)
+	ListSessions() ([]*sessiontypes.Session, error)
 	UpdateSessionExpiresAt(sessionID string, expiresAt time.Time) error
+	UpdateSessionLastActivityAt(sessionID string, lastActivityAt time.Time) error
 	DeleteExpiredSessions() error
+	DeleteAllSessions() error
 }
 
 type AppStatusStore interface {
@@ -144,6 +149,10 @@ type DownstreamStore interface {
 	SetDownstreamVersionStatus(appID string, sequence int64, status types.DownstreamVersionStatus, statusInfo string) error
 	GetDownstreamVersionStatus(appID string, sequence int64) (types.DownstreamVersionStatus, error)
 	GetDownstreamVersionSource(appID string, sequence int64) (string, error)
+	// GetDownstreamVersionConfigChangedBy returns what changed the config of the version, if it was recorded
+	GetDownstreamVersionConfigChangedBy(appID string, sequence int64) (string, error)
+	// SetDownstreamVersionConfigChangedBy records what changed the config of the version, such as the kots cli
+	SetDownstreamVersionConfigChangedBy(appID string, sequence int64, changedBy string) error
 	GetIgnoreRBACErrors(appID string, sequence int64) (bool, error)
 	GetCurrentDownstreamVersion(appID string, clusterID string) (*downstreamtypes.DownstreamVersion, error)
 	GetStatusForVersion(appID string, clusterID string, sequence int64) (types.DownstreamVersionStatus, error)
@@ -161,6 +170,14 @@ type DownstreamStore interface {
 	IsDownstreamDeploySuccessful(appID string, clusterID string, sequence int64) (bool, error)
 	UpdateDownstreamDeployStatus(appID string, clusterID string, sequence int64, isError bool, output downstreamtypes.DownstreamOutput) error
 	DeleteDownstreamDeployStatus(appID string, clusterID string, sequence int64) error
+	// ListOpenGitOpsPullRequests returns the gitops pull requests that are still open for the downstream's versions
+	ListOpenGitOpsPullRequests(appID string, clusterID string) ([]gitopstypes.PullRequest, error)
+	// SetDownstreamVersionGitOpsPullRequest records the pull request opened for a version
+	SetDownstreamVersionGitOpsPullRequest(appID string, clusterID string, sequence int64, pr gitopstypes.PullRequest) error
+	// SetDownstreamVersionGitOpsCommitHash records the commit that a version was pushed as
+	SetDownstreamVersionGitOpsCommitHash(appID string, clusterID string, sequence int64, commitHash string) error
+	// SetGitOpsPullRequestStates updates the state of the downstream's gitops pull requests by number
+	SetGitOpsPullRequestStates(appID string, clusterID string, prs []gitopstypes.PullRequest) error
 }
 
 type SnapshotStore interface {
@@ -181,6 +198,11 @@ type VersionStore interface {
 	IsSnapshotsSupportedForVersion(a *apptypes.App, sequence int64, renderer rendertypes.Renderer) (bool, error)
 	GetTargetKotsVersionForVersion(appID string, sequence int64) (string, error)
 	GetAppVersionArchive(appID string, sequence int64, dstPath string) error
+	GetAppVersionArchiveSize(appID string, sequence int64) (int64, error)
+	// GetAppVersionArchivesReclaimableSize returns the storage that deleting the archives of all of the sequences would free
+	GetAppVersionArchivesReclaimableSize(appID string, sequences []int64) (int64, error)
+	DeleteAppVersion(appID string, sequence int64) error
+	UpdateAppVersionArchive(appID string, sequence int64, archiveDir string) error
 	GetAppVersionBaseSequence(appID string, versionLabel string) (int64, error)
 	GetAppVersionBaseArchive(appID string, versionLabel string) (string, int64, error)
 	CreatePendingDownloadAppVersion(appID string, update upstreamtypes.Update, kotsApplication *kotsv1beta1.Application, license *licensewrapper.LicenseWrapper) (int64, error)
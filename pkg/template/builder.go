@@ -10,9 +10,11 @@ import (
 	"github.com/replicatedhq/kots/pkg/docker/registry"
 	dockerregistrytypes "github.com/replicatedhq/kots/pkg/docker/registry/types"
 	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
 	registrytypes "github.com/replicatedhq/kots/pkg/registry/types"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kotskinds/pkg/licensewrapper"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -35,6 +37,15 @@ type BuilderOptions struct {
 	IdentityConfig  *kotsv1beta1.IdentityConfig
 	Namespace       string
 	DecryptValues   bool
+	// ValueReferences are the value references of the config items, which are resolved when the config context is created
+	ValueReferences map[string]kotsutil.ConfigValueReference
+	// Clientset is used for the cluster lookups of the templates instead of the cluster of the current kube context.
+	// kURL values and Lookup results are not read when it is set, since they need a cluster config.
+	Clientset kubernetes.Interface
+	// Eval is set when the templates are supplied by a user rather than the app. functions that read from the cluster
+	// or persist generated values fail, so that evaluating a template can't reveal secrets or have side effects.
+	// the config items are still rendered from the config of the app as usual.
+	Eval bool
 }
 
 // NewBuilder creates a builder with all available contexts.
@@ -44,7 +55,7 @@ func NewBuilder(opts BuilderOptions) (Builder, map[string]ItemValue, error) {
 	// do not fail on being unable to get dockerhub credentials, since they're just used to increase the rate limit
 	dockerHubRegistry := dockerregistrytypes.RegistryOptions{}
 	if opts.Namespace != "" {
-		clientset, err := k8sutil.GetClientset()
+		clientset, err := getBuilderClientset(opts)
 		if err == nil {
 			dockerHubRegistryCreds, _ := registry.GetDockerHubCredentials(clientset, opts.Namespace)
 			dockerHubRegistry = dockerregistrytypes.RegistryOptions{
@@ -60,15 +71,20 @@ func NewBuilder(opts BuilderOptions) (Builder, map[string]ItemValue, error) {
 	}
 
 	configCtx, err := b.newConfigContext(opts.ConfigGroups, opts.ExistingValues, opts.LocalRegistry,
-		opts.License, opts.Application, opts.VersionInfo, dockerHubRegistry, slug, opts.DecryptValues)
+		opts.License, opts.Application, opts.VersionInfo, dockerHubRegistry, slug, opts.DecryptValues, opts.ValueReferences, opts.Namespace, opts.Clientset)
 	if err != nil {
 		return Builder{}, nil, errors.Wrap(err, "create config context")
 	}
 
+	kurlContext := &kurlCtx{KurlValues: map[string]interface{}{}}
+	if opts.Clientset == nil {
+		kurlContext = newKurlContext("base", "default") // can be hardcoded because kurl always deploys to the default namespace
+	}
+
 	b.Ctx = []Ctx{
-		StaticCtx{},
+		StaticCtx{clientset: opts.Clientset, appSlug: slug, eval: opts.Eval},
 		licenseCtx{License: opts.License, App: opts.Application, VersionInfo: opts.VersionInfo},
-		newKurlContext("base", "default"), // can be hardcoded because kurl always deploys to the default namespace
+		kurlContext,
 		newVersionCtx(opts.VersionInfo),
 		newIdentityCtx(opts.IdentityConfig, opts.ApplicationInfo),
 		configCtx,
@@ -76,6 +92,13 @@ func NewBuilder(opts BuilderOptions) (Builder, map[string]ItemValue, error) {
 	return b, configCtx.ItemValues, nil
 }
 
+func getBuilderClientset(opts BuilderOptions) (kubernetes.Interface, error) {
+	if opts.Clientset != nil {
+		return opts.Clientset, nil
+	}
+	return k8sutil.GetClientset()
+}
+
 func (b *Builder) AddCtx(ctx Ctx) {
 	b.Ctx = append(b.Ctx, ctx)
 }
@@ -220,16 +243,22 @@ func (b *Builder) RenderTemplate(name string, text string) (string, error) {
 		{"repl{{", "}}"},
 	}
 
+	// the text is used as the name by callers that do not have one
+	path := name
+	if path == text {
+		path = ""
+	}
+
 	curText := text
 	for _, d := range delims {
-		tmpl, err := b.GetTemplate(name, curText, d.rdelim, d.ldelim)
+		tmpl, err := b.GetTemplate(templateName, curText, d.rdelim, d.ldelim)
 		if err != nil {
-			return "", errors.Wrap(err, "failed to get template")
+			return "", errors.Wrap(newTemplateError(err, path, text, curText, d.rdelim, d.ldelim), "failed to get template")
 		}
 
 		var contents bytes.Buffer
 		if err := tmpl.Execute(&contents, nil); err != nil {
-			return "", errors.Wrap(err, "failed to execute template")
+			return "", errors.Wrap(newTemplateError(err, path, text, curText, d.rdelim, d.ldelim), "failed to execute template")
 		}
 		curText = contents.String()
 	}
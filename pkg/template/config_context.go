@@ -12,11 +12,15 @@ import (
 	dockerregistrytypes "github.com/replicatedhq/kots/pkg/docker/registry/types"
 	"github.com/replicatedhq/kots/pkg/image"
 	"github.com/replicatedhq/kots/pkg/imageutil"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
 	"github.com/replicatedhq/kots/pkg/logger"
 	registrytypes "github.com/replicatedhq/kots/pkg/registry/types"
+	"github.com/replicatedhq/kots/pkg/util"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kotskinds/pkg/licensewrapper"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 var (
@@ -72,7 +76,7 @@ type ConfigCtx struct {
 }
 
 // newConfigContext creates and returns a context for template rendering
-func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, existingValues map[string]ItemValue, localRegistry registrytypes.RegistrySettings, license *licensewrapper.LicenseWrapper, app *kotsv1beta1.Application, info *VersionInfo, dockerHubRegistry dockerregistrytypes.RegistryOptions, appSlug string, decryptValues bool) (*ConfigCtx, error) {
+func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, existingValues map[string]ItemValue, localRegistry registrytypes.RegistrySettings, license *licensewrapper.LicenseWrapper, app *kotsv1beta1.Application, info *VersionInfo, dockerHubRegistry dockerregistrytypes.RegistryOptions, appSlug string, decryptValues bool, valueReferences map[string]kotsutil.ConfigValueReference, namespace string, clientset kubernetes.Interface) (*ConfigCtx, error) {
 	configCtx := &ConfigCtx{
 		ItemValues:        existingValues,
 		LocalRegistry:     localRegistry,
@@ -84,12 +88,17 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 		DecryptValues:     decryptValues,
 	}
 
+	kurlContext := &kurlCtx{KurlValues: map[string]interface{}{}}
+	if clientset == nil {
+		kurlContext = newKurlContext("base", "default")
+	}
+
 	builder := Builder{
 		Ctx: []Ctx{
 			configCtx,
-			StaticCtx{},
+			StaticCtx{clientset: clientset},
 			&licenseCtx{License: license, App: app, VersionInfo: info},
-			newKurlContext("base", "default"),
+			kurlContext,
 			newVersionCtx(info),
 		},
 	}
@@ -132,6 +141,20 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 			// build "default"
 			builtDefault, _ := builder.String(configItem.Default.String())
 
+			// items with a value reference always use the referenced value, which is not part of the config values
+			if reference, ok := valueReferences[node]; ok {
+				// a value that can't be resolved fails rendering rather than deploying the item without a value
+				value, err := resolveValueReference(&builder, reference, namespace, clientset)
+				if err != nil {
+					return nil, errors.Wrapf(err, "failed to resolve value reference for config item %s", node)
+				}
+				configCtx.ItemValues[node] = ItemValue{
+					Value:   value,
+					Default: builtDefault,
+				}
+				continue
+			}
+
 			if !isReadOnly(configItem) {
 				// if item is editable and the live state is valid, only apply the rendered default
 				// since that's not editable
@@ -171,6 +194,57 @@ func (b *Builder) newConfigContext(configGroups []kotsv1beta1.ConfigGroup, exist
 	return configCtx, nil
 }
 
+// resolveValueReference renders the templated fields of the reference and reads the referenced value
+// using the clientset of the current kube context if one is not provided
+func resolveValueReference(builder *Builder, reference kotsutil.ConfigValueReference, namespace string, clientset kubernetes.Interface) (string, error) {
+	renderKeyReference := func(ref *kotsutil.ConfigValueKeyReference) (*kotsutil.ConfigValueKeyReference, error) {
+		name, err := builder.String(ref.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render name")
+		}
+		key, err := builder.String(ref.Key)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to render key")
+		}
+		if clientset == nil {
+			c, err := k8sutil.GetClientset()
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get clientset")
+			}
+			clientset = c
+		}
+		return &kotsutil.ConfigValueKeyReference{Name: name, Key: key, Namespace: ref.Namespace}, nil
+	}
+
+	rendered := kotsutil.ConfigValueReference{}
+	switch {
+	case reference.SecretKeyRef != nil:
+		ref, err := renderKeyReference(reference.SecretKeyRef)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to render secret key reference")
+		}
+		rendered.SecretKeyRef = ref
+	case reference.ConfigMapKeyRef != nil:
+		ref, err := renderKeyReference(reference.ConfigMapKeyRef)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to render config map key reference")
+		}
+		rendered.ConfigMapKeyRef = ref
+	case reference.File != nil:
+		path, err := builder.String(reference.File.Path)
+		if err != nil {
+			return "", errors.Wrap(err, "failed to render file path")
+		}
+		rendered.File = &kotsutil.ConfigValueFileReference{Path: path}
+	}
+
+	if namespace == "" {
+		namespace = util.PodNamespace
+	}
+
+	return kotsutil.ResolveConfigValueReference(clientset, namespace, rendered)
+}
+
 // FuncMap represents the available functions in the ConfigCtx.
 func (ctx ConfigCtx) FuncMap() template.FuncMap {
 	return template.FuncMap{
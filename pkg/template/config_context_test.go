@@ -2,10 +2,13 @@ package template
 
 import (
 	"encoding/base64"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/replicatedhq/kots/pkg/crypto"
 	dockerregistrytypes "github.com/replicatedhq/kots/pkg/docker/registry/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
 	registrytypes "github.com/replicatedhq/kots/pkg/registry/types"
 	kotsv1beta1 "github.com/replicatedhq/kotskinds/apis/kots/v1beta1"
 	"github.com/replicatedhq/kotskinds/multitype"
@@ -492,13 +495,69 @@ func TestBuilder_NewConfigContext(t *testing.T) {
 				licenseWrapper := licensewrapper.LicenseWrapper{V1: tt.args.license}
 				licenseWrapperPtr = &licenseWrapper
 			}
-			got, err := builder.newConfigContext(tt.args.configGroups, tt.args.templateContext, localRegistry, licenseWrapperPtr, nil, nil, dockerregistrytypes.RegistryOptions{}, "app-slug", tt.args.decryptValues)
+			got, err := builder.newConfigContext(tt.args.configGroups, tt.args.templateContext, localRegistry, licenseWrapperPtr, nil, nil, dockerregistrytypes.RegistryOptions{}, "app-slug", tt.args.decryptValues, nil, "", nil)
 			req.NoError(err)
 			req.Equal(tt.want, got)
 		})
 	}
 }
 
+func TestBuilder_NewConfigContextValueReferences(t *testing.T) {
+	req := require.New(t)
+
+	filesDir := kotsutil.ConfigValueFilesDir
+	kotsutil.ConfigValueFilesDir = t.TempDir()
+	t.Cleanup(func() {
+		kotsutil.ConfigValueFilesDir = filesDir
+	})
+
+	dir := filepath.Join(kotsutil.ConfigValueFilesDir, "database")
+	req.NoError(os.Mkdir(dir, 0755))
+	req.NoError(os.WriteFile(filepath.Join(dir, "ca.crt"), []byte("ca-data"), 0644))
+
+	configGroups := []kotsv1beta1.ConfigGroup{
+		{
+			Name: "database",
+			Items: []kotsv1beta1.ConfigItem{
+				{
+					Name:    "ca_dir",
+					Type:    "text",
+					Default: multitype.BoolOrString{Type: multitype.String, StrVal: dir},
+				},
+				{
+					Name:  "ca",
+					Type:  "textarea",
+					Value: multitype.BoolOrString{Type: multitype.String, StrVal: "ignored"},
+				},
+			},
+		},
+	}
+	valueReferences := map[string]kotsutil.ConfigValueReference{
+		"ca": {
+			File: &kotsutil.ConfigValueFileReference{Path: `{{repl ConfigOption "ca_dir"}}/ca.crt`},
+		},
+	}
+	existingValues := map[string]ItemValue{
+		"ca": {Value: "saved value"},
+	}
+
+	builder := Builder{}
+	builder.AddCtx(StaticCtx{})
+
+	got, err := builder.newConfigContext(configGroups, existingValues, registrytypes.RegistrySettings{}, nil, nil, nil, dockerregistrytypes.RegistryOptions{}, "app-slug", false, valueReferences, "", nil)
+	req.NoError(err)
+
+	// the referenced value is used instead of the saved value
+	req.Equal("ca-data", got.ItemValues["ca"].Value)
+
+	// a reference that cannot be resolved is an error rather than an empty value
+	valueReferences["ca"] = kotsutil.ConfigValueReference{
+		File: &kotsutil.ConfigValueFileReference{Path: filepath.Join(dir, "missing")},
+	}
+	_, err = builder.newConfigContext(configGroups, existingValues, registrytypes.RegistrySettings{}, nil, nil, nil, dockerregistrytypes.RegistryOptions{}, "app-slug", false, valueReferences, "", nil)
+	req.ErrorContains(err, "failed to resolve value reference for config item ca")
+}
+
 func Test_localImageName(t *testing.T) {
 	ctxWithRegistry := ConfigCtx{
 		LocalRegistry: registrytypes.RegistrySettings{
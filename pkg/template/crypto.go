@@ -0,0 +1,535 @@
+package template
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/util"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+	corev1 "k8s.io/api/core/v1"
+	kuberneteserrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	certUtil "k8s.io/client-go/util/cert"
+	"k8s.io/client-go/util/keyutil"
+	"k8s.io/client-go/util/retry"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// keys and values that are generated by name, and bcrypt hashes, are persisted per app in a kotsadm-owned secret, so
+// that the functions return the same key when they are called again with the same name, including after kotsadm
+// restarts and in cli runs. the secret is deleted when the app is removed. they are cached for the lifetime of the
+// process. without an app or access to the cluster, they are only kept in the cache, and a new key is generated by
+// the next process.
+var (
+	generatedMu     sync.Mutex
+	sshKeyMap       = map[string]sshKeyPair{}
+	jwtKeyMap       = map[string]*rsa.PrivateKey{}
+	generateOnceMap = map[string]string{}
+	bcryptHashMap   = map[string]string{}
+)
+
+const (
+	// jwtKeyBits is the size of the RSA keys that JWTs are signed with
+	jwtKeyBits = 2048
+
+	// the keys of the generated values secret, each of which holds a json map of name to value
+	generatedSSHKeysKey      = "sshKeys"
+	generatedJWTKeysKey      = "jwtKeys"
+	generatedGenerateOnceKey = "generateOnce"
+	// the bcrypt hashes are the names, since they can only be matched to a password by comparing them with it
+	generatedBcryptHashesKey = "bcryptHashes"
+
+	// maxGeneratedBcryptHashes is the number of bcrypt hashes that are kept per app
+	maxGeneratedBcryptHashes = 10
+)
+
+// generatedValuesSecretName is the secret in the kotsadm namespace that the generated keys and values of the app are persisted in
+func generatedValuesSecretName(appSlug string) string {
+	return fmt.Sprintf("kotsadm-%s-generated-values", appSlug)
+}
+
+// getGeneratedValue returns the value that was persisted for the app under the key of the secret and the name
+func (ctx StaticCtx) getGeneratedValue(secretKey string, name string) (string, bool, error) {
+	values, err := ctx.getGeneratedValues(secretKey)
+	if err != nil {
+		return "", false, err
+	}
+	value, ok := values[name]
+	return value, ok, nil
+}
+
+// getGeneratedValues returns the values that were persisted for the app under the key of the secret
+func (ctx StaticCtx) getGeneratedValues(secretKey string) (map[string]string, error) {
+	if ctx.appSlug == "" || ctx.eval {
+		return map[string]string{}, nil
+	}
+
+	clientset, err := ctx.getClientset()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get clientset")
+	}
+
+	secret, err := clientset.CoreV1().Secrets(util.PodNamespace).Get(context.TODO(), generatedValuesSecretName(ctx.appSlug), metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get secret")
+	}
+
+	values := map[string]string{}
+	if data, ok := secret.Data[secretKey]; ok {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, errors.Wrapf(err, "failed to unmarshal %s", secretKey)
+		}
+	}
+	return values, nil
+}
+
+// setGeneratedValue persists the value for the app under the key of the secret and the name
+func (ctx StaticCtx) setGeneratedValue(secretKey string, name string, value string) error {
+	return ctx.updateGeneratedValues(secretKey, func(values map[string]string) {
+		values[name] = value
+	})
+}
+
+// updateGeneratedValues persists the values for the app under the key of the secret after they are updated
+func (ctx StaticCtx) updateGeneratedValues(secretKey string, update func(values map[string]string)) error {
+	if ctx.appSlug == "" || ctx.eval {
+		return nil
+	}
+
+	clientset, err := ctx.getClientset()
+	if err != nil {
+		return errors.Wrap(err, "failed to get clientset")
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secrets := clientset.CoreV1().Secrets(util.PodNamespace)
+
+		secret, err := secrets.Get(context.TODO(), generatedValuesSecretName(ctx.appSlug), metav1.GetOptions{})
+		exists := err == nil
+		if kuberneteserrors.IsNotFound(err) {
+			secret = &corev1.Secret{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: "v1",
+					Kind:       "Secret",
+				},
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      generatedValuesSecretName(ctx.appSlug),
+					Namespace: util.PodNamespace,
+					Labels:    kotsadmtypes.GetKotsadmLabels(),
+				},
+			}
+		} else if err != nil {
+			return errors.Wrap(err, "failed to get secret")
+		}
+
+		values := map[string]string{}
+		if data, ok := secret.Data[secretKey]; ok {
+			if err := json.Unmarshal(data, &values); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal %s", secretKey)
+			}
+		}
+		update(values)
+
+		data, err := json.Marshal(values)
+		if err != nil {
+			return errors.Wrapf(err, "failed to marshal %s", secretKey)
+		}
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[secretKey] = data
+
+		if exists {
+			_, err = secrets.Update(context.TODO(), secret, metav1.UpdateOptions{})
+		} else {
+			_, err = secrets.Create(context.TODO(), secret, metav1.CreateOptions{})
+		}
+		return err
+	})
+}
+
+// DeleteGeneratedValues deletes the keys and values that were generated for the app, when the app is removed
+func DeleteGeneratedValues(clientset kubernetes.Interface, appSlug string) error {
+	err := clientset.CoreV1().Secrets(util.PodNamespace).Delete(context.TODO(), generatedValuesSecretName(appSlug), metav1.DeleteOptions{})
+	if err != nil && !kuberneteserrors.IsNotFound(err) {
+		return errors.Wrap(err, "failed to delete secret")
+	}
+	return nil
+}
+
+func (ctx StaticCtx) generatedCacheKey(name string) string {
+	return fmt.Sprintf("%s/%s", ctx.appSlug, name)
+}
+
+// bcryptHash returns a bcrypt hash of the password. hashes are salted, so a hash that was generated for the password
+// before is returned instead of a new one, so that rendering a new version does not change the manifests it is in.
+func (ctx StaticCtx) bcryptHash(password string, args ...int) (string, error) {
+	cost := bcrypt.DefaultCost
+	if len(args) > 0 {
+		cost = args[0]
+	}
+
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	// the cache is only kept in memory, since a fast hash of the password would weaken the persisted bcrypt hashes
+	cacheKey := ctx.generatedCacheKey(fmt.Sprintf("%d/%x", cost, sha256.Sum256([]byte(password))))
+	if hash, ok := bcryptHashMap[cacheKey]; ok {
+		return hash, nil
+	}
+
+	persisted, err := ctx.getGeneratedValues(generatedBcryptHashesKey)
+	if err != nil {
+		fmt.Printf("Failed to get generated bcrypt hashes: %v\n", err)
+	}
+	for hash := range persisted {
+		if hashCost, err := bcrypt.Cost([]byte(hash)); err != nil || hashCost != cost {
+			continue
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			bcryptHashMap[cacheKey] = hash
+			return hash, nil
+		}
+	}
+
+	generated, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to generate bcrypt hash")
+	}
+	hash := string(generated)
+
+	// the hashes are kept with the time they were generated, and only the newest are kept so that finding the hash of
+	// a password does not get slower as passwords change
+	err = ctx.updateGeneratedValues(generatedBcryptHashesKey, func(values map[string]string) {
+		values[hash] = time.Now().UTC().Format(time.RFC3339Nano)
+		for len(values) > maxGeneratedBcryptHashes {
+			oldest := ""
+			for h, generatedAt := range values {
+				if oldest == "" || generatedAt < values[oldest] {
+					oldest = h
+				}
+			}
+			delete(values, oldest)
+		}
+	})
+	if err != nil {
+		fmt.Printf("Failed to persist generated bcrypt hash: %v\n", err)
+	}
+	bcryptHashMap[cacheKey] = hash
+	return hash, nil
+}
+
+// htpasswd returns an htpasswd entry for the user with a bcrypt hash of the password
+func (ctx StaticCtx) htpasswd(username string, password string) (string, error) {
+	if strings.Contains(username, ":") {
+		return "", errors.New("username must not contain a colon")
+	}
+
+	hash, err := ctx.bcryptHash(password)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s:%s", username, hash), nil
+}
+
+// sshKeyPair is an ed25519 key pair in the OpenSSH formats. the private key is marshalled once because the
+// OpenSSH format includes a random check value, which would change the output every time.
+type sshKeyPair struct {
+	PrivateKey string
+	PublicKey  string
+}
+
+// getSSHKeyPair returns the named key pair, generating it on first use
+func (ctx StaticCtx) getSSHKeyPair(name string) (sshKeyPair, error) {
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	cacheKey := ctx.generatedCacheKey(name)
+	if p, ok := sshKeyMap[cacheKey]; ok {
+		return p, nil
+	}
+
+	persisted, ok, err := ctx.getGeneratedValue(generatedSSHKeysKey, name)
+	if err != nil {
+		fmt.Printf("Failed to get generated ssh key %s: %v\n", name, err)
+	}
+	if ok {
+		p, err := sshKeyPairFromPrivateKey(name, persisted)
+		if err != nil {
+			return sshKeyPair{}, errors.Wrap(err, "failed to parse generated key")
+		}
+		sshKeyMap[cacheKey] = p
+		return p, nil
+	}
+
+	p, err := generateSSHKeyPair(name)
+	if err != nil {
+		return sshKeyPair{}, err
+	}
+	if err := ctx.setGeneratedValue(generatedSSHKeysKey, name, p.PrivateKey); err != nil {
+		fmt.Printf("Failed to persist generated ssh key %s: %v\n", name, err)
+	}
+	sshKeyMap[cacheKey] = p
+	return p, nil
+}
+
+func generateSSHKeyPair(name string) (sshKeyPair, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return sshKeyPair{}, errors.Wrap(err, "failed to generate key")
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, name)
+	if err != nil {
+		return sshKeyPair{}, errors.Wrap(err, "failed to marshal private key")
+	}
+
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return sshKeyPair{}, errors.Wrap(err, "failed to create public key")
+	}
+
+	return sshKeyPair{
+		PrivateKey: string(pem.EncodeToMemory(block)),
+		PublicKey:  authorizedKey(sshPublicKey, name),
+	}, nil
+}
+
+func sshKeyPairFromPrivateKey(name string, privateKey string) (sshKeyPair, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		return sshKeyPair{}, errors.Wrap(err, "failed to parse private key")
+	}
+	return sshKeyPair{
+		PrivateKey: privateKey,
+		PublicKey:  authorizedKey(signer.PublicKey(), name),
+	}, nil
+}
+
+// authorizedKey returns the public key in the authorized_keys format, with the name as the comment
+func authorizedKey(publicKey ssh.PublicKey, name string) string {
+	return fmt.Sprintf("%s %s", strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))), name)
+}
+
+// sshPrivateKey returns the named ed25519 key in the OpenSSH private key format
+func (ctx StaticCtx) sshPrivateKey(name string) (string, error) {
+	p, err := ctx.getSSHKeyPair(name)
+	if err != nil {
+		return "", err
+	}
+	return p.PrivateKey, nil
+}
+
+// sshPublicKey returns the public key of the named ed25519 key in the authorized_keys format
+func (ctx StaticCtx) sshPublicKey(name string) (string, error) {
+	p, err := ctx.getSSHKeyPair(name)
+	if err != nil {
+		return "", err
+	}
+	return p.PublicKey, nil
+}
+
+func (ctx StaticCtx) getJWTKey(name string) (*rsa.PrivateKey, error) {
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	cacheKey := ctx.generatedCacheKey(name)
+	if key, ok := jwtKeyMap[cacheKey]; ok {
+		return key, nil
+	}
+
+	persisted, ok, err := ctx.getGeneratedValue(generatedJWTKeysKey, name)
+	if err != nil {
+		fmt.Printf("Failed to get generated jwt key %s: %v\n", name, err)
+	}
+	if ok {
+		key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(persisted))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse generated key")
+		}
+		jwtKeyMap[cacheKey] = key
+		return key, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, jwtKeyBits)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to generate key")
+	}
+	keyPEM, err := marshalJWTPrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.setGeneratedValue(generatedJWTKeysKey, name, keyPEM); err != nil {
+		fmt.Printf("Failed to persist generated jwt key %s: %v\n", name, err)
+	}
+	jwtKeyMap[cacheKey] = key
+	return key, nil
+}
+
+func marshalJWTPrivateKey(key *rsa.PrivateKey) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal private key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+// jwtSign returns a JWT with the claims that is signed with RS256 by the named key, generating the key on first use
+func (ctx StaticCtx) jwtSign(name string, claims map[string]interface{}) (string, error) {
+	key, err := ctx.getJWTKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims(claims)).SignedString(key)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign token")
+	}
+	return signed, nil
+}
+
+// jwtPrivateKey returns the named JWT signing key in the PKCS#8 PEM format
+func (ctx StaticCtx) jwtPrivateKey(name string) (string, error) {
+	key, err := ctx.getJWTKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	return marshalJWTPrivateKey(key)
+}
+
+// jwtPublicKey returns the public key of the named JWT signing key in the PEM format, to verify the tokens with
+func (ctx StaticCtx) jwtPublicKey(name string) (string, error) {
+	key, err := ctx.getJWTKey(name)
+	if err != nil {
+		return "", err
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", errors.Wrap(err, "failed to marshal public key")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// pkcs12Bundle returns a base64 encoded PKCS#12 bundle of the PEM encoded cert and key, and optionally CA certs,
+// protected by the password
+func (ctx StaticCtx) pkcs12Bundle(certData string, keyData string, password string, args ...string) (string, error) {
+	certs, err := certUtil.ParseCertsPEM([]byte(certData))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse cert")
+	}
+
+	key, err := keyutil.ParsePrivateKeyPEM([]byte(keyData))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse key")
+	}
+
+	// certs that follow the leaf cert are included as CA certs, like the CA certs that are passed in
+	caCerts := certs[1:]
+	if len(args) > 0 && args[0] != "" {
+		parsed, err := certUtil.ParseCertsPEM([]byte(args[0]))
+		if err != nil {
+			return "", errors.Wrap(err, "failed to parse ca cert")
+		}
+		caCerts = append(caCerts, parsed...)
+	}
+
+	bundle, err := pkcs12.Modern.Encode(key, certs[0], caCerts, password)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encode pkcs12 bundle")
+	}
+	return base64.StdEncoding.EncodeToString(bundle), nil
+}
+
+func (ctx StaticCtx) sha256Sum(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+func (ctx StaticCtx) hmacSHA256(key string, value string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (ctx StaticCtx) newUUID() string {
+	return uuid.NewString()
+}
+
+// generateOnce returns the value of the key in the secret in the app namespace if it exists, and otherwise the
+// value that is passed in. the first value that is passed in for a secret and key is persisted with the other
+// generated values of the app, so that all templates that use it agree until the secret is deployed with it.
+func (ctx StaticCtx) generateOnce(secretName string, key string, value string) string {
+	namespace := util.AppNamespace()
+	name := fmt.Sprintf("%s/%s/%s", namespace, secretName, key)
+	cacheKey := ctx.generatedCacheKey(name)
+
+	existing, err := ctx.getSecretValue(namespace, secretName, key)
+	if err != nil {
+		fmt.Printf("Failed to get value of key %s in secret %s: %v\n", key, secretName, err)
+	}
+
+	generatedMu.Lock()
+	defer generatedMu.Unlock()
+
+	if existing != "" {
+		generateOnceMap[cacheKey] = existing
+		return existing
+	}
+	if generated, ok := generateOnceMap[cacheKey]; ok {
+		return generated
+	}
+
+	persisted, ok, err := ctx.getGeneratedValue(generatedGenerateOnceKey, name)
+	if err != nil {
+		fmt.Printf("Failed to get generated value of key %s in secret %s: %v\n", key, secretName, err)
+	}
+	if ok {
+		generateOnceMap[cacheKey] = persisted
+		return persisted
+	}
+
+	if err := ctx.setGeneratedValue(generatedGenerateOnceKey, name, value); err != nil {
+		fmt.Printf("Failed to persist generated value of key %s in secret %s: %v\n", key, secretName, err)
+	}
+	generateOnceMap[cacheKey] = value
+	return value
+}
+
+func (ctx StaticCtx) getSecretValue(namespace string, secretName string, key string) (string, error) {
+	clientset, err := ctx.getClientset()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get clientset")
+	}
+
+	secret, err := clientset.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+	if kuberneteserrors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrap(err, "failed to get secret")
+	}
+
+	return string(secret.Data[key]), nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"sort"
 	"strings"
 	"text/template"
 
@@ -207,6 +208,10 @@ func (d *depGraph) GetHeadNodes() ([]string, error) {
 	}
 
 	if len(headNodes) == 0 && len(d.Dependencies) != 0 {
+		if cycle := d.findCycle(); cycle != nil {
+			return headNodes, &DependencyCycleError{Cycle: cycle}
+		}
+
 		waitList := []string{}
 		for k, v := range d.Dependencies {
 			depsList := []string{}
@@ -222,6 +227,74 @@ func (d *depGraph) GetHeadNodes() ([]string, error) {
 	return headNodes, nil
 }
 
+// DependencyCycleError is returned when config items depend on each other, so none of them can be rendered first
+type DependencyCycleError struct {
+	// Cycle is the path of config items in the cycle. it starts and ends with the same item.
+	Cycle []string `json:"cycle"`
+}
+
+func (e *DependencyCycleError) Error() string {
+	quoted := []string{}
+	for _, item := range e.Cycle {
+		quoted = append(quoted, fmt.Sprintf("%q", item))
+	}
+	return fmt.Sprintf("config items have a dependency cycle: %s", strings.Join(quoted, " -> "))
+}
+
+// findCycle returns the first dependency cycle in the graph, visiting the nodes in order so that the same
+// cycle is always reported. dependencies on items that are not in the graph are not followed.
+func (d *depGraph) findCycle() []string {
+	nodes := []string{}
+	for node := range d.Dependencies {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+
+	visited := map[string]bool{}
+	onPath := map[string]int{}
+	path := []string{}
+
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		if idx, ok := onPath[node]; ok {
+			return append(append([]string{}, path[idx:]...), node)
+		}
+		if visited[node] {
+			return nil
+		}
+		visited[node] = true
+
+		onPath[node] = len(path)
+		path = append(path, node)
+
+		deps := []string{}
+		for dep := range d.Dependencies[node] {
+			if _, ok := d.Dependencies[dep]; ok {
+				deps = append(deps, dep)
+			}
+		}
+		sort.Strings(deps)
+
+		for _, dep := range deps {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+
+		path = path[:len(path)-1]
+		delete(onPath, node)
+		return nil
+	}
+
+	for _, node := range nodes {
+		if cycle := visit(node); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
 func (d *depGraph) PrintData() string {
 	return fmt.Sprintf("deps: %+v", d.Dependencies)
 }
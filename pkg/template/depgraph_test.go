@@ -558,3 +558,27 @@ func createConfigGroups(itemNames []string, itemValue map[string]string, itemDef
 
 	return groups
 }
+
+func TestDepGraphCycle(t *testing.T) {
+	graph := depGraph{}
+	graph.AddDep("alpha", "bravo")
+	graph.AddDep("bravo", "charlie")
+	graph.AddDep("charlie", "delta")
+	graph.AddDep("delta", "bravo")
+	graph.AddDep("echo", "echo")
+
+	_, err := graph.GetHeadNodes()
+	require.Error(t, err)
+
+	cycleErr, ok := err.(*DependencyCycleError)
+	require.True(t, ok, "error is not a dependency cycle error: %v", err)
+	require.Equal(t, []string{"bravo", "charlie", "delta", "bravo"}, cycleErr.Cycle)
+	require.EqualError(t, err, `config items have a dependency cycle: "bravo" -> "charlie" -> "delta" -> "bravo"`)
+
+	graph.ResolveDep("alpha")
+	graph.ResolveDep("bravo")
+	graph.ResolveDep("charlie")
+	graph.ResolveDep("delta")
+	_, err = graph.GetHeadNodes()
+	require.EqualError(t, err, `config items have a dependency cycle: "echo" -> "echo"`)
+}
@@ -0,0 +1,122 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateName is the name of the go templates created by RenderTemplate. the name of the template that is
+// rendered is not used because it is often the template text itself, which makes the errors unreadable.
+const templateName = "template"
+
+var (
+	templateErrorRegexp     = regexp.MustCompile(`(?s)^template: ` + regexp.QuoteMeta(templateName) + `:(\d+)(?::(\d+))?: (.*)$`)
+	templateExecutingRegexp = regexp.MustCompile(`(?s)^executing "` + regexp.QuoteMeta(templateName) + `" at <.*?>: (.*)$`)
+)
+
+// TemplateError is an error parsing or executing a template, with the location of the expression that failed
+type TemplateError struct {
+	// Path is the file or config item the template was read from, if known
+	Path string `json:"path,omitempty"`
+	// Line and Column are the 1-based position of the failing expression in the template. Column is 0 if unknown.
+	Line   int `json:"line"`
+	Column int `json:"column,omitempty"`
+	// Expression is the template action that failed, such as repl{{ ConfigOption "hostname" }}
+	Expression string `json:"expression,omitempty"`
+	Message    string `json:"message"`
+}
+
+func (e *TemplateError) Error() string {
+	location := fmt.Sprintf("line %d", e.Line)
+	if e.Column > 0 {
+		location = fmt.Sprintf("%s, column %d", location, e.Column)
+	}
+	if e.Path != "" {
+		location = fmt.Sprintf("%s:%d", e.Path, e.Line)
+		if e.Column > 0 {
+			location = fmt.Sprintf("%s:%d", location, e.Column)
+		}
+	}
+
+	if e.Expression == "" {
+		return fmt.Sprintf("%s: %s", location, e.Message)
+	}
+	return fmt.Sprintf("%s: %s in %s", location, e.Message, e.Expression)
+}
+
+// newTemplateError converts an error from the go template package to a TemplateError. text is the template that
+// was rendered and curText is the text that failed, which differs from text when a previous delimiter has
+// been rendered already. errors that do not have a location are returned as is.
+func newTemplateError(err error, path string, text string, curText string, leftDelim string, rightDelim string) error {
+	matches := templateErrorRegexp.FindStringSubmatch(err.Error())
+	if matches == nil {
+		return err
+	}
+
+	line, _ := strconv.Atoi(matches[1])
+	column := -1 // the go template package reports a 0-based byte offset
+	if matches[2] != "" {
+		column, _ = strconv.Atoi(matches[2])
+	}
+
+	message := matches[3]
+	if m := templateExecutingRegexp.FindStringSubmatch(message); m != nil {
+		message = m[1]
+	}
+
+	templateErr := &TemplateError{
+		Path:    path,
+		Line:    line,
+		Message: message,
+	}
+	if column >= 0 {
+		templateErr.Column = column + 1
+	}
+
+	lines := strings.Split(curText, "\n")
+	if line < 1 || line > len(lines) {
+		return templateErr
+	}
+	expression, start := findExpression(lines[line-1], column, leftDelim, rightDelim)
+	if expression == "" {
+		return templateErr
+	}
+	templateErr.Expression = expression
+	if column < 0 {
+		templateErr.Column = start + 1
+	}
+
+	// the lines of the rendered text can differ from the original template, so the location is
+	// taken from the original template if the expression can be found there
+	if curText != text {
+		if idx := strings.Index(text, expression); idx != -1 {
+			offset := templateErr.Column - 1 - start
+			templateErr.Line = strings.Count(text[:idx], "\n") + 1
+			templateErr.Column = idx - (strings.LastIndex(text[:idx], "\n") + 1) + offset + 1
+		}
+	}
+
+	return templateErr
+}
+
+// findExpression returns the template action on the line that contains the column, or the first action on the line
+// if the column is unknown, and the offset it starts at. actions that span multiple lines are not found.
+func findExpression(line string, column int, leftDelim string, rightDelim string) (string, int) {
+	start := strings.Index(line, leftDelim)
+	if column >= 0 && column <= len(line) {
+		start = strings.LastIndex(line[:column], leftDelim)
+	}
+	if start == -1 {
+		return "", -1
+	}
+
+	end := strings.Index(line[start+len(leftDelim):], rightDelim)
+	if end == -1 {
+		// the action is not terminated on this line, which is often the error itself
+		return strings.TrimSpace(line[start:]), start
+	}
+
+	return line[start : start+len(leftDelim)+end+len(rightDelim)], start
+}
@@ -0,0 +1,103 @@
+package template
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderTemplateErrors(t *testing.T) {
+	builder := Builder{
+		Ctx: []Ctx{StaticCtx{}, testContext{}},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		template string
+		want     *TemplateError
+		wantErr  string
+	}{
+		{
+			name:     "execution error",
+			path:     "manifests/deployment.yaml",
+			template: "kind: Deployment\nspec:\n  replicas: repl{{ index \"\" 2 }}\n",
+			want: &TemplateError{
+				Path:       "manifests/deployment.yaml",
+				Line:       3,
+				Column:     20,
+				Expression: `repl{{ index "" 2 }}`,
+				Message:    "error calling index: index out of range: 2",
+			},
+			wantErr: `failed to execute template: manifests/deployment.yaml:3:20: error calling index: index out of range: 2 in repl{{ index "" 2 }}`,
+		},
+		{
+			name:     "parse error",
+			path:     "manifests/configmap.yaml",
+			template: "data:\n  a: b\n  c: '{{repl NotAFunction \"x\" }}'\n",
+			want: &TemplateError{
+				Path:       "manifests/configmap.yaml",
+				Line:       3,
+				Column:     7,
+				Expression: `{{repl NotAFunction "x" }}`,
+				Message:    `function "NotAFunction" not defined`,
+			},
+			wantErr: `failed to get template: manifests/configmap.yaml:3:7: function "NotAFunction" not defined in {{repl NotAFunction "x" }}`,
+		},
+		{
+			name:     "unterminated expression",
+			path:     "manifests/configmap.yaml",
+			template: "data:\n  a: '{{repl ConfigOption \"test}}'\n",
+			want: &TemplateError{
+				Path:       "manifests/configmap.yaml",
+				Line:       2,
+				Column:     7,
+				Expression: `{{repl ConfigOption "test}}`,
+				Message:    "unterminated quoted string",
+			},
+		},
+		{
+			name:     "error after the other delimiter was rendered",
+			path:     "values.yaml",
+			template: "a: '{{repl ToUpper \"a\\nb\" }}'\nb: repl{{ ConfigOption }}\n",
+			want: &TemplateError{
+				Path:       "values.yaml",
+				Line:       2,
+				Column:     11,
+				Expression: `repl{{ ConfigOption }}`,
+				Message:    "wrong number of args for ConfigOption: want 1 got 0",
+			},
+		},
+		{
+			name:     "template used as the name",
+			template: `repl{{ ConfigOption }}`,
+			want: &TemplateError{
+				Line:       1,
+				Column:     8,
+				Expression: `repl{{ ConfigOption }}`,
+				Message:    "wrong number of args for ConfigOption: want 1 got 0",
+			},
+			wantErr: `failed to execute template: line 1, column 8: wrong number of args for ConfigOption: want 1 got 0 in repl{{ ConfigOption }}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name := tt.path
+			if name == "" {
+				name = tt.template
+			}
+
+			_, err := builder.RenderTemplate(name, tt.template)
+			require.Error(t, err)
+
+			var templateErr *TemplateError
+			require.True(t, errors.As(err, &templateErr), "error is not a template error: %v", err)
+			assert.Equal(t, tt.want, templateErr)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
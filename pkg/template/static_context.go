@@ -48,8 +48,25 @@ type Ctx interface {
 type StaticCtx struct {
 	// a new clientset will be initialized if nil
 	clientset kubernetes.Interface
+	// appSlug is the app that generated keys and values are persisted for. they are not persisted if it's empty.
+	appSlug string
+	// eval refuses cluster access and the functions that persist generated values, see BuilderOptions.Eval
+	eval bool
 }
 
+// evalRefusedFuncs are the functions that read from the cluster or persist generated values
+var evalRefusedFuncs = []string{
+	"Lookup",
+	"GenerateOnce",
+	"SSHPrivateKey",
+	"SSHPublicKey",
+	"JWTSign",
+	"JWTPrivateKey",
+	"JWTPublicKey",
+}
+
+var errEvalClusterAccess = errors.New("cluster access is not available when evaluating templates")
+
 type TLSPair struct {
 	Cert string
 	Key  string
@@ -93,6 +110,19 @@ func (ctx StaticCtx) FuncMap() template.FuncMap {
 	funcMap["TLSCertFromCA"] = ctx.tlsCertFromCa
 	funcMap["TLSKeyFromCA"] = ctx.tlsKeyFromCa
 
+	funcMap["BcryptHash"] = ctx.bcryptHash
+	funcMap["Htpasswd"] = ctx.htpasswd
+	funcMap["SSHPrivateKey"] = ctx.sshPrivateKey
+	funcMap["SSHPublicKey"] = ctx.sshPublicKey
+	funcMap["JWTSign"] = ctx.jwtSign
+	funcMap["JWTPrivateKey"] = ctx.jwtPrivateKey
+	funcMap["JWTPublicKey"] = ctx.jwtPublicKey
+	funcMap["PKCS12"] = ctx.pkcs12Bundle
+	funcMap["SHA256Sum"] = ctx.sha256Sum
+	funcMap["HMACSHA256"] = ctx.hmacSHA256
+	funcMap["UUID"] = ctx.newUUID
+	funcMap["GenerateOnce"] = ctx.generateOnce
+
 	funcMap["KotsVersion"] = ctx.kotsVersion
 	funcMap["IsKurl"] = ctx.isKurl
 	funcMap["Distribution"] = ctx.distribution
@@ -112,6 +142,15 @@ func (ctx StaticCtx) FuncMap() template.FuncMap {
 
 	funcMap["PrivateCACert"] = ctx.privateCACert
 
+	if ctx.eval {
+		for _, name := range evalRefusedFuncs {
+			name := name
+			funcMap[name] = func(args ...interface{}) (string, error) {
+				return "", errors.Errorf("%s is not available when evaluating templates", name)
+			}
+		}
+	}
+
 	return funcMap
 }
 
@@ -647,6 +686,9 @@ func getK8sServerVersion(clientset kubernetes.Interface) (*k8sversion.Info, erro
 }
 
 func (ctx StaticCtx) getClientset() (kubernetes.Interface, error) {
+	if ctx.eval {
+		return nil, errEvalClusterAccess
+	}
 	if ctx.clientset != nil {
 		return ctx.clientset, nil
 	}
@@ -665,6 +707,10 @@ func indent(spaces int, v string) string {
 
 // use the lookup function from helm to mimic the behavior of the lookup function in helm.
 func (ctx StaticCtx) lookup(apiversion string, resource string, namespace string, name string) map[string]interface{} {
+	if ctx.clientset != nil {
+		// like helm template, nothing is looked up when the templates are not rendered against the current cluster
+		return map[string]interface{}{}
+	}
 	config, err := k8sutil.GetClusterConfig()
 	if err != nil {
 		fmt.Printf("Failed to get cluster config: %v\n", err)
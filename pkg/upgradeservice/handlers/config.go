@@ -179,6 +179,14 @@ func (h *Handler) LiveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(params.AppArchive, "upstream"))
+	if err != nil {
+		response.Error = "failed to find config validations"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
 	registrySettings := registrytypes.RegistrySettings{
 		Hostname:   params.RegistryEndpoint,
 		Username:   params.RegistryUsername,
@@ -202,7 +210,7 @@ func (h *Handler) LiveConfig(w http.ResponseWriter, r *http.Request) {
 
 	response.ConfigGroups = []kotsv1beta1.ConfigGroup{}
 	if renderedConfig != nil {
-		validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec)
+		validationErrors, err := configvalidation.ValidateConfigSpec(renderedConfig.Spec, configSpecExtras.Validations)
 		if err != nil {
 			response.Error = "failed to validate config spec"
 			logger.Error(errors.Wrap(err, response.Error))
@@ -283,7 +291,15 @@ func (h *Handler) SaveConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	validationErrors, err := configvalidation.ValidateConfigSpec(kotsv1beta1.ConfigSpec{Groups: request.ConfigGroups})
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(params.AppArchive, "upstream"))
+	if err != nil {
+		response.Error = "failed to find config validations"
+		logger.Error(errors.Wrap(err, response.Error))
+		JSON(w, http.StatusInternalServerError, response)
+		return
+	}
+
+	validationErrors, err := configvalidation.ValidateConfigSpec(kotsv1beta1.ConfigSpec{Groups: request.ConfigGroups}, configSpecExtras.Validations)
 	if err != nil {
 		response.Error = "failed to validate config spec."
 		logger.Error(errors.Wrap(err, response.Error))
@@ -341,6 +357,7 @@ func (h *Handler) SaveConfig(w http.ResponseWriter, r *http.Request) {
 
 	values := kotsKinds.ConfigValues.Spec.Values
 	kotsKinds.ConfigValues.Spec.Values = kotsadmconfig.UpdateAppConfigValues(values, request.ConfigGroups)
+	kotsKinds.ConfigValues.Spec.Values = kotsadmconfig.RemoveReferencedConfigValues(kotsKinds.ConfigValues.Spec.Values, kotsKinds.ConfigValueReferences)
 
 	configValuesSpec, err := kotsKinds.Marshal("kots.io", "v1beta1", "ConfigValues")
 	if err != nil {
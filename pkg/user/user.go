@@ -4,9 +4,12 @@ import (
 	"sync"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/kots/pkg/k8sutil"
 	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/mfa"
 	"github.com/replicatedhq/kots/pkg/store"
 	usertypes "github.com/replicatedhq/kots/pkg/user/types"
+	"github.com/replicatedhq/kots/pkg/util"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -14,9 +17,13 @@ var (
 	loginMutex         sync.Mutex
 	ErrInvalidPassword = errors.New("invalid password")
 	ErrTooManyAttempts = errors.New("too many attempts")
+	ErrMFARequired     = errors.New("mfa code required")
+	ErrInvalidMFACode  = errors.New("invalid mfa code")
 )
 
-func LogIn(password string) (*usertypes.User, error) {
+// LogIn validates the password and, if multi-factor authentication is enabled, the mfa code.
+// an invalid mfa code counts as a failed login attempt the same as an invalid password.
+func LogIn(password string, mfaCode string) (*usertypes.User, error) {
 	loginMutex.Lock()
 	defer loginMutex.Unlock()
 
@@ -41,6 +48,15 @@ func LogIn(password string) (*usertypes.User, error) {
 		return nil, errors.Wrap(err, "failed to compare password")
 	}
 
+	if err := verifyMFA(mfaCode); err != nil {
+		if err == ErrInvalidMFACode {
+			if err := store.GetStore().FlagInvalidPassword(); err != nil {
+				logger.Infof("failed to flag failed login: %v", err)
+			}
+		}
+		return nil, err
+	}
+
 	if err := store.GetStore().FlagSuccessfulLogin(); err != nil {
 		logger.Error(errors.Wrap(err, "failed to flag successful login"))
 	}
@@ -49,3 +65,31 @@ func LogIn(password string) (*usertypes.User, error) {
 		ID: "000000",
 	}, nil
 }
+
+func verifyMFA(mfaCode string) error {
+	clientset, err := k8sutil.GetClientset()
+	if err != nil {
+		return errors.Wrap(err, "failed to get k8s clientset")
+	}
+
+	enabled, err := mfa.IsEnabled(clientset, util.PodNamespace)
+	if err != nil {
+		return errors.Wrap(err, "failed to check if mfa is enabled")
+	}
+	if !enabled {
+		return nil
+	}
+
+	if mfaCode == "" {
+		return ErrMFARequired
+	}
+
+	if err := mfa.Verify(clientset, util.PodNamespace, mfaCode); err != nil {
+		if errors.Is(err, mfa.ErrInvalidCode) {
+			return ErrInvalidMFACode
+		}
+		return errors.Wrap(err, "failed to verify mfa code")
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package versionretention
+
+import (
+	"time"
+
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+)
+
+// Policy describes which app versions are retained when garbage collecting old versions.
+// the currently deployed version, pending versions and rollback targets are always retained.
+type Policy struct {
+	// KeepCount is the number of most recent versions to retain
+	KeepCount int
+	// KeepDays retains versions that have been deployed within this number of days
+	KeepDays int
+}
+
+func PolicyFromInstallationParams(params kotsutil.InstallationParams) Policy {
+	return Policy{
+		KeepCount: params.VersionRetentionCount,
+		KeepDays:  params.VersionRetentionDays,
+	}
+}
+
+// IsEnabled returns false if no retention limits are configured, in which case all versions are retained
+func (p Policy) IsEnabled() bool {
+	return p.KeepCount > 0 || p.KeepDays > 0
+}
+
+// PrunableVersions returns the past versions that are not retained by the policy.
+// versions are expected to be sorted the way the store returns them, newest first.
+// sequences in the "protected" map are never returned.
+func PrunableVersions(versions *downstreamtypes.DownstreamVersions, protected map[int64]bool, policy Policy, now time.Time) []*downstreamtypes.DownstreamVersion {
+	if versions == nil || !policy.IsEnabled() {
+		return nil
+	}
+
+	// if nothing has been deployed yet, all versions are pending and must be kept
+	if versions.CurrentVersion == nil {
+		return nil
+	}
+
+	retained := map[int64]bool{
+		versions.CurrentVersion.Sequence: true,
+	}
+	for _, v := range versions.PendingVersions {
+		retained[v.Sequence] = true
+	}
+	for i, v := range versions.AllVersions {
+		if i >= policy.KeepCount {
+			break
+		}
+		retained[v.Sequence] = true
+	}
+
+	deployedAfter := now.AddDate(0, 0, -policy.KeepDays)
+
+	prunable := []*downstreamtypes.DownstreamVersion{}
+	for _, v := range versions.PastVersions {
+		if retained[v.Sequence] || protected[v.Sequence] {
+			continue
+		}
+		if policy.KeepDays > 0 && v.DeployedAt != nil && v.DeployedAt.After(deployedAfter) {
+			continue
+		}
+		prunable = append(prunable, v)
+	}
+
+	return prunable
+}
@@ -0,0 +1,104 @@
+package versionretention
+
+import (
+	"testing"
+	"time"
+
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrunableVersions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(days int) *time.Time {
+		t := now.AddDate(0, 0, -days)
+		return &t
+	}
+
+	// sequences 9 and 8 are pending, 7 is deployed, 6 through 0 are past versions
+	newVersions := func() *downstreamtypes.DownstreamVersions {
+		versions := &downstreamtypes.DownstreamVersions{}
+		for sequence := int64(9); sequence >= 0; sequence-- {
+			v := &downstreamtypes.DownstreamVersion{Sequence: sequence}
+			if sequence <= 7 {
+				v.DeployedAt = daysAgo(int(10 * (8 - sequence)))
+			}
+			versions.AllVersions = append(versions.AllVersions, v)
+			switch {
+			case sequence > 7:
+				versions.PendingVersions = append(versions.PendingVersions, v)
+			case sequence == 7:
+				versions.CurrentVersion = v
+			default:
+				versions.PastVersions = append(versions.PastVersions, v)
+			}
+		}
+		return versions
+	}
+
+	tests := []struct {
+		name      string
+		versions  *downstreamtypes.DownstreamVersions
+		protected map[int64]bool
+		policy    Policy
+		want      []int64
+	}{
+		{
+			name:     "no policy retains everything",
+			versions: newVersions(),
+			policy:   Policy{},
+			want:     nil,
+		},
+		{
+			name:     "keep count includes pending and current versions",
+			versions: newVersions(),
+			policy:   Policy{KeepCount: 4},
+			want:     []int64{5, 4, 3, 2, 1, 0},
+		},
+		{
+			name:     "keep count smaller than pending and current versions",
+			versions: newVersions(),
+			policy:   Policy{KeepCount: 1},
+			want:     []int64{6, 5, 4, 3, 2, 1, 0},
+		},
+		{
+			name:     "keep days retains recently deployed versions",
+			versions: newVersions(),
+			policy:   Policy{KeepDays: 35},
+			want:     []int64{4, 3, 2, 1, 0},
+		},
+		{
+			name:     "keep count and keep days are combined",
+			versions: newVersions(),
+			policy:   Policy{KeepCount: 5, KeepDays: 45},
+			want:     []int64{3, 2, 1, 0},
+		},
+		{
+			name:      "protected versions are retained",
+			versions:  newVersions(),
+			protected: map[int64]bool{6: true, 2: true},
+			policy:    Policy{KeepCount: 1},
+			want:      []int64{5, 4, 3, 1, 0},
+		},
+		{
+			name: "nothing is pruned if no version has been deployed",
+			versions: &downstreamtypes.DownstreamVersions{
+				AllVersions:     []*downstreamtypes.DownstreamVersion{{Sequence: 1}, {Sequence: 0}},
+				PendingVersions: []*downstreamtypes.DownstreamVersion{{Sequence: 1}, {Sequence: 0}},
+			},
+			policy: Policy{KeepCount: 1},
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PrunableVersions(tt.versions, tt.protected, tt.policy, now)
+
+			var gotSequences []int64
+			for _, v := range got {
+				gotSequences = append(gotSequences, v.Sequence)
+			}
+			assert.Equal(t, tt.want, gotSequences)
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package types
+
+import "time"
+
+type GarbageCollectReport struct {
+	DryRun                bool        `json:"dryRun"`
+	Apps                  []AppReport `json:"apps"`
+	TotalReclaimableBytes int64       `json:"totalReclaimableBytes"`
+}
+
+type AppReport struct {
+	AppSlug          string          `json:"appSlug"`
+	PrunedVersions   []PrunedVersion `json:"prunedVersions"`
+	ReclaimableBytes int64           `json:"reclaimableBytes"`
+}
+
+type PrunedVersion struct {
+	Sequence     int64      `json:"sequence"`
+	VersionLabel string     `json:"versionLabel"`
+	CreatedOn    *time.Time `json:"createdOn,omitempty"`
+	DeployedAt   *time.Time `json:"deployedAt,omitempty"`
+	ArchiveSize  int64      `json:"archiveSize"`
+}
@@ -0,0 +1,189 @@
+package versionretention
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	downstreamtypes "github.com/replicatedhq/kots/pkg/api/downstream/types"
+	apptypes "github.com/replicatedhq/kots/pkg/app/types"
+	"github.com/replicatedhq/kots/pkg/filestore"
+	kotsadmtypes "github.com/replicatedhq/kots/pkg/kotsadm/types"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/store"
+	"github.com/replicatedhq/kots/pkg/versionretention/types"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+)
+
+const (
+	// garbageCollectCronSpec - daily cron spec for the version garbage collection job
+	garbageCollectCronSpec = "0 2 * * *"
+)
+
+// StartGarbageCollectCronJob - start the cron job which prunes old app versions according to the retention policy
+// configured in the kotsadm config map. the job is a no-op if no retention policy is configured.
+func StartGarbageCollectCronJob() error {
+	logger.Debug("starting app version garbage collection cron job")
+
+	cronJob := cron.New(cron.WithChain(
+		cron.Recover(cron.DefaultLogger),
+	))
+
+	_, err := cronJob.AddFunc(garbageCollectCronSpec, func() {
+		installParams, err := kotsutil.GetInstallationParams(kotsadmtypes.KotsadmConfigMap)
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to get installation params"))
+			return
+		}
+
+		policy := PolicyFromInstallationParams(installParams)
+		if !policy.IsEnabled() {
+			return
+		}
+
+		logger.Debug("running app version garbage collection job")
+		report, err := GarbageCollect(policy, false)
+		if err != nil {
+			logger.Error(errors.Wrap(err, "failed to garbage collect app versions"))
+			return
+		}
+		logger.Infof("app version garbage collection reclaimed %d bytes", report.TotalReclaimableBytes)
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to add cron job")
+	}
+	cronJob.Start()
+	return nil
+}
+
+// GarbageCollect prunes the versions of all installed apps that are not retained by the policy.
+// when dryRun is true, nothing is deleted and the returned report lists what would be pruned.
+func GarbageCollect(policy Policy, dryRun bool) (*types.GarbageCollectReport, error) {
+	report := &types.GarbageCollectReport{
+		DryRun: dryRun,
+		Apps:   []types.AppReport{},
+	}
+
+	if !policy.IsEnabled() {
+		return report, nil
+	}
+
+	apps, err := store.GetStore().ListInstalledApps()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list installed apps")
+	}
+
+	for _, a := range apps {
+		appReport, err := garbageCollectApp(a, policy, dryRun)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to garbage collect versions for app %s", a.Slug)
+		}
+		report.Apps = append(report.Apps, *appReport)
+		report.TotalReclaimableBytes += appReport.ReclaimableBytes
+	}
+
+	return report, nil
+}
+
+func garbageCollectApp(a *apptypes.App, policy Policy, dryRun bool) (*types.AppReport, error) {
+	appReport := &types.AppReport{
+		AppSlug:        a.Slug,
+		PrunedVersions: []types.PrunedVersion{},
+	}
+
+	prunable, err := getPrunableVersions(a.ID, policy)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get prunable versions")
+	}
+
+	// archives can share storage, so the bytes that are freed are less than the sum of the archive sizes
+	sequences := []int64{}
+	for _, v := range prunable {
+		sequences = append(sequences, v.Sequence)
+	}
+	reclaimableBytes, err := store.GetStore().GetAppVersionArchivesReclaimableSize(a.ID, sequences)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get reclaimable archive size")
+	}
+	appReport.ReclaimableBytes = reclaimableBytes
+
+	for _, v := range prunable {
+		archiveSize, err := store.GetStore().GetAppVersionArchiveSize(a.ID, v.Sequence)
+		if err != nil && errors.Cause(err) != filestore.ErrNotFound {
+			return nil, errors.Wrapf(err, "failed to get archive size for sequence %d", v.Sequence)
+		}
+
+		if !dryRun {
+			logger.Info("pruning app version",
+				zap.String("appID", a.ID),
+				zap.Int64("sequence", v.Sequence),
+				zap.String("versionLabel", v.VersionLabel))
+			if err := store.GetStore().DeleteAppVersion(a.ID, v.Sequence); err != nil {
+				return nil, errors.Wrapf(err, "failed to delete sequence %d", v.Sequence)
+			}
+		}
+
+		appReport.PrunedVersions = append(appReport.PrunedVersions, types.PrunedVersion{
+			Sequence:     v.Sequence,
+			VersionLabel: v.VersionLabel,
+			CreatedOn:    v.CreatedOn,
+			DeployedAt:   v.DeployedAt,
+			ArchiveSize:  archiveSize,
+		})
+	}
+
+	return appReport, nil
+}
+
+// getPrunableVersions returns the versions of the app that can be pruned from every downstream
+func getPrunableVersions(appID string, policy Policy) ([]*downstreamtypes.DownstreamVersion, error) {
+	downstreams, err := store.GetStore().ListDownstreamsForApp(appID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list downstreams for app")
+	}
+	if len(downstreams) == 0 {
+		return nil, nil
+	}
+
+	var prunable []*downstreamtypes.DownstreamVersion
+	for i, d := range downstreams {
+		versions, err := store.GetStore().GetDownstreamVersions(appID, d.ClusterID, false)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get versions for downstream %s", d.ClusterID)
+		}
+
+		// the previously deployed version is the rollback target
+		previousSequence, err := store.GetStore().GetPreviouslyDeployedSequence(appID, d.ClusterID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get previously deployed sequence for downstream %s", d.ClusterID)
+		}
+		protected := map[int64]bool{
+			previousSequence: true,
+		}
+
+		downstreamPrunable := PrunableVersions(versions, protected, policy, time.Now())
+		if i == 0 {
+			prunable = downstreamPrunable
+			continue
+		}
+		prunable = intersectVersions(prunable, downstreamPrunable)
+	}
+
+	return prunable, nil
+}
+
+func intersectVersions(a []*downstreamtypes.DownstreamVersion, b []*downstreamtypes.DownstreamVersion) []*downstreamtypes.DownstreamVersion {
+	inB := map[int64]bool{}
+	for _, v := range b {
+		inB[v.Sequence] = true
+	}
+
+	result := []*downstreamtypes.DownstreamVersion{}
+	for _, v := range a {
+		if inB[v.Sequence] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
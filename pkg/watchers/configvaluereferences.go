@@ -0,0 +1,324 @@
+package watchers
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	apptypes "github.com/replicatedhq/kots/pkg/app/types"
+	"github.com/replicatedhq/kots/pkg/kotsadmconfig"
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/replicatedhq/kots/pkg/logger"
+	"github.com/replicatedhq/kots/pkg/preflight"
+	registrytypes "github.com/replicatedhq/kots/pkg/registry/types"
+	"github.com/replicatedhq/kots/pkg/render"
+	rendertypes "github.com/replicatedhq/kots/pkg/render/types"
+	"github.com/replicatedhq/kots/pkg/reporting"
+	"github.com/replicatedhq/kots/pkg/store"
+	"github.com/replicatedhq/kots/pkg/template"
+	"github.com/replicatedhq/kots/pkg/util"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// changedObject is a secret or config map whose data changed
+type changedObject struct {
+	isSecret  bool
+	namespace string
+	name      string
+	oldData   map[string][]byte
+	newData   map[string][]byte
+}
+
+// appConfigValueReferences are the value references of an app version
+type appConfigValueReferences struct {
+	sequence   int64
+	references map[string]kotsutil.ConfigValueReference
+}
+
+var (
+	// the value references of the latest version of each app by app id, so that the archive is only read
+	// when a new version is created or when a change may be referenced
+	configValueReferencesMtx   sync.Mutex
+	configValueReferencesCache = map[string]appConfigValueReferences{}
+)
+
+// watchConfigValueReferences creates a new app version when a secret or config map key that is referenced by a config
+// item with triggerUpdate changes. only references to the kotsadm namespace are watched.
+func watchConfigValueReferences(clientset kubernetes.Interface) error {
+	logger.Info("starting config value references watcher")
+
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(util.PodNamespace))
+	secretInformer := factory.Core().V1().Secrets().Informer()
+	configMapInformer := factory.Core().V1().ConfigMaps().Informer()
+
+	secretHandler, err := secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSecret := oldObj.(*corev1.Secret)
+			newSecret := newObj.(*corev1.Secret)
+			handleChangedObject(changedObject{
+				isSecret:  true,
+				namespace: newSecret.Namespace,
+				name:      newSecret.Name,
+				oldData:   oldSecret.Data,
+				newData:   newSecret.Data,
+			})
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "add secret event handler")
+	}
+
+	configMapHandler, err := configMapInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldConfigMap := oldObj.(*corev1.ConfigMap)
+			newConfigMap := newObj.(*corev1.ConfigMap)
+			handleChangedObject(changedObject{
+				isSecret:  false,
+				namespace: newConfigMap.Namespace,
+				name:      newConfigMap.Name,
+				oldData:   configMapData(oldConfigMap),
+				newData:   configMapData(newConfigMap),
+			})
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "add config map event handler")
+	}
+
+	ctx := context.Background()
+	go secretInformer.Run(ctx.Done())
+	go configMapInformer.Run(ctx.Done())
+
+	if !cache.WaitForCacheSync(ctx.Done(), secretHandler.HasSynced, configMapHandler.HasSynced) {
+		return errors.New("sync secret and config map cache")
+	}
+
+	return nil
+}
+
+func configMapData(configMap *corev1.ConfigMap) map[string][]byte {
+	data := map[string][]byte{}
+	for key, value := range configMap.BinaryData {
+		data[key] = value
+	}
+	for key, value := range configMap.Data {
+		data[key] = []byte(value)
+	}
+	return data
+}
+
+func handleChangedObject(obj changedObject) {
+	if reflect.DeepEqual(obj.oldData, obj.newData) {
+		return
+	}
+
+	apps, err := store.GetStore().ListInstalledApps()
+	if err != nil {
+		logger.Warnf("failed to list installed apps: %v", err)
+		return
+	}
+
+	for _, app := range apps {
+		if err := maybeCreateVersionForChangedObject(app, obj); err != nil {
+			logger.Warnf("failed to create version for changed config value reference for app %s: %v", app.Slug, err)
+		}
+	}
+}
+
+func maybeCreateVersionForChangedObject(app *apptypes.App, obj changedObject) error {
+	latestSequence, err := store.GetStore().GetLatestAppSequence(app.ID, true)
+	if err != nil {
+		return errors.Wrap(err, "failed to get latest app sequence")
+	}
+
+	references, err := getConfigValueReferences(app.ID, latestSequence)
+	if err != nil {
+		return errors.Wrap(err, "failed to get config value references")
+	}
+	if !mayReferenceObject(references, obj) {
+		return nil
+	}
+
+	archiveDir, err := os.MkdirTemp("", "kotsadm")
+	if err != nil {
+		return errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(archiveDir)
+
+	if err := store.GetStore().GetAppVersionArchive(app.ID, latestSequence, archiveDir); err != nil {
+		return errors.Wrap(err, "failed to get app version archive")
+	}
+
+	kotsKinds, err := kotsutil.LoadKotsKinds(archiveDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to load kots kinds from path")
+	}
+
+	registrySettings, err := store.GetStore().GetRegistryDetailsForApp(app.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get registry settings")
+	}
+
+	changed, err := isReferencedKeyChanged(kotsKinds, registrySettings, app, latestSequence, obj)
+	if err != nil {
+		return errors.Wrap(err, "failed to check referenced keys")
+	}
+	if !changed {
+		return nil
+	}
+
+	logger.Infof("config value reference %s/%s changed, creating a new version of app %s", obj.namespace, obj.name, app.Slug)
+
+	downstreams, err := store.GetStore().ListDownstreamsForApp(app.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to list downstreams for app")
+	}
+
+	nextAppSequence, err := store.GetStore().GetNextAppSequence(app.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to get next app sequence")
+	}
+
+	err = render.RenderDir(rendertypes.RenderDirOptions{
+		ArchiveDir:       archiveDir,
+		App:              app,
+		Downstreams:      downstreams,
+		RegistrySettings: registrySettings,
+		Sequence:         nextAppSequence,
+		ReportingInfo:    reporting.GetReportingInfo(app.ID),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to render archive directory")
+	}
+
+	newSequence, err := store.GetStore().CreateAppVersion(app.ID, &latestSequence, archiveDir, kotsadmconfig.ConfigReferenceChangeSource, false, false, false)
+	if err != nil {
+		return errors.Wrap(err, "failed to create an app version")
+	}
+
+	if err := preflight.Run(app.ID, app.Slug, newSequence, app.IsAirgap, false, archiveDir); err != nil {
+		return errors.Wrap(err, "failed to run preflights")
+	}
+
+	return nil
+}
+
+// getConfigValueReferences returns the value references of the app version, which are read from the archive
+// the first time that the version is seen
+func getConfigValueReferences(appID string, sequence int64) (map[string]kotsutil.ConfigValueReference, error) {
+	configValueReferencesMtx.Lock()
+	defer configValueReferencesMtx.Unlock()
+
+	if cached, ok := configValueReferencesCache[appID]; ok && cached.sequence == sequence {
+		return cached.references, nil
+	}
+
+	archiveDir, err := os.MkdirTemp("", "kotsadm")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create temp dir")
+	}
+	defer os.RemoveAll(archiveDir)
+
+	if err := store.GetStore().GetAppVersionArchive(appID, sequence, archiveDir); err != nil {
+		return nil, errors.Wrap(err, "failed to get app version archive")
+	}
+
+	configSpecExtras, err := kotsutil.FindConfigSpecExtrasInPath(filepath.Join(archiveDir, "upstream"))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to find config value references")
+	}
+
+	configValueReferencesCache[appID] = appConfigValueReferences{
+		sequence:   sequence,
+		references: configSpecExtras.ValueReferences,
+	}
+
+	return configSpecExtras.ValueReferences, nil
+}
+
+// mayReferenceObject returns true if a reference with triggerUpdate may be to the changed object. references
+// with a templated name may be to any object, which isReferencedKeyChanged checks once they're rendered.
+func mayReferenceObject(references map[string]kotsutil.ConfigValueReference, obj changedObject) bool {
+	for _, reference := range references {
+		if !reference.TriggerUpdate {
+			continue
+		}
+
+		ref := reference.ConfigMapKeyRef
+		if obj.isSecret {
+			ref = reference.SecretKeyRef
+		}
+		if ref == nil {
+			continue
+		}
+
+		if ref.Namespace != "" && ref.Namespace != obj.namespace {
+			continue
+		}
+		if ref.Name == obj.name || strings.Contains(ref.Name, "{{") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isReferencedKeyChanged returns true if a key of the changed object is referenced by a config item with triggerUpdate
+func isReferencedKeyChanged(kotsKinds *kotsutil.KotsKinds, registrySettings registrytypes.RegistrySettings, app *apptypes.App, sequence int64, obj changedObject) (bool, error) {
+	var builder *template.Builder
+	for _, reference := range kotsKinds.ConfigValueReferences {
+		if !reference.TriggerUpdate {
+			continue
+		}
+
+		ref := reference.ConfigMapKeyRef
+		if obj.isSecret {
+			ref = reference.SecretKeyRef
+		}
+		if ref == nil {
+			continue
+		}
+
+		namespace := ref.Namespace
+		if namespace == "" {
+			namespace = util.PodNamespace
+		}
+		if namespace != obj.namespace {
+			continue
+		}
+
+		// the name and key can be templated
+		if builder == nil {
+			b, err := render.NewBuilder(kotsKinds, registrySettings, app.Slug, sequence, app.IsAirgap, util.PodNamespace)
+			if err != nil {
+				return false, errors.Wrap(err, "failed to create builder")
+			}
+			builder = b
+		}
+		name, err := builder.String(ref.Name)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to render name")
+		}
+		if name != obj.name {
+			continue
+		}
+		key, err := builder.String(ref.Key)
+		if err != nil {
+			return false, errors.Wrap(err, "failed to render key")
+		}
+
+		if !bytes.Equal(obj.oldData[key], obj.newData[key]) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,68 @@
+package watchers
+
+import (
+	"testing"
+
+	"github.com/replicatedhq/kots/pkg/kotsutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_mayReferenceObject(t *testing.T) {
+	references := map[string]kotsutil.ConfigValueReference{
+		"password": {
+			SecretKeyRef:  &kotsutil.ConfigValueKeyReference{Name: "db-creds", Key: "password"},
+			TriggerUpdate: true,
+		},
+		"host": {
+			ConfigMapKeyRef: &kotsutil.ConfigValueKeyReference{Name: "db-config", Key: "host"},
+			TriggerUpdate:   false,
+		},
+		"token": {
+			SecretKeyRef:  &kotsutil.ConfigValueKeyReference{Name: `repl{{ ConfigOption "token_secret" }}`, Key: "token"},
+			TriggerUpdate: true,
+		},
+	}
+
+	tests := []struct {
+		name       string
+		references map[string]kotsutil.ConfigValueReference
+		obj        changedObject
+		want       bool
+	}{
+		{
+			name:       "referenced secret",
+			references: references,
+			obj:        changedObject{isSecret: true, namespace: "default", name: "db-creds"},
+			want:       true,
+		},
+		{
+			name:       "config map with the name of a referenced secret",
+			references: references,
+			obj:        changedObject{isSecret: false, namespace: "default", name: "db-creds"},
+			want:       false,
+		},
+		{
+			name:       "referenced config map without trigger update",
+			references: references,
+			obj:        changedObject{isSecret: false, namespace: "default", name: "db-config"},
+			want:       false,
+		},
+		{
+			name:       "secret that may be referenced by a templated name",
+			references: references,
+			obj:        changedObject{isSecret: true, namespace: "default", name: "other"},
+			want:       true,
+		},
+		{
+			name:       "no references",
+			references: map[string]kotsutil.ConfigValueReference{},
+			obj:        changedObject{isSecret: true, namespace: "default", name: "db-creds"},
+			want:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, mayReferenceObject(tt.references, tt.obj))
+		})
+	}
+}
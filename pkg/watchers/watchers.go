@@ -30,6 +30,10 @@ func Start(clusterID string) error {
 		return errors.Wrap(err, "watch embedded cluster nodes")
 	}
 
+	if err := watchConfigValueReferences(clientset); err != nil {
+		return errors.Wrap(err, "watch config value references")
+	}
+
 	return nil
 }
 